@@ -2,21 +2,25 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
+	_ "time/tzdata" // タイムゾーンの設定をコンテナでも使えるようにする
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/gigaonion/taskalyst/backend/internal/config"
 	"github.com/gigaonion/taskalyst/backend/internal/handler"
+	apimiddleware "github.com/gigaonion/taskalyst/backend/internal/handler/middleware"
 	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
 	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
 	"github.com/gigaonion/taskalyst/backend/internal/usecase"
 	"github.com/gigaonion/taskalyst/backend/pkg/customvalidator"
+	"github.com/gigaonion/taskalyst/backend/pkg/storage"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -48,26 +52,73 @@ func main() {
 	apiTokenHandler := handler.NewApiTokenHandler(apiTokenUsecase)
 	calendarUsecase := usecase.NewCalendarUsecase(repo, txManager)
 	calendarHandler := handler.NewCalendarHandler(calendarUsecase)
-	caldavUsecase := usecase.NewCalDavUsecase(repo, txManager)
+	caldavUsecase := usecase.NewCalDavUsecase(repo, txManager, cfg.PublicURL)
 	caldavHandler := handler.NewCalDavHandler(caldavUsecase, calendarUsecase)
 	resultUsecase := usecase.NewResultUsecase(repo, txManager)
 	resultHandler := handler.NewResultHandler(resultUsecase)
+	tagUsecase := usecase.NewTagUsecase(repo, txManager)
+	tagHandler := handler.NewTagHandler(tagUsecase)
+	searchUsecase := usecase.NewSearchUsecase(repo, txManager)
+	searchHandler := handler.NewSearchHandler(searchUsecase)
+	templateUsecase := usecase.NewTemplateUsecase(repo, txManager)
+	templateHandler := handler.NewTemplateHandler(templateUsecase)
+	perspectiveUsecase := usecase.NewPerspectiveUsecase(repo, txManager)
+	perspectiveHandler := handler.NewPerspectiveHandler(perspectiveUsecase)
+	quickAddUsecase := usecase.NewQuickAddUsecase(repo, txManager)
+	quickAddHandler := handler.NewQuickAddHandler(quickAddUsecase)
+	taskActivityUsecase := usecase.NewTaskActivityUsecase(repo, txManager)
+	taskActivityHandler := handler.NewTaskActivityHandler(taskActivityUsecase)
+	store, err := newStorage(cfg)
+	if err != nil {
+		log.Fatalf("failed to init storage: %v", err)
+	}
+	attachmentUsecase := usecase.NewAttachmentUsecase(repo, txManager, store, cfg.AttachmentMaxBytes)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentUsecase)
+	noteUsecase := usecase.NewNoteUsecase(repo, txManager)
+	noteHandler := handler.NewNoteHandler(noteUsecase)
+	taskBulkUsecase := usecase.NewTaskBulkUsecase(repo, txManager)
+	taskBulkHandler := handler.NewTaskBulkHandler(taskBulkUsecase)
+	trashUsecase := usecase.NewTrashUsecase(repo, txManager, store, cfg.TrashRetentionDays)
+	trashHandler := handler.NewTrashHandler(trashUsecase)
+	undoUsecase := usecase.NewUndoUsecase(repo, txManager, cfg.UndoWindow)
+	undoHandler := handler.NewUndoHandler(undoUsecase)
+	pomodoroUsecase := usecase.NewPomodoroUsecase(repo, txManager)
+	pomodoroHandler := handler.NewPomodoroHandler(pomodoroUsecase)
+	listener := db.NewListener(pool, db.EventsChannel)
+	streamUsecase := usecase.NewStreamUsecase(repo, listener)
+	streamHandler := handler.NewStreamHandler(streamUsecase)
+	heartbeatUsecase := usecase.NewHeartbeatUsecase(repo, txManager)
+	heartbeatHandler := handler.NewHeartbeatHandler(heartbeatUsecase)
+	activityUsecase := usecase.NewActivityUsecase(repo, txManager, cfg.ActivityRetentionDays)
+	activityHandler := handler.NewActivityHandler(activityUsecase)
+	billingUsecase := usecase.NewBillingUsecase(repo, txManager)
+	billingHandler := handler.NewBillingHandler(billingUsecase)
 
 	e := echo.New()
 	e.Validator = &customvalidator.CustomValidator{Validator: validator.New()} //バリデータを登録
-	handler.RegisterRoutes(e, userHandler, projectHandler, taskHandler, timeHandler, apiTokenHandler, cfg, calendarHandler, resultHandler, caldavHandler, repo)
+	handler.RegisterRoutes(e, userHandler, projectHandler, taskHandler, timeHandler, apiTokenHandler, cfg, calendarHandler, resultHandler, caldavHandler, tagHandler, searchHandler, templateHandler, perspectiveHandler, quickAddHandler, taskActivityHandler, attachmentHandler, noteHandler, taskBulkHandler, trashHandler, undoHandler, pomodoroHandler, streamHandler, heartbeatHandler, activityHandler, billingHandler, repo)
 
 	e.Use(middleware.RequestLogger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: cfg.AllowedOrigins,
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "X-API-KEY"},
+		AllowOrigins:  cfg.AllowedOrigins,
+		AllowHeaders:  []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "X-API-KEY"},
+		ExposeHeaders: []string{apimiddleware.OperationIDHeader},
 	}))
 
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 	})
 
+	// 保持期間を過ぎたゴミ箱の中身と取り消し期間を過ぎた記録を定期的に削除する
+	maintenanceCtx, stopMaintenance := context.WithCancel(ctx)
+	defer stopMaintenance()
+	go runMaintenance(maintenanceCtx, trashUsecase, undoUsecase, activityUsecase)
+	// 変更通知の LISTEN と、時間で切り替わるポモドーロのフェーズの進行
+	go listener.Run(maintenanceCtx)
+	go runPomodoroTicker(maintenanceCtx, pomodoroUsecase)
+	go runAutoTracking(maintenanceCtx, heartbeatUsecase, activityUsecase, timeUsecase)
+
 	go func() {
 		if err := e.Start(":" + cfg.Port); err != nil && err != http.ErrServerClosed {
 			e.Logger.Fatal("shutting down the server")
@@ -79,6 +130,9 @@ func main() {
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 
+	// e.Shutdown はリクエストのコンテキストを止めないので、先に LISTEN を止めて開いているストリームを閉じる
+	stopMaintenance()
+
 	// タイムアウト
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -86,3 +140,90 @@ func main() {
 		e.Logger.Fatal(err)
 	}
 }
+
+// runMaintenance は1時間ごとに期限切れのゴミ箱と取り消し用の記録を削除する
+func runMaintenance(ctx context.Context, trash usecase.TrashUsecase, undo usecase.UndoUsecase, activity usecase.ActivityUsecase) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		if n, err := trash.PurgeExpired(ctx, now); err != nil {
+			log.Printf("failed to purge trash: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d expired trash items", n)
+		}
+		if _, err := undo.Prune(ctx, now); err != nil {
+			log.Printf("failed to prune operations: %v", err)
+		}
+		if _, err := activity.Prune(ctx, now); err != nil {
+			log.Printf("failed to prune activity events: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runPomodoroTicker は数秒ごとにフェーズの終わりを過ぎたセッションを進め、切り替えを通知させる
+func runPomodoroTicker(ctx context.Context, pomodoro usecase.PomodoroUsecase) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := pomodoro.AdvanceDue(ctx, time.Now()); err != nil {
+			log.Printf("failed to advance pomodoro sessions: %v", err)
+		}
+	}
+}
+
+// runAutoTracking は1分ごとに届いた heartbeat とデスクトップのアクティビティを自動生成のエントリにまとめ、
+// 自動停止の設定に当てはまるタイマーを止める
+func runAutoTracking(ctx context.Context, heartbeat usecase.HeartbeatUsecase, activity usecase.ActivityUsecase, timer usecase.TimeUsecase) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := heartbeat.Aggregate(ctx, time.Now()); err != nil {
+			log.Printf("failed to aggregate heartbeats: %v", err)
+		}
+		if _, err := activity.Rollup(ctx, time.Now()); err != nil {
+			log.Printf("failed to roll up activity: %v", err)
+		}
+		// 一部のタイマーで失敗しても、止めたものは数える
+		n, err := timer.AutoStopTimers(ctx, time.Now())
+		if err != nil {
+			log.Printf("failed to auto-stop timers: %v", err)
+		}
+		if n > 0 {
+			log.Printf("auto-stopped %d timers", n)
+		}
+	}
+}
+
+// newStorage は設定に応じて添付ファイルの保存先を作る
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.StorageBackend {
+	case "local":
+		return storage.NewLocal(cfg.StorageDir)
+	case "s3":
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+}
@@ -0,0 +1,95 @@
+-- name: UpsertActivityEvent :one
+-- 同じ開始時刻のイベントは再送とみなし、未処理なら終わりだけを延ばす
+INSERT INTO activity_events (
+    user_id, bucket, app, title, started_at, ended_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) ON CONFLICT (user_id, bucket, started_at) DO UPDATE
+SET ended_at = GREATEST(activity_events.ended_at, EXCLUDED.ended_at)
+WHERE activity_events.rolled_up_at IS NULL
+RETURNING *;
+
+-- name: GetLastActivityEvent :one
+SELECT * FROM activity_events
+WHERE user_id = $1 AND bucket = $2
+ORDER BY started_at DESC
+LIMIT 1
+FOR UPDATE;
+
+-- name: ExtendActivityEvent :one
+UPDATE activity_events
+SET ended_at = GREATEST(ended_at, $2)
+WHERE id = $1
+RETURNING *;
+
+-- name: TryLockActivityRollup :one
+-- ロールアップは1つのサーバーだけで行う (トランザクションの終わりで解放される)
+SELECT pg_try_advisory_xact_lock(hashtext('activity_rollup'))::bool;
+
+-- name: ListPendingActivityEvents :many
+-- 終わってから時間がたったもの (heartbeat でまだ延びるものは待つ)
+SELECT * FROM activity_events
+WHERE rolled_up_at IS NULL AND ended_at <= $1
+ORDER BY user_id, started_at
+LIMIT $2;
+
+-- name: GetPreviousActivityEvent :one
+-- ロールアップ済みのイベントのうち直前のもの
+SELECT * FROM activity_events
+WHERE user_id = $1 AND started_at < $2 AND rolled_up_at IS NOT NULL
+ORDER BY started_at DESC
+LIMIT 1;
+
+-- name: MarkActivityEventRolledUp :exec
+UPDATE activity_events
+SET
+    rolled_up_at = $2,
+    project_id = $3,
+    category_id = $4,
+    time_entry_id = $5
+WHERE id = $1;
+
+-- name: DeleteActivityEventsBefore :execrows
+-- 保持期間を過ぎた生のイベント (作ったエントリは残る)
+DELETE FROM activity_events
+WHERE ended_at < $1 AND rolled_up_at IS NOT NULL;
+
+-- name: GetActivityTotals :one
+-- 期間に含まれる部分の秒数
+SELECT
+    COALESCE(SUM(EXTRACT(EPOCH FROM LEAST(ended_at, @to_time::timestamptz) - GREATEST(started_at, @from_time::timestamptz))), 0)::bigint AS total_seconds,
+    COALESCE(SUM(EXTRACT(EPOCH FROM LEAST(ended_at, @to_time::timestamptz) - GREATEST(started_at, @from_time::timestamptz)))
+        FILTER (WHERE rolled_up_at IS NOT NULL AND project_id IS NULL AND category_id IS NULL), 0)::bigint AS unclassified_seconds,
+    COUNT(*) FILTER (WHERE rolled_up_at IS NULL) AS pending_events
+FROM activity_events
+WHERE user_id = @user_id AND started_at < @to_time::timestamptz AND ended_at > @from_time::timestamptz;
+
+-- name: ListUnclassifiedActivity :many
+-- ルールに合わなかった時間をアプリとウィンドウタイトルごとに集計する
+SELECT
+    app,
+    title,
+    SUM(EXTRACT(EPOCH FROM LEAST(ended_at, @to_time::timestamptz) - GREATEST(started_at, @from_time::timestamptz)))::bigint AS seconds,
+    COUNT(*) AS events
+FROM activity_events
+WHERE user_id = @user_id AND started_at < @to_time::timestamptz AND ended_at > @from_time::timestamptz
+  AND rolled_up_at IS NOT NULL AND project_id IS NULL AND category_id IS NULL
+GROUP BY app, title
+ORDER BY seconds DESC
+LIMIT @max_rows;
+
+-- name: CreateActivityRule :one
+INSERT INTO activity_rules (
+    user_id, app_pattern, title_pattern, project_id, category_id, position
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: ListActivityRules :many
+SELECT * FROM activity_rules
+WHERE user_id = $1
+ORDER BY position, created_at;
+
+-- name: DeleteActivityRule :execrows
+DELETE FROM activity_rules
+WHERE id = $1 AND user_id = $2;
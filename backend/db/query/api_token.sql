@@ -16,7 +16,23 @@ DELETE FROM api_tokens
 WHERE id = $1 AND user_id = $2;
 
 -- name: GetUserByTokenHash :one
-SELECT u.* FROM api_tokens t
+SELECT u.*, t.name as token_name FROM api_tokens t
 JOIN users u ON t.user_id = u.id
 WHERE t.token_hash = $1 
   AND (t.expires_at IS NULL OR t.expires_at > NOW());
+
+-- name: CreateStreamTicket :exec
+INSERT INTO stream_tickets (
+    token_hash, user_id, expires_at
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: ConsumeStreamTicket :one
+DELETE FROM stream_tickets
+WHERE token_hash = $1 AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: DeleteExpiredStreamTickets :exec
+DELETE FROM stream_tickets
+WHERE expires_at <= NOW();
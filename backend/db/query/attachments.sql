@@ -0,0 +1,44 @@
+-- name: CreateTaskAttachment :one
+-- タスクの所有者でなければ行を返さない
+INSERT INTO attachments (id, user_id, task_id, filename, content_type, size_bytes, storage_key)
+SELECT sqlc.arg('id'), t.user_id, t.id, sqlc.arg('filename'), sqlc.arg('content_type'), sqlc.arg('size_bytes'), sqlc.arg('storage_key')
+FROM tasks t
+WHERE t.id = sqlc.arg('task_id') AND t.user_id = sqlc.arg('user_id') AND t.deleted_at IS NULL
+RETURNING *;
+
+-- name: CreateEventAttachment :one
+-- 予定の所有者でなければ行を返さない
+INSERT INTO attachments (id, user_id, event_id, filename, content_type, size_bytes, storage_key)
+SELECT sqlc.arg('id'), e.user_id, e.id, sqlc.arg('filename'), sqlc.arg('content_type'), sqlc.arg('size_bytes'), sqlc.arg('storage_key')
+FROM scheduled_events e
+WHERE e.id = sqlc.arg('event_id') AND e.user_id = sqlc.arg('user_id') AND e.deleted_at IS NULL
+RETURNING *;
+
+-- name: GetAttachment :one
+SELECT * FROM attachments
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListTaskAttachments :many
+SELECT * FROM attachments
+WHERE task_id = $1 AND user_id = $2
+ORDER BY created_at, id;
+
+-- name: ListEventAttachments :many
+SELECT * FROM attachments
+WHERE event_id = $1 AND user_id = $2
+ORDER BY created_at, id;
+
+-- name: ListAttachmentsByTaskIDs :many
+SELECT * FROM attachments
+WHERE task_id = ANY(sqlc.arg('task_ids')::uuid[])
+ORDER BY created_at, id;
+
+-- name: ListAttachmentsByEventIDs :many
+SELECT * FROM attachments
+WHERE event_id = ANY(sqlc.arg('event_ids')::uuid[])
+ORDER BY created_at, id;
+
+-- name: DeleteAttachment :one
+DELETE FROM attachments
+WHERE id = $1 AND user_id = $2
+RETURNING *;
@@ -0,0 +1,38 @@
+-- name: UpsertProjectBilling :one
+INSERT INTO project_billing (
+    project_id, user_id, hourly_rate_minor, currency, rounding_minutes, rounding_mode
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (project_id) DO UPDATE SET
+    hourly_rate_minor = EXCLUDED.hourly_rate_minor,
+    currency = EXCLUDED.currency,
+    rounding_minutes = EXCLUDED.rounding_minutes,
+    rounding_mode = EXCLUDED.rounding_mode,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetProjectBilling :one
+SELECT * FROM project_billing
+WHERE project_id = $1 AND user_id = $2;
+
+-- name: DeleteProjectBilling :execrows
+DELETE FROM project_billing
+WHERE project_id = $1 AND user_id = $2;
+
+-- name: ListInvoiceTimeEntries :many
+-- 請求書に載せる期間内の終了済みのエントリ (請求の対象外のものも含む)
+SELECT
+    t.id, t.task_id, tk.title AS task_title,
+    t.started_at, t.ended_at, t.note, t.is_billable
+FROM time_entries t
+JOIN projects p ON t.project_id = p.id
+LEFT JOIN tasks tk ON t.task_id = tk.id AND tk.deleted_at IS NULL
+WHERE
+    t.user_id = $1
+    AND p.deleted_at IS NULL
+    AND t.project_id = $2
+    AND t.ended_at IS NOT NULL
+    AND t.started_at >= @from_date
+    AND t.started_at < @to_date
+ORDER BY t.started_at;
@@ -7,12 +7,17 @@ INSERT INTO calendars (
 
 -- name: ListCalendars :many
 SELECT * FROM calendars
-WHERE user_id = $1
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY created_at;
+
+-- name: ListCalendarsByProject :many
+SELECT * FROM calendars
+WHERE user_id = $1 AND project_id = $2 AND deleted_at IS NULL
 ORDER BY created_at;
 
 -- name: GetDefaultCalendar :one
 SELECT * FROM calendars
-WHERE user_id = $1
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at
 LIMIT 1;
 
@@ -30,18 +35,23 @@ INSERT INTO scheduled_events (
 ) RETURNING *;
 
 -- name: ListEventsByRange :many
-SELECT e.*, p.title as project_title, p.category_id
+SELECT e.*, p.title as project_title, p.category_id,
+    ARRAY(SELECT et.tag_id FROM event_tags et WHERE et.event_id = e.id)::uuid[] as tag_ids
 FROM scheduled_events e
 JOIN projects p ON e.project_id = p.id
 WHERE
     e.user_id = $1
+    AND e.deleted_at IS NULL
     AND e.end_at >= sqlc.arg('start_time')
     AND e.start_at <= sqlc.arg('end_time')
+    AND (sqlc.narg('tag_id')::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM event_tags et WHERE et.event_id = e.id AND et.tag_id = @tag_id
+    ))
 ORDER BY e.start_at ASC;
 
 -- name: GetEventByICalUID :one
 SELECT * FROM scheduled_events
-WHERE user_id = $1 AND ical_uid = $2 LIMIT 1;
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL LIMIT 1;
 -- name: CreateTimetableSlot :one
 INSERT INTO timetable_slots (
     user_id, project_id, day_of_week, start_time, end_time, location, note
@@ -56,6 +66,11 @@ JOIN projects p ON ts.project_id = p.id
 WHERE ts.user_id = $1
 ORDER BY ts.day_of_week, ts.start_time;
 
+-- name: ListTimetableSlotsByProject :many
+SELECT * FROM timetable_slots
+WHERE user_id = $1 AND project_id = $2
+ORDER BY day_of_week, start_time;
+
 -- name: ListTimetableSlotsByDayOfWeek :many
 SELECT ts.*, p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color
 FROM timetable_slots ts
@@ -65,20 +80,21 @@ ORDER BY ts.start_time;
 
 -- name: ListEventsByCalendar :many
 SELECT * FROM scheduled_events
-WHERE user_id = $1 AND calendar_id = $2
+WHERE user_id = $1 AND calendar_id = $2 AND deleted_at IS NULL
 ORDER BY start_at ASC;
 
 -- name: ListEventsByCalendarAndRange :many
 SELECT * FROM scheduled_events
 WHERE user_id = $1 
   AND calendar_id = $2
+  AND deleted_at IS NULL
   AND end_at >= sqlc.arg('start_time')
   AND start_at <= sqlc.arg('end_time')
 ORDER BY start_at ASC;
 
 -- name: GetCalendar :one
 SELECT * FROM calendars
-WHERE id = $1 AND user_id = $2 LIMIT 1;
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL LIMIT 1;
 
 -- name: UpdateEventByICalUID :one
 UPDATE scheduled_events
@@ -94,13 +110,10 @@ SET
     etag = COALESCE(sqlc.narg('etag'), etag),
     sequence = COALESCE(sqlc.narg('sequence'), sequence),
     updated_at = NOW()
-WHERE user_id = $1 AND ical_uid = $2
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL
 RETURNING *;
 
--- name: DeleteEventByICalUID :exec
-DELETE FROM scheduled_events
-WHERE user_id = $1 AND ical_uid = $2;
-
--- name: DeleteCalendar :exec
-DELETE FROM calendars
-WHERE id = $1 AND user_id = $2;
+-- name: TrashEventByICalUID :execrows
+UPDATE scheduled_events
+SET deleted_at = NOW()
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL;
@@ -0,0 +1,69 @@
+-- name: CreateHeartbeat :execrows
+-- 同じ entity・時刻の heartbeat は再送とみなして無視する
+INSERT INTO heartbeats (
+    user_id, entity, type, category, project, branch, language, is_write, user_agent, time
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) ON CONFLICT (user_id, entity, time) DO NOTHING;
+
+-- name: TryLockHeartbeatAggregation :one
+-- 集計は1つのサーバーだけで行う (トランザクションの終わりで解放される)
+SELECT pg_try_advisory_xact_lock(hashtext('heartbeat_aggregation'))::bool;
+
+-- name: ListPendingHeartbeats :many
+SELECT * FROM heartbeats
+WHERE aggregated_at IS NULL
+ORDER BY user_id, time
+LIMIT $1;
+
+-- name: GetPreviousHeartbeat :one
+-- 集計済みの heartbeat のうち直前のもの
+SELECT * FROM heartbeats
+WHERE user_id = $1 AND time < $2 AND aggregated_at IS NOT NULL
+ORDER BY time DESC
+LIMIT 1;
+
+-- name: MarkHeartbeatAggregated :exec
+UPDATE heartbeats
+SET
+    aggregated_at = $2,
+    project_id = $3,
+    task_id = $4,
+    time_entry_id = $5
+WHERE id = $1;
+
+-- name: CreateAutoTimeEntry :one
+INSERT INTO time_entries (
+    user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated
+) VALUES (
+    $1, $2, $3, $4, $5, $6, TRUE
+) RETURNING *;
+
+-- name: ExtendAutoTimeEntry :execrows
+-- 自動生成したエントリの終わりを延ばす (手動で計測中に戻したエントリなどは対象外)
+UPDATE time_entries
+SET ended_at = GREATEST(ended_at, @ended_at::timestamptz), updated_at = NOW()
+WHERE id = @id AND user_id = @user_id AND is_auto_generated AND ended_at IS NOT NULL;
+
+-- name: GetProjectByTitle :one
+-- ルールに合わないときは heartbeat の project と同じ名前のプロジェクトに対応づける
+SELECT id FROM projects
+WHERE user_id = $1 AND lower(title) = lower(@title::text) AND deleted_at IS NULL AND is_archived = FALSE
+ORDER BY created_at
+LIMIT 1;
+
+-- name: CreateHeartbeatRule :one
+INSERT INTO heartbeat_rules (
+    user_id, source_project, entity_prefix, language, project_id, task_id, position
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: ListHeartbeatRules :many
+SELECT * FROM heartbeat_rules
+WHERE user_id = $1
+ORDER BY position, created_at;
+
+-- name: DeleteHeartbeatRule :execrows
+DELETE FROM heartbeat_rules
+WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,49 @@
+-- name: CreateNoteLink :exec
+INSERT INTO note_links (
+    user_id, task_id, comment_id, target_type, target_id, target_text
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
+
+-- name: DeleteTaskNoteLinks :exec
+-- タスク本体のノートのリンク (コメントのものは残す)
+DELETE FROM note_links
+WHERE task_id = $1 AND comment_id IS NULL;
+
+-- name: DeleteCommentNoteLinks :exec
+DELETE FROM note_links
+WHERE comment_id = $1;
+
+-- name: ListTasksByTitles :many
+-- wiki リンクの解決用。同じタイトルが複数あれば未完了、更新の新しい順に並べる
+SELECT id, title FROM tasks
+WHERE user_id = sqlc.arg('user_id')
+  AND deleted_at IS NULL
+  AND lower(title) = ANY(sqlc.arg('titles')::text[])
+ORDER BY (status = 'DONE'), updated_at DESC;
+
+-- name: ListProjectsByTitles :many
+SELECT id, title FROM projects
+WHERE user_id = sqlc.arg('user_id')
+  AND deleted_at IS NULL
+  AND lower(title) = ANY(sqlc.arg('titles')::text[])
+ORDER BY is_archived, created_at DESC;
+
+-- name: ListBacklinks :many
+-- 対象を参照しているタスクのノートとコメント。未解決のリンクは対象の現在のタイトルで照合する
+SELECT task_id, task_title, task_status, project_id, project_title, comment_id, updated_at FROM (
+    SELECT DISTINCT ON (t.id, l.comment_id)
+        t.id as task_id, t.title as task_title, t.status as task_status,
+        p.id as project_id, p.title as project_title,
+        l.comment_id, t.updated_at
+    FROM note_links l
+    JOIN tasks t ON t.id = l.task_id
+    JOIN projects p ON p.id = t.project_id
+    WHERE l.user_id = sqlc.arg('user_id')
+      AND t.deleted_at IS NULL
+      AND l.target_type = sqlc.arg('target_type')
+      AND (l.target_id = sqlc.arg('target_id')::uuid
+           OR (l.target_id IS NULL AND lower(l.target_text) = lower(sqlc.arg('target_title')::text)))
+    ORDER BY t.id, l.comment_id
+) b
+ORDER BY b.updated_at DESC;
@@ -0,0 +1,21 @@
+-- name: CreateOperation :one
+INSERT INTO operations (
+    user_id, kind, inverse
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: GetOperationForUpdate :one
+-- 同じ操作を同時に取り消さないように行をロックする
+SELECT * FROM operations
+WHERE id = $1 AND user_id = $2
+FOR UPDATE;
+
+-- name: MarkOperationUndone :exec
+UPDATE operations
+SET undone_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteOperationsBefore :execrows
+DELETE FROM operations
+WHERE created_at < $1;
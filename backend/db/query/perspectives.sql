@@ -0,0 +1,28 @@
+-- name: CreatePerspective :one
+INSERT INTO perspectives (
+    user_id, name, filter
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: GetPerspective :one
+SELECT * FROM perspectives
+WHERE id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: ListPerspectives :many
+SELECT * FROM perspectives
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: UpdatePerspective :one
+UPDATE perspectives
+SET
+    name = COALESCE(sqlc.narg('name'), name),
+    filter = COALESCE(sqlc.narg('filter'), filter),
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeletePerspective :execrows
+DELETE FROM perspectives
+WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,36 @@
+-- name: CreatePomodoroSession :one
+INSERT INTO pomodoro_sessions (
+    user_id, project_id, task_id, note,
+    work_seconds, short_break_seconds, long_break_seconds, long_break_every,
+    phase, phase_started_at, phase_seconds, time_entry_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+) RETURNING *;
+
+-- name: GetActivePomodoroSession :one
+-- 進行中のセッション。状態を進めるので行をロックする
+SELECT * FROM pomodoro_sessions
+WHERE user_id = $1 AND ended_at IS NULL
+FOR UPDATE;
+
+-- name: UpdatePomodoroSession :one
+UPDATE pomodoro_sessions
+SET
+    phase = $2,
+    phase_started_at = $3,
+    phase_seconds = $4,
+    paused_at = $5,
+    paused_seconds = $6,
+    completed_pomodoros = $7,
+    interruptions = $8,
+    time_entry_id = $9,
+    ended_at = $10,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListDuePomodoroSessions :many
+-- フェーズの終わりを過ぎたまま進んでいないセッションのユーザー
+SELECT user_id FROM pomodoro_sessions
+WHERE ended_at IS NULL AND paused_at IS NULL
+  AND phase_started_at + make_interval(secs => phase_seconds + paused_seconds) <= @now;
@@ -0,0 +1,39 @@
+-- name: CreateProjectStatus :one
+INSERT INTO project_statuses (
+    project_id, name, category, color, position
+)
+SELECT p.id, sqlc.arg('name')::varchar, sqlc.arg('category')::task_status, sqlc.narg('color')::varchar,
+    (SELECT COALESCE(MAX(ps.position), 0) + 1 FROM project_statuses ps WHERE ps.project_id = p.id)
+FROM projects p
+WHERE p.id = sqlc.arg('project_id') AND p.user_id = sqlc.arg('user_id')
+RETURNING *;
+
+-- name: ListProjectStatuses :many
+SELECT ps.* FROM project_statuses ps
+JOIN projects p ON ps.project_id = p.id
+WHERE ps.project_id = $1 AND p.user_id = $2
+ORDER BY ps.position, ps.created_at;
+
+-- name: GetProjectStatus :one
+SELECT ps.* FROM project_statuses ps
+JOIN projects p ON ps.project_id = p.id
+WHERE ps.id = $1 AND p.user_id = $2
+LIMIT 1;
+
+-- name: UpdateProjectStatus :one
+UPDATE project_statuses ps
+SET
+    name = COALESCE(sqlc.narg('name'), ps.name),
+    color = COALESCE(sqlc.narg('color'), ps.color),
+    position = COALESCE(sqlc.narg('position'), ps.position)
+FROM projects p
+WHERE ps.project_id = p.id
+  AND ps.id = $1 AND p.user_id = $2
+RETURNING ps.*;
+
+-- name: DeleteProjectStatus :execrows
+-- 削除されたステータスのタスクは custom_status_id が NULL になり、基本カテゴリのみ残る
+DELETE FROM project_statuses ps
+USING projects p
+WHERE ps.project_id = p.id
+  AND ps.id = $1 AND p.user_id = $2;
@@ -7,25 +7,48 @@ INSERT INTO projects (
 
 -- name: GetProject :one
 SELECT * FROM projects
-WHERE id = $1 AND user_id = $2 LIMIT 1;
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetProjectForUpdate :one
+-- WIP制限の判定中に同じプロジェクトへの並行更新を防ぐ
+SELECT * FROM projects
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: UpdateProject :one
+UPDATE projects
+SET
+    title = COALESCE(sqlc.narg('title'), title),
+    description = COALESCE(sqlc.narg('description'), description),
+    color = COALESCE(sqlc.narg('color'), color),
+    is_archived = COALESCE(sqlc.narg('is_archived'), is_archived),
+    -- 0 を指定するとWIP制限を解除する
+    wip_limit = CASE
+        WHEN sqlc.narg('wip_limit')::int IS NULL THEN wip_limit
+        ELSE NULLIF(@wip_limit, 0)
+    END,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING *;
 
 -- name: ListProjects :many
 SELECT 
     p.id, p.user_id, p.category_id, p.title, p.description, 
     COALESCE(p.color, '#808080')::varchar as color, 
-    p.is_archived, p.created_at, p.updated_at, 
+    p.is_archived, p.created_at, p.updated_at, p.wip_limit,
     c.name as category_name, c.root_type, 
     COALESCE(c.color, '#808080')::varchar as category_color
 FROM projects p
 JOIN categories c ON p.category_id = c.id
 WHERE
     p.user_id = $1
+    AND p.deleted_at IS NULL
     AND (sqlc.narg('is_archived')::boolean IS NULL OR p.is_archived = @is_archived)
 ORDER BY p.updated_at DESC;
 
 -- name: GetDefaultProject :one
 SELECT * FROM projects
-WHERE user_id = $1
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at ASC
 LIMIT 1;
 
@@ -40,3 +63,7 @@ INSERT INTO categories (
 SELECT * FROM categories
 WHERE user_id = $1
 ORDER BY root_type, name;
+
+-- name: GetCategory :one
+SELECT * FROM categories
+WHERE id = $1 AND user_id = $2;
@@ -10,17 +10,23 @@ SELECT
     r.*, 
     p.title as project_title, 
     COALESCE(p.color, '#808080')::varchar as project_color, 
-    COALESCE(t.title, '')::varchar as task_title
+    COALESCE(t.title, '')::varchar as task_title,
+    ARRAY(SELECT rt.tag_id FROM result_tags rt WHERE rt.result_id = r.id)::uuid[] as tag_ids
 FROM results r
 JOIN projects p ON r.project_id = p.id
 LEFT JOIN tasks t ON r.target_task_id = t.id
 WHERE
     r.user_id = $1
+    AND r.deleted_at IS NULL
     AND (sqlc.narg('project_id')::uuid IS NULL OR r.project_id = @project_id)
     AND r.recorded_at >= @from_date
     AND r.recorded_at <= @to_date
+    AND (sqlc.narg('tag_id')::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM result_tags rt WHERE rt.result_id = r.id AND rt.tag_id = @tag_id
+    ))
 ORDER BY r.recorded_at DESC;
 
--- name: DeleteResult :exec
-DELETE FROM results
-WHERE id = $1 AND user_id = $2;
+-- name: TrashResult :execrows
+UPDATE results
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
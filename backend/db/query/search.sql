@@ -0,0 +1,79 @@
+-- name: SearchAll :many
+-- 全文検索(tsvector)と部分一致(pg_trgm)のフォールバックを組み合わせ、種別ごとに上位を返す
+SELECT entity_type, id, project_id, title, body, rank, occurred_at, status
+FROM (
+    SELECT s.*, ROW_NUMBER() OVER (PARTITION BY s.entity_type ORDER BY s.rank DESC, s.occurred_at DESC) AS rn
+    FROM (
+        SELECT
+            'task'::text AS entity_type, t.id, t.project_id, t.title,
+            COALESCE(t.note_markdown, '')::text AS body,
+            (ts_rank(to_tsvector('simple', t.title || ' ' || COALESCE(t.note_markdown, '')), websearch_to_tsquery('simple', @query::text))
+                + CASE WHEN t.title ILIKE @pattern::text THEN 1 ELSE 0 END)::float8 AS rank,
+            COALESCE(t.due_date, t.created_at)::timestamptz AS occurred_at,
+            t.status::text AS status
+        FROM tasks t
+        WHERE t.user_id = @user_id
+          AND t.deleted_at IS NULL
+          AND (to_tsvector('simple', t.title || ' ' || COALESCE(t.note_markdown, '')) @@ websearch_to_tsquery('simple', @query::text)
+            OR t.title ILIKE @pattern::text
+            OR t.note_markdown ILIKE @pattern::text)
+        UNION ALL
+        SELECT
+            'event'::text, e.id, e.project_id, e.title,
+            (COALESCE(e.description, '') || ' ' || COALESCE(e.location, ''))::text,
+            (ts_rank(to_tsvector('simple', e.title || ' ' || COALESCE(e.description, '') || ' ' || COALESCE(e.location, '')), websearch_to_tsquery('simple', @query::text))
+                + CASE WHEN e.title ILIKE @pattern::text THEN 1 ELSE 0 END)::float8,
+            e.start_at::timestamptz,
+            ''::text
+        FROM scheduled_events e
+        WHERE e.user_id = @user_id
+          AND e.deleted_at IS NULL
+          AND (to_tsvector('simple', e.title || ' ' || COALESCE(e.description, '') || ' ' || COALESCE(e.location, '')) @@ websearch_to_tsquery('simple', @query::text)
+            OR e.title ILIKE @pattern::text
+            OR e.description ILIKE @pattern::text
+            OR e.location ILIKE @pattern::text)
+        UNION ALL
+        SELECT
+            'time_entry'::text, te.id, te.project_id, p.title,
+            COALESCE(te.note, '')::text,
+            ts_rank(to_tsvector('simple', COALESCE(te.note, '')), websearch_to_tsquery('simple', @query::text))::float8,
+            te.started_at::timestamptz,
+            ''::text
+        FROM time_entries te
+        JOIN projects p ON te.project_id = p.id
+        WHERE te.user_id = @user_id
+          AND (to_tsvector('simple', COALESCE(te.note, '')) @@ websearch_to_tsquery('simple', @query::text)
+            OR te.note ILIKE @pattern::text)
+        UNION ALL
+        SELECT
+            'result'::text, r.id, r.project_id, r.type,
+            COALESCE(r.note, '')::text,
+            ts_rank(to_tsvector('simple', r.type || ' ' || COALESCE(r.note, '')), websearch_to_tsquery('simple', @query::text))::float8,
+            r.recorded_at::timestamptz,
+            ''::text
+        FROM results r
+        WHERE r.user_id = @user_id
+          AND r.deleted_at IS NULL
+          AND (to_tsvector('simple', r.type || ' ' || COALESCE(r.note, '')) @@ websearch_to_tsquery('simple', @query::text)
+            OR r.note ILIKE @pattern::text)
+        UNION ALL
+        SELECT
+            'project'::text, pr.id, pr.id, pr.title,
+            COALESCE(pr.description, '')::text,
+            (ts_rank(to_tsvector('simple', pr.title || ' ' || COALESCE(pr.description, '')), websearch_to_tsquery('simple', @query::text))
+                + CASE WHEN pr.title ILIKE @pattern::text THEN 1 ELSE 0 END)::float8,
+            pr.updated_at::timestamptz,
+            ''::text
+        FROM projects pr
+        WHERE pr.user_id = @user_id
+          AND pr.deleted_at IS NULL
+          AND (to_tsvector('simple', pr.title || ' ' || COALESCE(pr.description, '')) @@ websearch_to_tsquery('simple', @query::text)
+            OR pr.title ILIKE @pattern::text)
+    ) s
+    WHERE (sqlc.narg('project_id')::uuid IS NULL OR s.project_id = @project_id)
+      AND (sqlc.narg('from_date')::timestamptz IS NULL OR s.occurred_at >= @from_date)
+      AND (sqlc.narg('to_date')::timestamptz IS NULL OR s.occurred_at <= @to_date)
+      AND (sqlc.narg('status')::text IS NULL OR s.status = @status)
+) ranked
+WHERE rn <= @per_type::int
+ORDER BY entity_type, rank DESC;
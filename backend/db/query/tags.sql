@@ -0,0 +1,144 @@
+-- name: CreateTag :one
+INSERT INTO tags (
+    user_id, name, color
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: ListTags :many
+SELECT * FROM tags
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetTag :one
+SELECT * FROM tags
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateTag :one
+UPDATE tags
+SET
+    name = COALESCE(sqlc.narg('name'), name),
+    color = COALESCE(sqlc.narg('color'), color)
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteTag :execrows
+DELETE FROM tags
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpsertTagByName :one
+-- CalDAVのCATEGORIES等、名前でタグを解決する
+INSERT INTO tags (
+    user_id, name
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: AddTaskTag :execrows
+INSERT INTO task_tags (task_id, tag_id)
+SELECT t.id, g.id
+FROM tasks t, tags g
+WHERE t.id = sqlc.arg('task_id') AND g.id = sqlc.arg('tag_id')
+  AND t.user_id = sqlc.arg('user_id') AND g.user_id = sqlc.arg('user_id')
+  AND t.deleted_at IS NULL
+ON CONFLICT (task_id, tag_id) DO UPDATE SET tag_id = EXCLUDED.tag_id;
+
+-- name: TaskHasTag :one
+SELECT EXISTS (
+    SELECT 1 FROM task_tags
+    WHERE task_id = $1 AND tag_id = $2
+)::bool;
+
+-- name: RemoveTaskTag :execrows
+DELETE FROM task_tags tt
+USING tags g
+WHERE tt.tag_id = g.id
+  AND tt.task_id = $1 AND tt.tag_id = $2 AND g.user_id = $3;
+
+-- name: ClearTaskTags :exec
+DELETE FROM task_tags
+WHERE task_id = $1;
+
+-- name: ListTaskTagNames :many
+SELECT tt.task_id, g.name
+FROM task_tags tt
+JOIN tags g ON tt.tag_id = g.id
+WHERE tt.task_id = ANY(sqlc.arg('task_ids')::uuid[])
+ORDER BY g.name;
+
+-- name: AddEventTag :execrows
+INSERT INTO event_tags (event_id, tag_id)
+SELECT e.id, g.id
+FROM scheduled_events e, tags g
+WHERE e.id = sqlc.arg('event_id') AND g.id = sqlc.arg('tag_id')
+  AND e.user_id = sqlc.arg('user_id') AND g.user_id = sqlc.arg('user_id')
+  AND e.deleted_at IS NULL
+ON CONFLICT (event_id, tag_id) DO UPDATE SET tag_id = EXCLUDED.tag_id;
+
+-- name: RemoveEventTag :execrows
+DELETE FROM event_tags et
+USING tags g
+WHERE et.tag_id = g.id
+  AND et.event_id = $1 AND et.tag_id = $2 AND g.user_id = $3;
+
+-- name: ClearEventTags :exec
+DELETE FROM event_tags
+WHERE event_id = $1;
+
+-- name: ListEventTagNames :many
+SELECT et.event_id, g.name
+FROM event_tags et
+JOIN tags g ON et.tag_id = g.id
+WHERE et.event_id = ANY(sqlc.arg('event_ids')::uuid[])
+ORDER BY g.name;
+
+-- name: AddTimeEntryTag :execrows
+INSERT INTO time_entry_tags (time_entry_id, tag_id)
+SELECT te.id, g.id
+FROM time_entries te, tags g
+WHERE te.id = sqlc.arg('time_entry_id') AND g.id = sqlc.arg('tag_id')
+  AND te.user_id = sqlc.arg('user_id') AND g.user_id = sqlc.arg('user_id')
+ON CONFLICT (time_entry_id, tag_id) DO UPDATE SET tag_id = EXCLUDED.tag_id;
+
+-- name: RemoveTimeEntryTag :execrows
+DELETE FROM time_entry_tags tet
+USING tags g
+WHERE tet.tag_id = g.id
+  AND tet.time_entry_id = $1 AND tet.tag_id = $2 AND g.user_id = $3;
+
+-- name: AddResultTag :execrows
+INSERT INTO result_tags (result_id, tag_id)
+SELECT r.id, g.id
+FROM results r, tags g
+WHERE r.id = sqlc.arg('result_id') AND g.id = sqlc.arg('tag_id')
+  AND r.user_id = sqlc.arg('user_id') AND g.user_id = sqlc.arg('user_id')
+  AND r.deleted_at IS NULL
+ON CONFLICT (result_id, tag_id) DO UPDATE SET tag_id = EXCLUDED.tag_id;
+
+-- name: RemoveResultTag :execrows
+DELETE FROM result_tags rt
+USING tags g
+WHERE rt.tag_id = g.id
+  AND rt.result_id = $1 AND rt.tag_id = $2 AND g.user_id = $3;
+
+-- name: GetTagStats :many
+-- タグ別の集計(時間・タスク・実績)
+SELECT
+    g.id, g.name, COALESCE(g.color, '#808080')::varchar as color,
+    (SELECT COUNT(*) FROM task_tags tt JOIN tasks t ON tt.task_id = t.id
+        WHERE tt.tag_id = g.id AND t.deleted_at IS NULL AND t.status != 'DONE')::bigint as open_tasks,
+    (SELECT COUNT(*) FROM task_tags tt JOIN tasks t ON tt.task_id = t.id
+        WHERE tt.tag_id = g.id AND t.deleted_at IS NULL AND t.status = 'DONE'
+          AND t.completed_at >= @from_date AND t.completed_at <= @to_date)::bigint as done_tasks,
+    (SELECT COALESCE(SUM(EXTRACT(EPOCH FROM (COALESCE(te.ended_at, NOW()) - te.started_at))), 0)
+        FROM time_entry_tags tet JOIN time_entries te ON tet.time_entry_id = te.id
+        WHERE tet.tag_id = g.id
+          AND te.started_at >= @from_date AND te.started_at <= @to_date)::bigint as total_seconds,
+    (SELECT COUNT(*) FROM result_tags rt JOIN results r ON rt.result_id = r.id
+        WHERE rt.tag_id = g.id AND r.deleted_at IS NULL
+          AND r.recorded_at >= @from_date AND r.recorded_at <= @to_date)::bigint as result_count
+FROM tags g
+WHERE g.user_id = $1
+ORDER BY total_seconds DESC, g.name;
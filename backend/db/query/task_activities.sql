@@ -0,0 +1,66 @@
+-- name: CreateTaskActivity :exec
+INSERT INTO task_activities (
+    task_id, user_id, action, source, actor, changes
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
+
+-- name: ListTaskActivities :many
+SELECT * FROM task_activities
+WHERE task_id = $1 AND user_id = $2
+ORDER BY created_at, id;
+
+-- name: CreateTaskComment :one
+INSERT INTO task_comments (task_id, user_id, body_markdown)
+SELECT t.id, t.user_id, sqlc.arg('body_markdown')::text
+FROM tasks t
+WHERE t.id = sqlc.arg('task_id') AND t.user_id = sqlc.arg('user_id') AND t.deleted_at IS NULL
+RETURNING *;
+
+-- name: ListTaskComments :many
+SELECT * FROM task_comments
+WHERE task_id = $1 AND user_id = $2
+ORDER BY created_at, id;
+
+-- name: UpdateTaskComment :one
+UPDATE task_comments
+SET
+    body_markdown = $3,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteTaskComment :execrows
+DELETE FROM task_comments
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetCycleTimeStats :many
+-- 完了したタスクのサイクルタイム(最初にTODOから動いた時刻から完了まで)とリードタイム(作成から完了まで)
+WITH started AS (
+    SELECT a.task_id, MIN(a.created_at) AS started_at
+    FROM task_activities a
+    WHERE a.user_id = sqlc.arg('user_id') AND a.changes->'status'->>'from' = 'TODO'
+    GROUP BY a.task_id
+), durations AS (
+    SELECT
+        t.project_id,
+        EXTRACT(EPOCH FROM t.completed_at - COALESCE(s.started_at, t.created_at)) AS cycle_seconds,
+        EXTRACT(EPOCH FROM t.completed_at - t.created_at) AS lead_seconds
+    FROM tasks t
+    LEFT JOIN started s ON s.task_id = t.id
+    WHERE t.user_id = sqlc.arg('user_id')
+      AND t.deleted_at IS NULL
+      AND t.status = 'DONE'
+      AND t.completed_at >= sqlc.arg('from_date')
+      AND t.completed_at < sqlc.arg('to_date')
+)
+SELECT
+    p.id as project_id, p.title as project_title,
+    COUNT(*) as completed_count,
+    AVG(d.cycle_seconds)::float8 as avg_cycle_seconds,
+    (percentile_cont(0.5) WITHIN GROUP (ORDER BY d.cycle_seconds))::float8 as median_cycle_seconds,
+    AVG(d.lead_seconds)::float8 as avg_lead_seconds
+FROM durations d
+JOIN projects p ON d.project_id = p.id
+GROUP BY p.id
+ORDER BY p.title;
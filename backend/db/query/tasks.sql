@@ -1,14 +1,14 @@
 -- name: CreateTask :one
 INSERT INTO tasks (
     user_id, project_id, title, note_markdown, due_date, priority,
-    calendar_id, ical_uid, status
+    calendar_id, ical_uid, status, position
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 ) RETURNING *;
 
 -- name: GetTask :one
 SELECT * FROM tasks
-WHERE id = $1 AND user_id = $2 LIMIT 1;
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL LIMIT 1;
 
 -- name: ListTasksWithStats :many
 -- タスクと同時に、チェックリストの進捗を取得
@@ -16,22 +16,90 @@ SELECT
     t.id, t.project_id, t.title, t.status, t.due_date, t.priority,
     p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color,
     COUNT(ci.id) as total_items,
-    COUNT(ci.id) FILTER (WHERE ci.is_completed) as done_items
+    COUNT(ci.id) FILTER (WHERE ci.is_completed) as done_items,
+    ARRAY(SELECT tt.tag_id FROM task_tags tt WHERE tt.task_id = t.id)::uuid[] as tag_ids,
+    t.custom_status_id, COALESCE(ps.name, t.status::text)::varchar as status_name,
+    t.estimated_minutes,
+    COALESCE(SUM(ci.estimated_minutes), 0)::int as checklist_estimated_minutes,
+    (SELECT COALESCE(SUM(EXTRACT(EPOCH FROM (COALESCE(te.ended_at, NOW()) - te.started_at))), 0) / 60
+        FROM time_entries te WHERE te.task_id = t.id)::int as actual_minutes
 FROM tasks t
 JOIN projects p ON t.project_id = p.id
+LEFT JOIN project_statuses ps ON t.custom_status_id = ps.id
 LEFT JOIN checklist_items ci ON t.id = ci.task_id
 WHERE
     t.user_id = $1
+    AND t.deleted_at IS NULL
     AND (sqlc.narg('project_id')::uuid IS NULL OR t.project_id = @project_id)
     AND (sqlc.narg('status')::task_status IS NULL OR t.status = @status)
     AND (sqlc.narg('from_date')::timestamptz IS NULL OR t.due_date >= @from_date)
     AND (sqlc.narg('to_date')::timestamptz IS NULL OR t.due_date <= @to_date)
-GROUP BY t.id, p.id
+    AND (sqlc.narg('tag_id')::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM task_tags tt WHERE tt.task_id = t.id AND tt.tag_id = @tag_id
+    ))
+    AND TRUE /* task_filter */
+GROUP BY t.id, p.id, ps.id
 ORDER BY
     CASE WHEN t.status = 'DONE' THEN 1 ELSE 0 END,
     t.due_date ASC NULLS LAST,
     t.created_at DESC;
 
+-- name: GetLastTaskPosition :one
+-- プロジェクト内で最後尾の順位キー
+SELECT COALESCE(MAX(position), '')::text FROM tasks
+WHERE project_id = $1;
+
+-- name: ListBoardTasks :many
+-- カンバン表示用(レーン内は順位キー順)
+SELECT
+    t.id, t.project_id, t.title, t.status, t.custom_status_id, t.due_date, t.priority, t.position,
+    COUNT(ci.id) as total_items,
+    COUNT(ci.id) FILTER (WHERE ci.is_completed) as done_items,
+    ARRAY(SELECT tt.tag_id FROM task_tags tt WHERE tt.task_id = t.id)::uuid[] as tag_ids
+FROM tasks t
+LEFT JOIN checklist_items ci ON t.id = ci.task_id
+WHERE t.project_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+GROUP BY t.id
+ORDER BY t.position, t.created_at;
+
+-- name: ListMatrixTasks :many
+-- 優先度マトリクス用の未完了タスク(アーカイブ済みプロジェクトを除く)。期限の近い順
+SELECT
+    t.id, t.project_id, t.title, t.status, t.custom_status_id, t.due_date, t.priority, t.estimated_minutes,
+    p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color
+FROM tasks t
+JOIN projects p ON t.project_id = p.id
+WHERE t.user_id = sqlc.arg('user_id') AND t.status != 'DONE' AND t.deleted_at IS NULL
+  AND p.is_archived = FALSE AND p.deleted_at IS NULL
+  AND (sqlc.narg('project_id')::uuid IS NULL OR t.project_id = sqlc.narg('project_id'))
+ORDER BY t.due_date ASC NULLS LAST, t.priority DESC NULLS LAST, t.created_at;
+
+-- name: ListTaskPositions :many
+SELECT id, position FROM tasks
+WHERE project_id = $1
+ORDER BY position, created_at;
+
+-- name: CountTasksInLane :one
+-- 指定タスク自身を除いたレーン内の件数
+SELECT COUNT(*) FROM tasks
+WHERE project_id = $1 AND status = $2 AND id != $3 AND deleted_at IS NULL;
+
+-- name: MoveTask :one
+UPDATE tasks
+SET
+    status = sqlc.arg('status')::task_status,
+    custom_status_id = sqlc.narg('custom_status_id'),
+    position = sqlc.arg('position'),
+    completed_at = CASE WHEN sqlc.arg('status')::task_status = 'DONE' THEN COALESCE(completed_at, NOW()) ELSE NULL END,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetTaskPosition :exec
+UPDATE tasks
+SET position = $2
+WHERE id = $1;
+
 -- name: UpdateTask :one
 UPDATE tasks
 SET
@@ -41,25 +109,67 @@ SET
     completed_at = COALESCE(sqlc.narg('completed_at'), completed_at),
     due_date = COALESCE(sqlc.narg('due_date'), due_date),
     priority = COALESCE(sqlc.narg('priority'), priority),
+    -- 独自ステータスの指定がなければ、基本カテゴリと一致する間だけ現在の値を保つ
+    custom_status_id = CASE
+        WHEN sqlc.narg('custom_status_id')::uuid IS NOT NULL THEN sqlc.narg('custom_status_id')
+        WHEN sqlc.narg('status')::task_status IS NULL THEN custom_status_id
+        ELSE (SELECT ps.id FROM project_statuses ps WHERE ps.id = tasks.custom_status_id AND ps.category = sqlc.narg('status'))
+    END,
+    -- 負の値を指定すると見積もりを解除する
+    estimated_minutes = CASE
+        WHEN sqlc.narg('estimated_minutes')::int IS NULL THEN estimated_minutes
+        WHEN @estimated_minutes < 0 THEN NULL
+        ELSE @estimated_minutes
+    END,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetTaskProject :one
+-- 独自ステータスはプロジェクトごとのものなので外す
+UPDATE tasks
+SET
+    project_id = sqlc.arg('project_id'),
+    position = sqlc.arg('position'),
+    custom_status_id = NULL,
+    updated_at = NOW()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RevertTask :one
+-- 取り消しで記録しておいた内容に戻す。独自ステータスが消えていれば外す
+UPDATE tasks
+SET
+    project_id = sqlc.arg('project_id'),
+    title = sqlc.arg('title'),
+    note_markdown = sqlc.narg('note_markdown'),
+    status = sqlc.arg('status'),
+    custom_status_id = (SELECT ps.id FROM project_statuses ps WHERE ps.id = sqlc.narg('custom_status_id') AND ps.project_id = sqlc.arg('project_id')),
+    completed_at = sqlc.narg('completed_at'),
+    due_date = sqlc.narg('due_date'),
+    priority = sqlc.narg('priority'),
+    position = sqlc.arg('position'),
+    estimated_minutes = sqlc.narg('estimated_minutes'),
     updated_at = NOW()
-WHERE id = $1 AND user_id = $2
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
 RETURNING *;
 
 -- name: ListTasksByCalendar :many
 SELECT * FROM tasks
-WHERE user_id = $1 AND calendar_id = $2
+WHERE user_id = $1 AND calendar_id = $2 AND deleted_at IS NULL
 ORDER BY created_at DESC;
 
 -- name: ListTasksByCalendarAndRange :many
 SELECT * FROM tasks
 WHERE user_id = $1
   AND calendar_id = $2
+  AND deleted_at IS NULL
   AND (due_date IS NULL OR (due_date >= sqlc.arg('start_time') AND due_date <= sqlc.arg('end_time')))
 ORDER BY created_at DESC;
 
 -- name: GetTaskByICalUID :one
 SELECT * FROM tasks
-WHERE user_id = $1 AND ical_uid = $2 LIMIT 1;
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL LIMIT 1;
 
 -- name: UpdateTaskByICalUID :one
 UPDATE tasks
@@ -72,25 +182,32 @@ SET
     etag = COALESCE(sqlc.narg('etag'), etag),
     sequence = COALESCE(sqlc.narg('sequence'), sequence),
     completed_at = COALESCE(sqlc.narg('completed_at'), completed_at),
+    custom_status_id = CASE
+        WHEN sqlc.narg('status')::task_status IS NULL THEN custom_status_id
+        ELSE (SELECT ps.id FROM project_statuses ps WHERE ps.id = tasks.custom_status_id AND ps.category = sqlc.narg('status'))
+    END,
     updated_at = NOW()
-WHERE user_id = $1 AND ical_uid = $2
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL
 RETURNING *;
 
--- name: DeleteTaskByICalUID :exec
-DELETE FROM tasks
-WHERE user_id = $1 AND ical_uid = $2;
+-- name: TrashTaskByICalUID :execrows
+UPDATE tasks
+SET deleted_at = NOW()
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL;
 
--- name: DeleteTask :exec
-DELETE FROM tasks
-WHERE id = $1 AND user_id = $2;
+-- name: TrashTask :execrows
+UPDATE tasks
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 
 
 -- name: CreateChecklistItem :one
 INSERT INTO checklist_items (
-    task_id, content, position
+    task_id, content, position, estimated_minutes
 ) VALUES (
     $1, $2,
-    (SELECT COALESCE(MAX(position), 0) + 1 FROM checklist_items WHERE task_id = $1)
+    (SELECT COALESCE(MAX(position), 0) + 1 FROM checklist_items WHERE task_id = $1),
+    $3
 ) RETURNING *;
 
 -- name: ListChecklistItems :many
@@ -103,10 +220,52 @@ UPDATE checklist_items
 SET 
     content = COALESCE(sqlc.narg('content'), content),
     is_completed = COALESCE(sqlc.narg('is_completed'), is_completed),
-    position = COALESCE(sqlc.narg('position'), position)
+    position = COALESCE(sqlc.narg('position'), position),
+    estimated_minutes = CASE
+        WHEN sqlc.narg('estimated_minutes')::int IS NULL THEN estimated_minutes
+        WHEN @estimated_minutes < 0 THEN NULL
+        ELSE @estimated_minutes
+    END
 WHERE id = $1
 RETURNING *;
 
 -- name: DeleteChecklistItem :exec
 DELETE FROM checklist_items
 WHERE id = $1;
+
+-- name: ListTasksByProject :many
+SELECT * FROM tasks
+WHERE project_id = $1 AND user_id = $2 AND deleted_at IS NULL
+ORDER BY position, created_at;
+
+-- name: GetEstimateReport :many
+-- 期間内に完了したタスクの見積もりと実績(分)を期間・プロジェクトごとに集計 (期間の区切りは timezone で数える)
+-- 見積もりはタスク自身の値、なければチェックリストの見積もりの合計
+WITH task_estimates AS (
+    SELECT
+        t.project_id, t.completed_at,
+        COALESCE(t.estimated_minutes, (SELECT SUM(ci.estimated_minutes) FROM checklist_items ci WHERE ci.task_id = t.id)) AS estimated,
+        (SELECT COALESCE(SUM(EXTRACT(EPOCH FROM (te.ended_at - te.started_at))), 0) / 60
+            FROM time_entries te WHERE te.task_id = t.id AND te.ended_at IS NOT NULL) AS actual
+    FROM tasks t
+    WHERE t.user_id = sqlc.arg('user_id')
+      AND t.deleted_at IS NULL
+      AND t.status = 'DONE'
+      AND t.completed_at >= sqlc.arg('from_date')
+      AND t.completed_at < sqlc.arg('to_date')
+)
+SELECT
+    date_trunc(sqlc.arg('interval')::text, e.completed_at, sqlc.arg('timezone')::text)::timestamptz as period,
+    p.id as project_id, p.title as project_title,
+    c.id as category_id, c.name as category_name,
+    COUNT(*) as task_count,
+    COUNT(*) FILTER (WHERE e.estimated IS NOT NULL) as estimated_count,
+    COUNT(*) FILTER (WHERE e.actual > e.estimated) as underestimated_count,
+    COALESCE(SUM(e.estimated), 0)::bigint as estimated_minutes,
+    COALESCE(SUM(e.actual) FILTER (WHERE e.estimated IS NOT NULL), 0)::float8 as estimated_actual_minutes,
+    COALESCE(SUM(e.actual), 0)::float8 as actual_minutes
+FROM task_estimates e
+JOIN projects p ON e.project_id = p.id
+JOIN categories c ON p.category_id = c.id
+GROUP BY 1, p.id, c.id
+ORDER BY period, p.title;
@@ -0,0 +1,20 @@
+-- name: CreateTemplate :one
+INSERT INTO templates (
+    user_id, kind, name, body
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetTemplate :one
+SELECT * FROM templates
+WHERE id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: ListTemplates :many
+SELECT * FROM templates
+WHERE user_id = $1
+  AND (sqlc.narg('kind')::template_kind IS NULL OR kind = @kind)
+ORDER BY kind, name;
+
+-- name: DeleteTemplate :execrows
+DELETE FROM templates
+WHERE id = $1 AND user_id = $2;
@@ -1,16 +1,81 @@
 -- name: CreateTimeEntry :one
+-- is_billable を省略すると請求の対象にする
 INSERT INTO time_entries (
-  user_id, project_id, task_id, started_at,ended_at, note
+  user_id, project_id, task_id, started_at,ended_at, note, is_billable
 ) VALUES (
-    $1, $2, $3, $4, $5 ,$6
+    $1, $2, $3, $4, $5 ,$6, COALESCE(sqlc.narg('is_billable')::boolean, TRUE)
 ) RETURNING *;
 
 -- name: StopTimeEntry :one
+-- 計測中のエントリだけを止める (終わったエントリの ended_at は変えない)
 UPDATE time_entries
 SET ended_at = $3, updated_at = NOW()
-WHERE id = $1 AND user_id = $2
+WHERE id = $1 AND user_id = $2 AND ended_at IS NULL
 RETURNING *;
 
+-- name: FinishTimeEntry :execrows
+-- 計測中なら ended_at で止める。手動で止められていれば何もしない
+UPDATE time_entries
+SET ended_at = $3, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND ended_at IS NULL AND started_at < $3;
+
+-- name: GetTimeEntry :one
+SELECT * FROM time_entries
+WHERE id = $1 AND user_id = $2;
+
+-- name: ReopenTimeEntry :one
+-- 停止を取り消して計測中に戻す
+UPDATE time_entries
+SET ended_at = NULL, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND ended_at IS NOT NULL
+RETURNING *;
+
+-- name: RevertTimeEntry :one
+-- 取り消しで記録しておいた内容に戻す
+UPDATE time_entries
+SET
+    project_id = sqlc.arg('project_id'),
+    task_id = sqlc.narg('task_id'),
+    started_at = sqlc.arg('started_at'),
+    ended_at = sqlc.narg('ended_at'),
+    note = sqlc.narg('note'),
+    is_billable = sqlc.arg('is_billable'),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+RETURNING *;
+
+-- name: RestoreTimeEntry :one
+-- 取り消しで削除したエントリを同じ ID で作り直す
+INSERT INTO time_entries (
+    id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, is_billable, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
+-- name: DeleteTimeEntry :execrows
+DELETE FROM time_entries
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListOverlappingTimeEntries :many
+-- 範囲と重なるエントリ。ended_at が NULL (計測中・終わりを指定しない) なら終わりがないものとして扱う
+SELECT * FROM time_entries
+WHERE user_id = @user_id
+  AND NOT (id = ANY(@exclude_ids::uuid[]))
+  AND tstzrange(started_at, ended_at) && tstzrange(@started_at::timestamptz, sqlc.narg('ended_at')::timestamptz)
+ORDER BY started_at
+FOR UPDATE;
+
+-- name: ListTimeEntryTagIDs :many
+SELECT tag_id FROM time_entry_tags
+WHERE time_entry_id = $1;
+
+-- name: CopyTimeEntryTags :exec
+-- 分割・結合したエントリにタグを引き継ぐ
+INSERT INTO time_entry_tags (time_entry_id, tag_id)
+SELECT @dst_id, tag_id FROM time_entry_tags
+WHERE time_entry_id = @src_id
+ON CONFLICT DO NOTHING;
+
 -- name: UpdateTimeEntry :one
 UPDATE time_entries
 SET 
@@ -19,40 +84,115 @@ SET
     started_at = COALESCE(sqlc.narg('started_at'), started_at),
     ended_at = COALESCE(sqlc.narg('ended_at'), ended_at),
     note = COALESCE(sqlc.narg('note'), note),
+    is_billable = COALESCE(sqlc.narg('is_billable'), is_billable),
     updated_at = NOW()
 WHERE id = $1 AND user_id = $2
 RETURNING *;
 
--- name: GetRunningTimeEntries :many
--- 計測中のエントリ
+-- name: GetRunningTimeEntry :one
+-- 計測中のエントリ (ユーザーごとに最大1件)
 SELECT t.*, p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color
 FROM time_entries t
 JOIN projects p ON t.project_id = p.id
-WHERE t.user_id = $1 AND t.ended_at IS NULL
-ORDER BY t.started_at DESC;
+WHERE t.user_id = $1 AND t.ended_at IS NULL AND p.deleted_at IS NULL;
+
+-- name: StopRunningTimeEntry :one
+-- 計測中のエントリがあれば止める
+UPDATE time_entries
+SET ended_at = $2, updated_at = NOW()
+WHERE user_id = $1 AND ended_at IS NULL
+RETURNING *;
+
+-- name: ListRunningTimeEntriesWithPreferences :many
+-- 自動停止の設定を確かめるため、計測中のエントリをユーザーの設定と一緒に取得する
+SELECT t.id, t.user_id, t.started_at, u.preferences
+FROM time_entries t
+JOIN users u ON t.user_id = u.id
+WHERE t.ended_at IS NULL;
+
+-- name: GetLastActivityAt :one
+-- since 以降で最後に heartbeat かデスクトップのアクティビティがあった日時 (なければ NULL)
+SELECT GREATEST(
+    (SELECT MAX(h.time) FROM heartbeats h WHERE h.user_id = @user_id AND h.time >= @since::timestamptz),
+    (SELECT MAX(a.ended_at) FROM activity_events a WHERE a.user_id = @user_id AND a.ended_at >= @since::timestamptz)
+)::timestamptz AS last_activity_at;
+
+-- name: CreateTimerAutoStop :exec
+INSERT INTO timer_auto_stops (
+    time_entry_id, user_id, reason, ended_at
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: ListTimerAutoStops :many
+SELECT s.time_entry_id, s.reason, s.ended_at, s.stopped_at,
+    t.project_id, p.title as project_title, t.task_id, t.started_at
+FROM timer_auto_stops s
+JOIN time_entries t ON s.time_entry_id = t.id
+JOIN projects p ON t.project_id = p.id
+WHERE s.user_id = $1
+ORDER BY s.stopped_at DESC
+LIMIT $2;
 
 -- name: ListTimeEntries :many
-SELECT t.*, p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color
+-- started_at の新しい順。カーソル (cursor_started_at, cursor_id) を指定するとその次から取得する
+SELECT t.*, p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color,
+    p.category_id, c.name as category_name, c.root_type, tk.title as task_title,
+    EXTRACT(EPOCH FROM (COALESCE(t.ended_at, NOW()) - t.started_at))::bigint as duration_seconds,
+    ARRAY(SELECT tet.tag_id FROM time_entry_tags tet WHERE tet.time_entry_id = t.id)::uuid[] as tag_ids,
+    ARRAY(SELECT g.name FROM time_entry_tags tet JOIN tags g ON tet.tag_id = g.id
+        WHERE tet.time_entry_id = t.id ORDER BY g.name)::text[] as tag_names
 FROM time_entries t
 JOIN projects p ON t.project_id = p.id
+JOIN categories c ON p.category_id = c.id
+LEFT JOIN tasks tk ON t.task_id = tk.id AND tk.deleted_at IS NULL
 WHERE 
     t.user_id = $1
+    AND p.deleted_at IS NULL
     AND t.started_at >= @from_date
-    AND t.started_at <= @to_date
-ORDER BY t.started_at DESC;
+    AND t.started_at < @to_date
+    AND (sqlc.narg('project_id')::uuid IS NULL OR t.project_id = sqlc.narg('project_id'))
+    AND (sqlc.narg('category_id')::uuid IS NULL OR p.category_id = sqlc.narg('category_id'))
+    AND (sqlc.narg('root_type')::root_category_type IS NULL OR c.root_type = sqlc.narg('root_type'))
+    AND (sqlc.narg('task_id')::uuid IS NULL OR t.task_id = sqlc.narg('task_id'))
+    AND (sqlc.narg('tag_id')::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM time_entry_tags tet WHERE tet.time_entry_id = t.id AND tet.tag_id = @tag_id
+    ))
+    AND (sqlc.narg('is_auto_generated')::bool IS NULL OR t.is_auto_generated = sqlc.narg('is_auto_generated'))
+    AND (sqlc.narg('cursor_started_at')::timestamptz IS NULL
+        OR (t.started_at, t.id) < (sqlc.narg('cursor_started_at'), sqlc.narg('cursor_id')::uuid))
+ORDER BY t.started_at DESC, t.id DESC
+LIMIT @page_size;
 
 -- name: GetGrowthStats :many
--- GROWTHカテゴリの実績のみを日別集計
+-- GROWTHカテゴリの実績を timezone での日別に集計する (from_day〜to_day)。日をまたぐエントリは日ごとに按分する
+WITH entries AS (
+    SELECT te.started_at, COALESCE(te.ended_at, NOW()) AS ended_at
+    FROM time_entries te
+    JOIN projects p ON te.project_id = p.id
+    JOIN categories c ON p.category_id = c.id
+    WHERE
+        te.user_id = @user_id
+        AND c.root_type = 'GROWTH'
+        AND te.started_at < ((@to_day::date + 1)::timestamp AT TIME ZONE @timezone::text)
+        AND COALESCE(te.ended_at, NOW()) > (@from_day::date::timestamp AT TIME ZONE @timezone::text)
+)
 SELECT
-    DATE(te.started_at)::text as date,
-    SUM(EXTRACT(EPOCH FROM (COALESCE(te.ended_at, NOW()) - te.started_at)))::bigint as total_seconds
-FROM time_entries te
-JOIN projects p ON te.project_id = p.id
-JOIN categories c ON p.category_id = c.id
-WHERE
-    te.user_id = $1
-    AND c.root_type = 'GROWTH'
-    AND te.started_at >= @from_date
-    AND te.started_at <= @to_date
-GROUP BY DATE(te.started_at)
+    d::date::text as date,
+    SUM(EXTRACT(EPOCH FROM (
+        LEAST(e.ended_at, (d + interval '1 day') AT TIME ZONE @timezone::text)
+        - GREATEST(e.started_at, d AT TIME ZONE @timezone::text)
+    )))::bigint as total_seconds
+FROM entries e
+CROSS JOIN LATERAL generate_series(
+    (e.started_at AT TIME ZONE @timezone::text)::date::timestamp,
+    (e.ended_at AT TIME ZONE @timezone::text)::date::timestamp,
+    interval '1 day'
+) AS d
+WHERE d >= @from_day::date AND d <= @to_day::date
+GROUP BY d
+HAVING SUM(EXTRACT(EPOCH FROM (
+    LEAST(e.ended_at, (d + interval '1 day') AT TIME ZONE @timezone::text)
+    - GREATEST(e.started_at, d AT TIME ZONE @timezone::text)
+))) > 0
 ORDER BY date;
@@ -0,0 +1,220 @@
+-- name: TrashEvent :execrows
+UPDATE scheduled_events
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: TrashProject :one
+UPDATE projects
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: TrashProjectItems :exec
+-- プロジェクトと同じ日時で中のタスク・予定・実績をゴミ箱に入れる(復元時は同じ日時のものだけ戻す)
+WITH trashed_tasks AS (
+    UPDATE tasks t SET deleted_at = p.deleted_at
+    FROM projects p
+    WHERE p.id = sqlc.arg('project_id') AND t.project_id = p.id AND t.deleted_at IS NULL
+), trashed_events AS (
+    UPDATE scheduled_events e SET deleted_at = p.deleted_at
+    FROM projects p
+    WHERE p.id = sqlc.arg('project_id') AND e.project_id = p.id AND e.deleted_at IS NULL
+)
+UPDATE results r SET deleted_at = p.deleted_at
+FROM projects p
+WHERE p.id = sqlc.arg('project_id') AND r.project_id = p.id AND r.deleted_at IS NULL;
+
+-- name: TrashCalendar :one
+UPDATE calendars
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: TrashCalendarItems :exec
+-- カレンダーと同じ日時で中のタスク・予定をゴミ箱に入れる
+WITH trashed_tasks AS (
+    UPDATE tasks t SET deleted_at = c.deleted_at
+    FROM calendars c
+    WHERE c.id = sqlc.arg('calendar_id') AND t.calendar_id = c.id AND t.deleted_at IS NULL
+)
+UPDATE scheduled_events e SET deleted_at = c.deleted_at
+FROM calendars c
+WHERE c.id = sqlc.arg('calendar_id') AND e.calendar_id = c.id AND e.deleted_at IS NULL;
+
+-- name: ListTrash :many
+-- ゴミ箱の一覧。プロジェクト・カレンダーと一緒に入ったものは親だけを表示する
+SELECT kind, id, title, project_id, deleted_at FROM (
+    SELECT 'project'::text AS kind, p.id, p.title::text AS title, NULL::uuid AS project_id, p.deleted_at
+    FROM projects p
+    WHERE p.user_id = sqlc.arg('user_id') AND p.deleted_at IS NOT NULL
+    UNION ALL
+    SELECT 'calendar'::text, c.id, c.name::text, c.project_id, c.deleted_at
+    FROM calendars c
+    WHERE c.user_id = sqlc.arg('user_id') AND c.deleted_at IS NOT NULL
+    UNION ALL
+    SELECT 'task'::text, t.id, t.title::text, t.project_id, t.deleted_at
+    FROM tasks t
+    JOIN projects p ON t.project_id = p.id
+    LEFT JOIN calendars c ON t.calendar_id = c.id
+    WHERE t.user_id = sqlc.arg('user_id') AND t.deleted_at IS NOT NULL
+      AND p.deleted_at IS DISTINCT FROM t.deleted_at
+      AND c.deleted_at IS DISTINCT FROM t.deleted_at
+    UNION ALL
+    SELECT 'event'::text, e.id, e.title::text, e.project_id, e.deleted_at
+    FROM scheduled_events e
+    JOIN projects p ON e.project_id = p.id
+    LEFT JOIN calendars c ON e.calendar_id = c.id
+    WHERE e.user_id = sqlc.arg('user_id') AND e.deleted_at IS NOT NULL
+      AND p.deleted_at IS DISTINCT FROM e.deleted_at
+      AND c.deleted_at IS DISTINCT FROM e.deleted_at
+    UNION ALL
+    SELECT 'result'::text, r.id, r.type::text, r.project_id, r.deleted_at
+    FROM results r
+    JOIN projects p ON r.project_id = p.id
+    WHERE r.user_id = sqlc.arg('user_id') AND r.deleted_at IS NOT NULL
+      AND p.deleted_at IS DISTINCT FROM r.deleted_at
+) trash
+WHERE sqlc.narg('kind')::text IS NULL OR kind = sqlc.narg('kind')
+ORDER BY deleted_at DESC, kind, id;
+
+-- name: RestoreTask :one
+UPDATE tasks
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: RestoreEvent :one
+UPDATE scheduled_events
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: RestoreResult :one
+UPDATE results
+SET deleted_at = NULL
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: RestoreProjectItems :exec
+-- プロジェクトを戻す前に、一緒にゴミ箱に入ったものを戻す
+WITH restored_tasks AS (
+    UPDATE tasks t SET deleted_at = NULL
+    FROM projects p
+    WHERE p.id = sqlc.arg('project_id') AND p.user_id = sqlc.arg('user_id')
+      AND t.project_id = p.id AND t.deleted_at = p.deleted_at
+), restored_events AS (
+    UPDATE scheduled_events e SET deleted_at = NULL
+    FROM projects p
+    WHERE p.id = sqlc.arg('project_id') AND p.user_id = sqlc.arg('user_id')
+      AND e.project_id = p.id AND e.deleted_at = p.deleted_at
+)
+UPDATE results r SET deleted_at = NULL
+FROM projects p
+WHERE p.id = sqlc.arg('project_id') AND p.user_id = sqlc.arg('user_id')
+  AND r.project_id = p.id AND r.deleted_at = p.deleted_at;
+
+-- name: RestoreProject :one
+UPDATE projects
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: RestoreCalendarItems :exec
+-- カレンダーを戻す前に、一緒にゴミ箱に入ったものを戻す
+WITH restored_tasks AS (
+    UPDATE tasks t SET deleted_at = NULL
+    FROM calendars c
+    WHERE c.id = sqlc.arg('calendar_id') AND c.user_id = sqlc.arg('user_id')
+      AND t.calendar_id = c.id AND t.deleted_at = c.deleted_at
+)
+UPDATE scheduled_events e SET deleted_at = NULL
+FROM calendars c
+WHERE c.id = sqlc.arg('calendar_id') AND c.user_id = sqlc.arg('user_id')
+  AND e.calendar_id = c.id AND e.deleted_at = c.deleted_at;
+
+-- name: RestoreCalendar :one
+UPDATE calendars
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: CountTasksByICalUID :one
+-- 復元で同じ UID の有効なタスクが重複しないか確認する
+SELECT COUNT(*) FROM tasks
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL;
+
+-- name: CountEventsByICalUID :one
+SELECT COUNT(*) FROM scheduled_events
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL;
+
+-- name: DeletePurgedAttachments :many
+-- 完全に削除する対象(とその中のタスク・予定)の添付ファイルの行を消し、実体の保存先を返す
+DELETE FROM attachments a
+WHERE a.user_id = sqlc.arg('user_id') AND (
+    a.task_id IN (
+        SELECT t.id FROM tasks t
+        WHERE t.user_id = sqlc.arg('user_id')
+          AND (t.id = sqlc.narg('task_id') OR t.project_id = sqlc.narg('project_id') OR t.calendar_id = sqlc.narg('calendar_id')))
+    OR a.event_id IN (
+        SELECT e.id FROM scheduled_events e
+        WHERE e.user_id = sqlc.arg('user_id')
+          AND (e.id = sqlc.narg('event_id') OR e.project_id = sqlc.narg('project_id') OR e.calendar_id = sqlc.narg('calendar_id')))
+)
+RETURNING a.storage_key;
+
+-- name: PurgeTask :execrows
+DELETE FROM tasks
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: PurgeEvent :execrows
+DELETE FROM scheduled_events
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: PurgeResult :execrows
+DELETE FROM results
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: PurgeProject :execrows
+-- 中のタスク・予定・実績・記録は外部キーでまとめて消える
+DELETE FROM projects
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: PurgeCalendar :execrows
+DELETE FROM calendars
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: ListExpiredTrash :many
+-- 保持期間を過ぎたもの。ListTrash と同じく親と一緒に入ったものは親だけを返す
+SELECT kind, id, user_id FROM (
+    SELECT 'project'::text AS kind, p.id, p.user_id, p.deleted_at
+    FROM projects p
+    WHERE p.deleted_at < sqlc.arg('before')
+    UNION ALL
+    SELECT 'calendar'::text, c.id, c.user_id, c.deleted_at
+    FROM calendars c
+    WHERE c.deleted_at < sqlc.arg('before')
+    UNION ALL
+    SELECT 'task'::text, t.id, t.user_id, t.deleted_at
+    FROM tasks t
+    JOIN projects p ON t.project_id = p.id
+    LEFT JOIN calendars c ON t.calendar_id = c.id
+    WHERE t.deleted_at < sqlc.arg('before')
+      AND p.deleted_at IS DISTINCT FROM t.deleted_at
+      AND c.deleted_at IS DISTINCT FROM t.deleted_at
+    UNION ALL
+    SELECT 'event'::text, e.id, e.user_id, e.deleted_at
+    FROM scheduled_events e
+    JOIN projects p ON e.project_id = p.id
+    LEFT JOIN calendars c ON e.calendar_id = c.id
+    WHERE e.deleted_at < sqlc.arg('before')
+      AND p.deleted_at IS DISTINCT FROM e.deleted_at
+      AND c.deleted_at IS DISTINCT FROM e.deleted_at
+    UNION ALL
+    SELECT 'result'::text, r.id, r.user_id, r.deleted_at
+    FROM results r
+    JOIN projects p ON r.project_id = p.id
+    WHERE r.deleted_at < sqlc.arg('before')
+      AND p.deleted_at IS DISTINCT FROM r.deleted_at
+) trash
+ORDER BY deleted_at
+LIMIT sqlc.arg('max_items')::int;
@@ -2,10 +2,13 @@
 CREATE EXTENSION IF NOT EXISTS "pgcrypto";
 -- Enable Exclude --
 CREATE EXTENSION IF NOT EXISTS "btree_gist";
+-- Enable Trigram (日本語など単語区切りのない文字列の部分一致検索用) --
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
 -- Immute Data --
 CREATE TYPE user_role AS ENUM('ADMIN', 'USER');
 CREATE TYPE task_status AS ENUM('TODO','DOING','DONE');
 CREATE TYPE root_category_type AS ENUM('GROWTH','LIFE','WORK','HOBBY','OTHER');
+CREATE TYPE template_kind AS ENUM('TASK','PROJECT');
 -- Table --
 -- user
 CREATE TABLE users(
@@ -27,6 +30,12 @@ CREATE TABLE api_tokens(
   expires_at TIMESTAMPTZ,
   created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
+-- 変更通知のストリームに接続するための使い捨てのチケット (EventSource はヘッダーを付けられないのでクエリで渡す)
+CREATE TABLE stream_tickets(
+  token_hash VARCHAR(64) PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMPTZ NOT NULL
+);
 --category
 CREATE TABLE categories(
   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -37,6 +46,15 @@ CREATE TABLE categories(
   created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
   UNIQUE(user_id,name)
 );
+-- tag
+CREATE TABLE tags(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(50) NOT NULL,
+  color VARCHAR(7) DEFAULT '#808080',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE(user_id,name)
+);
 -- calendar
 CREATE TABLE calendars (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -48,7 +66,9 @@ CREATE TABLE calendars (
     sync_token VARCHAR(255) NOT NULL DEFAULT '1',
     supported_components VARCHAR(50)[] DEFAULT ARRAY['VEVENT', 'VTODO'],
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- ゴミ箱に入れた日時(NULL なら有効)。中のタスク・予定も同じ日時で入る
+    deleted_at TIMESTAMPTZ
 );
 -- project
 CREATE TABLE projects(
@@ -60,7 +80,23 @@ CREATE TABLE projects(
   color VARCHAR(7) DEFAULT '#808080',
   is_archived BOOLEAN NOT NULL DEFAULT FALSE,
   created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  -- DOINGレーンの上限(NULLは無制限)
+  wip_limit INTEGER CHECK (wip_limit > 0),
+  -- ゴミ箱に入れた日時(NULL なら有効)。中のタスク・予定・実績も同じ日時で入る
+  deleted_at TIMESTAMPTZ
+);
+-- project workflow
+CREATE TABLE project_statuses(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  name VARCHAR(50) NOT NULL,
+  -- 基本カテゴリ(TODO=未着手, DOING=進行中, DONE=完了)
+  category task_status NOT NULL,
+  color VARCHAR(7) DEFAULT '#808080',
+  position INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE(project_id, name)
 );
 --task
 CREATE TABLE tasks(
@@ -71,7 +107,7 @@ CREATE TABLE tasks(
   note_markdown TEXT,
   status task_status NOT NULL DEFAULT 'TODO',
   due_date TIMESTAMPTZ,
-  priority SMALLINT DEFAULT 0,
+  priority SMALLINT DEFAULT 0 CHECK (priority BETWEEN 0 AND 3), -- 0:なし 1:低 2:中 3:高 (2以上を重要として扱う)
   created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
   updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
   -- for caldav
@@ -79,7 +115,15 @@ CREATE TABLE tasks(
   ical_uid VARCHAR(255),
   etag VARCHAR(64),
   sequence INTEGER NOT NULL DEFAULT 0,
-  completed_at TIMESTAMPTZ
+  completed_at TIMESTAMPTZ,
+  -- for kanban (辞書順で比較する順位キー)
+  position TEXT COLLATE "C" NOT NULL DEFAULT '',
+  -- プロジェクト独自のステータス(status は常にその基本カテゴリを保持する)
+  custom_status_id UUID REFERENCES project_statuses(id) ON DELETE SET NULL,
+  -- 見積もり(分)。未設定ならチェックリストの見積もりの合計を使う
+  estimated_minutes INTEGER CHECK (estimated_minutes >= 0),
+  -- ゴミ箱に入れた日時(NULL なら有効)
+  deleted_at TIMESTAMPTZ
 );
 -- child task
 CREATE TABLE checklist_items(
@@ -87,7 +131,27 @@ CREATE TABLE checklist_items(
   task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
   content VARCHAR(255) NOT NULL,
   is_completed BOOLEAN NOT NULL DEFAULT FALSE,
-  position INTEGER NOT NULL DEFAULT 0
+  position INTEGER NOT NULL DEFAULT 0,
+  estimated_minutes INTEGER CHECK (estimated_minutes >= 0)
+);
+-- task history (追記のみ)
+CREATE TABLE task_activities(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  action VARCHAR(20) NOT NULL, -- CREATED / UPDATED
+  source VARCHAR(20) NOT NULL, -- web / token / caldav / system
+  actor VARCHAR(100),          -- トークン名、CalDAVクライアント名など
+  changes JSONB NOT NULL DEFAULT '{}', -- {"field": {"from": ..., "to": ...}}
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE TABLE task_comments(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  body_markdown TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 -- time table
 CREATE TABLE timetable_slots(
@@ -132,6 +196,8 @@ CREATE TABLE scheduled_events (
 
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- ゴミ箱に入れた日時(NULL なら有効)
+    deleted_at TIMESTAMPTZ,
     CONSTRAINT valid_event_duration CHECK (end_at > start_at)
 );
 CREATE TABLE time_entries (
@@ -147,9 +213,132 @@ CREATE TABLE time_entries (
 
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- 請求の対象にするか (請求書には対象のエントリだけを載せる)
+    is_billable BOOLEAN NOT NULL DEFAULT TRUE,
     
     CONSTRAINT valid_duration CHECK (ended_at IS NULL OR ended_at > started_at)
 );
+-- 設定 (timer_max_minutes など) に従って自動で止めた計測と、その理由
+CREATE TABLE timer_auto_stops(
+  time_entry_id UUID PRIMARY KEY REFERENCES time_entries(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- max_duration: 最大の計測時間 / clock: 指定した時刻 / idle: heartbeat・アクティビティが途切れた
+  reason VARCHAR(20) NOT NULL CHECK (reason IN ('max_duration', 'clock', 'idle')),
+  -- 計測の終わりとした日時 (idle なら最後のアクティビティ)
+  ended_at TIMESTAMPTZ NOT NULL,
+  stopped_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- 請求の設定 (金額は通貨の補助単位で、JPY なら円、USD ならセント。rounding_minutes が 0 なら丸めない)
+CREATE TABLE project_billing(
+  project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  hourly_rate_minor BIGINT NOT NULL CHECK (hourly_rate_minor >= 0),
+  currency VARCHAR(3) NOT NULL,
+  -- 丸めはエントリごとに行う (up: 切り上げ / down: 切り捨て / nearest: 四捨五入)
+  rounding_minutes INTEGER NOT NULL DEFAULT 0 CHECK (rounding_minutes BETWEEN 0 AND 1440),
+  rounding_mode VARCHAR(10) NOT NULL DEFAULT 'up' CHECK (rounding_mode IN ('up', 'down', 'nearest')),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- pomodoro (phase の長さは開始時の設定を保存する。作業フェーズの時間は time_entries に記録する)
+CREATE TABLE pomodoro_sessions(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+  note TEXT,
+  work_seconds INT NOT NULL CHECK (work_seconds > 0),
+  short_break_seconds INT NOT NULL CHECK (short_break_seconds > 0),
+  long_break_seconds INT NOT NULL CHECK (long_break_seconds > 0),
+  long_break_every INT NOT NULL CHECK (long_break_every > 0),
+  -- 現在のフェーズ (work / short_break / long_break)
+  phase VARCHAR(20) NOT NULL CHECK (phase IN ('work', 'short_break', 'long_break')),
+  phase_started_at TIMESTAMPTZ NOT NULL,
+  phase_seconds INT NOT NULL,
+  -- 一時停止中ならその日時。停止していた時間は paused_seconds に足してフェーズの終わりを延ばす
+  paused_at TIMESTAMPTZ,
+  paused_seconds INT NOT NULL DEFAULT 0,
+  completed_pomodoros INT NOT NULL DEFAULT 0,
+  interruptions INT NOT NULL DEFAULT 0,
+  -- 作業フェーズで計測中のエントリ
+  time_entry_id UUID REFERENCES time_entries(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ended_at TIMESTAMPTZ
+);
+-- activity heartbeats (WakaTime 互換のエディタプラグインなどから受け取る)
+CREATE TABLE heartbeats(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- 作業の対象 (ファイルパス・アプリ名・ドメインなど)
+  entity TEXT NOT NULL,
+  type VARCHAR(20) NOT NULL DEFAULT 'file',
+  category VARCHAR(50),
+  -- プラグインが送るプロジェクト名 (Taskalyst のプロジェクトとはルールで対応づける)
+  project VARCHAR(200),
+  branch VARCHAR(200),
+  language VARCHAR(100),
+  is_write BOOLEAN NOT NULL DEFAULT FALSE,
+  user_agent VARCHAR(255),
+  time TIMESTAMPTZ NOT NULL,
+  -- 集計した日時 (NULL なら未集計) と、対応づけたプロジェクト・まとめたエントリ
+  aggregated_at TIMESTAMPTZ,
+  project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+  task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+  time_entry_id UUID REFERENCES time_entries(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  -- プラグインの再送を重複させない
+  UNIQUE(user_id, entity, time)
+);
+-- heartbeat をプロジェクトに対応づけるルール。指定した条件をすべて満たすもののうち position の小さいルールを使う
+CREATE TABLE heartbeat_rules(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- heartbeat の project (大文字小文字を区別しない)
+  source_project VARCHAR(200),
+  -- entity の前方一致 (ディレクトリなど)
+  entity_prefix TEXT,
+  language VARCHAR(100),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+  position INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK (source_project IS NOT NULL OR entity_prefix IS NOT NULL OR language IS NOT NULL)
+);
+-- desktop activity を分類するルール (正規表現は Go の regexp)。指定したパターンすべてに一致するもののうち position の小さいルールを使う。
+-- project_id があれば自動生成のエントリを作り、category_id だけならレポートでの分類にだけ使う
+CREATE TABLE activity_rules(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  app_pattern TEXT,
+  title_pattern TEXT,
+  project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+  category_id UUID REFERENCES categories(id) ON DELETE CASCADE,
+  position INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK (app_pattern IS NOT NULL OR title_pattern IS NOT NULL),
+  CHECK (project_id IS NOT NULL OR category_id IS NOT NULL)
+);
+-- desktop activity (ActivityWatch の watcher のような常駐アプリから受け取るアプリ・ウィンドウのフォーカス)
+CREATE TABLE activity_events(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- 送信元 (例: aw-watcher-window_myhost)
+  bucket VARCHAR(100) NOT NULL,
+  app VARCHAR(255) NOT NULL,
+  title TEXT NOT NULL DEFAULT '',
+  started_at TIMESTAMPTZ NOT NULL,
+  ended_at TIMESTAMPTZ NOT NULL,
+  -- ロールアップした日時 (NULL なら未処理) と分類の結果 (ルールに合わなければ NULL)
+  rolled_up_at TIMESTAMPTZ,
+  project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+  category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+  time_entry_id UUID REFERENCES time_entries(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE(user_id, bucket, started_at),
+  CHECK (ended_at >= started_at)
+);
 -- achievement
 CREATE TABLE results (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -160,7 +349,84 @@ CREATE TABLE results (
     type VARCHAR(50) NOT NULL,
     value NUMERIC(12, 2) NOT NULL,
     recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    note TEXT
+    note TEXT,
+    -- ゴミ箱に入れた日時(NULL なら有効)
+    deleted_at TIMESTAMPTZ
+);
+-- template (body はテンプレート種別ごとのJSON)
+CREATE TABLE templates(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind template_kind NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  body JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE(user_id, name)
+);
+-- perspectives (saved task filters)
+CREATE TABLE perspectives(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  filter TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE(user_id, name)
+);
+-- wiki links in notes (task_id はノートを持つタスク、コメント内のリンクは comment_id も持つ)
+-- 未解決のリンクは target_id が NULL で、target_text のタイトルで照合する
+CREATE TABLE note_links(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  comment_id UUID REFERENCES task_comments(id) ON DELETE CASCADE,
+  target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('task', 'project')),
+  target_id UUID,
+  target_text VARCHAR(255) NOT NULL
+);
+-- attachments (ファイルの実体は storage_key で外部ストレージに置く)
+CREATE TABLE attachments(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  task_id UUID REFERENCES tasks(id) ON DELETE CASCADE,
+  event_id UUID REFERENCES scheduled_events(id) ON DELETE CASCADE,
+  filename VARCHAR(255) NOT NULL,
+  content_type VARCHAR(255) NOT NULL,
+  size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+  storage_key TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT attachment_target CHECK ((task_id IS NULL) <> (event_id IS NULL))
+);
+-- undo journal (変更の逆操作を同じトランザクションで記録する。inverse は usecase の undoStep の配列)
+CREATE TABLE operations(
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind VARCHAR(50) NOT NULL,
+  inverse JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  undone_at TIMESTAMPTZ
+);
+-- tagging
+CREATE TABLE task_tags(
+  task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY(task_id, tag_id)
+);
+CREATE TABLE event_tags(
+  event_id UUID NOT NULL REFERENCES scheduled_events(id) ON DELETE CASCADE,
+  tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY(event_id, tag_id)
+);
+CREATE TABLE time_entry_tags(
+  time_entry_id UUID NOT NULL REFERENCES time_entries(id) ON DELETE CASCADE,
+  tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY(time_entry_id, tag_id)
+);
+CREATE TABLE result_tags(
+  result_id UUID NOT NULL REFERENCES results(id) ON DELETE CASCADE,
+  tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY(result_id, tag_id)
 );
 -- performance
 -- Categorie
@@ -171,10 +437,122 @@ CREATE INDEX idx_projects_active ON projects(user_id) WHERE is_archived = FALSE;
 CREATE INDEX idx_tasks_active_user ON tasks(user_id, due_date) WHERE status != 'DONE';
 CREATE INDEX idx_tasks_project ON tasks(project_id);
 CREATE INDEX idx_tasks_ical_uid ON tasks(ical_uid);
+CREATE INDEX idx_tasks_board ON tasks(project_id, status, position);
+CREATE INDEX idx_tasks_custom_status ON tasks(custom_status_id);
+CREATE INDEX idx_task_activities_task ON task_activities(task_id, created_at);
+CREATE INDEX idx_task_comments_task ON task_comments(task_id, created_at);
+CREATE INDEX idx_attachments_task ON attachments(task_id) WHERE task_id IS NOT NULL;
+CREATE INDEX idx_note_links_source ON note_links(task_id, comment_id);
+CREATE INDEX idx_note_links_target ON note_links(user_id, target_type, target_id);
 -- calendar
 CREATE INDEX idx_scheduled_events_range ON scheduled_events (user_id, start_at, end_at);
 CREATE INDEX idx_scheduled_events_ical_uid ON scheduled_events(ical_uid);
+CREATE INDEX idx_attachments_event ON attachments(event_id) WHERE event_id IS NOT NULL;
 -- time
 CREATE INDEX idx_time_entries_range ON time_entries(user_id, started_at DESC);
 CREATE INDEX idx_time_entries_project ON time_entries(project_id, started_at DESC);
+CREATE INDEX idx_time_entries_task ON time_entries(task_id);
+CREATE INDEX idx_time_entries_span ON time_entries USING gist (user_id, tstzrange(started_at, ended_at));
+-- 計測中のエントリはユーザーごとに1件まで
+CREATE UNIQUE INDEX idx_time_entries_running ON time_entries(user_id) WHERE ended_at IS NULL;
 CREATE INDEX idx_results_user_date ON results(user_id, recorded_at DESC);
+-- trash (ゴミ箱の一覧と期限切れの削除用)
+CREATE INDEX idx_projects_trash ON projects(user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_tasks_trash ON tasks(user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_scheduled_events_trash ON scheduled_events(user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_calendars_trash ON calendars(user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_results_trash ON results(user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+-- pomodoro (進行中のセッションはユーザーごとに1件まで)
+CREATE UNIQUE INDEX idx_pomodoro_sessions_active ON pomodoro_sessions(user_id) WHERE ended_at IS NULL;
+-- undo (取り消し期間を過ぎたものの削除用)
+CREATE INDEX idx_operations_created ON operations(created_at);
+-- tag
+CREATE INDEX idx_task_tags_tag ON task_tags(tag_id);
+CREATE INDEX idx_event_tags_tag ON event_tags(tag_id);
+CREATE INDEX idx_time_entry_tags_tag ON time_entry_tags(tag_id);
+CREATE INDEX idx_result_tags_tag ON result_tags(tag_id);
+-- heartbeat (未集計のものは定期的にエントリにまとめる)
+CREATE INDEX idx_heartbeats_user_time ON heartbeats(user_id, time);
+CREATE INDEX idx_heartbeats_pending ON heartbeats(time) WHERE aggregated_at IS NULL;
+CREATE INDEX idx_heartbeat_rules_user ON heartbeat_rules(user_id, position);
+-- desktop activity (未集計のものは定期的にエントリにまとめる)
+CREATE INDEX idx_activity_events_user_time ON activity_events(user_id, started_at);
+CREATE INDEX idx_activity_events_pending ON activity_events(ended_at) WHERE rolled_up_at IS NULL;
+CREATE INDEX idx_activity_rules_user ON activity_rules(user_id, position);
+-- timer auto-stop
+CREATE INDEX idx_timer_auto_stops_user ON timer_auto_stops(user_id, stopped_at);
+-- search (全文検索: 式インデックスは db/query/search.sql の式と一致させること)
+CREATE INDEX idx_tasks_search ON tasks USING GIN (to_tsvector('simple', title || ' ' || COALESCE(note_markdown, '')));
+CREATE INDEX idx_tasks_title_trgm ON tasks USING GIN (title gin_trgm_ops);
+CREATE INDEX idx_tasks_note_trgm ON tasks USING GIN (note_markdown gin_trgm_ops);
+CREATE INDEX idx_scheduled_events_search ON scheduled_events USING GIN (to_tsvector('simple', title || ' ' || COALESCE(description, '') || ' ' || COALESCE(location, '')));
+CREATE INDEX idx_scheduled_events_title_trgm ON scheduled_events USING GIN (title gin_trgm_ops);
+CREATE INDEX idx_scheduled_events_description_trgm ON scheduled_events USING GIN (description gin_trgm_ops);
+CREATE INDEX idx_scheduled_events_location_trgm ON scheduled_events USING GIN (location gin_trgm_ops);
+CREATE INDEX idx_time_entries_search ON time_entries USING GIN (to_tsvector('simple', COALESCE(note, '')));
+CREATE INDEX idx_time_entries_note_trgm ON time_entries USING GIN (note gin_trgm_ops);
+CREATE INDEX idx_results_search ON results USING GIN (to_tsvector('simple', type || ' ' || COALESCE(note, '')));
+CREATE INDEX idx_results_note_trgm ON results USING GIN (note gin_trgm_ops);
+CREATE INDEX idx_projects_search ON projects USING GIN (to_tsvector('simple', title || ' ' || COALESCE(description, '')));
+CREATE INDEX idx_projects_title_trgm ON projects USING GIN (title gin_trgm_ops);
+-- realtime (変更を NOTIFY taskalyst_events で通知する。ペイロードは {"user_id", "type", "id", "op"})
+CREATE FUNCTION notify_change() RETURNS trigger AS $$
+DECLARE
+  rec RECORD;
+  kind TEXT := TG_ARGV[0];
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    rec := OLD;
+  ELSE
+    rec := NEW;
+  END IF;
+
+  IF TG_TABLE_NAME = 'time_entries' THEN
+    -- 計測中かどうかが変わったらタイマーの開始・停止として通知する
+    IF TG_OP = 'INSERT' THEN
+      IF NEW.ended_at IS NULL THEN
+        kind := 'timer.started';
+      END IF;
+    ELSIF TG_OP = 'UPDATE' THEN
+      IF OLD.ended_at IS NULL AND NEW.ended_at IS NOT NULL THEN
+        kind := 'timer.stopped';
+      ELSIF OLD.ended_at IS NOT NULL AND NEW.ended_at IS NULL THEN
+        kind := 'timer.started';
+      END IF;
+    ELSIF OLD.ended_at IS NULL THEN
+      kind := 'timer.stopped';
+    END IF;
+  ELSIF TG_TABLE_NAME = 'pomodoro_sessions' THEN
+    IF NEW.ended_at IS NOT NULL THEN
+      kind := 'pomodoro.ended';
+    ELSIF TG_OP = 'INSERT' THEN
+      kind := 'pomodoro.phase';
+    ELSIF OLD.phase IS DISTINCT FROM NEW.phase OR OLD.phase_started_at IS DISTINCT FROM NEW.phase_started_at THEN
+      kind := 'pomodoro.phase';
+    END IF;
+  END IF;
+
+  PERFORM pg_notify('taskalyst_events', json_build_object(
+    'user_id', rec.user_id, 'type', kind, 'id', rec.id, 'op', lower(TG_OP)
+  )::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER time_entries_notify AFTER INSERT OR UPDATE OR DELETE ON time_entries
+  FOR EACH ROW EXECUTE FUNCTION notify_change('time_entry.changed');
+CREATE TRIGGER tasks_notify AFTER INSERT OR UPDATE OR DELETE ON tasks
+  FOR EACH ROW EXECUTE FUNCTION notify_change('task.changed');
+CREATE TRIGGER scheduled_events_notify AFTER INSERT OR UPDATE OR DELETE ON scheduled_events
+  FOR EACH ROW EXECUTE FUNCTION notify_change('event.changed');
+CREATE TRIGGER pomodoro_sessions_insert_notify AFTER INSERT ON pomodoro_sessions
+  FOR EACH ROW EXECUTE FUNCTION notify_change('pomodoro.changed');
+-- 状態が変わらない更新 (時刻を進めただけ) では通知しない
+CREATE TRIGGER pomodoro_sessions_update_notify AFTER UPDATE ON pomodoro_sessions
+  FOR EACH ROW
+  WHEN (OLD.phase IS DISTINCT FROM NEW.phase
+    OR OLD.phase_started_at IS DISTINCT FROM NEW.phase_started_at
+    OR OLD.paused_at IS DISTINCT FROM NEW.paused_at
+    OR OLD.interruptions IS DISTINCT FROM NEW.interruptions
+    OR OLD.ended_at IS DISTINCT FROM NEW.ended_at)
+  EXECUTE FUNCTION notify_change('pomodoro.changed');
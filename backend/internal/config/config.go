@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/caarlos0/env/v11"
 )
 
@@ -12,6 +14,26 @@ type Config struct {
 	JWTRefreshSecret string   `env:"JWT_REFRESH_SECRET,required"`
 	GoEnv            string   `env:"GO_ENV" envDefault:"development"`
 	AllowedOrigins   []string `env:"ALLOWED_ORIGINS" envDefault:"*"`
+	// 外部から見たこのサーバーのURL (CalDAV の ATTACH などに使う)
+	PublicURL string `env:"PUBLIC_URL" envDefault:"http://localhost:8080"`
+
+	// 添付ファイル
+	StorageBackend     string `env:"STORAGE_BACKEND" envDefault:"local"` // local / s3
+	StorageDir         string `env:"STORAGE_DIR" envDefault:"./data/attachments"`
+	S3Endpoint         string `env:"S3_ENDPOINT"`
+	S3Region           string `env:"S3_REGION" envDefault:"us-east-1"`
+	S3Bucket           string `env:"S3_BUCKET"`
+	S3AccessKey        string `env:"S3_ACCESS_KEY"`
+	S3SecretKey        string `env:"S3_SECRET_KEY"`
+	S3PathStyle        bool   `env:"S3_PATH_STYLE" envDefault:"true"`
+	AttachmentMaxBytes int64  `env:"ATTACHMENT_MAX_BYTES" envDefault:"26214400"` // 25MiB
+
+	// ゴミ箱の保持日数。過ぎたものは自動で完全に削除する(0 なら自動削除しない)
+	TrashRetentionDays int `env:"TRASH_RETENTION_DAYS" envDefault:"30"`
+	// 変更を取り消せる期間
+	UndoWindow time.Duration `env:"UNDO_WINDOW" envDefault:"10m"`
+	// デスクトップのアクティビティの生のイベントを残す日数 (0 なら削除しない)
+	ActivityRetentionDays int `env:"ACTIVITY_RETENTION_DAYS" envDefault:"90"`
 }
 
 func Load() (*Config, error) {
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// 一括送信で受け付けるイベントの上限
+	maxActivityEventsPerRequest = 1000
+	// heartbeat の pulsetime の既定値(秒)
+	defaultActivityPulsetime = 60
+)
+
+type ActivityHandler struct {
+	u usecase.ActivityUsecase
+}
+
+func NewActivityHandler(u usecase.ActivityUsecase) *ActivityHandler {
+	return &ActivityHandler{u: u}
+}
+
+// ActivityEventRequest は ActivityWatch のイベント。duration は秒 (24時間まで)
+type ActivityEventRequest struct {
+	Timestamp time.Time `json:"timestamp" validate:"required"`
+	Duration  float64   `json:"duration" validate:"min=0,max=86400"`
+	Data      struct {
+		App   string `json:"app" validate:"required,max=255"`
+		Title string `json:"title" validate:"max=2000"`
+	} `json:"data"`
+}
+
+func (r ActivityEventRequest) input() usecase.ActivityEventInput {
+	return usecase.ActivityEventInput{
+		App:       r.Data.App,
+		Title:     r.Data.Title,
+		Timestamp: r.Timestamp,
+		Duration:  time.Duration(r.Duration * float64(time.Second)),
+	}
+}
+
+type CreateActivityRuleRequest struct {
+	AppPattern   string     `json:"app_pattern" validate:"max=500"`
+	TitlePattern string     `json:"title_pattern" validate:"max=500"`
+	ProjectID    *uuid.UUID `json:"project_id"`
+	CategoryID   *uuid.UUID `json:"category_id"`
+	Position     int32      `json:"position"`
+}
+
+func bucketParam(c echo.Context) (string, error) {
+	bucket := c.Param("bucket")
+	if bucket == "" || len(bucket) > 100 {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid bucket")
+	}
+	return bucket, nil
+}
+
+// CreateEvents はバケットにイベントの配列を追加する
+func (h *ActivityHandler) CreateEvents(c echo.Context) error {
+	userID := getUserID(c)
+	bucket, err := bucketParam(c)
+	if err != nil {
+		return err
+	}
+	var reqs []ActivityEventRequest
+	if err := c.Bind(&reqs); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if len(reqs) > maxActivityEventsPerRequest {
+		return echo.NewHTTPError(http.StatusBadRequest, "too many events")
+	}
+	inputs := make([]usecase.ActivityEventInput, len(reqs))
+	for i := range reqs {
+		if err := c.Validate(&reqs[i]); err != nil {
+			return err
+		}
+		inputs[i] = reqs[i].input()
+	}
+
+	n, err := h.u.IngestEvents(c.Request().Context(), userID, bucket, inputs)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, map[string]int{"saved": n})
+}
+
+// Heartbeat は1件のイベントを直前のイベントにまとめる。pulsetime: まとめる間隔の上限(秒)
+func (h *ActivityHandler) Heartbeat(c echo.Context) error {
+	userID := getUserID(c)
+	bucket, err := bucketParam(c)
+	if err != nil {
+		return err
+	}
+	pulsetime := float64(defaultActivityPulsetime)
+	if s := c.QueryParam("pulsetime"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 || v > 3600 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid pulsetime")
+		}
+		pulsetime = v
+	}
+	var req ActivityEventRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	event, err := h.u.Heartbeat(c.Request().Context(), userID, bucket, req.input(), time.Duration(pulsetime*float64(time.Second)))
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, event)
+}
+
+func (h *ActivityHandler) CreateRule(c echo.Context) error {
+	userID := getUserID(c)
+	var req CreateActivityRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	rule, err := h.u.CreateRule(c.Request().Context(), userID, usecase.ActivityRuleInput{
+		AppPattern:   req.AppPattern,
+		TitlePattern: req.TitlePattern,
+		ProjectID:    req.ProjectID,
+		CategoryID:   req.CategoryID,
+		Position:     req.Position,
+	})
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, rule)
+}
+
+func (h *ActivityHandler) ListRules(c echo.Context) error {
+	userID := getUserID(c)
+	rules, err := h.u.ListRules(c.Request().Context(), userID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, rules)
+}
+
+func (h *ActivityHandler) DeleteRule(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid rule id")
+	}
+
+	if err := h.u.DeleteRule(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetReport は分類できなかった時間を返す。from/to: 日付 (to の日は含まない、既定は直近7日)
+func (h *ActivityHandler) GetReport(c echo.Context) error {
+	userID := getUserID(c)
+
+	// 省略したときの期間はユーザーのタイムゾーンで決める
+	to := parseDateQuery(c, "to", time.Time{})
+	from := parseDateQuery(c, "from", time.Time{})
+
+	report, err := h.u.GetReport(c.Request().Context(), userID, from, to)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, report)
+}
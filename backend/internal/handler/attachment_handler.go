@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// multipart のヘッダなどファイル以外に許す大きさ
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	u usecase.AttachmentUsecase
+}
+
+func NewAttachmentHandler(u usecase.AttachmentUsecase) *AttachmentHandler {
+	return &AttachmentHandler{u: u}
+}
+
+// Upload returns a handler for POST /<target>/:id/attachments (multipart の file フィールド)
+func (h *AttachmentHandler) Upload(target usecase.AttachmentTarget) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID := getUserID(c)
+		targetID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+		}
+
+		req := c.Request()
+		req.Body = http.MaxBytesReader(c.Response(), req.Body, h.u.MaxBytes()+multipartOverhead)
+		fh, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "file is too large")
+			}
+			return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid file")
+		}
+		defer f.Close()
+
+		att, err := h.u.Upload(req.Context(), userID, target, targetID, fh.Filename, f, fh.Size)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return c.JSON(http.StatusCreated, att)
+	}
+}
+
+// List returns a handler for GET /<target>/:id/attachments
+func (h *AttachmentHandler) List(target usecase.AttachmentTarget) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID := getUserID(c)
+		targetID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+		}
+
+		items, err := h.u.List(c.Request().Context(), userID, target, targetID)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return c.JSON(http.StatusOK, items)
+	}
+}
+
+// Download は添付ファイルを返す。ブラウザで開かれないよう常にダウンロード扱いにする
+func (h *AttachmentHandler) Download(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid attachment id")
+	}
+
+	att, rc, err := h.u.Open(c.Request().Context(), userID, id)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	defer rc.Close()
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}))
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(att.SizeBytes, 10))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	return c.Stream(http.StatusOK, att.ContentType, rc)
+}
+
+func (h *AttachmentHandler) Delete(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid attachment id")
+	}
+
+	if err := h.u.Delete(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
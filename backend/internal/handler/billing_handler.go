@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type BillingHandler struct {
+	u usecase.BillingUsecase
+}
+
+func NewBillingHandler(u usecase.BillingUsecase) *BillingHandler {
+	return &BillingHandler{u: u}
+}
+
+// SetBillingRequest の hourly_rate は通貨の単位 (通貨の小数桁数まで。JPY なら整数)
+type SetBillingRequest struct {
+	HourlyRate      float64 `json:"hourly_rate" validate:"min=0,max=10000000"`
+	Currency        string  `json:"currency" validate:"required,iso4217"`
+	RoundingMinutes int32   `json:"rounding_minutes" validate:"min=0,max=1440"`
+	RoundingMode    string  `json:"rounding_mode" validate:"omitempty,oneof=up down nearest"`
+}
+
+func projectIDParam(c echo.Context) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, "invalid project id")
+	}
+	return id, nil
+}
+
+func (h *BillingHandler) GetBilling(c echo.Context) error {
+	userID := getUserID(c)
+	projectID, err := projectIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	billing, err := h.u.GetBilling(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, billing)
+}
+
+func (h *BillingHandler) SetBilling(c echo.Context) error {
+	userID := getUserID(c)
+	projectID, err := projectIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req SetBillingRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	billing, err := h.u.SetBilling(c.Request().Context(), userID, projectID, usecase.BillingInput{
+		HourlyRate:      usecase.NewMoney(req.HourlyRate, req.Currency),
+		Currency:        req.Currency,
+		RoundingMinutes: req.RoundingMinutes,
+		RoundingMode:    req.RoundingMode,
+	})
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, billing)
+}
+
+func (h *BillingHandler) DeleteBilling(c echo.Context) error {
+	userID := getUserID(c)
+	projectID, err := projectIDParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.u.DeleteBilling(c.Request().Context(), userID, projectID); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetInvoice は請求書を返す。from/to: 日付 (to の日は含まない、既定は今月)。
+// format=html か Accept に text/html があれば印刷用の HTML にする
+func (h *BillingHandler) GetInvoice(c echo.Context) error {
+	userID := getUserID(c)
+	projectID, err := projectIDParam(c)
+	if err != nil {
+		return err
+	}
+	to := parseDateQuery(c, "to", time.Time{})
+	from := parseDateQuery(c, "from", time.Time{})
+
+	invoice, err := h.u.GetInvoice(c.Request().Context(), userID, projectID, from, to)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	if c.QueryParam("format") == "html" || strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "text/html") {
+		var b strings.Builder
+		if err := invoiceTemplate.Execute(&b, invoice); err != nil {
+			return HandleError(c, err)
+		}
+		return c.HTML(http.StatusOK, b.String())
+	}
+	return c.JSON(http.StatusOK, invoice)
+}
+
+// formatDuration は秒数を H:MM にする
+func formatDuration(seconds int64) string {
+	return fmt.Sprintf("%d:%02d", seconds/3600, seconds%3600/60)
+}
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"duration": formatDuration,
+	"date":     func(t time.Time) string { return t.Format(time.DateOnly) },
+	"clock":    func(t time.Time) string { return t.Format("15:04") },
+	"lastDay":  func(t time.Time) string { return t.AddDate(0, 0, -1).Format(time.DateOnly) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice - {{.ProjectTitle}}</title>
+<style>
+  body { font-family: sans-serif; margin: 2em; color: #222; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { border-bottom: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+  .num { text-align: right; white-space: nowrap; }
+  tfoot td { font-weight: bold; border-bottom: none; }
+  .meta { color: #555; }
+  @media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>{{.ProjectTitle}}</h1>
+<p class="meta">
+  {{date .From}} – {{lastDay .To}} ({{.Timezone}})<br>
+  {{.HourlyRate}} {{.Currency}} / h{{if gt .RoundingMinutes 0}}, {{.RoundingMinutes}} min ({{.RoundingMode}}){{end}}<br>
+  Issued {{date .IssuedAt}}
+</p>
+<table>
+<thead>
+<tr><th>Date</th><th>Time</th><th>Task</th><th>Note</th><th class="num">Actual</th><th class="num">Billed</th><th class="num">Amount</th></tr>
+</thead>
+<tbody>
+{{- range .Lines}}
+<tr><td>{{.Date}}</td><td>{{clock .StartedAt}}–{{clock .EndedAt}}</td><td>{{.TaskTitle}}</td><td>{{.Note}}</td><td class="num">{{duration .ActualSeconds}}</td><td class="num">{{duration .BilledSeconds}}</td><td class="num">{{.Amount}}</td></tr>
+{{- end}}
+</tbody>
+<tfoot>
+<tr><td colspan="4">Total</td><td class="num">{{duration .ActualSeconds}}</td><td class="num">{{duration .BilledSeconds}}</td><td class="num">{{.Total}} {{.Currency}}</td></tr>
+</tfoot>
+</table>
+{{- if gt .NonBillableSeconds 0}}
+<p class="meta">Non-billable: {{duration .NonBillableSeconds}}</p>
+{{- end}}
+</body>
+</html>
+`))
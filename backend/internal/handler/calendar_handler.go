@@ -65,13 +65,31 @@ func (h *CalendarHandler) ListEvents(c echo.Context) error {
 	start := parseDateQuery(c, "start", time.Now().AddDate(0, 0, -7))
 	end := parseDateQuery(c, "end", time.Now().AddDate(0, 0, 7))
 
-	events, err := h.u.ListEvents(c.Request().Context(), userID, start, end)
+	tagID, err := parseUUIDQuery(c, "tag_id")
+	if err != nil {
+		return err
+	}
+
+	events, err := h.u.ListEvents(c.Request().Context(), userID, start, end, tagID)
 	if err != nil {
 		return HandleError(c, err)
 	}
 	return c.JSON(http.StatusOK, events)
 }
 
+func (h *CalendarHandler) DeleteEvent(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid event id")
+	}
+
+	if err := h.u.DeleteEvent(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
 func (h *CalendarHandler) CreateTimetableSlot(c echo.Context) error {
 	userID := getUserID(c)
 	var req CreateTimetableSlotRequest
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// 一括送信で受け付ける heartbeat の上限
+	maxHeartbeatsPerRequest = 1000
+	// 送信元の時計のずれとして許す、未来の time の幅
+	maxHeartbeatClockSkew = 5 * time.Minute
+)
+
+type HeartbeatHandler struct {
+	u usecase.HeartbeatUsecase
+}
+
+func NewHeartbeatHandler(u usecase.HeartbeatUsecase) *HeartbeatHandler {
+	return &HeartbeatHandler{u: u}
+}
+
+// HeartbeatRequest は WakaTime API の heartbeat。time は UNIX 時刻(秒、小数あり、2000年以降)
+type HeartbeatRequest struct {
+	Entity   string  `json:"entity" validate:"required,max=2000"`
+	Type     string  `json:"type" validate:"max=20"`
+	Category string  `json:"category" validate:"max=50"`
+	Project  string  `json:"project" validate:"max=200"`
+	Branch   string  `json:"branch" validate:"max=200"`
+	Language string  `json:"language" validate:"max=100"`
+	IsWrite  bool    `json:"is_write"`
+	Time     float64 `json:"time" validate:"required,gte=946684800"`
+}
+
+// input は time が未来すぎる heartbeat を拒否する (time が大きすぎると int64 や timestamptz に収まらない)
+func (r HeartbeatRequest) input(now time.Time) (usecase.HeartbeatInput, error) {
+	if r.Time > float64(now.Add(maxHeartbeatClockSkew).Unix()) {
+		return usecase.HeartbeatInput{}, echo.NewHTTPError(http.StatusBadRequest, "time is out of range")
+	}
+	sec, frac := math.Modf(r.Time)
+	return usecase.HeartbeatInput{
+		Entity:   r.Entity,
+		Type:     r.Type,
+		Category: r.Category,
+		Project:  r.Project,
+		Branch:   r.Branch,
+		Language: r.Language,
+		IsWrite:  r.IsWrite,
+		Time:     time.Unix(int64(sec), int64(frac*1e9)),
+	}, nil
+}
+
+type CreateHeartbeatRuleRequest struct {
+	SourceProject string     `json:"source_project" validate:"max=200"`
+	EntityPrefix  string     `json:"entity_prefix" validate:"max=2000"`
+	Language      string     `json:"language" validate:"max=100"`
+	ProjectID     uuid.UUID  `json:"project_id" validate:"required"`
+	TaskID        *uuid.UUID `json:"task_id"`
+	Position      int32      `json:"position"`
+}
+
+// Create は1件の heartbeat を受け取る (POST /users/current/heartbeats)
+func (h *HeartbeatHandler) Create(c echo.Context) error {
+	userID := getUserID(c)
+	var req HeartbeatRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+	input, err := req.input(time.Now())
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.u.Ingest(c.Request().Context(), userID, c.Request().UserAgent(), []usecase.HeartbeatInput{input}); err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, map[string]any{"data": req})
+}
+
+// CreateBulk は heartbeat の配列を受け取る (POST /users/current/heartbeats.bulk)。
+// WakaTime と同じく、1件ごとの結果を [本文, ステータス] の組で返す
+func (h *HeartbeatHandler) CreateBulk(c echo.Context) error {
+	userID := getUserID(c)
+	var reqs []HeartbeatRequest
+	if err := c.Bind(&reqs); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if len(reqs) > maxHeartbeatsPerRequest {
+		return echo.NewHTTPError(http.StatusBadRequest, "too many heartbeats")
+	}
+
+	now := time.Now()
+	responses := make([][2]any, len(reqs))
+	inputs := make([]usecase.HeartbeatInput, 0, len(reqs))
+	for i := range reqs {
+		if err := c.Validate(&reqs[i]); err != nil {
+			responses[i] = [2]any{map[string]string{"error": err.Error()}, http.StatusBadRequest}
+			continue
+		}
+		input, err := reqs[i].input(now)
+		if err != nil {
+			responses[i] = [2]any{map[string]string{"error": err.Error()}, http.StatusBadRequest}
+			continue
+		}
+		inputs = append(inputs, input)
+		responses[i] = [2]any{map[string]any{"data": reqs[i]}, http.StatusCreated}
+	}
+
+	if _, err := h.u.Ingest(c.Request().Context(), userID, c.Request().UserAgent(), inputs); err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, map[string]any{"responses": responses})
+}
+
+func (h *HeartbeatHandler) CreateRule(c echo.Context) error {
+	userID := getUserID(c)
+	var req CreateHeartbeatRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	rule, err := h.u.CreateRule(c.Request().Context(), userID, usecase.HeartbeatRuleInput{
+		SourceProject: req.SourceProject,
+		EntityPrefix:  req.EntityPrefix,
+		Language:      req.Language,
+		ProjectID:     req.ProjectID,
+		TaskID:        req.TaskID,
+		Position:      req.Position,
+	})
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, rule)
+}
+
+func (h *HeartbeatHandler) ListRules(c echo.Context) error {
+	userID := getUserID(c)
+	rules, err := h.u.ListRules(c.Request().Context(), userID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, rules)
+}
+
+func (h *HeartbeatHandler) DeleteRule(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid rule id")
+	}
+
+	if err := h.u.DeleteRule(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -1,18 +1,35 @@
 package middleware
 
 import (
+	"encoding/base64"
 	"net/http"
 	"strings"
 
 	"github.com/gigaonion/taskalyst/backend/internal/config"
 	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
 	"github.com/gigaonion/taskalyst/backend/pkg/auth"
 	"github.com/labstack/echo/v4"
 )
 
+// StreamPath は変更通知のストリームのパス
+const StreamPath = "/api/stream"
+
 func AuthMiddleware(cfg *config.Config, repo *repository.Queries) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			// EventSource はヘッダーを付けられないので、ストリームだけはクエリの使い捨てのチケットも受け付ける。
+			// URL はアクセスログに残るため、JWT や PAT はクエリでは受け付けない
+			if ticket := c.QueryParam("ticket"); ticket != "" && c.Path() == StreamPath {
+				userID, err := repo.ConsumeStreamTicket(c.Request().Context(), auth.HashPAT(ticket))
+				if err != nil {
+					return echo.NewHTTPError(http.StatusUnauthorized, "invalid stream ticket")
+				}
+				c.Set("user_id", userID)
+				setActor(c, usecase.Actor{Source: usecase.ActorSourceWeb})
+				return next(c)
+			}
+
 			// PAT
 			apiKey := c.Request().Header.Get("X-API-KEY")
 			if apiKey != "" {
@@ -21,6 +38,7 @@ func AuthMiddleware(cfg *config.Config, repo *repository.Queries) echo.Middlewar
 				if err == nil {
 					c.Set("user_id", user.ID)
 					c.Set("role", string(user.Role))
+					setActor(c, usecase.Actor{Source: usecase.ActorSourceToken, Name: user.TokenName})
 					return next(c)
 				}
 				return echo.NewHTTPError(http.StatusUnauthorized, "invalid api key")
@@ -35,11 +53,18 @@ func AuthMiddleware(cfg *config.Config, repo *repository.Queries) echo.Middlewar
 					if err == nil {
 						c.Set("user_id", claims.UserID)
 						c.Set("role", claims.Role)
+						setActor(c, usecase.Actor{Source: usecase.ActorSourceWeb})
 						return next(c)
 					}
 				}
 				if len(parts) == 2 && parts[0] == "Basic" {
 					username, password, ok := c.Request().BasicAuth()
+					if !ok {
+						// WakaTime のプラグインは API キー (PAT) だけを Basic で送る
+						if raw, err := base64.StdEncoding.DecodeString(parts[1]); err == nil && !strings.Contains(string(raw), ":") {
+							password, ok = string(raw), true
+						}
+					}
 					if ok {
 						// We can use password as PAT
 						hash := auth.HashPAT(password)
@@ -48,6 +73,15 @@ func AuthMiddleware(cfg *config.Config, repo *repository.Queries) echo.Middlewar
 							c.Set("user_id", user.ID)
 							c.Set("role", string(user.Role))
 							c.Set("username", username) // Optional but good for principal URLs
+							actor := usecase.Actor{Source: usecase.ActorSourceToken, Name: user.TokenName}
+							if strings.HasPrefix(c.Path(), "/dav") {
+								// CalDAVクライアントは User-Agent で識別する
+								actor = usecase.Actor{Source: usecase.ActorSourceCalDAV, Name: c.Request().UserAgent()}
+								if actor.Name == "" {
+									actor.Name = user.TokenName
+								}
+							}
+							setActor(c, actor)
 							return next(c)
 						}
 					}
@@ -61,3 +95,11 @@ func AuthMiddleware(cfg *config.Config, repo *repository.Queries) echo.Middlewar
 		}
 	}
 }
+
+// setActor は操作の主体をリクエストのコンテキストに載せる(タスクの履歴に記録される)
+func setActor(c echo.Context, a usecase.Actor) {
+	if r := []rune(a.Name); len(r) > 100 {
+		a.Name = string(r[:100])
+	}
+	c.SetRequest(c.Request().WithContext(usecase.WithActor(c.Request().Context(), a)))
+}
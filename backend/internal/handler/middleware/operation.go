@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+// OperationIDHeader は取り消せる変更を行ったレスポンスに付ける操作IDのヘッダー
+const OperationIDHeader = "X-Operation-ID"
+
+// OperationMiddleware は usecase が記録した操作のIDをレスポンスヘッダーで返す。
+// POST /api/undo/:operationId に渡すと変更を取り消せる
+func OperationMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := usecase.WithOperationSlot(c.Request().Context())
+			c.SetRequest(c.Request().WithContext(ctx))
+			c.Response().Before(func() {
+				if c.Response().Status >= 400 {
+					return
+				}
+				if id, ok := usecase.OperationIDFrom(ctx); ok {
+					c.Response().Header().Set(OperationIDHeader, id.String())
+				}
+			})
+			return next(c)
+		}
+	}
+}
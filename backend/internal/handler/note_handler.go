@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type NoteHandler struct {
+	u usecase.NoteUsecase
+}
+
+func NewNoteHandler(u usecase.NoteUsecase) *NoteHandler {
+	return &NoteHandler{u: u}
+}
+
+type PreviewNoteRequest struct {
+	Markdown string `json:"markdown" validate:"max=200000"`
+}
+
+func (h *NoteHandler) GetTaskNote(c echo.Context) error {
+	userID := getUserID(c)
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid task id")
+	}
+
+	note, err := h.u.RenderTaskNote(c.Request().Context(), userID, taskID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, note)
+}
+
+func (h *NoteHandler) Preview(c echo.Context) error {
+	userID := getUserID(c)
+
+	var req PreviewNoteRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	note, err := h.u.Preview(c.Request().Context(), userID, req.Markdown)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, note)
+}
+
+// Backlinks は targetType("task" / "project")の :id を参照しているノートを返す
+func (h *NoteHandler) Backlinks(targetType string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID := getUserID(c)
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+		}
+
+		backlinks, err := h.u.ListBacklinks(c.Request().Context(), userID, targetType, id)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return c.JSON(http.StatusOK, backlinks)
+	}
+}
+
+func (h *NoteHandler) SyncChecklist(c echo.Context) error {
+	userID := getUserID(c)
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid task id")
+	}
+
+	items, err := h.u.SyncChecklist(c.Request().Context(), userID, taskID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, items)
+}
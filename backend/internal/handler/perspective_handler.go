@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type PerspectiveHandler struct {
+	u usecase.PerspectiveUsecase
+}
+
+func NewPerspectiveHandler(u usecase.PerspectiveUsecase) *PerspectiveHandler {
+	return &PerspectiveHandler{u: u}
+}
+
+type CreatePerspectiveRequest struct {
+	Name   string `json:"name" validate:"required,max=100"`
+	Filter string `json:"filter" validate:"required,max=1000"`
+}
+
+type UpdatePerspectiveRequest struct {
+	Name   *string `json:"name" validate:"omitempty,min=1,max=100"`
+	Filter *string `json:"filter" validate:"omitempty,min=1,max=1000"`
+}
+
+func (h *PerspectiveHandler) Create(c echo.Context) error {
+	userID := getUserID(c)
+
+	var req CreatePerspectiveRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	p, err := h.u.CreatePerspective(c.Request().Context(), userID, req.Name, req.Filter)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, p)
+}
+
+func (h *PerspectiveHandler) List(c echo.Context) error {
+	userID := getUserID(c)
+
+	perspectives, err := h.u.ListPerspectives(c.Request().Context(), userID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, perspectives)
+}
+
+func (h *PerspectiveHandler) Update(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid perspective id")
+	}
+
+	var req UpdatePerspectiveRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	p, err := h.u.UpdatePerspective(c.Request().Context(), userID, id, req.Name, req.Filter)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, p)
+}
+
+func (h *PerspectiveHandler) Delete(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid perspective id")
+	}
+
+	if err := h.u.DeletePerspective(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type PomodoroHandler struct {
+	u usecase.PomodoroUsecase
+}
+
+func NewPomodoroHandler(u usecase.PomodoroUsecase) *PomodoroHandler {
+	return &PomodoroHandler{u: u}
+}
+
+type StartPomodoroRequest struct {
+	ProjectID uuid.UUID  `json:"project_id" validate:"required"`
+	TaskID    *uuid.UUID `json:"task_id"`
+	Note      string     `json:"note"`
+}
+
+func (h *PomodoroHandler) Start(c echo.Context) error {
+	userID := getUserID(c)
+	var req StartPomodoroRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	state, err := h.u.Start(c.Request().Context(), userID, req.ProjectID, req.TaskID, req.Note)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, state)
+}
+
+// Get は進行中のセッションの状態を返す。なければ 204
+func (h *PomodoroHandler) Get(c echo.Context) error {
+	userID := getUserID(c)
+	state, err := h.u.Get(c.Request().Context(), userID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	if state == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+	return c.JSON(http.StatusOK, state)
+}
+
+// Action は状態を変える操作 (pause / resume / skip / interrupt / stop) のハンドラを返す
+func (h *PomodoroHandler) Action(fn func(u usecase.PomodoroUsecase, ctx context.Context, userID uuid.UUID) (*usecase.PomodoroState, error)) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state, err := fn(h.u, c.Request().Context(), getUserID(c))
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return c.JSON(http.StatusOK, state)
+	}
+}
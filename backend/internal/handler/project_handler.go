@@ -30,6 +30,27 @@ type CreateProjectRequest struct {
 	Color       string `json:"color"`
 }
 
+type UpdateProjectRequest struct {
+	Title       *string `json:"title" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description"`
+	Color       *string `json:"color" validate:"omitempty,hexcolor"`
+	IsArchived  *bool   `json:"is_archived"`
+	// 0 でWIP制限を解除
+	WipLimit *int32 `json:"wip_limit" validate:"omitempty,min=0"`
+}
+
+type CreateProjectStatusRequest struct {
+	Name     string `json:"name" validate:"required,max=50"`
+	Category string `json:"category" validate:"required,oneof=TODO DOING DONE"`
+	Color    string `json:"color" validate:"omitempty,hexcolor"`
+}
+
+type UpdateProjectStatusRequest struct {
+	Name     *string `json:"name" validate:"omitempty,min=1,max=50"`
+	Color    *string `json:"color" validate:"omitempty,hexcolor"`
+	Position *int32  `json:"position"`
+}
+
 func (h *ProjectHandler) CreateCategory(c echo.Context) error {
 	userID := getUserID(c)
 
@@ -106,3 +127,111 @@ func (h *ProjectHandler) ListProjects(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, projects)
 }
+
+func (h *ProjectHandler) UpdateProject(c echo.Context) error {
+	userID := getUserID(c)
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid project id")
+	}
+
+	var req UpdateProjectRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	project, err := h.u.UpdateProject(c.Request().Context(), userID, projectID, req.Title, req.Description, req.Color, req.IsArchived, req.WipLimit)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, project)
+}
+
+func (h *ProjectHandler) DeleteProject(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid project id")
+	}
+
+	if err := h.u.DeleteProject(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// --- Project Status ---
+
+func (h *ProjectHandler) CreateStatus(c echo.Context) error {
+	userID := getUserID(c)
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid project id")
+	}
+
+	var req CreateProjectStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	status, err := h.u.CreateProjectStatus(c.Request().Context(), userID, projectID, req.Name, repository.TaskStatus(req.Category), req.Color)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, status)
+}
+
+func (h *ProjectHandler) ListStatuses(c echo.Context) error {
+	userID := getUserID(c)
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid project id")
+	}
+
+	statuses, err := h.u.ListProjectStatuses(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, statuses)
+}
+
+func (h *ProjectHandler) UpdateStatus(c echo.Context) error {
+	userID := getUserID(c)
+	statusID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid status id")
+	}
+
+	var req UpdateProjectStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	status, err := h.u.UpdateProjectStatus(c.Request().Context(), userID, statusID, req.Name, req.Color, req.Position)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+func (h *ProjectHandler) DeleteStatus(c echo.Context) error {
+	userID := getUserID(c)
+	statusID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid status id")
+	}
+
+	if err := h.u.DeleteProjectStatus(c.Request().Context(), userID, statusID); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/gigaonion/taskalyst/backend/pkg/quickadd"
+	"github.com/labstack/echo/v4"
+)
+
+type QuickAddHandler struct {
+	u usecase.QuickAddUsecase
+}
+
+func NewQuickAddHandler(u usecase.QuickAddUsecase) *QuickAddHandler {
+	return &QuickAddHandler{u: u}
+}
+
+type QuickAddRequest struct {
+	Text     string `json:"text" validate:"required,max=500"`
+	Kind     string `json:"kind" validate:"omitempty,oneof=task event"`
+	Timezone string `json:"timezone"` // IANA名 (例: Asia/Tokyo)。省略時はユーザー設定のタイムゾーン
+	Preview  bool   `json:"preview"`
+}
+
+// QuickAdd handles POST /api/quick-add
+// preview=true の場合は解釈結果だけを返す
+func (h *QuickAddHandler) QuickAdd(c echo.Context) error {
+	userID := getUserID(c)
+
+	var req QuickAddRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	opts := usecase.QuickAddOptions{Preview: req.Preview}
+	if req.Kind != "" {
+		kind := quickadd.Kind(req.Kind)
+		opts.Kind = &kind
+	}
+	if req.Timezone != "" {
+		loc, err := time.LoadLocation(req.Timezone)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid timezone")
+		}
+		opts.Location = loc
+	}
+
+	res, err := h.u.QuickAdd(c.Request().Context(), userID, req.Text, opts)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	if req.Preview {
+		return c.JSON(http.StatusOK, res)
+	}
+	return c.JSON(http.StatusCreated, res)
+}
@@ -31,16 +31,17 @@ type CreateResultRequest struct {
 
 // レスポンス(pgtype.Numericをfloat64に変換)
 type ResultResponse struct {
-	ID           string  `json:"id"`
-	ProjectID    string  `json:"project_id"`
-	TaskID       string  `json:"task_id,omitempty"`
-	Type         string  `json:"type"`
-	Value        float64 `json:"value"`
-	RecordedAt   string  `json:"recorded_at"`
-	Note         string  `json:"note"`
-	ProjectTitle string  `json:"project_title,omitempty"`
-	ProjectColor string  `json:"project_color,omitempty"`
-	TaskTitle    string  `json:"task_title,omitempty"`
+	ID           string   `json:"id"`
+	ProjectID    string   `json:"project_id"`
+	TaskID       string   `json:"task_id,omitempty"`
+	Type         string   `json:"type"`
+	Value        float64  `json:"value"`
+	RecordedAt   string   `json:"recorded_at"`
+	Note         string   `json:"note"`
+	ProjectTitle string   `json:"project_title,omitempty"`
+	ProjectColor string   `json:"project_color,omitempty"`
+	TaskTitle    string   `json:"task_title,omitempty"`
+	TagIDs       []string `json:"tag_ids,omitempty"`
 }
 
 func toFloat64(n pgtype.Numeric) float64 {
@@ -82,6 +83,7 @@ func toResultListResponse(r repository.ListResultsRow) ResultResponse {
 		ProjectTitle: r.ProjectTitle,
 		ProjectColor: r.ProjectColor,
 		TaskTitle:    r.TaskTitle,
+		TagIDs:       uuidStrings(r.TagIds),
 	}
 }
 
@@ -130,15 +132,16 @@ func (h *ResultHandler) List(c echo.Context) error {
 	to := parseDateQuery(c, "to", time.Now())
 	from := parseDateQuery(c, "from", to.AddDate(0, -1, 0))
 
-	var pID *uuid.UUID
-	if pid := c.QueryParam("project_id"); pid != "" {
-		id, err := uuid.Parse(pid)
-		if err == nil {
-			pID = &id
-		}
+	pID, err := parseUUIDQuery(c, "project_id")
+	if err != nil {
+		return err
+	}
+	tagID, err := parseUUIDQuery(c, "tag_id")
+	if err != nil {
+		return err
 	}
 
-	rows, err := h.u.ListResults(c.Request().Context(), userID, pID, from, to)
+	rows, err := h.u.ListResults(c.Request().Context(), userID, pID, from, to, tagID)
 	if err != nil {
 		return HandleError(c, err)
 	}
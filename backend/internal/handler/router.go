@@ -4,11 +4,13 @@ import (
 	"github.com/gigaonion/taskalyst/backend/internal/config"
 	"github.com/gigaonion/taskalyst/backend/internal/handler/middleware"
 	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/gigaonion/taskalyst/backend/pkg/markdown"
 	"github.com/labstack/echo/v4"
 	"net/http"
 )
 
-func RegisterRoutes(e *echo.Echo, userHandler *UserHandler, projectHandler *ProjectHandler, taskHandler *TaskHandler, timeHandler *TimeHandler, apiTokenHandler *ApiTokenHandler, cfg *config.Config, calendarHandler *CalendarHandler, resultHandler *ResultHandler, caldavHandler *CalDavHandler, repo *repository.Queries) {
+func RegisterRoutes(e *echo.Echo, userHandler *UserHandler, projectHandler *ProjectHandler, taskHandler *TaskHandler, timeHandler *TimeHandler, apiTokenHandler *ApiTokenHandler, cfg *config.Config, calendarHandler *CalendarHandler, resultHandler *ResultHandler, caldavHandler *CalDavHandler, tagHandler *TagHandler, searchHandler *SearchHandler, templateHandler *TemplateHandler, perspectiveHandler *PerspectiveHandler, quickAddHandler *QuickAddHandler, taskActivityHandler *TaskActivityHandler, attachmentHandler *AttachmentHandler, noteHandler *NoteHandler, taskBulkHandler *TaskBulkHandler, trashHandler *TrashHandler, undoHandler *UndoHandler, pomodoroHandler *PomodoroHandler, streamHandler *StreamHandler, heartbeatHandler *HeartbeatHandler, activityHandler *ActivityHandler, billingHandler *BillingHandler, repo *repository.Queries) {
 	// Auth Group
 	authGroup := e.Group("/auth")
 	authGroup.POST("/signup", userHandler.SignUp)
@@ -17,8 +19,11 @@ func RegisterRoutes(e *echo.Echo, userHandler *UserHandler, projectHandler *Proj
 
 	api := e.Group("/api")
 	api.Use(middleware.AuthMiddleware(cfg, repo))
+	api.Use(middleware.OperationMiddleware())
 
 	api.GET("/users/me", userHandler.GetMe)
+	api.GET("/users/me/preferences", userHandler.GetPreferences)
+	api.PATCH("/users/me/preferences", userHandler.UpdatePreferences)
 
 	// API Tokens
 	api.POST("/tokens", apiTokenHandler.Create)
@@ -30,20 +35,79 @@ func RegisterRoutes(e *echo.Echo, userHandler *UserHandler, projectHandler *Proj
 
 	api.POST("/projects", projectHandler.CreateProject)
 	api.GET("/projects", projectHandler.ListProjects)
+	api.PATCH("/projects/:id", projectHandler.UpdateProject)
+	api.DELETE("/projects/:id", projectHandler.DeleteProject)
+	api.GET("/projects/:id/board", taskHandler.GetBoard)
+	api.POST("/projects/:id/statuses", projectHandler.CreateStatus)
+	api.GET("/projects/:id/statuses", projectHandler.ListStatuses)
+	api.PATCH("/project-statuses/:id", projectHandler.UpdateStatus)
+	api.DELETE("/project-statuses/:id", projectHandler.DeleteStatus)
+
+	// Billing
+	api.GET("/projects/:id/billing", billingHandler.GetBilling)
+	api.PUT("/projects/:id/billing", billingHandler.SetBilling)
+	api.DELETE("/projects/:id/billing", billingHandler.DeleteBilling)
+	api.GET("/projects/:id/invoice", billingHandler.GetInvoice)
 
 	api.POST("/tasks", taskHandler.CreateTask)
 	api.GET("/tasks", taskHandler.ListTasks)
+	api.POST("/tasks/bulk", taskBulkHandler.Bulk)
+	api.GET("/tasks/matrix", taskHandler.GetMatrix)
+	api.PATCH("/tasks/:id", taskHandler.UpdateTask)
+	api.DELETE("/tasks/:id", taskHandler.DeleteTask)
 	api.PATCH("/tasks/:id/status", taskHandler.UpdateTaskStatus)
+	api.POST("/tasks/:id/move", taskHandler.MoveTask)
+	api.GET("/tasks/:id/activity", taskActivityHandler.GetTimeline)
+	api.POST("/tasks/:id/comments", taskActivityHandler.AddComment)
+	api.PATCH("/task-comments/:id", taskActivityHandler.UpdateComment)
+	api.DELETE("/task-comments/:id", taskActivityHandler.DeleteComment)
 
 	api.POST("/tasks/:id/checklist", taskHandler.AddChecklistItem)
-	api.PATCH("/checklist-items/:id", taskHandler.ToggleChecklistItem)
+	api.PATCH("/checklist-items/:id", taskHandler.UpdateChecklistItem)
 
+	api.GET("/time-entries", timeHandler.ListEntries)
 	api.POST("/time-entries", timeHandler.StartTimer)
+	api.POST("/time-entries/switch", timeHandler.SwitchTimer)
+	api.GET("/time-entries/current", timeHandler.CurrentTimer)
+	api.GET("/time-entries/auto-stops", timeHandler.ListAutoStops)
+	api.POST("/time-entries/manual", timeHandler.CreateEntry)
+	api.POST("/time-entries/merge", timeHandler.MergeEntries)
+	api.PATCH("/time-entries/:id", timeHandler.UpdateEntry)
+	api.DELETE("/time-entries/:id", timeHandler.DeleteEntry)
 	api.PATCH("/time-entries/:id/stop", timeHandler.StopTimer)
+	api.POST("/time-entries/:id/split", timeHandler.SplitEntry)
+
+	// Pomodoro
+	api.GET("/pomodoro", pomodoroHandler.Get)
+	api.POST("/pomodoro", pomodoroHandler.Start)
+	api.POST("/pomodoro/pause", pomodoroHandler.Action(usecase.PomodoroUsecase.Pause))
+	api.POST("/pomodoro/resume", pomodoroHandler.Action(usecase.PomodoroUsecase.Resume))
+	api.POST("/pomodoro/skip", pomodoroHandler.Action(usecase.PomodoroUsecase.Skip))
+	api.POST("/pomodoro/interrupt", pomodoroHandler.Action(usecase.PomodoroUsecase.Interrupt))
+	api.DELETE("/pomodoro", pomodoroHandler.Action(usecase.PomodoroUsecase.Stop))
+
+	// Activity heartbeats (WakaTime 互換。プラグインの api_url に <host>/api/compat/wakatime/v1 を設定する)
+	api.POST("/compat/wakatime/v1/users/current/heartbeats", heartbeatHandler.Create)
+	api.POST("/compat/wakatime/v1/users/current/heartbeats.bulk", heartbeatHandler.CreateBulk)
+	api.GET("/heartbeat-rules", heartbeatHandler.ListRules)
+	api.POST("/heartbeat-rules", heartbeatHandler.CreateRule)
+	api.DELETE("/heartbeat-rules/:id", heartbeatHandler.DeleteRule)
+
+	// Desktop activity (ActivityWatch のバケットと同じ形でイベントを受け取る)
+	api.POST("/activity/buckets/:bucket/events", activityHandler.CreateEvents)
+	api.POST("/activity/buckets/:bucket/heartbeat", activityHandler.Heartbeat)
+	api.GET("/activity/report", activityHandler.GetReport)
+	api.GET("/activity-rules", activityHandler.ListRules)
+	api.POST("/activity-rules", activityHandler.CreateRule)
+	api.DELETE("/activity-rules/:id", activityHandler.DeleteRule)
+
 	api.GET("/stats/growth", timeHandler.GetStats)
+	api.GET("/stats/cycle-time", taskActivityHandler.GetCycleTime)
+	api.GET("/stats/estimates", taskHandler.GetEstimateReport)
 
 	api.POST("/events", calendarHandler.CreateEvent)
 	api.GET("/events", calendarHandler.ListEvents)
+	api.DELETE("/events/:id", calendarHandler.DeleteEvent)
 
 	api.POST("/calendars", calendarHandler.CreateCalendar)
 	api.GET("/calendars", calendarHandler.ListCalendars)
@@ -58,6 +122,71 @@ func RegisterRoutes(e *echo.Echo, userHandler *UserHandler, projectHandler *Proj
 	api.GET("/results", resultHandler.List)
 	api.DELETE("/results/:id", resultHandler.Delete)
 
+	// Tags
+	api.POST("/tags", tagHandler.Create)
+	api.GET("/tags", tagHandler.List)
+	api.PATCH("/tags/:id", tagHandler.Update)
+	api.DELETE("/tags/:id", tagHandler.Delete)
+	api.GET("/stats/tags", tagHandler.GetStats)
+
+	api.PUT("/tasks/:id/tags/:tagID", tagHandler.Attach(usecase.TagTargetTask))
+	api.DELETE("/tasks/:id/tags/:tagID", tagHandler.Detach(usecase.TagTargetTask))
+	api.PUT("/events/:id/tags/:tagID", tagHandler.Attach(usecase.TagTargetEvent))
+	api.DELETE("/events/:id/tags/:tagID", tagHandler.Detach(usecase.TagTargetEvent))
+	api.PUT("/time-entries/:id/tags/:tagID", tagHandler.Attach(usecase.TagTargetTimeEntry))
+	api.DELETE("/time-entries/:id/tags/:tagID", tagHandler.Detach(usecase.TagTargetTimeEntry))
+	api.PUT("/results/:id/tags/:tagID", tagHandler.Attach(usecase.TagTargetResult))
+	api.DELETE("/results/:id/tags/:tagID", tagHandler.Detach(usecase.TagTargetResult))
+
+	// Search
+	api.GET("/search", searchHandler.Search)
+
+	// Templates
+	api.POST("/templates", templateHandler.Create)
+	api.GET("/templates", templateHandler.List)
+	api.GET("/templates/:id", templateHandler.Get)
+	api.DELETE("/templates/:id", templateHandler.Delete)
+	api.POST("/templates/from-task/:id", templateHandler.CaptureTask)
+	api.POST("/templates/from-project/:id", templateHandler.CaptureProject)
+	api.POST("/templates/:id/instantiate", templateHandler.Instantiate)
+
+	// Perspectives (GET /tasks?perspective=:id で利用)
+	api.POST("/perspectives", perspectiveHandler.Create)
+	api.GET("/perspectives", perspectiveHandler.List)
+	api.PATCH("/perspectives/:id", perspectiveHandler.Update)
+	api.DELETE("/perspectives/:id", perspectiveHandler.Delete)
+
+	// Quick add
+	api.POST("/quick-add", quickAddHandler.QuickAdd)
+
+	// Attachments
+	api.POST("/tasks/:id/attachments", attachmentHandler.Upload(usecase.AttachmentTargetTask))
+	api.GET("/tasks/:id/attachments", attachmentHandler.List(usecase.AttachmentTargetTask))
+	api.POST("/events/:id/attachments", attachmentHandler.Upload(usecase.AttachmentTargetEvent))
+	api.GET("/events/:id/attachments", attachmentHandler.List(usecase.AttachmentTargetEvent))
+	api.GET("/attachments/:id", attachmentHandler.Download)
+	api.DELETE("/attachments/:id", attachmentHandler.Delete)
+
+	// Notes
+	api.GET("/tasks/:id/note", noteHandler.GetTaskNote)
+	api.POST("/tasks/:id/checklist/sync", noteHandler.SyncChecklist)
+	api.GET("/tasks/:id/backlinks", noteHandler.Backlinks(markdown.KindTask))
+	api.GET("/projects/:id/backlinks", noteHandler.Backlinks(markdown.KindProject))
+	api.POST("/notes/preview", noteHandler.Preview)
+
+	// Trash
+	api.GET("/trash", trashHandler.List)
+	api.DELETE("/trash", trashHandler.Empty)
+	api.POST("/trash/:kind/:id/restore", trashHandler.Restore)
+	api.DELETE("/trash/:kind/:id", trashHandler.Purge)
+
+	// Undo (変更のレスポンスの X-Operation-ID を指定する)
+	api.POST("/undo/:operationId", undoHandler.Undo)
+
+	// Realtime (Server-Sent Events)
+	api.POST("/stream/tickets", streamHandler.CreateTicket)
+	e.GET(middleware.StreamPath, streamHandler.Stream, middleware.AuthMiddleware(cfg, repo))
+
 	// CalDAV
 	dav := e.Group("/dav")
 	dav.Use(middleware.AuthMiddleware(cfg, repo))
@@ -73,6 +202,9 @@ func RegisterRoutes(e *echo.Echo, userHandler *UserHandler, projectHandler *Proj
 	dav.Match([]string{"OPTIONS", "PROPFIND", "REPORT"}, "/calendars/:userID/:calendarID/", caldavHandler.CalendarCollection)
 	dav.GET("/calendars/:userID/:calendarID", caldavHandler.GetCalendar) // For manual download
 
+	// Attachments (VTODO/VEVENT の ATTACH が指すURL)
+	dav.GET("/attachments/:id", attachmentHandler.Download)
+
 	// Calendar Resource
 	dav.Match([]string{"OPTIONS", "PROPFIND", "GET", "PUT", "DELETE"}, "/calendars/:userID/:calendarID/:resource", caldavHandler.CalendarResource)
 
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type SearchHandler struct {
+	u usecase.SearchUsecase
+}
+
+func NewSearchHandler(u usecase.SearchUsecase) *SearchHandler {
+	return &SearchHandler{u: u}
+}
+
+// Search handles GET /api/search?q=&project_id=&status=&from=&to=&limit=
+// status (TODO/DOING/DONE) はタスクの状態なので、指定するとタスクだけを返す
+func (h *SearchHandler) Search(c echo.Context) error {
+	userID := getUserID(c)
+
+	q := c.QueryParam("q")
+	if q == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "q is required")
+	}
+
+	var status *repository.TaskStatus
+	if s := c.QueryParam("status"); s != "" {
+		st := repository.TaskStatus(s)
+		switch st {
+		case repository.TaskStatusTODO, repository.TaskStatusDOING, repository.TaskStatusDONE:
+			status = &st
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid status")
+		}
+	}
+
+	var from, to *time.Time
+	if f := c.QueryParam("from"); f != "" {
+		if t, err := time.Parse("2006-01-02", f); err == nil {
+			from = &t
+		}
+	}
+	if t := c.QueryParam("to"); t != "" {
+		if tm, err := time.Parse("2006-01-02", t); err == nil {
+			// 終了日はその日の終わりまで含める
+			tm = tm.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			to = &tm
+		}
+	}
+
+	limit := defaultSearchLimit
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 {
+		limit = min(l, maxSearchLimit)
+	}
+
+	projectID, err := parseUUIDQuery(c, "project_id")
+	if err != nil {
+		return err
+	}
+	res, err := h.u.Search(c.Request().Context(), userID, q, projectID, status, from, to, limit)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, res)
+}
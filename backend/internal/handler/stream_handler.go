@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+// 接続を保つためのコメントを送る間隔
+const streamHeartbeat = 25 * time.Second
+
+type StreamHandler struct {
+	u usecase.StreamUsecase
+}
+
+func NewStreamHandler(u usecase.StreamUsecase) *StreamHandler {
+	return &StreamHandler{u: u}
+}
+
+// CreateTicket は Stream に接続するための使い捨てのチケットを返す。
+// EventSource はヘッダーを付けられないので、GET /api/stream?ticket=... で渡す
+func (h *StreamHandler) CreateTicket(c echo.Context) error {
+	userID := getUserID(c)
+	ticket, err := h.u.CreateTicket(c.Request().Context(), userID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, ticket)
+}
+
+// Stream は変更の通知を Server-Sent Events で送り続ける。
+// event は timer.started / timer.stopped / time_entry.changed / task.changed / event.changed /
+// pomodoro.phase / pomodoro.changed / pomodoro.ended / resync (取り直しが必要)
+func (h *StreamHandler) Stream(c echo.Context) error {
+	userID := getUserID(c)
+	ctx := c.Request().Context()
+	events, unsubscribe := h.u.Subscribe(userID)
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no") // リバースプロキシでバッファさせない
+	res.WriteHeader(http.StatusOK)
+	fmt.Fprint(res, "retry: 3000\n\n")
+	res.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			fmt.Fprint(res, ": ping\n\n")
+		case ev, ok := <-events:
+			if !ok {
+				// サーバーの終了
+				return nil
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(res, "event: %s\ndata: %s\n\n", ev.Type, data)
+		}
+		res.Flush()
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type TagHandler struct {
+	u usecase.TagUsecase
+}
+
+func NewTagHandler(u usecase.TagUsecase) *TagHandler {
+	return &TagHandler{u: u}
+}
+
+type CreateTagRequest struct {
+	Name  string `json:"name" validate:"required,max=50"`
+	Color string `json:"color" validate:"omitempty,hexcolor"`
+}
+
+type UpdateTagRequest struct {
+	Name  *string `json:"name" validate:"omitempty,min=1,max=50"`
+	Color *string `json:"color" validate:"omitempty,hexcolor"`
+}
+
+func (h *TagHandler) Create(c echo.Context) error {
+	userID := getUserID(c)
+
+	var req CreateTagRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	tag, err := h.u.CreateTag(c.Request().Context(), userID, req.Name, req.Color)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, tag)
+}
+
+func (h *TagHandler) List(c echo.Context) error {
+	userID := getUserID(c)
+
+	tags, err := h.u.ListTags(c.Request().Context(), userID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, tags)
+}
+
+func (h *TagHandler) Update(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid tag id")
+	}
+
+	var req UpdateTagRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	tag, err := h.u.UpdateTag(c.Request().Context(), userID, id, req.Name, req.Color)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (h *TagHandler) Delete(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid tag id")
+	}
+
+	if err := h.u.DeleteTag(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Attach returns a handler for PUT /<target>/:id/tags/:tagID
+func (h *TagHandler) Attach(target usecase.TagTarget) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID := getUserID(c)
+		targetID, tagID, err := parseTaggingParams(c)
+		if err != nil {
+			return err
+		}
+
+		if err := h.u.AttachTag(c.Request().Context(), userID, target, targetID, tagID); err != nil {
+			return HandleError(c, err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// Detach returns a handler for DELETE /<target>/:id/tags/:tagID
+func (h *TagHandler) Detach(target usecase.TagTarget) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID := getUserID(c)
+		targetID, tagID, err := parseTaggingParams(c)
+		if err != nil {
+			return err
+		}
+
+		if err := h.u.DetachTag(c.Request().Context(), userID, target, targetID, tagID); err != nil {
+			return HandleError(c, err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func (h *TagHandler) GetStats(c echo.Context) error {
+	userID := getUserID(c)
+
+	to := parseDateQuery(c, "to", time.Now())
+	from := parseDateQuery(c, "from", to.AddDate(0, -1, 0))
+
+	stats, err := h.u.GetTagStats(c.Request().Context(), userID, from, to)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+func parseTaggingParams(c echo.Context) (uuid.UUID, uuid.UUID, error) {
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+	tagID, err := uuid.Parse(c.Param("tagID"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, "invalid tag id")
+	}
+	return targetID, tagID, nil
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type TaskActivityHandler struct {
+	u usecase.TaskActivityUsecase
+}
+
+func NewTaskActivityHandler(u usecase.TaskActivityUsecase) *TaskActivityHandler {
+	return &TaskActivityHandler{u: u}
+}
+
+type TaskCommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+// GetTimeline handles GET /api/tasks/:id/activity
+func (h *TaskActivityHandler) GetTimeline(c echo.Context) error {
+	userID := getUserID(c)
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid task id")
+	}
+
+	entries, err := h.u.GetTimeline(c.Request().Context(), userID, taskID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+func (h *TaskActivityHandler) AddComment(c echo.Context) error {
+	userID := getUserID(c)
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid task id")
+	}
+
+	var req TaskCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	comment, err := h.u.AddComment(c.Request().Context(), userID, taskID, req.Body)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, comment)
+}
+
+func (h *TaskActivityHandler) UpdateComment(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid comment id")
+	}
+
+	var req TaskCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	comment, err := h.u.UpdateComment(c.Request().Context(), userID, id, req.Body)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, comment)
+}
+
+func (h *TaskActivityHandler) DeleteComment(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid comment id")
+	}
+
+	if err := h.u.DeleteComment(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetCycleTime handles GET /api/stats/cycle-time?from=&to=
+func (h *TaskActivityHandler) GetCycleTime(c echo.Context) error {
+	userID := getUserID(c)
+
+	to := parseDateQuery(c, "to", time.Now())
+	from := parseDateQuery(c, "from", to.AddDate(0, -1, 0))
+
+	stats, err := h.u.GetCycleTime(c.Request().Context(), userID, from, to)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type TaskBulkHandler struct {
+	u usecase.TaskBulkUsecase
+}
+
+func NewTaskBulkHandler(u usecase.TaskBulkUsecase) *TaskBulkHandler {
+	return &TaskBulkHandler{u: u}
+}
+
+// TaskBulkRequest は task_ids か filter(フィルタ式)で対象を指定する。
+// 操作ごとに必要な項目: set_status は status か status_id、move_project は project_id、
+// shift_due は days、add_tag / remove_tag は tag_id
+type TaskBulkRequest struct {
+	TaskIDs   []uuid.UUID `json:"task_ids" validate:"max=500"`
+	Filter    string      `json:"filter" validate:"max=1000"`
+	Operation string      `json:"operation" validate:"required,oneof=set_status move_project shift_due add_tag remove_tag delete"`
+	Status    string      `json:"status" validate:"omitempty,oneof=TODO DOING DONE"`
+	StatusID  *uuid.UUID  `json:"status_id"`
+	ProjectID *uuid.UUID  `json:"project_id"`
+	Days      int         `json:"days" validate:"min=-3650,max=3650"`
+	TagID     *uuid.UUID  `json:"tag_id"`
+	Atomic    bool        `json:"atomic"`
+}
+
+// Bulk は各タスクの結果を返す。atomic 指定で失敗があり全体を取り消した場合は 422
+func (h *TaskBulkHandler) Bulk(c echo.Context) error {
+	userID := getUserID(c)
+
+	var req TaskBulkRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	result, err := h.u.Bulk(c.Request().Context(), userID, usecase.TaskBulkInput{
+		TaskIDs:   req.TaskIDs,
+		Filter:    req.Filter,
+		Operation: usecase.BulkOperation(req.Operation),
+		Status:    repository.TaskStatus(req.Status),
+		StatusID:  req.StatusID,
+		ProjectID: req.ProjectID,
+		Days:      req.Days,
+		TagID:     req.TagID,
+		Atomic:    req.Atomic,
+	})
+	if err != nil {
+		return HandleError(c, err)
+	}
+	if !result.Applied {
+		return c.JSON(http.StatusUnprocessableEntity, result)
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
@@ -21,20 +22,43 @@ func NewTaskHandler(u usecase.TaskUsecase) *TaskHandler {
 type CreateTaskRequest struct {
 	ProjectID string     `json:"project_id" validate:"required"`
 	Title     string     `json:"title" validate:"required"`
-	Note      string     `json:"note"`
+	Note      string     `json:"note" validate:"max=200000"`
 	DueDate   *time.Time `json:"due_date"`
+	Priority  int16      `json:"priority" validate:"min=0,max=3"` // 0:なし 1:低 2:中 3:高
 }
 
+// UpdateTaskStatusRequest は status(基本ステータス)か status_id(プロジェクト独自のステータス)のどちらかを指定する
 type UpdateTaskStatusRequest struct {
-	Status string `json:"status" validate:"required,oneof=TODO DOING DONE"`
+	Status   string     `json:"status" validate:"required_without=StatusID,omitempty,oneof=TODO DOING DONE"`
+	StatusID *uuid.UUID `json:"status_id"`
+}
+
+// MoveTaskRequest はボード上の移動先。prev_id/next_id は移動先レーンで前後に並ぶタスク
+type MoveTaskRequest struct {
+	Status   string     `json:"status" validate:"required_without=StatusID,omitempty,oneof=TODO DOING DONE"`
+	StatusID *uuid.UUID `json:"status_id"`
+	PrevID   *uuid.UUID `json:"prev_id"`
+	NextID   *uuid.UUID `json:"next_id"`
+}
+
+// UpdateTaskRequest は指定した項目だけを更新する。estimated_minutes に負の値を指定すると見積もりを解除する
+type UpdateTaskRequest struct {
+	Title            *string    `json:"title" validate:"omitempty,min=1"`
+	Note             *string    `json:"note" validate:"omitempty,max=200000"`
+	DueDate          *time.Time `json:"due_date"`
+	Priority         *int16     `json:"priority" validate:"omitempty,min=0,max=3"`
+	EstimatedMinutes *int32     `json:"estimated_minutes"`
+	SyncChecklist    bool       `json:"sync_checklist"` // ノートのタスクリストをチェックリストに反映する
 }
 
 type AddChecklistItemRequest struct {
-	Content string `json:"content" validate:"required"`
+	Content          string `json:"content" validate:"required"`
+	EstimatedMinutes *int32 `json:"estimated_minutes" validate:"omitempty,min=0"`
 }
 
-type ToggleChecklistItemRequest struct {
-	IsCompleted bool `json:"is_completed"`
+type UpdateChecklistItemRequest struct {
+	IsCompleted      *bool  `json:"is_completed"`
+	EstimatedMinutes *int32 `json:"estimated_minutes"`
 }
 
 func (h *TaskHandler) CreateTask(c echo.Context) error {
@@ -54,7 +78,7 @@ func (h *TaskHandler) CreateTask(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid project id")
 	}
 
-	task, err := h.u.CreateTask(c.Request().Context(), userID, projectID, req.Title, req.Note, req.DueDate)
+	task, err := h.u.CreateTask(c.Request().Context(), userID, projectID, req.Title, req.Note, req.DueDate, req.Priority)
 	if err != nil {
 		return HandleError(c, err)
 	}
@@ -65,12 +89,9 @@ func (h *TaskHandler) CreateTask(c echo.Context) error {
 func (h *TaskHandler) ListTasks(c echo.Context) error {
 	userID := getUserID(c)
 
-	var projectID *uuid.UUID
-	if pid := c.QueryParam("project_id"); pid != "" {
-		id, err := uuid.Parse(pid)
-		if err == nil {
-			projectID = &id
-		}
+	projectID, err := parseUUIDQuery(c, "project_id")
+	if err != nil {
+		return err
 	}
 
 	var status *repository.TaskStatus
@@ -90,7 +111,18 @@ func (h *TaskHandler) ListTasks(c echo.Context) error {
 			to = &tm
 		}
 	}
-	tasks, err := h.u.ListTasks(c.Request().Context(), userID, projectID, status, from, to)
+	tagID, err := parseUUIDQuery(c, "tag_id")
+	if err != nil {
+		return err
+	}
+	// filter: フィルタ式 (例: priority>=2 AND due<7d AND tag:exam)
+	// perspective: 保存済みパースペクティブのID
+	perspectiveID, err := parseUUIDQuery(c, "perspective")
+	if err != nil {
+		return err
+	}
+
+	tasks, err := h.u.ListTasks(c.Request().Context(), userID, projectID, status, from, to, tagID, c.QueryParam("filter"), perspectiveID)
 	if err != nil {
 		return HandleError(c, err)
 	}
@@ -98,6 +130,35 @@ func (h *TaskHandler) ListTasks(c echo.Context) error {
 	return c.JSON(http.StatusOK, tasks)
 }
 
+func (h *TaskHandler) UpdateTask(c echo.Context) error {
+	userID := getUserID(c)
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid task id")
+	}
+
+	var req UpdateTaskRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	task, err := h.u.UpdateTask(c.Request().Context(), userID, taskID, usecase.UpdateTaskInput{
+		Title:            req.Title,
+		Note:             req.Note,
+		DueDate:          req.DueDate,
+		Priority:         req.Priority,
+		EstimatedMinutes: req.EstimatedMinutes,
+		SyncChecklist:    req.SyncChecklist,
+	})
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, task)
+}
+
 func (h *TaskHandler) UpdateTaskStatus(c echo.Context) error {
 	userID := getUserID(c)
 	taskID, err := uuid.Parse(c.Param("id"))
@@ -114,7 +175,7 @@ func (h *TaskHandler) UpdateTaskStatus(c echo.Context) error {
 		return err
 	}
 
-	task, err := h.u.UpdateTaskStatus(c.Request().Context(), userID, taskID, repository.TaskStatus(req.Status))
+	task, err := h.u.UpdateTaskStatus(c.Request().Context(), userID, taskID, repository.TaskStatus(req.Status), req.StatusID)
 	if err != nil {
 		return HandleError(c, err)
 	}
@@ -122,6 +183,56 @@ func (h *TaskHandler) UpdateTaskStatus(c echo.Context) error {
 	return c.JSON(http.StatusOK, task)
 }
 
+func (h *TaskHandler) DeleteTask(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid task id")
+	}
+
+	if err := h.u.DeleteTask(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *TaskHandler) MoveTask(c echo.Context) error {
+	userID := getUserID(c)
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid task id")
+	}
+
+	var req MoveTaskRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	task, err := h.u.MoveTask(c.Request().Context(), userID, taskID, repository.TaskStatus(req.Status), req.StatusID, req.PrevID, req.NextID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, task)
+}
+
+// カンバン
+func (h *TaskHandler) GetBoard(c echo.Context) error {
+	userID := getUserID(c)
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid project id")
+	}
+
+	board, err := h.u.GetBoard(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, board)
+}
+
 // チェックリスト
 func (h *TaskHandler) AddChecklistItem(c echo.Context) error {
 	taskID, err := uuid.Parse(c.Param("id"))
@@ -138,7 +249,7 @@ func (h *TaskHandler) AddChecklistItem(c echo.Context) error {
 		return err
 	}
 
-	item, err := h.u.AddChecklistItem(c.Request().Context(), taskID, req.Content)
+	item, err := h.u.AddChecklistItem(c.Request().Context(), taskID, req.Content, req.EstimatedMinutes)
 	if err != nil {
 		return HandleError(c, err)
 	}
@@ -146,21 +257,69 @@ func (h *TaskHandler) AddChecklistItem(c echo.Context) error {
 	return c.JSON(http.StatusCreated, item)
 }
 
-func (h *TaskHandler) ToggleChecklistItem(c echo.Context) error {
+func (h *TaskHandler) UpdateChecklistItem(c echo.Context) error {
 	itemID, err := uuid.Parse(c.Param("id")) // URL: /checklist-items/:id
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid item id")
 	}
 
-	var req ToggleChecklistItemRequest
+	var req UpdateChecklistItemRequest
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
 	}
 
-	item, err := h.u.ToggleChecklistItem(c.Request().Context(), itemID, req.IsCompleted)
+	item, err := h.u.UpdateChecklistItem(c.Request().Context(), itemID, req.IsCompleted, req.EstimatedMinutes)
 	if err != nil {
 		return HandleError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, item)
 }
+
+// GetMatrix は未完了タスクを優先度マトリクスで返す。
+// urgent_days: 期限がこの日数以内なら緊急とする (既定 2)
+func (h *TaskHandler) GetMatrix(c echo.Context) error {
+	userID := getUserID(c)
+
+	urgentDays := 2
+	if s := c.QueryParam("urgent_days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > 365 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid urgent_days")
+		}
+		urgentDays = n
+	}
+
+	projectID, err := parseUUIDQuery(c, "project_id")
+	if err != nil {
+		return err
+	}
+	matrix, err := h.u.GetMatrix(c.Request().Context(), userID, projectID, time.Duration(urgentDays)*24*time.Hour, time.Now())
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, matrix)
+}
+
+// 見積もりと実績の比較
+func (h *TaskHandler) GetEstimateReport(c echo.Context) error {
+	userID := getUserID(c)
+
+	// 省略したときの期間はユーザーのタイムゾーンで決める
+	to := parseDateQuery(c, "to", time.Time{})
+	from := parseDateQuery(c, "from", time.Time{})
+	interval := c.QueryParam("interval")
+	if interval == "" {
+		interval = "week"
+	}
+	groupBy := c.QueryParam("group_by")
+	if groupBy == "" {
+		groupBy = "project"
+	}
+
+	report, err := h.u.GetEstimateReport(c.Request().Context(), userID, from, to, interval, groupBy)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, report)
+}
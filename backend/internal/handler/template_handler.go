@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type TemplateHandler struct {
+	u usecase.TemplateUsecase
+}
+
+func NewTemplateHandler(u usecase.TemplateUsecase) *TemplateHandler {
+	return &TemplateHandler{u: u}
+}
+
+type CreateTemplateRequest struct {
+	Name string          `json:"name" validate:"required,max=100"`
+	Kind string          `json:"kind" validate:"required,oneof=TASK PROJECT"`
+	Body json.RawMessage `json:"body" validate:"required"`
+}
+
+type CaptureTemplateRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type InstantiateTemplateRequest struct {
+	ProjectID  *uuid.UUID        `json:"project_id"`
+	CategoryID *uuid.UUID        `json:"category_id"`
+	BaseDate   string            `json:"base_date" validate:"omitempty,datetime=2006-01-02"`
+	Variables  map[string]string `json:"variables"`
+}
+
+type TemplateResponse struct {
+	ID        string          `json:"id"`
+	Kind      string          `json:"kind"`
+	Name      string          `json:"name"`
+	Body      json.RawMessage `json:"body"`
+	CreatedAt string          `json:"created_at"`
+}
+
+func toTemplateResponse(t *repository.Template) TemplateResponse {
+	return TemplateResponse{
+		ID:        t.ID.String(),
+		Kind:      string(t.Kind),
+		Name:      t.Name,
+		Body:      json.RawMessage(t.Body),
+		CreatedAt: t.CreatedAt.Time.Format(time.RFC3339),
+	}
+}
+
+func (h *TemplateHandler) Create(c echo.Context) error {
+	userID := getUserID(c)
+
+	var req CreateTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	tmpl, err := h.u.CreateTemplate(c.Request().Context(), userID, repository.TemplateKind(req.Kind), req.Name, req.Body)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, toTemplateResponse(tmpl))
+}
+
+// CaptureTask handles POST /templates/from-task/:id
+func (h *TemplateHandler) CaptureTask(c echo.Context) error {
+	return h.capture(c, h.u.CaptureTask)
+}
+
+// CaptureProject handles POST /templates/from-project/:id
+func (h *TemplateHandler) CaptureProject(c echo.Context) error {
+	return h.capture(c, h.u.CaptureProject)
+}
+
+func (h *TemplateHandler) capture(c echo.Context, fn func(ctx context.Context, userID, id uuid.UUID, name string) (*repository.Template, error)) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+
+	var req CaptureTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	tmpl, err := fn(c.Request().Context(), userID, id, req.Name)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, toTemplateResponse(tmpl))
+}
+
+func (h *TemplateHandler) List(c echo.Context) error {
+	userID := getUserID(c)
+
+	var kind *repository.TemplateKind
+	if k := c.QueryParam("kind"); k != "" {
+		tk := repository.TemplateKind(k)
+		kind = &tk
+	}
+
+	templates, err := h.u.ListTemplates(c.Request().Context(), userID, kind)
+	if err != nil {
+		return HandleError(c, err)
+	}
+
+	res := make([]TemplateResponse, len(templates))
+	for i := range templates {
+		res[i] = toTemplateResponse(&templates[i])
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *TemplateHandler) Get(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid template id")
+	}
+
+	tmpl, err := h.u.GetTemplate(c.Request().Context(), userID, id)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, toTemplateResponse(tmpl))
+}
+
+func (h *TemplateHandler) Delete(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid template id")
+	}
+
+	if err := h.u.DeleteTemplate(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *TemplateHandler) Instantiate(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid template id")
+	}
+
+	var req InstantiateTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	baseDate := time.Now()
+	if req.BaseDate != "" {
+		baseDate, _ = time.Parse("2006-01-02", req.BaseDate)
+	}
+
+	res, err := h.u.Instantiate(c.Request().Context(), userID, id, usecase.InstantiateOptions{
+		ProjectID:  req.ProjectID,
+		CategoryID: req.CategoryID,
+		BaseDate:   baseDate,
+		Variables:  req.Variables,
+	})
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, res)
+}
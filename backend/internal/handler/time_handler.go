@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"encoding/csv"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
 	"github.com/gigaonion/taskalyst/backend/internal/usecase"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -21,6 +25,37 @@ type StartTimerRequest struct {
 	ProjectID string `json:"project_id" validate:"required"`
 	TaskID    string `json:"task_id"`
 	Note      string `json:"note"`
+	Billable  *bool  `json:"is_billable"`
+}
+
+// overlap は他のエントリと重なったときの扱い (reject: 409, trim: 重なった側を縮める, allow: 警告のみ)
+type CreateTimeEntryRequest struct {
+	ProjectID uuid.UUID  `json:"project_id" validate:"required"`
+	TaskID    *uuid.UUID `json:"task_id"`
+	StartedAt time.Time  `json:"started_at" validate:"required"`
+	EndedAt   time.Time  `json:"ended_at" validate:"required"`
+	Note      string     `json:"note"`
+	Billable  *bool      `json:"is_billable"`
+	Overlap   string     `json:"overlap" validate:"omitempty,oneof=reject trim allow"`
+}
+
+type UpdateTimeEntryRequest struct {
+	ProjectID *uuid.UUID `json:"project_id"`
+	TaskID    *uuid.UUID `json:"task_id"`
+	StartedAt *time.Time `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Note      *string    `json:"note"`
+	Billable  *bool      `json:"is_billable"`
+	Overlap   string     `json:"overlap" validate:"omitempty,oneof=reject trim allow"`
+}
+
+type SplitTimeEntryRequest struct {
+	At time.Time `json:"at" validate:"required"`
+}
+
+type MergeTimeEntriesRequest struct {
+	EntryIDs []uuid.UUID `json:"entry_ids" validate:"required,min=2,max=100"`
+	Overlap  string      `json:"overlap" validate:"omitempty,oneof=reject trim allow"`
 }
 
 type DateRangeRequest struct {
@@ -30,18 +65,46 @@ type DateRangeRequest struct {
 
 func (h *TimeHandler) StartTimer(c echo.Context) error {
 	userID := getUserID(c)
+	req, pID, tID, err := bindStartTimer(c)
+	if err != nil {
+		return err
+	}
+
+	entry, err := h.u.StartTimeEntry(c.Request().Context(), userID, pID, tID, req.Note, req.Billable)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, entry)
+}
+
+// SwitchTimer は計測中のタイマーを止めて新しく開始する
+func (h *TimeHandler) SwitchTimer(c echo.Context) error {
+	userID := getUserID(c)
+	req, pID, tID, err := bindStartTimer(c)
+	if err != nil {
+		return err
+	}
+
+	sw, err := h.u.SwitchTimeEntry(c.Request().Context(), userID, pID, tID, req.Note, req.Billable)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, sw)
+}
+
+func bindStartTimer(c echo.Context) (*StartTimerRequest, uuid.UUID, *uuid.UUID, error) {
 	var req StartTimerRequest
 	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+		return nil, uuid.Nil, nil, echo.NewHTTPError(http.StatusBadRequest, "invalid request")
 	}
 
 	if err := c.Validate(&req); err != nil {
-		return err
+		return nil, uuid.Nil, nil, err
 	}
 
 	pID, err := uuid.Parse(req.ProjectID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid project id")
+		return nil, uuid.Nil, nil, echo.NewHTTPError(http.StatusBadRequest, "invalid project id")
 	}
 
 	var tID *uuid.UUID
@@ -51,12 +114,258 @@ func (h *TimeHandler) StartTimer(c echo.Context) error {
 			tID = &id
 		}
 	}
+	return &req, pID, tID, nil
+}
 
-	entry, err := h.u.StartTimeEntry(c.Request().Context(), userID, pID, tID, req.Note)
+func (h *TimeHandler) CreateEntry(c echo.Context) error {
+	userID := getUserID(c)
+	var req CreateTimeEntryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	change, err := h.u.CreateTimeEntry(c.Request().Context(), userID, usecase.ManualTimeEntryInput{
+		ProjectID: req.ProjectID,
+		TaskID:    req.TaskID,
+		StartedAt: req.StartedAt,
+		EndedAt:   req.EndedAt,
+		Note:      req.Note,
+		Billable:  req.Billable,
+		Overlap:   usecase.OverlapMode(req.Overlap),
+	})
 	if err != nil {
 		return HandleError(c, err)
 	}
-	return c.JSON(http.StatusCreated, entry)
+	return c.JSON(http.StatusCreated, change)
+}
+
+func (h *TimeHandler) UpdateEntry(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid entry id")
+	}
+	var req UpdateTimeEntryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	change, err := h.u.UpdateTimeEntry(c.Request().Context(), userID, id, usecase.UpdateTimeEntryInput{
+		ProjectID: req.ProjectID,
+		TaskID:    req.TaskID,
+		StartedAt: req.StartedAt,
+		EndedAt:   req.EndedAt,
+		Note:      req.Note,
+		Billable:  req.Billable,
+		Overlap:   usecase.OverlapMode(req.Overlap),
+	})
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, change)
+}
+
+func (h *TimeHandler) DeleteEntry(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid entry id")
+	}
+
+	if err := h.u.DeleteTimeEntry(c.Request().Context(), userID, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *TimeHandler) SplitEntry(c echo.Context) error {
+	userID := getUserID(c)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid entry id")
+	}
+	var req SplitTimeEntryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	entries, err := h.u.SplitTimeEntry(c.Request().Context(), userID, id, req.At)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+func (h *TimeHandler) MergeEntries(c echo.Context) error {
+	userID := getUserID(c)
+	var req MergeTimeEntriesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	change, err := h.u.MergeTimeEntries(c.Request().Context(), userID, req.EntryIDs, usecase.OverlapMode(req.Overlap))
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, change)
+}
+
+// ListEntries は started_at の新しい順にエントリを返す。
+// from / to (YYYY-MM-DD、to の日を含む)、project_id、category_id、root_type、task_id、tag_id、
+// auto (true/false) で絞り込み、cursor と limit でページを指定する。
+// Accept: text/csv なら条件に一致するすべてのエントリを CSV で返す
+func (h *TimeHandler) ListEntries(c echo.Context) error {
+	userID := getUserID(c)
+
+	to := time.Now()
+	if c.QueryParam("to") != "" {
+		to = parseDateQuery(c, "to", to).AddDate(0, 0, 1)
+	}
+	filter := usecase.TimeEntryFilter{
+		From:   parseDateQuery(c, "from", to.AddDate(0, 0, -30)),
+		To:     to,
+		Cursor: c.QueryParam("cursor"),
+	}
+	for name, dst := range map[string]**uuid.UUID{
+		"project_id":  &filter.ProjectID,
+		"category_id": &filter.CategoryID,
+		"task_id":     &filter.TaskID,
+		"tag_id":      &filter.TagID,
+	} {
+		id, err := parseUUIDQuery(c, name)
+		if err != nil {
+			return err
+		}
+		*dst = id
+	}
+	if s := c.QueryParam("root_type"); s != "" {
+		rootType := repository.RootCategoryType(s)
+		switch rootType {
+		case repository.RootCategoryTypeGROWTH, repository.RootCategoryTypeLIFE, repository.RootCategoryTypeWORK,
+			repository.RootCategoryTypeHOBBY, repository.RootCategoryTypeOTHER:
+			filter.RootType = &rootType
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid root_type")
+		}
+	}
+	if s := c.QueryParam("auto"); s != "" {
+		auto, err := strconv.ParseBool(s)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid auto")
+		}
+		filter.AutoGenerated = &auto
+	}
+	if s := c.QueryParam("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > usecase.MaxTimeEntryPageSize {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		filter.Limit = n
+	}
+
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "text/csv") {
+		return h.writeEntriesCSV(c, userID, filter)
+	}
+
+	page, err := h.u.ListTimeEntries(c.Request().Context(), userID, filter)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// writeEntriesCSV はページをたどりながら CSV を書き出す
+func (h *TimeHandler) writeEntriesCSV(c echo.Context, userID uuid.UUID, filter usecase.TimeEntryFilter) error {
+	ctx := c.Request().Context()
+	filter.Limit = usecase.MaxTimeEntryPageSize
+	// 最初のページでエラーがあれば通常のエラーとして返す
+	page, err := h.u.ListTimeEntries(ctx, userID, filter)
+	if err != nil {
+		return HandleError(c, err)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="time-entries.csv"`)
+	res.WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(res)
+	_ = w.Write([]string{"id", "started_at", "ended_at", "duration_seconds", "project", "category", "root_type", "task", "note", "tags", "auto_generated", "billable"})
+	for {
+		for _, e := range page.Entries {
+			var endedAt string
+			if e.EndedAt.Valid {
+				endedAt = e.EndedAt.Time.Format(time.RFC3339)
+			}
+			_ = w.Write([]string{
+				e.ID.String(),
+				e.StartedAt.Time.Format(time.RFC3339),
+				endedAt,
+				strconv.FormatInt(e.DurationSeconds, 10),
+				csvText(e.ProjectTitle),
+				csvText(e.CategoryName),
+				string(e.RootType),
+				csvText(e.TaskTitle.String),
+				csvText(e.Note.String),
+				csvText(strings.Join(e.TagNames, ";")),
+				strconv.FormatBool(e.IsAutoGenerated),
+				strconv.FormatBool(e.IsBillable),
+			})
+		}
+		w.Flush()
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+		// ヘッダーは送信済みなので、エラーなら途中で打ち切られる
+		if page, err = h.u.ListTimeEntries(ctx, userID, filter); err != nil {
+			return err
+		}
+	}
+	return w.Error()
+}
+
+// csvText は表計算ソフトで数式として解釈される値の先頭に ' を付ける (CSV injection 対策)
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// CurrentTimer は計測中のエントリを返す。なければ 204
+func (h *TimeHandler) CurrentTimer(c echo.Context) error {
+	userID := getUserID(c)
+	entry, err := h.u.GetCurrentTimeEntry(c.Request().Context(), userID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	if entry == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+	return c.JSON(http.StatusOK, entry)
+}
+
+// ListAutoStops は設定に従って自動で止めた計測とその理由を返す
+func (h *TimeHandler) ListAutoStops(c echo.Context) error {
+	userID := getUserID(c)
+	stops, err := h.u.ListAutoStops(c.Request().Context(), userID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, stops)
 }
 
 func (h *TimeHandler) StopTimer(c echo.Context) error {
@@ -76,8 +385,9 @@ func (h *TimeHandler) StopTimer(c echo.Context) error {
 func (h *TimeHandler) GetStats(c echo.Context) error {
 	userID := getUserID(c)
 
-	to := parseDateQuery(c, "to", time.Now())
-	from := parseDateQuery(c, "from", to.AddDate(-1, 0, 0))
+	// 省略したときの期間はユーザーのタイムゾーンで決める
+	to := parseDateQuery(c, "to", time.Time{})
+	from := parseDateQuery(c, "from", time.Time{})
 
 	stats, err := h.u.GetContributionStats(c.Request().Context(), userID, from, to)
 	if err != nil {
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type TrashHandler struct {
+	u usecase.TrashUsecase
+}
+
+func NewTrashHandler(u usecase.TrashUsecase) *TrashHandler {
+	return &TrashHandler{u: u}
+}
+
+// parseTrashKind は :kind を確認する
+func parseTrashKind(s string) (usecase.TrashKind, bool) {
+	switch kind := usecase.TrashKind(s); kind {
+	case usecase.TrashKindProject, usecase.TrashKindCalendar, usecase.TrashKindTask, usecase.TrashKindEvent, usecase.TrashKindResult:
+		return kind, true
+	}
+	return "", false
+}
+
+// List は ?kind= で種類を絞り込める
+func (h *TrashHandler) List(c echo.Context) error {
+	userID := getUserID(c)
+
+	var kind *usecase.TrashKind
+	if s := c.QueryParam("kind"); s != "" {
+		k, ok := parseTrashKind(s)
+		if !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid kind")
+		}
+		kind = &k
+	}
+
+	items, err := h.u.List(c.Request().Context(), userID, kind)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+func (h *TrashHandler) Restore(c echo.Context) error {
+	userID := getUserID(c)
+	kind, ok := parseTrashKind(c.Param("kind"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid kind")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+
+	if err := h.u.Restore(c.Request().Context(), userID, kind, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *TrashHandler) Purge(c echo.Context) error {
+	userID := getUserID(c)
+	kind, ok := parseTrashKind(c.Param("kind"))
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid kind")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+
+	if err := h.u.Purge(c.Request().Context(), userID, kind, id); err != nil {
+		return HandleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Empty はゴミ箱を空にして削除した件数を返す
+func (h *TrashHandler) Empty(c echo.Context) error {
+	userID := getUserID(c)
+	n, err := h.u.Empty(c.Request().Context(), userID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]int{"purged": n})
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gigaonion/taskalyst/backend/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type UndoHandler struct {
+	u usecase.UndoUsecase
+}
+
+func NewUndoHandler(u usecase.UndoUsecase) *UndoHandler {
+	return &UndoHandler{u: u}
+}
+
+// Undo は変更のレスポンスの X-Operation-ID で指定した操作を取り消す
+func (h *UndoHandler) Undo(c echo.Context) error {
+	userID := getUserID(c)
+	operationID, err := uuid.Parse(c.Param("operationId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid operation id")
+	}
+
+	result, err := h.u.Undo(c.Request().Context(), userID, operationID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
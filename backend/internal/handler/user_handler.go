@@ -31,6 +31,19 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+type UpdatePreferencesRequest struct {
+	TimerConflict             *string `json:"timer_conflict" validate:"omitempty,oneof=reject stop"`
+	PomodoroWorkMinutes       *int    `json:"pomodoro_work_minutes" validate:"omitempty,min=1,max=180"`
+	PomodoroShortBreakMinutes *int    `json:"pomodoro_short_break_minutes" validate:"omitempty,min=1,max=60"`
+	PomodoroLongBreakMinutes  *int    `json:"pomodoro_long_break_minutes" validate:"omitempty,min=1,max=120"`
+	PomodoroLongBreakEvery    *int    `json:"pomodoro_long_break_every" validate:"omitempty,min=1,max=12"`
+	HeartbeatIdleMinutes      *int    `json:"heartbeat_idle_minutes" validate:"omitempty,min=1,max=120"`
+	Timezone                  *string `json:"timezone"`
+	TimerMaxMinutes           *int    `json:"timer_max_minutes" validate:"omitempty,min=0,max=10080"` // 0 で解除
+	TimerAutoStopAt           *string `json:"timer_auto_stop_at"`                                     // HH:MM、空文字で解除
+	TimerDiscardIdle          *bool   `json:"timer_discard_idle"`
+}
+
 type UserResponse struct {
 	ID    string `json:"id"`
 	Name  string `json:"name"`
@@ -118,3 +131,40 @@ func (h *UserHandler) GetMe(c echo.Context) error {
 		Role:  string(user.Role),
 	})
 }
+
+func (h *UserHandler) GetPreferences(c echo.Context) error {
+	userID := getUserID(c)
+	prefs, err := h.u.GetPreferences(c.Request().Context(), userID)
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, prefs)
+}
+
+func (h *UserHandler) UpdatePreferences(c echo.Context) error {
+	userID := getUserID(c)
+	var req UpdatePreferencesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	prefs, err := h.u.UpdatePreferences(c.Request().Context(), userID, usecase.UpdatePreferencesInput{
+		TimerConflict:             req.TimerConflict,
+		PomodoroWorkMinutes:       req.PomodoroWorkMinutes,
+		PomodoroShortBreakMinutes: req.PomodoroShortBreakMinutes,
+		PomodoroLongBreakMinutes:  req.PomodoroLongBreakMinutes,
+		PomodoroLongBreakEvery:    req.PomodoroLongBreakEvery,
+		HeartbeatIdleMinutes:      req.HeartbeatIdleMinutes,
+		Timezone:                  req.Timezone,
+		TimerMaxMinutes:           req.TimerMaxMinutes,
+		TimerAutoStopAt:           req.TimerAutoStopAt,
+		TimerDiscardIdle:          req.TimerDiscardIdle,
+	})
+	if err != nil {
+		return HandleError(c, err)
+	}
+	return c.JSON(http.StatusOK, prefs)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +21,20 @@ func parseDateQuery(c echo.Context, name string, defaultValue time.Time) time.Ti
 	return t
 }
 
+// parseUUIDQuery parses an optional UUID from query parameters.
+// A malformed value is a 400 rather than being ignored, so a filter is never silently dropped.
+func parseUUIDQuery(c echo.Context, name string) (*uuid.UUID, error) {
+	val := c.QueryParam(name)
+	if val == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(val)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid "+name)
+	}
+	return &id, nil
+}
+
 // getUserID extracts the user_id from the echo context.
 func getUserID(c echo.Context) uuid.UUID {
 	id, ok := c.Get("user_id").(uuid.UUID)
@@ -29,3 +44,12 @@ func getUserID(c echo.Context) uuid.UUID {
 	}
 	return id
 }
+
+// uuidStrings converts UUIDs to their string form.
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EventsChannel はトリガー (notify_change) が変更を通知するチャンネル
+const EventsChannel = "taskalyst_events"
+
+// 接続が切れたときに LISTEN し直すまでの待ち時間
+const listenRetryInterval = 5 * time.Second
+
+// Notification は通知の内容。Type は timer.started / task.changed など
+type Notification struct {
+	UserID uuid.UUID `json:"user_id"`
+	Type   string    `json:"type"`
+	ID     uuid.UUID `json:"id"`
+	Op     string    `json:"op"` // insert / update / delete
+}
+
+// NotificationResync は再接続したときに送る。切れていた間の通知は届かないので取り直してもらう
+const NotificationResync = "resync"
+
+// Listener は1本の接続で LISTEN し、通知をユーザーごとの購読者に配る。
+// NOTIFY はデータベース経由なので、複数のサーバーで動かしても同じ通知が届く
+type Listener struct {
+	pool    *pgxpool.Pool
+	channel string
+
+	mu     sync.Mutex
+	subs   map[uuid.UUID]map[chan Notification]struct{}
+	closed bool
+}
+
+func NewListener(pool *pgxpool.Pool, channel string) *Listener {
+	return &Listener{
+		pool:    pool,
+		channel: channel,
+		subs:    map[uuid.UUID]map[chan Notification]struct{}{},
+	}
+}
+
+// Run は ctx が終わるまで LISTEN を続け、切れたら再接続する。
+// 終わるときは購読者のチャンネルをすべて閉じ、ストリームを終わらせる
+func (l *Listener) Run(ctx context.Context) {
+	defer l.close()
+	connected := false
+	for {
+		err := l.listen(ctx, func() {
+			if connected {
+				l.broadcast(Notification{Type: NotificationResync})
+			}
+			connected = true
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("listener disconnected: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryInterval):
+		}
+	}
+}
+
+func (l *Listener) listen(ctx context.Context, onListen func()) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// LISTEN した接続はプールに戻さない
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		return err
+	}
+	onListen()
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var ev Notification
+		if err := json.Unmarshal([]byte(n.Payload), &ev); err != nil {
+			continue
+		}
+		l.dispatch(ev)
+	}
+}
+
+// Subscribe はユーザーの通知を受け取るチャンネルと、購読をやめる関数を返す
+func (l *Listener) Subscribe(userID uuid.UUID) (<-chan Notification, func()) {
+	ch := make(chan Notification, 16)
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if l.subs[userID] == nil {
+		l.subs[userID] = map[chan Notification]struct{}{}
+	}
+	l.subs[userID][ch] = struct{}{}
+	l.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			l.mu.Lock()
+			delete(l.subs[userID], ch)
+			if len(l.subs[userID]) == 0 {
+				delete(l.subs, userID)
+			}
+			l.mu.Unlock()
+		})
+	}
+}
+
+func (l *Listener) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, chans := range l.subs {
+		for ch := range chans {
+			close(ch)
+		}
+	}
+	l.subs = map[uuid.UUID]map[chan Notification]struct{}{}
+	l.closed = true
+}
+
+func (l *Listener) dispatch(ev Notification) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.subs[ev.UserID] {
+		send(ch, ev)
+	}
+}
+
+func (l *Listener) broadcast(ev Notification) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for userID, chans := range l.subs {
+		ev.UserID = userID
+		for ch := range chans {
+			send(ch, ev)
+		}
+	}
+}
+
+// send は受け手が詰まっていれば待たずに、溜まった通知を捨てて resync に置き換える。
+// 1人の遅いクライアントで全体を止めず、捨てた分は取り直してもらう
+func send(ch chan Notification, ev Notification) {
+	select {
+	case ch <- ev:
+		return
+	default:
+	}
+	for drained := false; !drained; {
+		select {
+		case <-ch:
+		default:
+			drained = true
+		}
+	}
+	// 送るのは l.mu を持っているときだけなので、空けた分には必ず入る
+	ch <- Notification{UserID: ev.UserID, Type: NotificationResync}
+}
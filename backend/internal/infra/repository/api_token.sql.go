@@ -12,6 +12,19 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const consumeStreamTicket = `-- name: ConsumeStreamTicket :one
+DELETE FROM stream_tickets
+WHERE token_hash = $1 AND expires_at > NOW()
+RETURNING user_id
+`
+
+func (q *Queries) ConsumeStreamTicket(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, consumeStreamTicket, tokenHash)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
 const createApiToken = `-- name: CreateApiToken :one
 INSERT INTO api_tokens (
     user_id, name, token_hash, expires_at
@@ -46,6 +59,25 @@ func (q *Queries) CreateApiToken(ctx context.Context, arg CreateApiTokenParams)
 	return i, err
 }
 
+const createStreamTicket = `-- name: CreateStreamTicket :exec
+INSERT INTO stream_tickets (
+    token_hash, user_id, expires_at
+) VALUES (
+    $1, $2, $3
+)
+`
+
+type CreateStreamTicketParams struct {
+	TokenHash string             `json:"token_hash"`
+	UserID    uuid.UUID          `json:"user_id"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+}
+
+func (q *Queries) CreateStreamTicket(ctx context.Context, arg CreateStreamTicketParams) error {
+	_, err := q.db.Exec(ctx, createStreamTicket, arg.TokenHash, arg.UserID, arg.ExpiresAt)
+	return err
+}
+
 const deleteApiToken = `-- name: DeleteApiToken :exec
 DELETE FROM api_tokens 
 WHERE id = $1 AND user_id = $2
@@ -61,16 +93,38 @@ func (q *Queries) DeleteApiToken(ctx context.Context, arg DeleteApiTokenParams)
 	return err
 }
 
+const deleteExpiredStreamTickets = `-- name: DeleteExpiredStreamTickets :exec
+DELETE FROM stream_tickets
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredStreamTickets(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteExpiredStreamTickets)
+	return err
+}
+
 const getUserByTokenHash = `-- name: GetUserByTokenHash :one
-SELECT u.id, u.email, u.password_hash, u.name, u.role, u.preferences, u.created_at, u.updated_at FROM api_tokens t
+SELECT u.id, u.email, u.password_hash, u.name, u.role, u.preferences, u.created_at, u.updated_at, t.name as token_name FROM api_tokens t
 JOIN users u ON t.user_id = u.id
 WHERE t.token_hash = $1 
   AND (t.expires_at IS NULL OR t.expires_at > NOW())
 `
 
-func (q *Queries) GetUserByTokenHash(ctx context.Context, tokenHash string) (User, error) {
+type GetUserByTokenHashRow struct {
+	ID           uuid.UUID          `json:"id"`
+	Email        string             `json:"email"`
+	PasswordHash string             `json:"password_hash"`
+	Name         string             `json:"name"`
+	Role         UserRole           `json:"role"`
+	Preferences  []byte             `json:"preferences"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	TokenName    string             `json:"token_name"`
+}
+
+func (q *Queries) GetUserByTokenHash(ctx context.Context, tokenHash string) (GetUserByTokenHashRow, error) {
 	row := q.db.QueryRow(ctx, getUserByTokenHash, tokenHash)
-	var i User
+	var i GetUserByTokenHashRow
 	err := row.Scan(
 		&i.ID,
 		&i.Email,
@@ -80,6 +134,7 @@ func (q *Queries) GetUserByTokenHash(ctx context.Context, tokenHash string) (Use
 		&i.Preferences,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokenName,
 	)
 	return i, err
 }
@@ -17,7 +17,7 @@ INSERT INTO calendars (
     user_id, name, color, description, project_id
 ) VALUES (
     $1, $2, $3, $4, $5
-) RETURNING id, user_id, project_id, name, color, description, sync_token, supported_components, created_at, updated_at
+) RETURNING id, user_id, project_id, name, color, description, sync_token, supported_components, created_at, updated_at, deleted_at
 `
 
 type CreateCalendarParams struct {
@@ -48,6 +48,7 @@ func (q *Queries) CreateCalendar(ctx context.Context, arg CreateCalendarParams)
 		&i.SupportedComponents,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -63,7 +64,7 @@ INSERT INTO scheduled_events (
     $4, $5, $6,
     $7, $8, $9,
     $10, $11, $12, $13, $14
-) RETURNING id, user_id, project_id, calendar_id, title, description, location, start_at, end_at, is_all_day, external_event_id, ical_uid, etag, sequence, status, transparency, rrule, dtstamp, url, created_at, updated_at
+) RETURNING id, user_id, project_id, calendar_id, title, description, location, start_at, end_at, is_all_day, external_event_id, ical_uid, etag, sequence, status, transparency, rrule, dtstamp, url, created_at, updated_at, deleted_at
 `
 
 type CreateEventParams struct {
@@ -123,6 +124,7 @@ func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) (Sched
 		&i.Url,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -169,39 +171,9 @@ func (q *Queries) CreateTimetableSlot(ctx context.Context, arg CreateTimetableSl
 	return i, err
 }
 
-const deleteCalendar = `-- name: DeleteCalendar :exec
-DELETE FROM calendars
-WHERE id = $1 AND user_id = $2
-`
-
-type DeleteCalendarParams struct {
-	ID     uuid.UUID `json:"id"`
-	UserID uuid.UUID `json:"user_id"`
-}
-
-func (q *Queries) DeleteCalendar(ctx context.Context, arg DeleteCalendarParams) error {
-	_, err := q.db.Exec(ctx, deleteCalendar, arg.ID, arg.UserID)
-	return err
-}
-
-const deleteEventByICalUID = `-- name: DeleteEventByICalUID :exec
-DELETE FROM scheduled_events
-WHERE user_id = $1 AND ical_uid = $2
-`
-
-type DeleteEventByICalUIDParams struct {
-	UserID  uuid.UUID   `json:"user_id"`
-	IcalUid pgtype.Text `json:"ical_uid"`
-}
-
-func (q *Queries) DeleteEventByICalUID(ctx context.Context, arg DeleteEventByICalUIDParams) error {
-	_, err := q.db.Exec(ctx, deleteEventByICalUID, arg.UserID, arg.IcalUid)
-	return err
-}
-
 const getCalendar = `-- name: GetCalendar :one
-SELECT id, user_id, project_id, name, color, description, sync_token, supported_components, created_at, updated_at FROM calendars
-WHERE id = $1 AND user_id = $2 LIMIT 1
+SELECT id, user_id, project_id, name, color, description, sync_token, supported_components, created_at, updated_at, deleted_at FROM calendars
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL LIMIT 1
 `
 
 type GetCalendarParams struct {
@@ -223,13 +195,14 @@ func (q *Queries) GetCalendar(ctx context.Context, arg GetCalendarParams) (Calen
 		&i.SupportedComponents,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getDefaultCalendar = `-- name: GetDefaultCalendar :one
-SELECT id, user_id, project_id, name, color, description, sync_token, supported_components, created_at, updated_at FROM calendars
-WHERE user_id = $1
+SELECT id, user_id, project_id, name, color, description, sync_token, supported_components, created_at, updated_at, deleted_at FROM calendars
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at
 LIMIT 1
 `
@@ -248,13 +221,14 @@ func (q *Queries) GetDefaultCalendar(ctx context.Context, userID uuid.UUID) (Cal
 		&i.SupportedComponents,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getEventByICalUID = `-- name: GetEventByICalUID :one
-SELECT id, user_id, project_id, calendar_id, title, description, location, start_at, end_at, is_all_day, external_event_id, ical_uid, etag, sequence, status, transparency, rrule, dtstamp, url, created_at, updated_at FROM scheduled_events
-WHERE user_id = $1 AND ical_uid = $2 LIMIT 1
+SELECT id, user_id, project_id, calendar_id, title, description, location, start_at, end_at, is_all_day, external_event_id, ical_uid, etag, sequence, status, transparency, rrule, dtstamp, url, created_at, updated_at, deleted_at FROM scheduled_events
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL LIMIT 1
 `
 
 type GetEventByICalUIDParams struct {
@@ -287,13 +261,14 @@ func (q *Queries) GetEventByICalUID(ctx context.Context, arg GetEventByICalUIDPa
 		&i.Url,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const listCalendars = `-- name: ListCalendars :many
-SELECT id, user_id, project_id, name, color, description, sync_token, supported_components, created_at, updated_at FROM calendars
-WHERE user_id = $1
+SELECT id, user_id, project_id, name, color, description, sync_token, supported_components, created_at, updated_at, deleted_at FROM calendars
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at
 `
 
@@ -317,6 +292,50 @@ func (q *Queries) ListCalendars(ctx context.Context, userID uuid.UUID) ([]Calend
 			&i.SupportedComponents,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCalendarsByProject = `-- name: ListCalendarsByProject :many
+SELECT id, user_id, project_id, name, color, description, sync_token, supported_components, created_at, updated_at, deleted_at FROM calendars
+WHERE user_id = $1 AND project_id = $2 AND deleted_at IS NULL
+ORDER BY created_at
+`
+
+type ListCalendarsByProjectParams struct {
+	UserID    uuid.UUID   `json:"user_id"`
+	ProjectID pgtype.UUID `json:"project_id"`
+}
+
+func (q *Queries) ListCalendarsByProject(ctx context.Context, arg ListCalendarsByProjectParams) ([]Calendar, error) {
+	rows, err := q.db.Query(ctx, listCalendarsByProject, arg.UserID, arg.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Calendar
+	for rows.Next() {
+		var i Calendar
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ProjectID,
+			&i.Name,
+			&i.Color,
+			&i.Description,
+			&i.SyncToken,
+			&i.SupportedComponents,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -329,8 +348,8 @@ func (q *Queries) ListCalendars(ctx context.Context, userID uuid.UUID) ([]Calend
 }
 
 const listEventsByCalendar = `-- name: ListEventsByCalendar :many
-SELECT id, user_id, project_id, calendar_id, title, description, location, start_at, end_at, is_all_day, external_event_id, ical_uid, etag, sequence, status, transparency, rrule, dtstamp, url, created_at, updated_at FROM scheduled_events
-WHERE user_id = $1 AND calendar_id = $2
+SELECT id, user_id, project_id, calendar_id, title, description, location, start_at, end_at, is_all_day, external_event_id, ical_uid, etag, sequence, status, transparency, rrule, dtstamp, url, created_at, updated_at, deleted_at FROM scheduled_events
+WHERE user_id = $1 AND calendar_id = $2 AND deleted_at IS NULL
 ORDER BY start_at ASC
 `
 
@@ -370,6 +389,7 @@ func (q *Queries) ListEventsByCalendar(ctx context.Context, arg ListEventsByCale
 			&i.Url,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -382,9 +402,10 @@ func (q *Queries) ListEventsByCalendar(ctx context.Context, arg ListEventsByCale
 }
 
 const listEventsByCalendarAndRange = `-- name: ListEventsByCalendarAndRange :many
-SELECT id, user_id, project_id, calendar_id, title, description, location, start_at, end_at, is_all_day, external_event_id, ical_uid, etag, sequence, status, transparency, rrule, dtstamp, url, created_at, updated_at FROM scheduled_events
+SELECT id, user_id, project_id, calendar_id, title, description, location, start_at, end_at, is_all_day, external_event_id, ical_uid, etag, sequence, status, transparency, rrule, dtstamp, url, created_at, updated_at, deleted_at FROM scheduled_events
 WHERE user_id = $1 
   AND calendar_id = $2
+  AND deleted_at IS NULL
   AND end_at >= $3
   AND start_at <= $4
 ORDER BY start_at ASC
@@ -433,6 +454,7 @@ func (q *Queries) ListEventsByCalendarAndRange(ctx context.Context, arg ListEven
 			&i.Url,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -445,13 +467,18 @@ func (q *Queries) ListEventsByCalendarAndRange(ctx context.Context, arg ListEven
 }
 
 const listEventsByRange = `-- name: ListEventsByRange :many
-SELECT e.id, e.user_id, e.project_id, e.calendar_id, e.title, e.description, e.location, e.start_at, e.end_at, e.is_all_day, e.external_event_id, e.ical_uid, e.etag, e.sequence, e.status, e.transparency, e.rrule, e.dtstamp, e.url, e.created_at, e.updated_at, p.title as project_title, p.category_id
+SELECT e.id, e.user_id, e.project_id, e.calendar_id, e.title, e.description, e.location, e.start_at, e.end_at, e.is_all_day, e.external_event_id, e.ical_uid, e.etag, e.sequence, e.status, e.transparency, e.rrule, e.dtstamp, e.url, e.created_at, e.updated_at, e.deleted_at, p.title as project_title, p.category_id,
+    ARRAY(SELECT et.tag_id FROM event_tags et WHERE et.event_id = e.id)::uuid[] as tag_ids
 FROM scheduled_events e
 JOIN projects p ON e.project_id = p.id
 WHERE
     e.user_id = $1
+    AND e.deleted_at IS NULL
     AND e.end_at >= $2
     AND e.start_at <= $3
+    AND ($4::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM event_tags et WHERE et.event_id = e.id AND et.tag_id = $4
+    ))
 ORDER BY e.start_at ASC
 `
 
@@ -459,6 +486,7 @@ type ListEventsByRangeParams struct {
 	UserID    uuid.UUID          `json:"user_id"`
 	StartTime pgtype.Timestamptz `json:"start_time"`
 	EndTime   pgtype.Timestamptz `json:"end_time"`
+	TagID     pgtype.UUID        `json:"tag_id"`
 }
 
 type ListEventsByRangeRow struct {
@@ -483,12 +511,19 @@ type ListEventsByRangeRow struct {
 	Url             pgtype.Text        `json:"url"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
 	ProjectTitle    string             `json:"project_title"`
 	CategoryID      uuid.UUID          `json:"category_id"`
+	TagIds          []uuid.UUID        `json:"tag_ids"`
 }
 
 func (q *Queries) ListEventsByRange(ctx context.Context, arg ListEventsByRangeParams) ([]ListEventsByRangeRow, error) {
-	rows, err := q.db.Query(ctx, listEventsByRange, arg.UserID, arg.StartTime, arg.EndTime)
+	rows, err := q.db.Query(ctx, listEventsByRange,
+		arg.UserID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.TagID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -518,8 +553,10 @@ func (q *Queries) ListEventsByRange(ctx context.Context, arg ListEventsByRangePa
 			&i.Url,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
 			&i.ProjectTitle,
 			&i.CategoryID,
+			&i.TagIds,
 		); err != nil {
 			return nil, err
 		}
@@ -640,6 +677,65 @@ func (q *Queries) ListTimetableSlotsByDayOfWeek(ctx context.Context, arg ListTim
 	return items, nil
 }
 
+const listTimetableSlotsByProject = `-- name: ListTimetableSlotsByProject :many
+SELECT id, user_id, project_id, day_of_week, start_time, end_time, note, location FROM timetable_slots
+WHERE user_id = $1 AND project_id = $2
+ORDER BY day_of_week, start_time
+`
+
+type ListTimetableSlotsByProjectParams struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ProjectID uuid.UUID `json:"project_id"`
+}
+
+func (q *Queries) ListTimetableSlotsByProject(ctx context.Context, arg ListTimetableSlotsByProjectParams) ([]TimetableSlot, error) {
+	rows, err := q.db.Query(ctx, listTimetableSlotsByProject, arg.UserID, arg.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TimetableSlot
+	for rows.Next() {
+		var i TimetableSlot
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ProjectID,
+			&i.DayOfWeek,
+			&i.StartTime,
+			&i.EndTime,
+			&i.Note,
+			&i.Location,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const trashEventByICalUID = `-- name: TrashEventByICalUID :execrows
+UPDATE scheduled_events
+SET deleted_at = NOW()
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL
+`
+
+type TrashEventByICalUIDParams struct {
+	UserID  uuid.UUID   `json:"user_id"`
+	IcalUid pgtype.Text `json:"ical_uid"`
+}
+
+func (q *Queries) TrashEventByICalUID(ctx context.Context, arg TrashEventByICalUIDParams) (int64, error) {
+	result, err := q.db.Exec(ctx, trashEventByICalUID, arg.UserID, arg.IcalUid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateEventByICalUID = `-- name: UpdateEventByICalUID :one
 UPDATE scheduled_events
 SET
@@ -654,8 +750,8 @@ SET
     etag = COALESCE($11, etag),
     sequence = COALESCE($12, sequence),
     updated_at = NOW()
-WHERE user_id = $1 AND ical_uid = $2
-RETURNING id, user_id, project_id, calendar_id, title, description, location, start_at, end_at, is_all_day, external_event_id, ical_uid, etag, sequence, status, transparency, rrule, dtstamp, url, created_at, updated_at
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL
+RETURNING id, user_id, project_id, calendar_id, title, description, location, start_at, end_at, is_all_day, external_event_id, ical_uid, etag, sequence, status, transparency, rrule, dtstamp, url, created_at, updated_at, deleted_at
 `
 
 type UpdateEventByICalUIDParams struct {
@@ -711,6 +807,7 @@ func (q *Queries) UpdateEventByICalUID(ctx context.Context, arg UpdateEventByICa
 		&i.Url,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -100,6 +100,48 @@ func (ns NullTaskStatus) Value() (driver.Value, error) {
 	return string(ns.TaskStatus), nil
 }
 
+type TemplateKind string
+
+const (
+	TemplateKindTASK    TemplateKind = "TASK"
+	TemplateKindPROJECT TemplateKind = "PROJECT"
+)
+
+func (e *TemplateKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TemplateKind(s)
+	case string:
+		*e = TemplateKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TemplateKind: %T", src)
+	}
+	return nil
+}
+
+type NullTemplateKind struct {
+	TemplateKind TemplateKind `json:"template_kind"`
+	Valid        bool         `json:"valid"` // Valid is true if TemplateKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTemplateKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.TemplateKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TemplateKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTemplateKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TemplateKind), nil
+}
+
 type UserRole string
 
 const (
@@ -142,6 +184,33 @@ func (ns NullUserRole) Value() (driver.Value, error) {
 	return string(ns.UserRole), nil
 }
 
+type ActivityEvent struct {
+	ID          uuid.UUID          `json:"id"`
+	UserID      uuid.UUID          `json:"user_id"`
+	Bucket      string             `json:"bucket"`
+	App         string             `json:"app"`
+	Title       string             `json:"title"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	EndedAt     pgtype.Timestamptz `json:"ended_at"`
+	RolledUpAt  pgtype.Timestamptz `json:"rolled_up_at"`
+	ProjectID   pgtype.UUID        `json:"project_id"`
+	CategoryID  pgtype.UUID        `json:"category_id"`
+	TimeEntryID pgtype.UUID        `json:"time_entry_id"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type ActivityRule struct {
+	ID           uuid.UUID          `json:"id"`
+	UserID       uuid.UUID          `json:"user_id"`
+	AppPattern   pgtype.Text        `json:"app_pattern"`
+	TitlePattern pgtype.Text        `json:"title_pattern"`
+	ProjectID    pgtype.UUID        `json:"project_id"`
+	CategoryID   pgtype.UUID        `json:"category_id"`
+	Position     int32              `json:"position"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+}
+
 type ApiToken struct {
 	ID        uuid.UUID          `json:"id"`
 	UserID    uuid.UUID          `json:"user_id"`
@@ -151,6 +220,18 @@ type ApiToken struct {
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
+type Attachment struct {
+	ID          uuid.UUID          `json:"id"`
+	UserID      uuid.UUID          `json:"user_id"`
+	TaskID      pgtype.UUID        `json:"task_id"`
+	EventID     pgtype.UUID        `json:"event_id"`
+	Filename    string             `json:"filename"`
+	ContentType string             `json:"content_type"`
+	SizeBytes   int64              `json:"size_bytes"`
+	StorageKey  string             `json:"storage_key"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
 type Calendar struct {
 	ID                  uuid.UUID          `json:"id"`
 	UserID              uuid.UUID          `json:"user_id"`
@@ -162,6 +243,7 @@ type Calendar struct {
 	SupportedComponents []string           `json:"supported_components"`
 	CreatedAt           pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt           pgtype.Timestamptz `json:"deleted_at"`
 }
 
 type Category struct {
@@ -174,11 +256,100 @@ type Category struct {
 }
 
 type ChecklistItem struct {
-	ID          uuid.UUID `json:"id"`
-	TaskID      uuid.UUID `json:"task_id"`
-	Content     string    `json:"content"`
-	IsCompleted bool      `json:"is_completed"`
-	Position    int32     `json:"position"`
+	ID               uuid.UUID   `json:"id"`
+	TaskID           uuid.UUID   `json:"task_id"`
+	Content          string      `json:"content"`
+	IsCompleted      bool        `json:"is_completed"`
+	Position         int32       `json:"position"`
+	EstimatedMinutes pgtype.Int4 `json:"estimated_minutes"`
+}
+
+type EventTag struct {
+	EventID uuid.UUID `json:"event_id"`
+	TagID   uuid.UUID `json:"tag_id"`
+}
+
+type Heartbeat struct {
+	ID           uuid.UUID          `json:"id"`
+	UserID       uuid.UUID          `json:"user_id"`
+	Entity       string             `json:"entity"`
+	Type         string             `json:"type"`
+	Category     pgtype.Text        `json:"category"`
+	Project      pgtype.Text        `json:"project"`
+	Branch       pgtype.Text        `json:"branch"`
+	Language     pgtype.Text        `json:"language"`
+	IsWrite      bool               `json:"is_write"`
+	UserAgent    pgtype.Text        `json:"user_agent"`
+	Time         pgtype.Timestamptz `json:"time"`
+	AggregatedAt pgtype.Timestamptz `json:"aggregated_at"`
+	ProjectID    pgtype.UUID        `json:"project_id"`
+	TaskID       pgtype.UUID        `json:"task_id"`
+	TimeEntryID  pgtype.UUID        `json:"time_entry_id"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type HeartbeatRule struct {
+	ID            uuid.UUID          `json:"id"`
+	UserID        uuid.UUID          `json:"user_id"`
+	SourceProject pgtype.Text        `json:"source_project"`
+	EntityPrefix  pgtype.Text        `json:"entity_prefix"`
+	Language      pgtype.Text        `json:"language"`
+	ProjectID     uuid.UUID          `json:"project_id"`
+	TaskID        pgtype.UUID        `json:"task_id"`
+	Position      int32              `json:"position"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
+type NoteLink struct {
+	ID         uuid.UUID   `json:"id"`
+	UserID     uuid.UUID   `json:"user_id"`
+	TaskID     uuid.UUID   `json:"task_id"`
+	CommentID  pgtype.UUID `json:"comment_id"`
+	TargetType string      `json:"target_type"`
+	TargetID   pgtype.UUID `json:"target_id"`
+	TargetText string      `json:"target_text"`
+}
+
+type Operation struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"user_id"`
+	Kind      string             `json:"kind"`
+	Inverse   []byte             `json:"inverse"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UndoneAt  pgtype.Timestamptz `json:"undone_at"`
+}
+
+type Perspective struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"user_id"`
+	Name      string             `json:"name"`
+	Filter    string             `json:"filter"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type PomodoroSession struct {
+	ID                 uuid.UUID          `json:"id"`
+	UserID             uuid.UUID          `json:"user_id"`
+	ProjectID          uuid.UUID          `json:"project_id"`
+	TaskID             pgtype.UUID        `json:"task_id"`
+	Note               pgtype.Text        `json:"note"`
+	WorkSeconds        int32              `json:"work_seconds"`
+	ShortBreakSeconds  int32              `json:"short_break_seconds"`
+	LongBreakSeconds   int32              `json:"long_break_seconds"`
+	LongBreakEvery     int32              `json:"long_break_every"`
+	Phase              string             `json:"phase"`
+	PhaseStartedAt     pgtype.Timestamptz `json:"phase_started_at"`
+	PhaseSeconds       int32              `json:"phase_seconds"`
+	PausedAt           pgtype.Timestamptz `json:"paused_at"`
+	PausedSeconds      int32              `json:"paused_seconds"`
+	CompletedPomodoros int32              `json:"completed_pomodoros"`
+	Interruptions      int32              `json:"interruptions"`
+	TimeEntryID        pgtype.UUID        `json:"time_entry_id"`
+	CreatedAt          pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt          pgtype.Timestamptz `json:"updated_at"`
+	EndedAt            pgtype.Timestamptz `json:"ended_at"`
 }
 
 type Project struct {
@@ -191,6 +362,28 @@ type Project struct {
 	IsArchived  bool               `json:"is_archived"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	WipLimit    pgtype.Int4        `json:"wip_limit"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type ProjectBilling struct {
+	ProjectID       uuid.UUID          `json:"project_id"`
+	UserID          uuid.UUID          `json:"user_id"`
+	HourlyRateMinor int64              `json:"hourly_rate_minor"`
+	Currency        string             `json:"currency"`
+	RoundingMinutes int32              `json:"rounding_minutes"`
+	RoundingMode    string             `json:"rounding_mode"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ProjectStatus struct {
+	ID        uuid.UUID          `json:"id"`
+	ProjectID uuid.UUID          `json:"project_id"`
+	Name      string             `json:"name"`
+	Category  TaskStatus         `json:"category"`
+	Color     pgtype.Text        `json:"color"`
+	Position  int32              `json:"position"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
 type Result struct {
@@ -202,6 +395,12 @@ type Result struct {
 	Value        pgtype.Numeric     `json:"value"`
 	RecordedAt   pgtype.Timestamptz `json:"recorded_at"`
 	Note         pgtype.Text        `json:"note"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type ResultTag struct {
+	ResultID uuid.UUID `json:"result_id"`
+	TagID    uuid.UUID `json:"tag_id"`
 }
 
 type ScheduledEvent struct {
@@ -226,24 +425,78 @@ type ScheduledEvent struct {
 	Url             pgtype.Text        `json:"url"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type StreamTicket struct {
+	TokenHash string             `json:"token_hash"`
+	UserID    uuid.UUID          `json:"user_id"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+}
+
+type Tag struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"user_id"`
+	Name      string             `json:"name"`
+	Color     pgtype.Text        `json:"color"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
 type Task struct {
+	ID               uuid.UUID          `json:"id"`
+	UserID           uuid.UUID          `json:"user_id"`
+	ProjectID        uuid.UUID          `json:"project_id"`
+	Title            string             `json:"title"`
+	NoteMarkdown     pgtype.Text        `json:"note_markdown"`
+	Status           TaskStatus         `json:"status"`
+	DueDate          pgtype.Timestamptz `json:"due_date"`
+	Priority         pgtype.Int2        `json:"priority"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	CalendarID       pgtype.UUID        `json:"calendar_id"`
+	IcalUid          pgtype.Text        `json:"ical_uid"`
+	Etag             pgtype.Text        `json:"etag"`
+	Sequence         int32              `json:"sequence"`
+	CompletedAt      pgtype.Timestamptz `json:"completed_at"`
+	Position         string             `json:"position"`
+	CustomStatusID   pgtype.UUID        `json:"custom_status_id"`
+	EstimatedMinutes pgtype.Int4        `json:"estimated_minutes"`
+	DeletedAt        pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type TaskActivity struct {
+	ID        uuid.UUID          `json:"id"`
+	TaskID    uuid.UUID          `json:"task_id"`
+	UserID    uuid.UUID          `json:"user_id"`
+	Action    string             `json:"action"`
+	Source    string             `json:"source"`
+	Actor     pgtype.Text        `json:"actor"`
+	Changes   []byte             `json:"changes"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type TaskComment struct {
 	ID           uuid.UUID          `json:"id"`
+	TaskID       uuid.UUID          `json:"task_id"`
 	UserID       uuid.UUID          `json:"user_id"`
-	ProjectID    uuid.UUID          `json:"project_id"`
-	Title        string             `json:"title"`
-	NoteMarkdown pgtype.Text        `json:"note_markdown"`
-	Status       TaskStatus         `json:"status"`
-	DueDate      pgtype.Timestamptz `json:"due_date"`
-	Priority     pgtype.Int2        `json:"priority"`
+	BodyMarkdown string             `json:"body_markdown"`
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
-	CalendarID   pgtype.UUID        `json:"calendar_id"`
-	IcalUid      pgtype.Text        `json:"ical_uid"`
-	Etag         pgtype.Text        `json:"etag"`
-	Sequence     int32              `json:"sequence"`
-	CompletedAt  pgtype.Timestamptz `json:"completed_at"`
+}
+
+type TaskTag struct {
+	TaskID uuid.UUID `json:"task_id"`
+	TagID  uuid.UUID `json:"tag_id"`
+}
+
+type Template struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"user_id"`
+	Kind      TemplateKind       `json:"kind"`
+	Name      string             `json:"name"`
+	Body      []byte             `json:"body"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
 type TimeEntry struct {
@@ -257,6 +510,12 @@ type TimeEntry struct {
 	IsAutoGenerated bool               `json:"is_auto_generated"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	IsBillable      bool               `json:"is_billable"`
+}
+
+type TimeEntryTag struct {
+	TimeEntryID uuid.UUID `json:"time_entry_id"`
+	TagID       uuid.UUID `json:"tag_id"`
 }
 
 type TimetableSlot struct {
@@ -51,7 +51,7 @@ INSERT INTO projects (
     user_id, category_id, title, description, color, is_archived
 ) VALUES (
     $1, $2, $3, $4, $5, $6
-) RETURNING id, user_id, category_id, title, description, color, is_archived, created_at, updated_at
+) RETURNING id, user_id, category_id, title, description, color, is_archived, created_at, updated_at, wip_limit, deleted_at
 `
 
 type CreateProjectParams struct {
@@ -83,13 +83,39 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (P
 		&i.IsArchived,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.WipLimit,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getCategory = `-- name: GetCategory :one
+SELECT id, user_id, name, root_type, color, created_at FROM categories
+WHERE id = $1 AND user_id = $2
+`
+
+type GetCategoryParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetCategory(ctx context.Context, arg GetCategoryParams) (Category, error) {
+	row := q.db.QueryRow(ctx, getCategory, arg.ID, arg.UserID)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.RootType,
+		&i.Color,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getDefaultProject = `-- name: GetDefaultProject :one
-SELECT id, user_id, category_id, title, description, color, is_archived, created_at, updated_at FROM projects
-WHERE user_id = $1
+SELECT id, user_id, category_id, title, description, color, is_archived, created_at, updated_at, wip_limit, deleted_at FROM projects
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at ASC
 LIMIT 1
 `
@@ -107,13 +133,15 @@ func (q *Queries) GetDefaultProject(ctx context.Context, userID uuid.UUID) (Proj
 		&i.IsArchived,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.WipLimit,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getProject = `-- name: GetProject :one
-SELECT id, user_id, category_id, title, description, color, is_archived, created_at, updated_at FROM projects
-WHERE id = $1 AND user_id = $2 LIMIT 1
+SELECT id, user_id, category_id, title, description, color, is_archived, created_at, updated_at, wip_limit, deleted_at FROM projects
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL LIMIT 1
 `
 
 type GetProjectParams struct {
@@ -134,6 +162,39 @@ func (q *Queries) GetProject(ctx context.Context, arg GetProjectParams) (Project
 		&i.IsArchived,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.WipLimit,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getProjectForUpdate = `-- name: GetProjectForUpdate :one
+SELECT id, user_id, category_id, title, description, color, is_archived, created_at, updated_at, wip_limit, deleted_at FROM projects
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+type GetProjectForUpdateParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// WIP制限の判定中に同じプロジェクトへの並行更新を防ぐ
+func (q *Queries) GetProjectForUpdate(ctx context.Context, arg GetProjectForUpdateParams) (Project, error) {
+	row := q.db.QueryRow(ctx, getProjectForUpdate, arg.ID, arg.UserID)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CategoryID,
+		&i.Title,
+		&i.Description,
+		&i.Color,
+		&i.IsArchived,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.WipLimit,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -175,13 +236,14 @@ const listProjects = `-- name: ListProjects :many
 SELECT 
     p.id, p.user_id, p.category_id, p.title, p.description, 
     COALESCE(p.color, '#808080')::varchar as color, 
-    p.is_archived, p.created_at, p.updated_at, 
+    p.is_archived, p.created_at, p.updated_at, p.wip_limit,
     c.name as category_name, c.root_type, 
     COALESCE(c.color, '#808080')::varchar as category_color
 FROM projects p
 JOIN categories c ON p.category_id = c.id
 WHERE
     p.user_id = $1
+    AND p.deleted_at IS NULL
     AND ($2::boolean IS NULL OR p.is_archived = $2)
 ORDER BY p.updated_at DESC
 `
@@ -201,6 +263,8 @@ type ListProjectsRow struct {
 	IsArchived    bool               `json:"is_archived"`
 	CreatedAt     pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	WipLimit      pgtype.Int4        `json:"wip_limit"`
+	DeletedAt     pgtype.Timestamptz `json:"deleted_at"`
 	CategoryName  string             `json:"category_name"`
 	RootType      RootCategoryType   `json:"root_type"`
 	CategoryColor string             `json:"category_color"`
@@ -225,6 +289,8 @@ func (q *Queries) ListProjects(ctx context.Context, arg ListProjectsParams) ([]L
 			&i.IsArchived,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.WipLimit,
+			&i.DeletedAt,
 			&i.CategoryName,
 			&i.RootType,
 			&i.CategoryColor,
@@ -238,3 +304,56 @@ func (q *Queries) ListProjects(ctx context.Context, arg ListProjectsParams) ([]L
 	}
 	return items, nil
 }
+
+const updateProject = `-- name: UpdateProject :one
+UPDATE projects
+SET
+    title = COALESCE($3, title),
+    description = COALESCE($4, description),
+    color = COALESCE($5, color),
+    is_archived = COALESCE($6, is_archived),
+    wip_limit = CASE
+        WHEN $7::int IS NULL THEN wip_limit
+        ELSE NULLIF($7, 0)
+    END,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING id, user_id, category_id, title, description, color, is_archived, created_at, updated_at, wip_limit, deleted_at
+`
+
+type UpdateProjectParams struct {
+	ID          uuid.UUID   `json:"id"`
+	UserID      uuid.UUID   `json:"user_id"`
+	Title       pgtype.Text `json:"title"`
+	Description pgtype.Text `json:"description"`
+	Color       pgtype.Text `json:"color"`
+	IsArchived  pgtype.Bool `json:"is_archived"`
+	WipLimit    pgtype.Int4 `json:"wip_limit"`
+}
+
+func (q *Queries) UpdateProject(ctx context.Context, arg UpdateProjectParams) (Project, error) {
+	row := q.db.QueryRow(ctx, updateProject,
+		arg.ID,
+		arg.UserID,
+		arg.Title,
+		arg.Description,
+		arg.Color,
+		arg.IsArchived,
+		arg.WipLimit,
+	)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CategoryID,
+		&i.Title,
+		&i.Description,
+		&i.Color,
+		&i.IsArchived,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.WipLimit,
+		&i.DeletedAt,
+	)
+	return i, err
+}
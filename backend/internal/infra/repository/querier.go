@@ -8,64 +8,267 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	AddEventTag(ctx context.Context, arg AddEventTagParams) (int64, error)
+	AddResultTag(ctx context.Context, arg AddResultTagParams) (int64, error)
+	AddTaskTag(ctx context.Context, arg AddTaskTagParams) (int64, error)
+	AddTimeEntryTag(ctx context.Context, arg AddTimeEntryTagParams) (int64, error)
+	ClearEventTags(ctx context.Context, eventID uuid.UUID) error
+	ClearTaskTags(ctx context.Context, taskID uuid.UUID) error
+	ConsumeStreamTicket(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	// 分割・結合したエントリにタグを引き継ぐ
+	CopyTimeEntryTags(ctx context.Context, arg CopyTimeEntryTagsParams) error
+	CountEventsByICalUID(ctx context.Context, arg CountEventsByICalUIDParams) (int64, error)
+	// 復元で同じ UID の有効なタスクが重複しないか確認する
+	CountTasksByICalUID(ctx context.Context, arg CountTasksByICalUIDParams) (int64, error)
+	// 指定タスク自身を除いたレーン内の件数
+	CountTasksInLane(ctx context.Context, arg CountTasksInLaneParams) (int64, error)
+	CreateActivityRule(ctx context.Context, arg CreateActivityRuleParams) (ActivityRule, error)
 	CreateApiToken(ctx context.Context, arg CreateApiTokenParams) (ApiToken, error)
+	CreateAutoTimeEntry(ctx context.Context, arg CreateAutoTimeEntryParams) (TimeEntry, error)
 	CreateCalendar(ctx context.Context, arg CreateCalendarParams) (Calendar, error)
 	CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error)
 	CreateChecklistItem(ctx context.Context, arg CreateChecklistItemParams) (ChecklistItem, error)
 	CreateEvent(ctx context.Context, arg CreateEventParams) (ScheduledEvent, error)
+	// 予定の所有者でなければ行を返さない
+	CreateEventAttachment(ctx context.Context, arg CreateEventAttachmentParams) (Attachment, error)
+	// 同じ entity・時刻の heartbeat は再送とみなして無視する
+	CreateHeartbeat(ctx context.Context, arg CreateHeartbeatParams) (int64, error)
+	CreateHeartbeatRule(ctx context.Context, arg CreateHeartbeatRuleParams) (HeartbeatRule, error)
+	CreateNoteLink(ctx context.Context, arg CreateNoteLinkParams) error
+	CreateOperation(ctx context.Context, arg CreateOperationParams) (Operation, error)
+	CreatePerspective(ctx context.Context, arg CreatePerspectiveParams) (Perspective, error)
+	CreatePomodoroSession(ctx context.Context, arg CreatePomodoroSessionParams) (PomodoroSession, error)
 	CreateProject(ctx context.Context, arg CreateProjectParams) (Project, error)
+	CreateProjectStatus(ctx context.Context, arg CreateProjectStatusParams) (ProjectStatus, error)
 	CreateResult(ctx context.Context, arg CreateResultParams) (Result, error)
+	CreateStreamTicket(ctx context.Context, arg CreateStreamTicketParams) error
+	CreateTag(ctx context.Context, arg CreateTagParams) (Tag, error)
 	CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error)
+	CreateTaskActivity(ctx context.Context, arg CreateTaskActivityParams) error
+	// タスクの所有者でなければ行を返さない
+	CreateTaskAttachment(ctx context.Context, arg CreateTaskAttachmentParams) (Attachment, error)
+	CreateTaskComment(ctx context.Context, arg CreateTaskCommentParams) (TaskComment, error)
+	CreateTemplate(ctx context.Context, arg CreateTemplateParams) (Template, error)
+	// is_billable を省略すると請求の対象にする
 	CreateTimeEntry(ctx context.Context, arg CreateTimeEntryParams) (TimeEntry, error)
+	CreateTimerAutoStop(ctx context.Context, arg CreateTimerAutoStopParams) error
 	CreateTimetableSlot(ctx context.Context, arg CreateTimetableSlotParams) (TimetableSlot, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	// 保持期間を過ぎた生のイベント (作ったエントリは残る)
+	DeleteActivityEventsBefore(ctx context.Context, endedAt pgtype.Timestamptz) (int64, error)
+	DeleteActivityRule(ctx context.Context, arg DeleteActivityRuleParams) (int64, error)
 	DeleteApiToken(ctx context.Context, arg DeleteApiTokenParams) error
-	DeleteCalendar(ctx context.Context, arg DeleteCalendarParams) error
+	DeleteAttachment(ctx context.Context, arg DeleteAttachmentParams) (Attachment, error)
 	DeleteChecklistItem(ctx context.Context, id uuid.UUID) error
-	DeleteEventByICalUID(ctx context.Context, arg DeleteEventByICalUIDParams) error
-	DeleteResult(ctx context.Context, arg DeleteResultParams) error
-	DeleteTask(ctx context.Context, arg DeleteTaskParams) error
-	DeleteTaskByICalUID(ctx context.Context, arg DeleteTaskByICalUIDParams) error
+	DeleteCommentNoteLinks(ctx context.Context, commentID pgtype.UUID) error
+	DeleteExpiredStreamTickets(ctx context.Context) error
+	DeleteHeartbeatRule(ctx context.Context, arg DeleteHeartbeatRuleParams) (int64, error)
+	DeleteOperationsBefore(ctx context.Context, createdAt pgtype.Timestamptz) (int64, error)
+	DeletePerspective(ctx context.Context, arg DeletePerspectiveParams) (int64, error)
+	DeleteProjectBilling(ctx context.Context, arg DeleteProjectBillingParams) (int64, error)
+	// 削除されたステータスのタスクは custom_status_id が NULL になり、基本カテゴリのみ残る
+	DeleteProjectStatus(ctx context.Context, arg DeleteProjectStatusParams) (int64, error)
+	// 完全に削除する対象(とその中のタスク・予定)の添付ファイルの行を消し、実体の保存先を返す
+	DeletePurgedAttachments(ctx context.Context, arg DeletePurgedAttachmentsParams) ([]string, error)
+	DeleteTag(ctx context.Context, arg DeleteTagParams) (int64, error)
+	DeleteTaskComment(ctx context.Context, arg DeleteTaskCommentParams) (int64, error)
+	// タスク本体のノートのリンク (コメントのものは残す)
+	DeleteTaskNoteLinks(ctx context.Context, taskID uuid.UUID) error
+	DeleteTemplate(ctx context.Context, arg DeleteTemplateParams) (int64, error)
+	DeleteTimeEntry(ctx context.Context, arg DeleteTimeEntryParams) (int64, error)
+	ExtendActivityEvent(ctx context.Context, arg ExtendActivityEventParams) (ActivityEvent, error)
+	// 自動生成したエントリの終わりを延ばす (手動で計測中に戻したエントリなどは対象外)
+	ExtendAutoTimeEntry(ctx context.Context, arg ExtendAutoTimeEntryParams) (int64, error)
+	// 計測中なら ended_at で止める。手動で止められていれば何もしない
+	FinishTimeEntry(ctx context.Context, arg FinishTimeEntryParams) (int64, error)
+	// 進行中のセッション。状態を進めるので行をロックする
+	GetActivePomodoroSession(ctx context.Context, userID uuid.UUID) (PomodoroSession, error)
+	// 期間に含まれる部分の秒数
+	GetActivityTotals(ctx context.Context, arg GetActivityTotalsParams) (GetActivityTotalsRow, error)
+	GetAttachment(ctx context.Context, arg GetAttachmentParams) (Attachment, error)
 	GetCalendar(ctx context.Context, arg GetCalendarParams) (Calendar, error)
+	GetCategory(ctx context.Context, arg GetCategoryParams) (Category, error)
+	// 完了したタスクのサイクルタイム(最初にTODOから動いた時刻から完了まで)とリードタイム(作成から完了まで)
+	GetCycleTimeStats(ctx context.Context, arg GetCycleTimeStatsParams) ([]GetCycleTimeStatsRow, error)
 	GetDefaultCalendar(ctx context.Context, userID uuid.UUID) (Calendar, error)
 	GetDefaultProject(ctx context.Context, userID uuid.UUID) (Project, error)
+	// 期間内に完了したタスクの見積もりと実績(分)を期間・プロジェクトごとに集計 (期間の区切りは timezone で数える)
+	// 見積もりはタスク自身の値、なければチェックリストの見積もりの合計
+	GetEstimateReport(ctx context.Context, arg GetEstimateReportParams) ([]GetEstimateReportRow, error)
 	GetEventByICalUID(ctx context.Context, arg GetEventByICalUIDParams) (ScheduledEvent, error)
-	// GROWTHカテゴリの実績のみを日別集計
+	// GROWTHカテゴリの実績を timezone での日別に集計する (from_day〜to_day)。日をまたぐエントリは日ごとに按分する
 	GetGrowthStats(ctx context.Context, arg GetGrowthStatsParams) ([]GetGrowthStatsRow, error)
+	// since 以降で最後に heartbeat かデスクトップのアクティビティがあった日時 (なければ NULL)
+	GetLastActivityAt(ctx context.Context, arg GetLastActivityAtParams) (pgtype.Timestamptz, error)
+	GetLastActivityEvent(ctx context.Context, arg GetLastActivityEventParams) (ActivityEvent, error)
+	// プロジェクト内で最後尾の順位キー
+	GetLastTaskPosition(ctx context.Context, projectID uuid.UUID) (string, error)
+	// 同じ操作を同時に取り消さないように行をロックする
+	GetOperationForUpdate(ctx context.Context, arg GetOperationForUpdateParams) (Operation, error)
+	GetPerspective(ctx context.Context, arg GetPerspectiveParams) (Perspective, error)
+	// ロールアップ済みのイベントのうち直前のもの
+	GetPreviousActivityEvent(ctx context.Context, arg GetPreviousActivityEventParams) (ActivityEvent, error)
+	// 集計済みの heartbeat のうち直前のもの
+	GetPreviousHeartbeat(ctx context.Context, arg GetPreviousHeartbeatParams) (Heartbeat, error)
 	GetProject(ctx context.Context, arg GetProjectParams) (Project, error)
-	// 計測中のエントリ
-	GetRunningTimeEntries(ctx context.Context, userID uuid.UUID) ([]GetRunningTimeEntriesRow, error)
+	GetProjectBilling(ctx context.Context, arg GetProjectBillingParams) (ProjectBilling, error)
+	// ルールに合わないときは heartbeat の project と同じ名前のプロジェクトに対応づける
+	GetProjectByTitle(ctx context.Context, arg GetProjectByTitleParams) (uuid.UUID, error)
+	// WIP制限の判定中に同じプロジェクトへの並行更新を防ぐ
+	GetProjectForUpdate(ctx context.Context, arg GetProjectForUpdateParams) (Project, error)
+	GetProjectStatus(ctx context.Context, arg GetProjectStatusParams) (ProjectStatus, error)
+	// 計測中のエントリ (ユーザーごとに最大1件)
+	GetRunningTimeEntry(ctx context.Context, userID uuid.UUID) (GetRunningTimeEntryRow, error)
+	GetTag(ctx context.Context, arg GetTagParams) (Tag, error)
+	// タグ別の集計(時間・タスク・実績)
+	GetTagStats(ctx context.Context, arg GetTagStatsParams) ([]GetTagStatsRow, error)
 	GetTask(ctx context.Context, arg GetTaskParams) (Task, error)
 	GetTaskByICalUID(ctx context.Context, arg GetTaskByICalUIDParams) (Task, error)
+	GetTemplate(ctx context.Context, arg GetTemplateParams) (Template, error)
+	GetTimeEntry(ctx context.Context, arg GetTimeEntryParams) (TimeEntry, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
-	GetUserByTokenHash(ctx context.Context, tokenHash string) (User, error)
+	GetUserByTokenHash(ctx context.Context, tokenHash string) (GetUserByTokenHashRow, error)
+	ListActivityRules(ctx context.Context, userID uuid.UUID) ([]ActivityRule, error)
 	ListApiTokens(ctx context.Context, userID uuid.UUID) ([]ListApiTokensRow, error)
+	ListAttachmentsByEventIDs(ctx context.Context, eventIds []uuid.UUID) ([]Attachment, error)
+	ListAttachmentsByTaskIDs(ctx context.Context, taskIds []uuid.UUID) ([]Attachment, error)
+	// 対象を参照しているタスクのノートとコメント。未解決のリンクは対象の現在のタイトルで照合する
+	ListBacklinks(ctx context.Context, arg ListBacklinksParams) ([]ListBacklinksRow, error)
+	// カンバン表示用(レーン内は順位キー順)
+	ListBoardTasks(ctx context.Context, arg ListBoardTasksParams) ([]ListBoardTasksRow, error)
 	ListCalendars(ctx context.Context, userID uuid.UUID) ([]Calendar, error)
+	ListCalendarsByProject(ctx context.Context, arg ListCalendarsByProjectParams) ([]Calendar, error)
 	ListCategories(ctx context.Context, userID uuid.UUID) ([]Category, error)
 	ListChecklistItems(ctx context.Context, taskID uuid.UUID) ([]ChecklistItem, error)
+	// フェーズの終わりを過ぎたまま進んでいないセッションのユーザー
+	ListDuePomodoroSessions(ctx context.Context, now pgtype.Timestamptz) ([]uuid.UUID, error)
+	ListEventAttachments(ctx context.Context, arg ListEventAttachmentsParams) ([]Attachment, error)
+	ListEventTagNames(ctx context.Context, eventIds []uuid.UUID) ([]ListEventTagNamesRow, error)
 	ListEventsByCalendar(ctx context.Context, arg ListEventsByCalendarParams) ([]ScheduledEvent, error)
 	ListEventsByCalendarAndRange(ctx context.Context, arg ListEventsByCalendarAndRangeParams) ([]ScheduledEvent, error)
 	ListEventsByRange(ctx context.Context, arg ListEventsByRangeParams) ([]ListEventsByRangeRow, error)
+	// 保持期間を過ぎたもの。ListTrash と同じく親と一緒に入ったものは親だけを返す
+	ListExpiredTrash(ctx context.Context, arg ListExpiredTrashParams) ([]ListExpiredTrashRow, error)
+	ListHeartbeatRules(ctx context.Context, userID uuid.UUID) ([]HeartbeatRule, error)
+	// 請求書に載せる期間内の終了済みのエントリ (請求の対象外のものも含む)
+	ListInvoiceTimeEntries(ctx context.Context, arg ListInvoiceTimeEntriesParams) ([]ListInvoiceTimeEntriesRow, error)
+	// 優先度マトリクス用の未完了タスク(アーカイブ済みプロジェクトを除く)。期限の近い順
+	ListMatrixTasks(ctx context.Context, arg ListMatrixTasksParams) ([]ListMatrixTasksRow, error)
+	// 範囲と重なるエントリ。ended_at が NULL (計測中・終わりを指定しない) なら終わりがないものとして扱う
+	ListOverlappingTimeEntries(ctx context.Context, arg ListOverlappingTimeEntriesParams) ([]TimeEntry, error)
+	// 終わってから時間がたったもの (heartbeat でまだ延びるものは待つ)
+	ListPendingActivityEvents(ctx context.Context, arg ListPendingActivityEventsParams) ([]ActivityEvent, error)
+	ListPendingHeartbeats(ctx context.Context, limit int32) ([]Heartbeat, error)
+	ListPerspectives(ctx context.Context, userID uuid.UUID) ([]Perspective, error)
+	ListProjectStatuses(ctx context.Context, arg ListProjectStatusesParams) ([]ProjectStatus, error)
 	ListProjects(ctx context.Context, arg ListProjectsParams) ([]ListProjectsRow, error)
+	ListProjectsByTitles(ctx context.Context, arg ListProjectsByTitlesParams) ([]ListProjectsByTitlesRow, error)
 	ListResults(ctx context.Context, arg ListResultsParams) ([]ListResultsRow, error)
+	// 自動停止の設定を確かめるため、計測中のエントリをユーザーの設定と一緒に取得する
+	ListRunningTimeEntriesWithPreferences(ctx context.Context) ([]ListRunningTimeEntriesWithPreferencesRow, error)
+	ListTags(ctx context.Context, userID uuid.UUID) ([]Tag, error)
+	ListTaskActivities(ctx context.Context, arg ListTaskActivitiesParams) ([]TaskActivity, error)
+	ListTaskAttachments(ctx context.Context, arg ListTaskAttachmentsParams) ([]Attachment, error)
+	ListTaskComments(ctx context.Context, arg ListTaskCommentsParams) ([]TaskComment, error)
+	ListTaskPositions(ctx context.Context, projectID uuid.UUID) ([]ListTaskPositionsRow, error)
+	ListTaskTagNames(ctx context.Context, taskIds []uuid.UUID) ([]ListTaskTagNamesRow, error)
 	ListTasksByCalendar(ctx context.Context, arg ListTasksByCalendarParams) ([]Task, error)
 	ListTasksByCalendarAndRange(ctx context.Context, arg ListTasksByCalendarAndRangeParams) ([]Task, error)
+	ListTasksByProject(ctx context.Context, arg ListTasksByProjectParams) ([]Task, error)
+	// wiki リンクの解決用。同じタイトルが複数あれば未完了、更新の新しい順に並べる
+	ListTasksByTitles(ctx context.Context, arg ListTasksByTitlesParams) ([]ListTasksByTitlesRow, error)
 	// タスクと同時に、チェックリストの進捗を取得
 	ListTasksWithStats(ctx context.Context, arg ListTasksWithStatsParams) ([]ListTasksWithStatsRow, error)
+	ListTemplates(ctx context.Context, arg ListTemplatesParams) ([]Template, error)
+	// started_at の新しい順。カーソル (cursor_started_at, cursor_id) を指定するとその次から取得する
 	ListTimeEntries(ctx context.Context, arg ListTimeEntriesParams) ([]ListTimeEntriesRow, error)
+	ListTimeEntryTagIDs(ctx context.Context, timeEntryID uuid.UUID) ([]uuid.UUID, error)
+	ListTimerAutoStops(ctx context.Context, arg ListTimerAutoStopsParams) ([]ListTimerAutoStopsRow, error)
 	ListTimetableSlots(ctx context.Context, userID uuid.UUID) ([]ListTimetableSlotsRow, error)
 	ListTimetableSlotsByDayOfWeek(ctx context.Context, arg ListTimetableSlotsByDayOfWeekParams) ([]ListTimetableSlotsByDayOfWeekRow, error)
+	ListTimetableSlotsByProject(ctx context.Context, arg ListTimetableSlotsByProjectParams) ([]TimetableSlot, error)
+	// ゴミ箱の一覧。プロジェクト・カレンダーと一緒に入ったものは親だけを表示する
+	ListTrash(ctx context.Context, arg ListTrashParams) ([]ListTrashRow, error)
+	// ルールに合わなかった時間をアプリとウィンドウタイトルごとに集計する
+	ListUnclassifiedActivity(ctx context.Context, arg ListUnclassifiedActivityParams) ([]ListUnclassifiedActivityRow, error)
+	MarkActivityEventRolledUp(ctx context.Context, arg MarkActivityEventRolledUpParams) error
+	MarkHeartbeatAggregated(ctx context.Context, arg MarkHeartbeatAggregatedParams) error
+	MarkOperationUndone(ctx context.Context, id uuid.UUID) error
+	MoveTask(ctx context.Context, arg MoveTaskParams) (Task, error)
+	PurgeCalendar(ctx context.Context, arg PurgeCalendarParams) (int64, error)
+	PurgeEvent(ctx context.Context, arg PurgeEventParams) (int64, error)
+	// 中のタスク・予定・実績・記録は外部キーでまとめて消える
+	PurgeProject(ctx context.Context, arg PurgeProjectParams) (int64, error)
+	PurgeResult(ctx context.Context, arg PurgeResultParams) (int64, error)
+	PurgeTask(ctx context.Context, arg PurgeTaskParams) (int64, error)
+	RemoveEventTag(ctx context.Context, arg RemoveEventTagParams) (int64, error)
+	RemoveResultTag(ctx context.Context, arg RemoveResultTagParams) (int64, error)
+	RemoveTaskTag(ctx context.Context, arg RemoveTaskTagParams) (int64, error)
+	RemoveTimeEntryTag(ctx context.Context, arg RemoveTimeEntryTagParams) (int64, error)
+	// 停止を取り消して計測中に戻す
+	ReopenTimeEntry(ctx context.Context, arg ReopenTimeEntryParams) (TimeEntry, error)
+	RestoreCalendar(ctx context.Context, arg RestoreCalendarParams) (Calendar, error)
+	// カレンダーを戻す前に、一緒にゴミ箱に入ったものを戻す
+	RestoreCalendarItems(ctx context.Context, arg RestoreCalendarItemsParams) error
+	RestoreEvent(ctx context.Context, arg RestoreEventParams) (ScheduledEvent, error)
+	RestoreProject(ctx context.Context, arg RestoreProjectParams) (Project, error)
+	// プロジェクトを戻す前に、一緒にゴミ箱に入ったものを戻す
+	RestoreProjectItems(ctx context.Context, arg RestoreProjectItemsParams) error
+	RestoreResult(ctx context.Context, arg RestoreResultParams) (Result, error)
+	RestoreTask(ctx context.Context, arg RestoreTaskParams) (Task, error)
+	// 取り消しで削除したエントリを同じ ID で作り直す
+	RestoreTimeEntry(ctx context.Context, arg RestoreTimeEntryParams) (TimeEntry, error)
+	// 取り消しで記録しておいた内容に戻す。独自ステータスが消えていれば外す
+	RevertTask(ctx context.Context, arg RevertTaskParams) (Task, error)
+	// 取り消しで記録しておいた内容に戻す
+	RevertTimeEntry(ctx context.Context, arg RevertTimeEntryParams) (TimeEntry, error)
+	// 全文検索(tsvector)と部分一致(pg_trgm)のフォールバックを組み合わせ、種別ごとに上位を返す
+	SearchAll(ctx context.Context, arg SearchAllParams) ([]SearchAllRow, error)
+	SetTaskPosition(ctx context.Context, arg SetTaskPositionParams) error
+	// 独自ステータスはプロジェクトごとのものなので外す
+	SetTaskProject(ctx context.Context, arg SetTaskProjectParams) (Task, error)
+	// 計測中のエントリがあれば止める
+	StopRunningTimeEntry(ctx context.Context, arg StopRunningTimeEntryParams) (TimeEntry, error)
+	// 計測中のエントリだけを止める (終わったエントリの ended_at は変えない)
 	StopTimeEntry(ctx context.Context, arg StopTimeEntryParams) (TimeEntry, error)
+	TaskHasTag(ctx context.Context, arg TaskHasTagParams) (bool, error)
+	TrashCalendar(ctx context.Context, arg TrashCalendarParams) (Calendar, error)
+	// カレンダーと同じ日時で中のタスク・予定をゴミ箱に入れる
+	TrashCalendarItems(ctx context.Context, calendarID uuid.UUID) error
+	TrashEvent(ctx context.Context, arg TrashEventParams) (int64, error)
+	TrashEventByICalUID(ctx context.Context, arg TrashEventByICalUIDParams) (int64, error)
+	TrashProject(ctx context.Context, arg TrashProjectParams) (Project, error)
+	// プロジェクトと同じ日時で中のタスク・予定・実績をゴミ箱に入れる(復元時は同じ日時のものだけ戻す)
+	TrashProjectItems(ctx context.Context, projectID uuid.UUID) error
+	TrashResult(ctx context.Context, arg TrashResultParams) (int64, error)
+	TrashTask(ctx context.Context, arg TrashTaskParams) (int64, error)
+	TrashTaskByICalUID(ctx context.Context, arg TrashTaskByICalUIDParams) (int64, error)
+	// ロールアップは1つのサーバーだけで行う (トランザクションの終わりで解放される)
+	TryLockActivityRollup(ctx context.Context) (bool, error)
+	// 集計は1つのサーバーだけで行う (トランザクションの終わりで解放される)
+	TryLockHeartbeatAggregation(ctx context.Context) (bool, error)
 	UpdateChecklistItem(ctx context.Context, arg UpdateChecklistItemParams) (ChecklistItem, error)
 	UpdateEventByICalUID(ctx context.Context, arg UpdateEventByICalUIDParams) (ScheduledEvent, error)
+	UpdatePerspective(ctx context.Context, arg UpdatePerspectiveParams) (Perspective, error)
+	UpdatePomodoroSession(ctx context.Context, arg UpdatePomodoroSessionParams) (PomodoroSession, error)
+	UpdateProject(ctx context.Context, arg UpdateProjectParams) (Project, error)
+	UpdateProjectStatus(ctx context.Context, arg UpdateProjectStatusParams) (ProjectStatus, error)
+	UpdateTag(ctx context.Context, arg UpdateTagParams) (Tag, error)
 	UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error)
 	UpdateTaskByICalUID(ctx context.Context, arg UpdateTaskByICalUIDParams) (Task, error)
+	UpdateTaskComment(ctx context.Context, arg UpdateTaskCommentParams) (TaskComment, error)
 	UpdateTimeEntry(ctx context.Context, arg UpdateTimeEntryParams) (TimeEntry, error)
 	UpdateUserPreferences(ctx context.Context, arg UpdateUserPreferencesParams) (User, error)
+	// 同じ開始時刻のイベントは再送とみなし、未処理なら終わりだけを延ばす
+	UpsertActivityEvent(ctx context.Context, arg UpsertActivityEventParams) (ActivityEvent, error)
+	UpsertProjectBilling(ctx context.Context, arg UpsertProjectBillingParams) (ProjectBilling, error)
+	// CalDAVのCATEGORIES等、名前でタグを解決する
+	UpsertTagByName(ctx context.Context, arg UpsertTagByNameParams) (Tag, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -17,7 +17,7 @@ INSERT INTO results (
     user_id, project_id, target_task_id, type, value, recorded_at, note
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7
-) RETURNING id, user_id, project_id, target_task_id, type, value, recorded_at, note
+) RETURNING id, user_id, project_id, target_task_id, type, value, recorded_at, note, deleted_at
 `
 
 type CreateResultParams struct {
@@ -50,39 +50,30 @@ func (q *Queries) CreateResult(ctx context.Context, arg CreateResultParams) (Res
 		&i.Value,
 		&i.RecordedAt,
 		&i.Note,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const deleteResult = `-- name: DeleteResult :exec
-DELETE FROM results
-WHERE id = $1 AND user_id = $2
-`
-
-type DeleteResultParams struct {
-	ID     uuid.UUID `json:"id"`
-	UserID uuid.UUID `json:"user_id"`
-}
-
-func (q *Queries) DeleteResult(ctx context.Context, arg DeleteResultParams) error {
-	_, err := q.db.Exec(ctx, deleteResult, arg.ID, arg.UserID)
-	return err
-}
-
 const listResults = `-- name: ListResults :many
 SELECT 
-    r.id, r.user_id, r.project_id, r.target_task_id, r.type, r.value, r.recorded_at, r.note, 
+    r.id, r.user_id, r.project_id, r.target_task_id, r.type, r.value, r.recorded_at, r.note, r.deleted_at, 
     p.title as project_title, 
     COALESCE(p.color, '#808080')::varchar as project_color, 
-    COALESCE(t.title, '')::varchar as task_title
+    COALESCE(t.title, '')::varchar as task_title,
+    ARRAY(SELECT rt.tag_id FROM result_tags rt WHERE rt.result_id = r.id)::uuid[] as tag_ids
 FROM results r
 JOIN projects p ON r.project_id = p.id
 LEFT JOIN tasks t ON r.target_task_id = t.id
 WHERE
     r.user_id = $1
+    AND r.deleted_at IS NULL
     AND ($2::uuid IS NULL OR r.project_id = $2)
     AND r.recorded_at >= $3
     AND r.recorded_at <= $4
+    AND ($5::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM result_tags rt WHERE rt.result_id = r.id AND rt.tag_id = $5
+    ))
 ORDER BY r.recorded_at DESC
 `
 
@@ -91,6 +82,7 @@ type ListResultsParams struct {
 	ProjectID pgtype.UUID        `json:"project_id"`
 	FromDate  pgtype.Timestamptz `json:"from_date"`
 	ToDate    pgtype.Timestamptz `json:"to_date"`
+	TagID     pgtype.UUID        `json:"tag_id"`
 }
 
 type ListResultsRow struct {
@@ -102,9 +94,11 @@ type ListResultsRow struct {
 	Value        pgtype.Numeric     `json:"value"`
 	RecordedAt   pgtype.Timestamptz `json:"recorded_at"`
 	Note         pgtype.Text        `json:"note"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
 	ProjectTitle string             `json:"project_title"`
 	ProjectColor string             `json:"project_color"`
 	TaskTitle    string             `json:"task_title"`
+	TagIds       []uuid.UUID        `json:"tag_ids"`
 }
 
 func (q *Queries) ListResults(ctx context.Context, arg ListResultsParams) ([]ListResultsRow, error) {
@@ -113,6 +107,7 @@ func (q *Queries) ListResults(ctx context.Context, arg ListResultsParams) ([]Lis
 		arg.ProjectID,
 		arg.FromDate,
 		arg.ToDate,
+		arg.TagID,
 	)
 	if err != nil {
 		return nil, err
@@ -130,9 +125,11 @@ func (q *Queries) ListResults(ctx context.Context, arg ListResultsParams) ([]Lis
 			&i.Value,
 			&i.RecordedAt,
 			&i.Note,
+			&i.DeletedAt,
 			&i.ProjectTitle,
 			&i.ProjectColor,
 			&i.TaskTitle,
+			&i.TagIds,
 		); err != nil {
 			return nil, err
 		}
@@ -143,3 +140,22 @@ func (q *Queries) ListResults(ctx context.Context, arg ListResultsParams) ([]Lis
 	}
 	return items, nil
 }
+
+const trashResult = `-- name: TrashResult :execrows
+UPDATE results
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type TrashResultParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) TrashResult(ctx context.Context, arg TrashResultParams) (int64, error) {
+	result, err := q.db.Exec(ctx, trashResult, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// sqlc の生成対象外。トランザクションの中で一部の処理だけを取り消せるようにする。
+
+// Savepoint は fn をセーブポイントの中で実行し、fn がエラーを返せばセーブポイントまで戻す。
+// トランザクションの外の Queries では使えない
+func (q *Queries) Savepoint(ctx context.Context, fn func(q *Queries) error) error {
+	tx, ok := q.db.(pgx.Tx)
+	if !ok {
+		return errors.New("savepoint requires a transaction")
+	}
+	// pgx ではトランザクション内の Begin がセーブポイントになる
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(New(sp)); err != nil {
+		if rbErr := sp.Rollback(ctx); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return sp.Commit(ctx)
+}
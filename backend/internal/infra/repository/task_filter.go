@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/pkg/filter"
+)
+
+// sqlc の生成対象外。フィルタ式から動的に条件を組み立てるため手書きしている。
+// 条件は ListTasksWithStats の WHERE 句の目印 (taskFilterPlaceholder) と置き換え、値はすべてパラメータで渡す。
+//
+// 項目: priority, status, tag, project, title, text, has, due, created, updated, completed
+// キーワード: done, open, overdue, today
+// タスクの依存関係を持たないため blocked には対応しない (使うとエラーになる)
+
+// taskFilterPlaceholder は db/query/tasks.sql の ListTasksWithStats に置いた条件の目印
+const taskFilterPlaceholder = "TRUE /* task_filter */"
+
+// ListTasksWithFilter は ListTasksWithStats にフィルタ式の条件を加えて実行する
+func (q *Queries) ListTasksWithFilter(ctx context.Context, arg ListTasksWithStatsParams, expr filter.Expr, now time.Time) ([]ListTasksWithStatsRow, error) {
+	args := []any{
+		arg.UserID,
+		arg.ProjectID,
+		arg.Status,
+		arg.FromDate,
+		arg.ToDate,
+		arg.TagID,
+	}
+	c := &taskFilterCompiler{args: args, now: now}
+	cond, err := c.compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Count(listTasksWithStats, taskFilterPlaceholder) != 1 {
+		return nil, fmt.Errorf("ListTasksWithStats must contain %q exactly once", taskFilterPlaceholder)
+	}
+	query := strings.Replace(listTasksWithStats, taskFilterPlaceholder, "("+cond+")", 1)
+
+	rows, err := q.db.Query(ctx, query, c.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTasksWithStatsRow
+	for rows.Next() {
+		var i ListTasksWithStatsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Status,
+			&i.DueDate,
+			&i.Priority,
+			&i.ProjectTitle,
+			&i.ProjectColor,
+			&i.TotalItems,
+			&i.DoneItems,
+			&i.TagIds,
+			&i.CustomStatusID,
+			&i.StatusName,
+			&i.EstimatedMinutes,
+			&i.ChecklistEstimatedMinutes,
+			&i.ActualMinutes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CompileTaskFilter は式がタスクの条件として有効か検証する(保存前のチェック用)
+func CompileTaskFilter(expr filter.Expr) error {
+	c := &taskFilterCompiler{now: time.Now()}
+	_, err := c.compile(expr)
+	return err
+}
+
+// タスクの日時項目と列の対応
+var taskTimeColumns = map[string]string{
+	"due":       "t.due_date",
+	"created":   "t.created_at",
+	"updated":   "t.updated_at",
+	"completed": "t.completed_at",
+}
+
+var compareOps = map[string]string{
+	"=": "=", "!=": "<>", "<": "<", "<=": "<=", ">": ">", ">=": ">=",
+}
+
+type taskFilterCompiler struct {
+	args []any
+	now  time.Time
+}
+
+// param は値をパラメータに追加し、プレースホルダを返す
+func (c *taskFilterCompiler) param(v any) string {
+	c.args = append(c.args, v)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+func (c *taskFilterCompiler) compile(e filter.Expr) (string, error) {
+	switch e := e.(type) {
+	case filter.And:
+		return c.binary(e.Left, e.Right, "AND")
+	case filter.Or:
+		return c.binary(e.Left, e.Right, "OR")
+	case filter.Not:
+		x, err := c.compile(e.X)
+		if err != nil {
+			return "", err
+		}
+		// NULL を含む比較も「一致しない」として扱う
+		return "(" + x + ") IS NOT TRUE", nil
+	case filter.Cond:
+		return c.cond(e)
+	}
+	return "", fmt.Errorf("filter: unknown node %T", e)
+}
+
+func (c *taskFilterCompiler) binary(l, r filter.Expr, op string) (string, error) {
+	left, err := c.compile(l)
+	if err != nil {
+		return "", err
+	}
+	right, err := c.compile(r)
+	if err != nil {
+		return "", err
+	}
+	return "(" + left + " " + op + " " + right + ")", nil
+}
+
+func (c *taskFilterCompiler) cond(e filter.Cond) (string, error) {
+	if e.Op == "" {
+		return c.keyword(e)
+	}
+	if col, ok := taskTimeColumns[e.Field]; ok {
+		return c.timeCond(e, col)
+	}
+
+	switch e.Field {
+	case "priority":
+		n, err := strconv.Atoi(e.Value)
+		if err != nil {
+			return "", filter.Errorf(e.Pos, "priority must be a number")
+		}
+		op, ok := compareOps[e.Op]
+		if e.Op == ":" {
+			op, ok = "=", true
+		}
+		if !ok {
+			return "", filter.Errorf(e.Pos, "unsupported operator %q for priority", e.Op)
+		}
+		return "COALESCE(t.priority, 0) " + op + " " + c.param(n) + "::int", nil
+
+	case "status":
+		// 基本ステータスとプロジェクト独自のステータス名のどちらにも一致させる
+		p := c.param(e.Value)
+		return c.equality(e, "(t.status::text = upper("+p+") OR lower(ps.name) = lower("+p+"))")
+
+	case "tag":
+		p := c.param(e.Value)
+		return c.equality(e, "EXISTS (SELECT 1 FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE tt.task_id = t.id AND lower(g.name) = lower("+p+"))")
+
+	case "project":
+		if e.Op == "~" {
+			return "p.title ILIKE " + c.param(containsPattern(e.Value)), nil
+		}
+		return c.equality(e, "lower(p.title) = lower("+c.param(e.Value)+")")
+
+	case "title":
+		switch e.Op {
+		case ":", "~":
+			return "t.title ILIKE " + c.param(containsPattern(e.Value)), nil
+		case "=":
+			return "t.title = " + c.param(e.Value), nil
+		}
+
+	case "text":
+		if e.Op == ":" || e.Op == "~" {
+			p := c.param(containsPattern(e.Value))
+			return "(t.title ILIKE " + p + " OR t.note_markdown ILIKE " + p + ")", nil
+		}
+
+	case "has":
+		if e.Op != ":" {
+			break
+		}
+		switch strings.ToLower(e.Value) {
+		case "due":
+			return "t.due_date IS NOT NULL", nil
+		case "note":
+			return "COALESCE(t.note_markdown, '') <> ''", nil
+		case "tag", "tags":
+			return "EXISTS (SELECT 1 FROM task_tags tt WHERE tt.task_id = t.id)", nil
+		case "checklist":
+			return "EXISTS (SELECT 1 FROM checklist_items c WHERE c.task_id = t.id)", nil
+		}
+		return "", filter.Errorf(e.Pos, "unknown value %q for has", e.Value)
+
+	default:
+		return "", filter.Errorf(e.Pos, "unknown field %q", e.Field)
+	}
+	return "", filter.Errorf(e.Pos, "unsupported operator %q for %s", e.Op, e.Field)
+}
+
+// equality は ":" と "=" を一致、"!=" を不一致として条件を組み立てる
+func (c *taskFilterCompiler) equality(e filter.Cond, match string) (string, error) {
+	switch e.Op {
+	case ":", "=":
+		return match, nil
+	case "!=":
+		return "(" + match + ") IS NOT TRUE", nil
+	}
+	return "", filter.Errorf(e.Pos, "unsupported operator %q for %s", e.Op, e.Field)
+}
+
+func (c *taskFilterCompiler) timeCond(e filter.Cond, col string) (string, error) {
+	t, err := filter.ResolveTime(e.Value, c.now)
+	if err != nil {
+		return "", filter.Errorf(e.Pos, "invalid time value %q", e.Value)
+	}
+	switch e.Op {
+	case ":", "=", "!=":
+		// 日付単位での一致
+		start := filter.DayOf(t)
+		match := "(" + col + " >= " + c.param(start) + " AND " + col + " < " + c.param(start.AddDate(0, 0, 1)) + ")"
+		if e.Op == "!=" {
+			return "(" + match + ") IS NOT TRUE", nil
+		}
+		return match, nil
+	case "<", "<=", ">", ">=":
+		return col + " " + e.Op + " " + c.param(t), nil
+	}
+	return "", filter.Errorf(e.Pos, "unsupported operator %q for %s", e.Op, e.Field)
+}
+
+// keyword は値を持たない項 (overdue など)
+func (c *taskFilterCompiler) keyword(e filter.Cond) (string, error) {
+	switch e.Field {
+	case "done":
+		return "t.status = 'DONE'", nil
+	case "open":
+		return "t.status <> 'DONE'", nil
+	case "overdue":
+		return "(t.status <> 'DONE' AND t.due_date < " + c.param(c.now) + ")", nil
+	case "today":
+		start := filter.DayOf(c.now)
+		return "(t.due_date >= " + c.param(start) + " AND t.due_date < " + c.param(start.AddDate(0, 0, 1)) + ")", nil
+	case "blocked":
+		return "", filter.Errorf(e.Pos, "keyword \"blocked\" is not supported (tasks have no dependencies)")
+	}
+	return "", filter.Errorf(e.Pos, "unknown keyword %q", e.Field)
+}
+
+func containsPattern(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(s) + "%"
+}
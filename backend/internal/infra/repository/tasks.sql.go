@@ -12,22 +12,43 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countTasksInLane = `-- name: CountTasksInLane :one
+SELECT COUNT(*) FROM tasks
+WHERE project_id = $1 AND status = $2 AND id != $3 AND deleted_at IS NULL
+`
+
+type CountTasksInLaneParams struct {
+	ProjectID uuid.UUID  `json:"project_id"`
+	Status    TaskStatus `json:"status"`
+	ID        uuid.UUID  `json:"id"`
+}
+
+// 指定タスク自身を除いたレーン内の件数
+func (q *Queries) CountTasksInLane(ctx context.Context, arg CountTasksInLaneParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countTasksInLane, arg.ProjectID, arg.Status, arg.ID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChecklistItem = `-- name: CreateChecklistItem :one
 INSERT INTO checklist_items (
-    task_id, content, position
+    task_id, content, position, estimated_minutes
 ) VALUES (
     $1, $2,
-    (SELECT COALESCE(MAX(position), 0) + 1 FROM checklist_items WHERE task_id = $1)
-) RETURNING id, task_id, content, is_completed, position
+    (SELECT COALESCE(MAX(position), 0) + 1 FROM checklist_items WHERE task_id = $1),
+    $3
+) RETURNING id, task_id, content, is_completed, position, estimated_minutes
 `
 
 type CreateChecklistItemParams struct {
-	TaskID  uuid.UUID `json:"task_id"`
-	Content string    `json:"content"`
+	TaskID           uuid.UUID   `json:"task_id"`
+	Content          string      `json:"content"`
+	EstimatedMinutes pgtype.Int4 `json:"estimated_minutes"`
 }
 
 func (q *Queries) CreateChecklistItem(ctx context.Context, arg CreateChecklistItemParams) (ChecklistItem, error) {
-	row := q.db.QueryRow(ctx, createChecklistItem, arg.TaskID, arg.Content)
+	row := q.db.QueryRow(ctx, createChecklistItem, arg.TaskID, arg.Content, arg.EstimatedMinutes)
 	var i ChecklistItem
 	err := row.Scan(
 		&i.ID,
@@ -35,6 +56,7 @@ func (q *Queries) CreateChecklistItem(ctx context.Context, arg CreateChecklistIt
 		&i.Content,
 		&i.IsCompleted,
 		&i.Position,
+		&i.EstimatedMinutes,
 	)
 	return i, err
 }
@@ -42,10 +64,10 @@ func (q *Queries) CreateChecklistItem(ctx context.Context, arg CreateChecklistIt
 const createTask = `-- name: CreateTask :one
 INSERT INTO tasks (
     user_id, project_id, title, note_markdown, due_date, priority,
-    calendar_id, ical_uid, status
+    calendar_id, ical_uid, status, position
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9
-) RETURNING id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at
 `
 
 type CreateTaskParams struct {
@@ -58,6 +80,7 @@ type CreateTaskParams struct {
 	CalendarID   pgtype.UUID        `json:"calendar_id"`
 	IcalUid      pgtype.Text        `json:"ical_uid"`
 	Status       TaskStatus         `json:"status"`
+	Position     string             `json:"position"`
 }
 
 func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error) {
@@ -71,6 +94,7 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		arg.CalendarID,
 		arg.IcalUid,
 		arg.Status,
+		arg.Position,
 	)
 	var i Task
 	err := row.Scan(
@@ -89,6 +113,10 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		&i.Etag,
 		&i.Sequence,
 		&i.CompletedAt,
+		&i.Position,
+		&i.CustomStatusID,
+		&i.EstimatedMinutes,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -103,39 +131,115 @@ func (q *Queries) DeleteChecklistItem(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const deleteTask = `-- name: DeleteTask :exec
-DELETE FROM tasks
-WHERE id = $1 AND user_id = $2
+const getEstimateReport = `-- name: GetEstimateReport :many
+WITH task_estimates AS (
+    SELECT
+        t.project_id, t.completed_at,
+        COALESCE(t.estimated_minutes, (SELECT SUM(ci.estimated_minutes) FROM checklist_items ci WHERE ci.task_id = t.id)) AS estimated,
+        (SELECT COALESCE(SUM(EXTRACT(EPOCH FROM (te.ended_at - te.started_at))), 0) / 60
+            FROM time_entries te WHERE te.task_id = t.id AND te.ended_at IS NOT NULL) AS actual
+    FROM tasks t
+    WHERE t.user_id = $1
+      AND t.deleted_at IS NULL
+      AND t.status = 'DONE'
+      AND t.completed_at >= $2
+      AND t.completed_at < $3
+)
+SELECT
+    date_trunc($4::text, e.completed_at, $5::text)::timestamptz as period,
+    p.id as project_id, p.title as project_title,
+    c.id as category_id, c.name as category_name,
+    COUNT(*) as task_count,
+    COUNT(*) FILTER (WHERE e.estimated IS NOT NULL) as estimated_count,
+    COUNT(*) FILTER (WHERE e.actual > e.estimated) as underestimated_count,
+    COALESCE(SUM(e.estimated), 0)::bigint as estimated_minutes,
+    COALESCE(SUM(e.actual) FILTER (WHERE e.estimated IS NOT NULL), 0)::float8 as estimated_actual_minutes,
+    COALESCE(SUM(e.actual), 0)::float8 as actual_minutes
+FROM task_estimates e
+JOIN projects p ON e.project_id = p.id
+JOIN categories c ON p.category_id = c.id
+GROUP BY 1, p.id, c.id
+ORDER BY period, p.title
 `
 
-type DeleteTaskParams struct {
-	ID     uuid.UUID `json:"id"`
-	UserID uuid.UUID `json:"user_id"`
+type GetEstimateReportParams struct {
+	UserID   uuid.UUID          `json:"user_id"`
+	FromDate pgtype.Timestamptz `json:"from_date"`
+	ToDate   pgtype.Timestamptz `json:"to_date"`
+	Interval string             `json:"interval"`
+	Timezone string             `json:"timezone"`
 }
 
-func (q *Queries) DeleteTask(ctx context.Context, arg DeleteTaskParams) error {
-	_, err := q.db.Exec(ctx, deleteTask, arg.ID, arg.UserID)
-	return err
+type GetEstimateReportRow struct {
+	Period                 pgtype.Timestamptz `json:"period"`
+	ProjectID              uuid.UUID          `json:"project_id"`
+	ProjectTitle           string             `json:"project_title"`
+	CategoryID             uuid.UUID          `json:"category_id"`
+	CategoryName           string             `json:"category_name"`
+	TaskCount              int64              `json:"task_count"`
+	EstimatedCount         int64              `json:"estimated_count"`
+	UnderestimatedCount    int64              `json:"underestimated_count"`
+	EstimatedMinutes       int64              `json:"estimated_minutes"`
+	EstimatedActualMinutes float64            `json:"estimated_actual_minutes"`
+	ActualMinutes          float64            `json:"actual_minutes"`
 }
 
-const deleteTaskByICalUID = `-- name: DeleteTaskByICalUID :exec
-DELETE FROM tasks
-WHERE user_id = $1 AND ical_uid = $2
-`
-
-type DeleteTaskByICalUIDParams struct {
-	UserID  uuid.UUID   `json:"user_id"`
-	IcalUid pgtype.Text `json:"ical_uid"`
+// 期間内に完了したタスクの見積もりと実績(分)を期間・プロジェクトごとに集計 (期間の区切りは timezone で数える)
+// 見積もりはタスク自身の値、なければチェックリストの見積もりの合計
+func (q *Queries) GetEstimateReport(ctx context.Context, arg GetEstimateReportParams) ([]GetEstimateReportRow, error) {
+	rows, err := q.db.Query(ctx, getEstimateReport,
+		arg.UserID,
+		arg.FromDate,
+		arg.ToDate,
+		arg.Interval,
+		arg.Timezone,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEstimateReportRow
+	for rows.Next() {
+		var i GetEstimateReportRow
+		if err := rows.Scan(
+			&i.Period,
+			&i.ProjectID,
+			&i.ProjectTitle,
+			&i.CategoryID,
+			&i.CategoryName,
+			&i.TaskCount,
+			&i.EstimatedCount,
+			&i.UnderestimatedCount,
+			&i.EstimatedMinutes,
+			&i.EstimatedActualMinutes,
+			&i.ActualMinutes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) DeleteTaskByICalUID(ctx context.Context, arg DeleteTaskByICalUIDParams) error {
-	_, err := q.db.Exec(ctx, deleteTaskByICalUID, arg.UserID, arg.IcalUid)
-	return err
+const getLastTaskPosition = `-- name: GetLastTaskPosition :one
+SELECT COALESCE(MAX(position), '')::text FROM tasks
+WHERE project_id = $1
+`
+
+// プロジェクト内で最後尾の順位キー
+func (q *Queries) GetLastTaskPosition(ctx context.Context, projectID uuid.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, getLastTaskPosition, projectID)
+	var column1 string
+	err := row.Scan(&column1)
+	return column1, err
 }
 
 const getTask = `-- name: GetTask :one
-SELECT id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at FROM tasks
-WHERE id = $1 AND user_id = $2 LIMIT 1
+SELECT id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at FROM tasks
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL LIMIT 1
 `
 
 type GetTaskParams struct {
@@ -162,13 +266,17 @@ func (q *Queries) GetTask(ctx context.Context, arg GetTaskParams) (Task, error)
 		&i.Etag,
 		&i.Sequence,
 		&i.CompletedAt,
+		&i.Position,
+		&i.CustomStatusID,
+		&i.EstimatedMinutes,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getTaskByICalUID = `-- name: GetTaskByICalUID :one
-SELECT id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at FROM tasks
-WHERE user_id = $1 AND ical_uid = $2 LIMIT 1
+SELECT id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at FROM tasks
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL LIMIT 1
 `
 
 type GetTaskByICalUIDParams struct {
@@ -195,12 +303,81 @@ func (q *Queries) GetTaskByICalUID(ctx context.Context, arg GetTaskByICalUIDPara
 		&i.Etag,
 		&i.Sequence,
 		&i.CompletedAt,
+		&i.Position,
+		&i.CustomStatusID,
+		&i.EstimatedMinutes,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
+const listBoardTasks = `-- name: ListBoardTasks :many
+SELECT
+    t.id, t.project_id, t.title, t.status, t.custom_status_id, t.due_date, t.priority, t.position,
+    COUNT(ci.id) as total_items,
+    COUNT(ci.id) FILTER (WHERE ci.is_completed) as done_items,
+    ARRAY(SELECT tt.tag_id FROM task_tags tt WHERE tt.task_id = t.id)::uuid[] as tag_ids
+FROM tasks t
+LEFT JOIN checklist_items ci ON t.id = ci.task_id
+WHERE t.project_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+GROUP BY t.id
+ORDER BY t.position, t.created_at
+`
+
+type ListBoardTasksParams struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+type ListBoardTasksRow struct {
+	ID             uuid.UUID          `json:"id"`
+	ProjectID      uuid.UUID          `json:"project_id"`
+	Title          string             `json:"title"`
+	Status         TaskStatus         `json:"status"`
+	CustomStatusID pgtype.UUID        `json:"custom_status_id"`
+	DueDate        pgtype.Timestamptz `json:"due_date"`
+	Priority       pgtype.Int2        `json:"priority"`
+	Position       string             `json:"position"`
+	TotalItems     int64              `json:"total_items"`
+	DoneItems      int64              `json:"done_items"`
+	TagIds         []uuid.UUID        `json:"tag_ids"`
+}
+
+// カンバン表示用(レーン内は順位キー順)
+func (q *Queries) ListBoardTasks(ctx context.Context, arg ListBoardTasksParams) ([]ListBoardTasksRow, error) {
+	rows, err := q.db.Query(ctx, listBoardTasks, arg.ProjectID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBoardTasksRow
+	for rows.Next() {
+		var i ListBoardTasksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Status,
+			&i.CustomStatusID,
+			&i.DueDate,
+			&i.Priority,
+			&i.Position,
+			&i.TotalItems,
+			&i.DoneItems,
+			&i.TagIds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listChecklistItems = `-- name: ListChecklistItems :many
-SELECT id, task_id, content, is_completed, position FROM checklist_items
+SELECT id, task_id, content, is_completed, position, estimated_minutes FROM checklist_items
 WHERE task_id = $1
 ORDER BY position ASC
 `
@@ -220,6 +397,7 @@ func (q *Queries) ListChecklistItems(ctx context.Context, taskID uuid.UUID) ([]C
 			&i.Content,
 			&i.IsCompleted,
 			&i.Position,
+			&i.EstimatedMinutes,
 		); err != nil {
 			return nil, err
 		}
@@ -231,9 +409,102 @@ func (q *Queries) ListChecklistItems(ctx context.Context, taskID uuid.UUID) ([]C
 	return items, nil
 }
 
+const listMatrixTasks = `-- name: ListMatrixTasks :many
+SELECT
+    t.id, t.project_id, t.title, t.status, t.custom_status_id, t.due_date, t.priority, t.estimated_minutes,
+    p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color
+FROM tasks t
+JOIN projects p ON t.project_id = p.id
+WHERE t.user_id = $1 AND t.status != 'DONE' AND t.deleted_at IS NULL
+  AND p.is_archived = FALSE AND p.deleted_at IS NULL
+  AND ($2::uuid IS NULL OR t.project_id = $2)
+ORDER BY t.due_date ASC NULLS LAST, t.priority DESC NULLS LAST, t.created_at
+`
+
+type ListMatrixTasksParams struct {
+	UserID    uuid.UUID   `json:"user_id"`
+	ProjectID pgtype.UUID `json:"project_id"`
+}
+
+type ListMatrixTasksRow struct {
+	ID               uuid.UUID          `json:"id"`
+	ProjectID        uuid.UUID          `json:"project_id"`
+	Title            string             `json:"title"`
+	Status           TaskStatus         `json:"status"`
+	CustomStatusID   pgtype.UUID        `json:"custom_status_id"`
+	DueDate          pgtype.Timestamptz `json:"due_date"`
+	Priority         pgtype.Int2        `json:"priority"`
+	EstimatedMinutes pgtype.Int4        `json:"estimated_minutes"`
+	ProjectTitle     string             `json:"project_title"`
+	ProjectColor     string             `json:"project_color"`
+}
+
+// 優先度マトリクス用の未完了タスク(アーカイブ済みプロジェクトを除く)。期限の近い順
+func (q *Queries) ListMatrixTasks(ctx context.Context, arg ListMatrixTasksParams) ([]ListMatrixTasksRow, error) {
+	rows, err := q.db.Query(ctx, listMatrixTasks, arg.UserID, arg.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMatrixTasksRow
+	for rows.Next() {
+		var i ListMatrixTasksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Status,
+			&i.CustomStatusID,
+			&i.DueDate,
+			&i.Priority,
+			&i.EstimatedMinutes,
+			&i.ProjectTitle,
+			&i.ProjectColor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTaskPositions = `-- name: ListTaskPositions :many
+SELECT id, position FROM tasks
+WHERE project_id = $1
+ORDER BY position, created_at
+`
+
+type ListTaskPositionsRow struct {
+	ID       uuid.UUID `json:"id"`
+	Position string    `json:"position"`
+}
+
+func (q *Queries) ListTaskPositions(ctx context.Context, projectID uuid.UUID) ([]ListTaskPositionsRow, error) {
+	rows, err := q.db.Query(ctx, listTaskPositions, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTaskPositionsRow
+	for rows.Next() {
+		var i ListTaskPositionsRow
+		if err := rows.Scan(&i.ID, &i.Position); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTasksByCalendar = `-- name: ListTasksByCalendar :many
-SELECT id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at FROM tasks
-WHERE user_id = $1 AND calendar_id = $2
+SELECT id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at FROM tasks
+WHERE user_id = $1 AND calendar_id = $2 AND deleted_at IS NULL
 ORDER BY created_at DESC
 `
 
@@ -267,6 +538,10 @@ func (q *Queries) ListTasksByCalendar(ctx context.Context, arg ListTasksByCalend
 			&i.Etag,
 			&i.Sequence,
 			&i.CompletedAt,
+			&i.Position,
+			&i.CustomStatusID,
+			&i.EstimatedMinutes,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -279,9 +554,10 @@ func (q *Queries) ListTasksByCalendar(ctx context.Context, arg ListTasksByCalend
 }
 
 const listTasksByCalendarAndRange = `-- name: ListTasksByCalendarAndRange :many
-SELECT id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at FROM tasks
+SELECT id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at FROM tasks
 WHERE user_id = $1
   AND calendar_id = $2
+  AND deleted_at IS NULL
   AND (due_date IS NULL OR (due_date >= $3 AND due_date <= $4))
 ORDER BY created_at DESC
 `
@@ -323,6 +599,61 @@ func (q *Queries) ListTasksByCalendarAndRange(ctx context.Context, arg ListTasks
 			&i.Etag,
 			&i.Sequence,
 			&i.CompletedAt,
+			&i.Position,
+			&i.CustomStatusID,
+			&i.EstimatedMinutes,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTasksByProject = `-- name: ListTasksByProject :many
+SELECT id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at FROM tasks
+WHERE project_id = $1 AND user_id = $2 AND deleted_at IS NULL
+ORDER BY position, created_at
+`
+
+type ListTasksByProjectParams struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) ListTasksByProject(ctx context.Context, arg ListTasksByProjectParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, listTasksByProject, arg.ProjectID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ProjectID,
+			&i.Title,
+			&i.NoteMarkdown,
+			&i.Status,
+			&i.DueDate,
+			&i.Priority,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CalendarID,
+			&i.IcalUid,
+			&i.Etag,
+			&i.Sequence,
+			&i.CompletedAt,
+			&i.Position,
+			&i.CustomStatusID,
+			&i.EstimatedMinutes,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -339,17 +670,29 @@ SELECT
     t.id, t.project_id, t.title, t.status, t.due_date, t.priority,
     p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color,
     COUNT(ci.id) as total_items,
-    COUNT(ci.id) FILTER (WHERE ci.is_completed) as done_items
+    COUNT(ci.id) FILTER (WHERE ci.is_completed) as done_items,
+    ARRAY(SELECT tt.tag_id FROM task_tags tt WHERE tt.task_id = t.id)::uuid[] as tag_ids,
+    t.custom_status_id, COALESCE(ps.name, t.status::text)::varchar as status_name,
+    t.estimated_minutes,
+    COALESCE(SUM(ci.estimated_minutes), 0)::int as checklist_estimated_minutes,
+    (SELECT COALESCE(SUM(EXTRACT(EPOCH FROM (COALESCE(te.ended_at, NOW()) - te.started_at))), 0) / 60
+        FROM time_entries te WHERE te.task_id = t.id)::int as actual_minutes
 FROM tasks t
 JOIN projects p ON t.project_id = p.id
+LEFT JOIN project_statuses ps ON t.custom_status_id = ps.id
 LEFT JOIN checklist_items ci ON t.id = ci.task_id
 WHERE
     t.user_id = $1
+    AND t.deleted_at IS NULL
     AND ($2::uuid IS NULL OR t.project_id = $2)
     AND ($3::task_status IS NULL OR t.status = $3)
     AND ($4::timestamptz IS NULL OR t.due_date >= $4)
     AND ($5::timestamptz IS NULL OR t.due_date <= $5)
-GROUP BY t.id, p.id
+    AND ($6::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM task_tags tt WHERE tt.task_id = t.id AND tt.tag_id = $6
+    ))
+    AND TRUE /* task_filter */
+GROUP BY t.id, p.id, ps.id
 ORDER BY
     CASE WHEN t.status = 'DONE' THEN 1 ELSE 0 END,
     t.due_date ASC NULLS LAST,
@@ -362,19 +705,26 @@ type ListTasksWithStatsParams struct {
 	Status    NullTaskStatus     `json:"status"`
 	FromDate  pgtype.Timestamptz `json:"from_date"`
 	ToDate    pgtype.Timestamptz `json:"to_date"`
+	TagID     pgtype.UUID        `json:"tag_id"`
 }
 
 type ListTasksWithStatsRow struct {
-	ID           uuid.UUID          `json:"id"`
-	ProjectID    uuid.UUID          `json:"project_id"`
-	Title        string             `json:"title"`
-	Status       TaskStatus         `json:"status"`
-	DueDate      pgtype.Timestamptz `json:"due_date"`
-	Priority     pgtype.Int2        `json:"priority"`
-	ProjectTitle string             `json:"project_title"`
-	ProjectColor string             `json:"project_color"`
-	TotalItems   int64              `json:"total_items"`
-	DoneItems    int64              `json:"done_items"`
+	ID                        uuid.UUID          `json:"id"`
+	ProjectID                 uuid.UUID          `json:"project_id"`
+	Title                     string             `json:"title"`
+	Status                    TaskStatus         `json:"status"`
+	DueDate                   pgtype.Timestamptz `json:"due_date"`
+	Priority                  pgtype.Int2        `json:"priority"`
+	ProjectTitle              string             `json:"project_title"`
+	ProjectColor              string             `json:"project_color"`
+	TotalItems                int64              `json:"total_items"`
+	DoneItems                 int64              `json:"done_items"`
+	TagIds                    []uuid.UUID        `json:"tag_ids"`
+	CustomStatusID            pgtype.UUID        `json:"custom_status_id"`
+	StatusName                string             `json:"status_name"`
+	EstimatedMinutes          pgtype.Int4        `json:"estimated_minutes"`
+	ChecklistEstimatedMinutes int32              `json:"checklist_estimated_minutes"`
+	ActualMinutes             int32              `json:"actual_minutes"`
 }
 
 // タスクと同時に、チェックリストの進捗を取得
@@ -385,6 +735,7 @@ func (q *Queries) ListTasksWithStats(ctx context.Context, arg ListTasksWithStats
 		arg.Status,
 		arg.FromDate,
 		arg.ToDate,
+		arg.TagID,
 	)
 	if err != nil {
 		return nil, err
@@ -404,6 +755,12 @@ func (q *Queries) ListTasksWithStats(ctx context.Context, arg ListTasksWithStats
 			&i.ProjectColor,
 			&i.TotalItems,
 			&i.DoneItems,
+			&i.TagIds,
+			&i.CustomStatusID,
+			&i.StatusName,
+			&i.EstimatedMinutes,
+			&i.ChecklistEstimatedMinutes,
+			&i.ActualMinutes,
 		); err != nil {
 			return nil, err
 		}
@@ -415,21 +772,259 @@ func (q *Queries) ListTasksWithStats(ctx context.Context, arg ListTasksWithStats
 	return items, nil
 }
 
+const moveTask = `-- name: MoveTask :one
+UPDATE tasks
+SET
+    status = $3::task_status,
+    custom_status_id = $4,
+    position = $5,
+    completed_at = CASE WHEN $3::task_status = 'DONE' THEN COALESCE(completed_at, NOW()) ELSE NULL END,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at
+`
+
+type MoveTaskParams struct {
+	ID             uuid.UUID   `json:"id"`
+	UserID         uuid.UUID   `json:"user_id"`
+	Status         TaskStatus  `json:"status"`
+	CustomStatusID pgtype.UUID `json:"custom_status_id"`
+	Position       string      `json:"position"`
+}
+
+func (q *Queries) MoveTask(ctx context.Context, arg MoveTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, moveTask,
+		arg.ID,
+		arg.UserID,
+		arg.Status,
+		arg.CustomStatusID,
+		arg.Position,
+	)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.Title,
+		&i.NoteMarkdown,
+		&i.Status,
+		&i.DueDate,
+		&i.Priority,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CalendarID,
+		&i.IcalUid,
+		&i.Etag,
+		&i.Sequence,
+		&i.CompletedAt,
+		&i.Position,
+		&i.CustomStatusID,
+		&i.EstimatedMinutes,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const revertTask = `-- name: RevertTask :one
+UPDATE tasks
+SET
+    project_id = $1,
+    title = $2,
+    note_markdown = $3,
+    status = $4,
+    custom_status_id = (SELECT ps.id FROM project_statuses ps WHERE ps.id = $5 AND ps.project_id = $1),
+    completed_at = $6,
+    due_date = $7,
+    priority = $8,
+    position = $9,
+    estimated_minutes = $10,
+    updated_at = NOW()
+WHERE id = $11 AND user_id = $12 AND deleted_at IS NULL
+RETURNING id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at
+`
+
+type RevertTaskParams struct {
+	ProjectID        uuid.UUID          `json:"project_id"`
+	Title            string             `json:"title"`
+	NoteMarkdown     pgtype.Text        `json:"note_markdown"`
+	Status           TaskStatus         `json:"status"`
+	CustomStatusID   pgtype.UUID        `json:"custom_status_id"`
+	CompletedAt      pgtype.Timestamptz `json:"completed_at"`
+	DueDate          pgtype.Timestamptz `json:"due_date"`
+	Priority         pgtype.Int2        `json:"priority"`
+	Position         string             `json:"position"`
+	EstimatedMinutes pgtype.Int4        `json:"estimated_minutes"`
+	ID               uuid.UUID          `json:"id"`
+	UserID           uuid.UUID          `json:"user_id"`
+}
+
+// 取り消しで記録しておいた内容に戻す。独自ステータスが消えていれば外す
+func (q *Queries) RevertTask(ctx context.Context, arg RevertTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, revertTask,
+		arg.ProjectID,
+		arg.Title,
+		arg.NoteMarkdown,
+		arg.Status,
+		arg.CustomStatusID,
+		arg.CompletedAt,
+		arg.DueDate,
+		arg.Priority,
+		arg.Position,
+		arg.EstimatedMinutes,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.Title,
+		&i.NoteMarkdown,
+		&i.Status,
+		&i.DueDate,
+		&i.Priority,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CalendarID,
+		&i.IcalUid,
+		&i.Etag,
+		&i.Sequence,
+		&i.CompletedAt,
+		&i.Position,
+		&i.CustomStatusID,
+		&i.EstimatedMinutes,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const setTaskPosition = `-- name: SetTaskPosition :exec
+UPDATE tasks
+SET position = $2
+WHERE id = $1
+`
+
+type SetTaskPositionParams struct {
+	ID       uuid.UUID `json:"id"`
+	Position string    `json:"position"`
+}
+
+func (q *Queries) SetTaskPosition(ctx context.Context, arg SetTaskPositionParams) error {
+	_, err := q.db.Exec(ctx, setTaskPosition, arg.ID, arg.Position)
+	return err
+}
+
+const setTaskProject = `-- name: SetTaskProject :one
+UPDATE tasks
+SET
+    project_id = $1,
+    position = $2,
+    custom_status_id = NULL,
+    updated_at = NOW()
+WHERE id = $3 AND user_id = $4 AND deleted_at IS NULL
+RETURNING id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at
+`
+
+type SetTaskProjectParams struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	Position  string    `json:"position"`
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+// 独自ステータスはプロジェクトごとのものなので外す
+func (q *Queries) SetTaskProject(ctx context.Context, arg SetTaskProjectParams) (Task, error) {
+	row := q.db.QueryRow(ctx, setTaskProject,
+		arg.ProjectID,
+		arg.Position,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.Title,
+		&i.NoteMarkdown,
+		&i.Status,
+		&i.DueDate,
+		&i.Priority,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CalendarID,
+		&i.IcalUid,
+		&i.Etag,
+		&i.Sequence,
+		&i.CompletedAt,
+		&i.Position,
+		&i.CustomStatusID,
+		&i.EstimatedMinutes,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const trashTask = `-- name: TrashTask :execrows
+UPDATE tasks
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type TrashTaskParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) TrashTask(ctx context.Context, arg TrashTaskParams) (int64, error) {
+	result, err := q.db.Exec(ctx, trashTask, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const trashTaskByICalUID = `-- name: TrashTaskByICalUID :execrows
+UPDATE tasks
+SET deleted_at = NOW()
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL
+`
+
+type TrashTaskByICalUIDParams struct {
+	UserID  uuid.UUID   `json:"user_id"`
+	IcalUid pgtype.Text `json:"ical_uid"`
+}
+
+func (q *Queries) TrashTaskByICalUID(ctx context.Context, arg TrashTaskByICalUIDParams) (int64, error) {
+	result, err := q.db.Exec(ctx, trashTaskByICalUID, arg.UserID, arg.IcalUid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateChecklistItem = `-- name: UpdateChecklistItem :one
 UPDATE checklist_items
 SET 
     content = COALESCE($2, content),
     is_completed = COALESCE($3, is_completed),
-    position = COALESCE($4, position)
+    position = COALESCE($4, position),
+    estimated_minutes = CASE
+        WHEN $5::int IS NULL THEN estimated_minutes
+        WHEN $5 < 0 THEN NULL
+        ELSE $5
+    END
 WHERE id = $1
-RETURNING id, task_id, content, is_completed, position
+RETURNING id, task_id, content, is_completed, position, estimated_minutes
 `
 
 type UpdateChecklistItemParams struct {
-	ID          uuid.UUID   `json:"id"`
-	Content     pgtype.Text `json:"content"`
-	IsCompleted pgtype.Bool `json:"is_completed"`
-	Position    pgtype.Int4 `json:"position"`
+	ID               uuid.UUID   `json:"id"`
+	Content          pgtype.Text `json:"content"`
+	IsCompleted      pgtype.Bool `json:"is_completed"`
+	Position         pgtype.Int4 `json:"position"`
+	EstimatedMinutes pgtype.Int4 `json:"estimated_minutes"`
 }
 
 func (q *Queries) UpdateChecklistItem(ctx context.Context, arg UpdateChecklistItemParams) (ChecklistItem, error) {
@@ -438,6 +1033,7 @@ func (q *Queries) UpdateChecklistItem(ctx context.Context, arg UpdateChecklistIt
 		arg.Content,
 		arg.IsCompleted,
 		arg.Position,
+		arg.EstimatedMinutes,
 	)
 	var i ChecklistItem
 	err := row.Scan(
@@ -446,6 +1042,7 @@ func (q *Queries) UpdateChecklistItem(ctx context.Context, arg UpdateChecklistIt
 		&i.Content,
 		&i.IsCompleted,
 		&i.Position,
+		&i.EstimatedMinutes,
 	)
 	return i, err
 }
@@ -459,20 +1056,32 @@ SET
     completed_at = COALESCE($6, completed_at),
     due_date = COALESCE($7, due_date),
     priority = COALESCE($8, priority),
+    custom_status_id = CASE
+        WHEN $9::uuid IS NOT NULL THEN $9
+        WHEN $5::task_status IS NULL THEN custom_status_id
+        ELSE (SELECT ps.id FROM project_statuses ps WHERE ps.id = tasks.custom_status_id AND ps.category = $5)
+    END,
+    estimated_minutes = CASE
+        WHEN $10::int IS NULL THEN estimated_minutes
+        WHEN $10 < 0 THEN NULL
+        ELSE $10
+    END,
     updated_at = NOW()
-WHERE id = $1 AND user_id = $2
-RETURNING id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at
 `
 
 type UpdateTaskParams struct {
-	ID           uuid.UUID          `json:"id"`
-	UserID       uuid.UUID          `json:"user_id"`
-	Title        pgtype.Text        `json:"title"`
-	NoteMarkdown pgtype.Text        `json:"note_markdown"`
-	Status       NullTaskStatus     `json:"status"`
-	CompletedAt  pgtype.Timestamptz `json:"completed_at"`
-	DueDate      pgtype.Timestamptz `json:"due_date"`
-	Priority     pgtype.Int2        `json:"priority"`
+	ID               uuid.UUID          `json:"id"`
+	UserID           uuid.UUID          `json:"user_id"`
+	Title            pgtype.Text        `json:"title"`
+	NoteMarkdown     pgtype.Text        `json:"note_markdown"`
+	Status           NullTaskStatus     `json:"status"`
+	CompletedAt      pgtype.Timestamptz `json:"completed_at"`
+	DueDate          pgtype.Timestamptz `json:"due_date"`
+	Priority         pgtype.Int2        `json:"priority"`
+	CustomStatusID   pgtype.UUID        `json:"custom_status_id"`
+	EstimatedMinutes pgtype.Int4        `json:"estimated_minutes"`
 }
 
 func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error) {
@@ -485,6 +1094,8 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, e
 		arg.CompletedAt,
 		arg.DueDate,
 		arg.Priority,
+		arg.CustomStatusID,
+		arg.EstimatedMinutes,
 	)
 	var i Task
 	err := row.Scan(
@@ -503,6 +1114,10 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, e
 		&i.Etag,
 		&i.Sequence,
 		&i.CompletedAt,
+		&i.Position,
+		&i.CustomStatusID,
+		&i.EstimatedMinutes,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -518,9 +1133,13 @@ SET
     etag = COALESCE($8, etag),
     sequence = COALESCE($9, sequence),
     completed_at = COALESCE($10, completed_at),
+    custom_status_id = CASE
+        WHEN $5::task_status IS NULL THEN custom_status_id
+        ELSE (SELECT ps.id FROM project_statuses ps WHERE ps.id = tasks.custom_status_id AND ps.category = $5)
+    END,
     updated_at = NOW()
-WHERE user_id = $1 AND ical_uid = $2
-RETURNING id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at
+WHERE user_id = $1 AND ical_uid = $2 AND deleted_at IS NULL
+RETURNING id, user_id, project_id, title, note_markdown, status, due_date, priority, created_at, updated_at, calendar_id, ical_uid, etag, sequence, completed_at, position, custom_status_id, estimated_minutes, deleted_at
 `
 
 type UpdateTaskByICalUIDParams struct {
@@ -566,6 +1185,10 @@ func (q *Queries) UpdateTaskByICalUID(ctx context.Context, arg UpdateTaskByICalU
 		&i.Etag,
 		&i.Sequence,
 		&i.CompletedAt,
+		&i.Position,
+		&i.CustomStatusID,
+		&i.EstimatedMinutes,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -12,23 +12,43 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const copyTimeEntryTags = `-- name: CopyTimeEntryTags :exec
+INSERT INTO time_entry_tags (time_entry_id, tag_id)
+SELECT $1, tag_id FROM time_entry_tags
+WHERE time_entry_id = $2
+ON CONFLICT DO NOTHING
+`
+
+type CopyTimeEntryTagsParams struct {
+	DstID uuid.UUID `json:"dst_id"`
+	SrcID uuid.UUID `json:"src_id"`
+}
+
+// 分割・結合したエントリにタグを引き継ぐ
+func (q *Queries) CopyTimeEntryTags(ctx context.Context, arg CopyTimeEntryTagsParams) error {
+	_, err := q.db.Exec(ctx, copyTimeEntryTags, arg.DstID, arg.SrcID)
+	return err
+}
+
 const createTimeEntry = `-- name: CreateTimeEntry :one
 INSERT INTO time_entries (
-  user_id, project_id, task_id, started_at,ended_at, note
+  user_id, project_id, task_id, started_at,ended_at, note, is_billable
 ) VALUES (
-    $1, $2, $3, $4, $5 ,$6
-) RETURNING id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at
+    $1, $2, $3, $4, $5 ,$6, COALESCE($7::boolean, TRUE)
+) RETURNING id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at, is_billable
 `
 
 type CreateTimeEntryParams struct {
-	UserID    uuid.UUID          `json:"user_id"`
-	ProjectID uuid.UUID          `json:"project_id"`
-	TaskID    pgtype.UUID        `json:"task_id"`
-	StartedAt pgtype.Timestamptz `json:"started_at"`
-	EndedAt   pgtype.Timestamptz `json:"ended_at"`
-	Note      pgtype.Text        `json:"note"`
+	UserID     uuid.UUID          `json:"user_id"`
+	ProjectID  uuid.UUID          `json:"project_id"`
+	TaskID     pgtype.UUID        `json:"task_id"`
+	StartedAt  pgtype.Timestamptz `json:"started_at"`
+	EndedAt    pgtype.Timestamptz `json:"ended_at"`
+	Note       pgtype.Text        `json:"note"`
+	IsBillable pgtype.Bool        `json:"is_billable"`
 }
 
+// is_billable を省略すると請求の対象にする
 func (q *Queries) CreateTimeEntry(ctx context.Context, arg CreateTimeEntryParams) (TimeEntry, error) {
 	row := q.db.QueryRow(ctx, createTimeEntry,
 		arg.UserID,
@@ -37,6 +57,7 @@ func (q *Queries) CreateTimeEntry(ctx context.Context, arg CreateTimeEntryParams
 		arg.StartedAt,
 		arg.EndedAt,
 		arg.Note,
+		arg.IsBillable,
 	)
 	var i TimeEntry
 	err := row.Scan(
@@ -50,30 +71,113 @@ func (q *Queries) CreateTimeEntry(ctx context.Context, arg CreateTimeEntryParams
 		&i.IsAutoGenerated,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsBillable,
 	)
 	return i, err
 }
 
+const createTimerAutoStop = `-- name: CreateTimerAutoStop :exec
+INSERT INTO timer_auto_stops (
+    time_entry_id, user_id, reason, ended_at
+) VALUES (
+    $1, $2, $3, $4
+)
+`
+
+type CreateTimerAutoStopParams struct {
+	TimeEntryID uuid.UUID          `json:"time_entry_id"`
+	UserID      uuid.UUID          `json:"user_id"`
+	Reason      string             `json:"reason"`
+	EndedAt     pgtype.Timestamptz `json:"ended_at"`
+}
+
+func (q *Queries) CreateTimerAutoStop(ctx context.Context, arg CreateTimerAutoStopParams) error {
+	_, err := q.db.Exec(ctx, createTimerAutoStop,
+		arg.TimeEntryID,
+		arg.UserID,
+		arg.Reason,
+		arg.EndedAt,
+	)
+	return err
+}
+
+const deleteTimeEntry = `-- name: DeleteTimeEntry :execrows
+DELETE FROM time_entries
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteTimeEntryParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteTimeEntry(ctx context.Context, arg DeleteTimeEntryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTimeEntry, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const finishTimeEntry = `-- name: FinishTimeEntry :execrows
+UPDATE time_entries
+SET ended_at = $3, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND ended_at IS NULL AND started_at < $3
+`
+
+type FinishTimeEntryParams struct {
+	ID      uuid.UUID          `json:"id"`
+	UserID  uuid.UUID          `json:"user_id"`
+	EndedAt pgtype.Timestamptz `json:"ended_at"`
+}
+
+// 計測中なら ended_at で止める。手動で止められていれば何もしない
+func (q *Queries) FinishTimeEntry(ctx context.Context, arg FinishTimeEntryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, finishTimeEntry, arg.ID, arg.UserID, arg.EndedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getGrowthStats = `-- name: GetGrowthStats :many
+WITH entries AS (
+    SELECT te.started_at, COALESCE(te.ended_at, NOW()) AS ended_at
+    FROM time_entries te
+    JOIN projects p ON te.project_id = p.id
+    JOIN categories c ON p.category_id = c.id
+    WHERE
+        te.user_id = $1
+        AND c.root_type = 'GROWTH'
+        AND te.started_at < (($2::date + 1)::timestamp AT TIME ZONE $3::text)
+        AND COALESCE(te.ended_at, NOW()) > ($4::date::timestamp AT TIME ZONE $3::text)
+)
 SELECT
-    DATE(te.started_at)::text as date,
-    SUM(EXTRACT(EPOCH FROM (COALESCE(te.ended_at, NOW()) - te.started_at)))::bigint as total_seconds
-FROM time_entries te
-JOIN projects p ON te.project_id = p.id
-JOIN categories c ON p.category_id = c.id
-WHERE
-    te.user_id = $1
-    AND c.root_type = 'GROWTH'
-    AND te.started_at >= $2
-    AND te.started_at <= $3
-GROUP BY DATE(te.started_at)
+    d::date::text as date,
+    SUM(EXTRACT(EPOCH FROM (
+        LEAST(e.ended_at, (d + interval '1 day') AT TIME ZONE $3::text)
+        - GREATEST(e.started_at, d AT TIME ZONE $3::text)
+    )))::bigint as total_seconds
+FROM entries e
+CROSS JOIN LATERAL generate_series(
+    (e.started_at AT TIME ZONE $3::text)::date::timestamp,
+    (e.ended_at AT TIME ZONE $3::text)::date::timestamp,
+    interval '1 day'
+) AS d
+WHERE d >= $4::date AND d <= $2::date
+GROUP BY d
+HAVING SUM(EXTRACT(EPOCH FROM (
+    LEAST(e.ended_at, (d + interval '1 day') AT TIME ZONE $3::text)
+    - GREATEST(e.started_at, d AT TIME ZONE $3::text)
+))) > 0
 ORDER BY date
 `
 
 type GetGrowthStatsParams struct {
-	UserID   uuid.UUID          `json:"user_id"`
-	FromDate pgtype.Timestamptz `json:"from_date"`
-	ToDate   pgtype.Timestamptz `json:"to_date"`
+	UserID   uuid.UUID   `json:"user_id"`
+	ToDay    pgtype.Date `json:"to_day"`
+	Timezone string      `json:"timezone"`
+	FromDay  pgtype.Date `json:"from_day"`
 }
 
 type GetGrowthStatsRow struct {
@@ -81,9 +185,14 @@ type GetGrowthStatsRow struct {
 	TotalSeconds int64  `json:"total_seconds"`
 }
 
-// GROWTHカテゴリの実績のみを日別集計
+// GROWTHカテゴリの実績を timezone での日別に集計する (from_day〜to_day)。日をまたぐエントリは日ごとに按分する
 func (q *Queries) GetGrowthStats(ctx context.Context, arg GetGrowthStatsParams) ([]GetGrowthStatsRow, error) {
-	rows, err := q.db.Query(ctx, getGrowthStats, arg.UserID, arg.FromDate, arg.ToDate)
+	rows, err := q.db.Query(ctx, getGrowthStats,
+		arg.UserID,
+		arg.ToDay,
+		arg.Timezone,
+		arg.FromDay,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -102,15 +211,34 @@ func (q *Queries) GetGrowthStats(ctx context.Context, arg GetGrowthStatsParams)
 	return items, nil
 }
 
-const getRunningTimeEntries = `-- name: GetRunningTimeEntries :many
-SELECT t.id, t.user_id, t.project_id, t.task_id, t.started_at, t.ended_at, t.note, t.is_auto_generated, t.created_at, t.updated_at, p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color
+const getLastActivityAt = `-- name: GetLastActivityAt :one
+SELECT GREATEST(
+    (SELECT MAX(h.time) FROM heartbeats h WHERE h.user_id = $1 AND h.time >= $2::timestamptz),
+    (SELECT MAX(a.ended_at) FROM activity_events a WHERE a.user_id = $1 AND a.ended_at >= $2::timestamptz)
+)::timestamptz AS last_activity_at
+`
+
+type GetLastActivityAtParams struct {
+	UserID uuid.UUID          `json:"user_id"`
+	Since  pgtype.Timestamptz `json:"since"`
+}
+
+// since 以降で最後に heartbeat かデスクトップのアクティビティがあった日時 (なければ NULL)
+func (q *Queries) GetLastActivityAt(ctx context.Context, arg GetLastActivityAtParams) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, getLastActivityAt, arg.UserID, arg.Since)
+	var lastActivityAt pgtype.Timestamptz
+	err := row.Scan(&lastActivityAt)
+	return lastActivityAt, err
+}
+
+const getRunningTimeEntry = `-- name: GetRunningTimeEntry :one
+SELECT t.id, t.user_id, t.project_id, t.task_id, t.started_at, t.ended_at, t.note, t.is_auto_generated, t.created_at, t.updated_at, t.is_billable, p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color
 FROM time_entries t
 JOIN projects p ON t.project_id = p.id
-WHERE t.user_id = $1 AND t.ended_at IS NULL
-ORDER BY t.started_at DESC
+WHERE t.user_id = $1 AND t.ended_at IS NULL AND p.deleted_at IS NULL
 `
 
-type GetRunningTimeEntriesRow struct {
+type GetRunningTimeEntryRow struct {
 	ID              uuid.UUID          `json:"id"`
 	UserID          uuid.UUID          `json:"user_id"`
 	ProjectID       uuid.UUID          `json:"project_id"`
@@ -121,20 +249,93 @@ type GetRunningTimeEntriesRow struct {
 	IsAutoGenerated bool               `json:"is_auto_generated"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	IsBillable      bool               `json:"is_billable"`
 	ProjectTitle    string             `json:"project_title"`
 	ProjectColor    string             `json:"project_color"`
 }
 
-// 計測中のエントリ
-func (q *Queries) GetRunningTimeEntries(ctx context.Context, userID uuid.UUID) ([]GetRunningTimeEntriesRow, error) {
-	rows, err := q.db.Query(ctx, getRunningTimeEntries, userID)
+// 計測中のエントリ (ユーザーごとに最大1件)
+func (q *Queries) GetRunningTimeEntry(ctx context.Context, userID uuid.UUID) (GetRunningTimeEntryRow, error) {
+	row := q.db.QueryRow(ctx, getRunningTimeEntry, userID)
+	var i GetRunningTimeEntryRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.TaskID,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.Note,
+		&i.IsAutoGenerated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsBillable,
+		&i.ProjectTitle,
+		&i.ProjectColor,
+	)
+	return i, err
+}
+
+const getTimeEntry = `-- name: GetTimeEntry :one
+SELECT id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at, is_billable FROM time_entries
+WHERE id = $1 AND user_id = $2
+`
+
+type GetTimeEntryParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetTimeEntry(ctx context.Context, arg GetTimeEntryParams) (TimeEntry, error) {
+	row := q.db.QueryRow(ctx, getTimeEntry, arg.ID, arg.UserID)
+	var i TimeEntry
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.TaskID,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.Note,
+		&i.IsAutoGenerated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsBillable,
+	)
+	return i, err
+}
+
+const listOverlappingTimeEntries = `-- name: ListOverlappingTimeEntries :many
+SELECT id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at, is_billable FROM time_entries
+WHERE user_id = $1
+  AND NOT (id = ANY($2::uuid[]))
+  AND tstzrange(started_at, ended_at) && tstzrange($3::timestamptz, $4::timestamptz)
+ORDER BY started_at
+FOR UPDATE
+`
+
+type ListOverlappingTimeEntriesParams struct {
+	UserID     uuid.UUID          `json:"user_id"`
+	ExcludeIds []uuid.UUID        `json:"exclude_ids"`
+	StartedAt  pgtype.Timestamptz `json:"started_at"`
+	EndedAt    pgtype.Timestamptz `json:"ended_at"`
+}
+
+// 範囲と重なるエントリ。ended_at が NULL (計測中・終わりを指定しない) なら終わりがないものとして扱う
+func (q *Queries) ListOverlappingTimeEntries(ctx context.Context, arg ListOverlappingTimeEntriesParams) ([]TimeEntry, error) {
+	rows, err := q.db.Query(ctx, listOverlappingTimeEntries,
+		arg.UserID,
+		arg.ExcludeIds,
+		arg.StartedAt,
+		arg.EndedAt,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetRunningTimeEntriesRow
+	var items []TimeEntry
 	for rows.Next() {
-		var i GetRunningTimeEntriesRow
+		var i TimeEntry
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
@@ -146,8 +347,47 @@ func (q *Queries) GetRunningTimeEntries(ctx context.Context, userID uuid.UUID) (
 			&i.IsAutoGenerated,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.ProjectTitle,
-			&i.ProjectColor,
+			&i.IsBillable,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRunningTimeEntriesWithPreferences = `-- name: ListRunningTimeEntriesWithPreferences :many
+SELECT t.id, t.user_id, t.started_at, u.preferences
+FROM time_entries t
+JOIN users u ON t.user_id = u.id
+WHERE t.ended_at IS NULL
+`
+
+type ListRunningTimeEntriesWithPreferencesRow struct {
+	ID          uuid.UUID          `json:"id"`
+	UserID      uuid.UUID          `json:"user_id"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	Preferences []byte             `json:"preferences"`
+}
+
+// 自動停止の設定を確かめるため、計測中のエントリをユーザーの設定と一緒に取得する
+func (q *Queries) ListRunningTimeEntriesWithPreferences(ctx context.Context) ([]ListRunningTimeEntriesWithPreferencesRow, error) {
+	rows, err := q.db.Query(ctx, listRunningTimeEntriesWithPreferences)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRunningTimeEntriesWithPreferencesRow
+	for rows.Next() {
+		var i ListRunningTimeEntriesWithPreferencesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.StartedAt,
+			&i.Preferences,
 		); err != nil {
 			return nil, err
 		}
@@ -160,20 +400,48 @@ func (q *Queries) GetRunningTimeEntries(ctx context.Context, userID uuid.UUID) (
 }
 
 const listTimeEntries = `-- name: ListTimeEntries :many
-SELECT t.id, t.user_id, t.project_id, t.task_id, t.started_at, t.ended_at, t.note, t.is_auto_generated, t.created_at, t.updated_at, p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color
+SELECT t.id, t.user_id, t.project_id, t.task_id, t.started_at, t.ended_at, t.note, t.is_auto_generated, t.created_at, t.updated_at, t.is_billable, p.title as project_title, COALESCE(p.color, '#808080')::varchar as project_color,
+    p.category_id, c.name as category_name, c.root_type, tk.title as task_title,
+    EXTRACT(EPOCH FROM (COALESCE(t.ended_at, NOW()) - t.started_at))::bigint as duration_seconds,
+    ARRAY(SELECT tet.tag_id FROM time_entry_tags tet WHERE tet.time_entry_id = t.id)::uuid[] as tag_ids,
+    ARRAY(SELECT g.name FROM time_entry_tags tet JOIN tags g ON tet.tag_id = g.id
+        WHERE tet.time_entry_id = t.id ORDER BY g.name)::text[] as tag_names
 FROM time_entries t
 JOIN projects p ON t.project_id = p.id
+JOIN categories c ON p.category_id = c.id
+LEFT JOIN tasks tk ON t.task_id = tk.id AND tk.deleted_at IS NULL
 WHERE 
     t.user_id = $1
+    AND p.deleted_at IS NULL
     AND t.started_at >= $2
-    AND t.started_at <= $3
-ORDER BY t.started_at DESC
+    AND t.started_at < $3
+    AND ($4::uuid IS NULL OR t.project_id = $4)
+    AND ($5::uuid IS NULL OR p.category_id = $5)
+    AND ($6::root_category_type IS NULL OR c.root_type = $6)
+    AND ($7::uuid IS NULL OR t.task_id = $7)
+    AND ($8::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM time_entry_tags tet WHERE tet.time_entry_id = t.id AND tet.tag_id = $8
+    ))
+    AND ($9::bool IS NULL OR t.is_auto_generated = $9)
+    AND ($10::timestamptz IS NULL
+        OR (t.started_at, t.id) < ($10, $11::uuid))
+ORDER BY t.started_at DESC, t.id DESC
+LIMIT $12
 `
 
 type ListTimeEntriesParams struct {
-	UserID   uuid.UUID          `json:"user_id"`
-	FromDate pgtype.Timestamptz `json:"from_date"`
-	ToDate   pgtype.Timestamptz `json:"to_date"`
+	UserID          uuid.UUID            `json:"user_id"`
+	FromDate        pgtype.Timestamptz   `json:"from_date"`
+	ToDate          pgtype.Timestamptz   `json:"to_date"`
+	ProjectID       pgtype.UUID          `json:"project_id"`
+	CategoryID      pgtype.UUID          `json:"category_id"`
+	RootType        NullRootCategoryType `json:"root_type"`
+	TaskID          pgtype.UUID          `json:"task_id"`
+	TagID           pgtype.UUID          `json:"tag_id"`
+	IsAutoGenerated pgtype.Bool          `json:"is_auto_generated"`
+	CursorStartedAt pgtype.Timestamptz   `json:"cursor_started_at"`
+	CursorID        pgtype.UUID          `json:"cursor_id"`
+	PageSize        int32                `json:"page_size"`
 }
 
 type ListTimeEntriesRow struct {
@@ -187,12 +455,34 @@ type ListTimeEntriesRow struct {
 	IsAutoGenerated bool               `json:"is_auto_generated"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	IsBillable      bool               `json:"is_billable"`
 	ProjectTitle    string             `json:"project_title"`
 	ProjectColor    string             `json:"project_color"`
+	CategoryID      uuid.UUID          `json:"category_id"`
+	CategoryName    string             `json:"category_name"`
+	RootType        RootCategoryType   `json:"root_type"`
+	TaskTitle       pgtype.Text        `json:"task_title"`
+	DurationSeconds int64              `json:"duration_seconds"`
+	TagIds          []uuid.UUID        `json:"tag_ids"`
+	TagNames        []string           `json:"tag_names"`
 }
 
+// started_at の新しい順。カーソル (cursor_started_at, cursor_id) を指定するとその次から取得する
 func (q *Queries) ListTimeEntries(ctx context.Context, arg ListTimeEntriesParams) ([]ListTimeEntriesRow, error) {
-	rows, err := q.db.Query(ctx, listTimeEntries, arg.UserID, arg.FromDate, arg.ToDate)
+	rows, err := q.db.Query(ctx, listTimeEntries,
+		arg.UserID,
+		arg.FromDate,
+		arg.ToDate,
+		arg.ProjectID,
+		arg.CategoryID,
+		arg.RootType,
+		arg.TaskID,
+		arg.TagID,
+		arg.IsAutoGenerated,
+		arg.CursorStartedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -211,8 +501,97 @@ func (q *Queries) ListTimeEntries(ctx context.Context, arg ListTimeEntriesParams
 			&i.IsAutoGenerated,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.IsBillable,
 			&i.ProjectTitle,
 			&i.ProjectColor,
+			&i.CategoryID,
+			&i.CategoryName,
+			&i.RootType,
+			&i.TaskTitle,
+			&i.DurationSeconds,
+			&i.TagIds,
+			&i.TagNames,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimeEntryTagIDs = `-- name: ListTimeEntryTagIDs :many
+SELECT tag_id FROM time_entry_tags
+WHERE time_entry_id = $1
+`
+
+func (q *Queries) ListTimeEntryTagIDs(ctx context.Context, timeEntryID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, listTimeEntryTagIDs, timeEntryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var tag_id uuid.UUID
+		if err := rows.Scan(&tag_id); err != nil {
+			return nil, err
+		}
+		items = append(items, tag_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimerAutoStops = `-- name: ListTimerAutoStops :many
+SELECT s.time_entry_id, s.reason, s.ended_at, s.stopped_at,
+    t.project_id, p.title as project_title, t.task_id, t.started_at
+FROM timer_auto_stops s
+JOIN time_entries t ON s.time_entry_id = t.id
+JOIN projects p ON t.project_id = p.id
+WHERE s.user_id = $1
+ORDER BY s.stopped_at DESC
+LIMIT $2
+`
+
+type ListTimerAutoStopsParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Limit  int32     `json:"limit"`
+}
+
+type ListTimerAutoStopsRow struct {
+	TimeEntryID  uuid.UUID          `json:"time_entry_id"`
+	Reason       string             `json:"reason"`
+	EndedAt      pgtype.Timestamptz `json:"ended_at"`
+	StoppedAt    pgtype.Timestamptz `json:"stopped_at"`
+	ProjectID    uuid.UUID          `json:"project_id"`
+	ProjectTitle string             `json:"project_title"`
+	TaskID       pgtype.UUID        `json:"task_id"`
+	StartedAt    pgtype.Timestamptz `json:"started_at"`
+}
+
+func (q *Queries) ListTimerAutoStops(ctx context.Context, arg ListTimerAutoStopsParams) ([]ListTimerAutoStopsRow, error) {
+	rows, err := q.db.Query(ctx, listTimerAutoStops, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTimerAutoStopsRow
+	for rows.Next() {
+		var i ListTimerAutoStopsRow
+		if err := rows.Scan(
+			&i.TimeEntryID,
+			&i.Reason,
+			&i.EndedAt,
+			&i.StoppedAt,
+			&i.ProjectID,
+			&i.ProjectTitle,
+			&i.TaskID,
+			&i.StartedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -224,11 +603,181 @@ func (q *Queries) ListTimeEntries(ctx context.Context, arg ListTimeEntriesParams
 	return items, nil
 }
 
+const reopenTimeEntry = `-- name: ReopenTimeEntry :one
+UPDATE time_entries
+SET ended_at = NULL, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND ended_at IS NOT NULL
+RETURNING id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at, is_billable
+`
+
+type ReopenTimeEntryParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// 停止を取り消して計測中に戻す
+func (q *Queries) ReopenTimeEntry(ctx context.Context, arg ReopenTimeEntryParams) (TimeEntry, error) {
+	row := q.db.QueryRow(ctx, reopenTimeEntry, arg.ID, arg.UserID)
+	var i TimeEntry
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.TaskID,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.Note,
+		&i.IsAutoGenerated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsBillable,
+	)
+	return i, err
+}
+
+const restoreTimeEntry = `-- name: RestoreTimeEntry :one
+INSERT INTO time_entries (
+    id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, is_billable, created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at, is_billable
+`
+
+type RestoreTimeEntryParams struct {
+	ID              uuid.UUID          `json:"id"`
+	UserID          uuid.UUID          `json:"user_id"`
+	ProjectID       uuid.UUID          `json:"project_id"`
+	TaskID          pgtype.UUID        `json:"task_id"`
+	StartedAt       pgtype.Timestamptz `json:"started_at"`
+	EndedAt         pgtype.Timestamptz `json:"ended_at"`
+	Note            pgtype.Text        `json:"note"`
+	IsAutoGenerated bool               `json:"is_auto_generated"`
+	IsBillable      bool               `json:"is_billable"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+// 取り消しで削除したエントリを同じ ID で作り直す
+func (q *Queries) RestoreTimeEntry(ctx context.Context, arg RestoreTimeEntryParams) (TimeEntry, error) {
+	row := q.db.QueryRow(ctx, restoreTimeEntry,
+		arg.ID,
+		arg.UserID,
+		arg.ProjectID,
+		arg.TaskID,
+		arg.StartedAt,
+		arg.EndedAt,
+		arg.Note,
+		arg.IsAutoGenerated,
+		arg.IsBillable,
+		arg.CreatedAt,
+	)
+	var i TimeEntry
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.TaskID,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.Note,
+		&i.IsAutoGenerated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsBillable,
+	)
+	return i, err
+}
+
+const revertTimeEntry = `-- name: RevertTimeEntry :one
+UPDATE time_entries
+SET
+    project_id = $1,
+    task_id = $2,
+    started_at = $3,
+    ended_at = $4,
+    note = $5,
+    is_billable = $6,
+    updated_at = NOW()
+WHERE id = $7 AND user_id = $8
+RETURNING id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at, is_billable
+`
+
+type RevertTimeEntryParams struct {
+	ProjectID  uuid.UUID          `json:"project_id"`
+	TaskID     pgtype.UUID        `json:"task_id"`
+	StartedAt  pgtype.Timestamptz `json:"started_at"`
+	EndedAt    pgtype.Timestamptz `json:"ended_at"`
+	Note       pgtype.Text        `json:"note"`
+	IsBillable bool               `json:"is_billable"`
+	ID         uuid.UUID          `json:"id"`
+	UserID     uuid.UUID          `json:"user_id"`
+}
+
+// 取り消しで記録しておいた内容に戻す
+func (q *Queries) RevertTimeEntry(ctx context.Context, arg RevertTimeEntryParams) (TimeEntry, error) {
+	row := q.db.QueryRow(ctx, revertTimeEntry,
+		arg.ProjectID,
+		arg.TaskID,
+		arg.StartedAt,
+		arg.EndedAt,
+		arg.Note,
+		arg.IsBillable,
+		arg.ID,
+		arg.UserID,
+	)
+	var i TimeEntry
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.TaskID,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.Note,
+		&i.IsAutoGenerated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsBillable,
+	)
+	return i, err
+}
+
+const stopRunningTimeEntry = `-- name: StopRunningTimeEntry :one
+UPDATE time_entries
+SET ended_at = $2, updated_at = NOW()
+WHERE user_id = $1 AND ended_at IS NULL
+RETURNING id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at, is_billable
+`
+
+type StopRunningTimeEntryParams struct {
+	UserID  uuid.UUID          `json:"user_id"`
+	EndedAt pgtype.Timestamptz `json:"ended_at"`
+}
+
+// 計測中のエントリがあれば止める
+func (q *Queries) StopRunningTimeEntry(ctx context.Context, arg StopRunningTimeEntryParams) (TimeEntry, error) {
+	row := q.db.QueryRow(ctx, stopRunningTimeEntry, arg.UserID, arg.EndedAt)
+	var i TimeEntry
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ProjectID,
+		&i.TaskID,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.Note,
+		&i.IsAutoGenerated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsBillable,
+	)
+	return i, err
+}
+
 const stopTimeEntry = `-- name: StopTimeEntry :one
 UPDATE time_entries
 SET ended_at = $3, updated_at = NOW()
-WHERE id = $1 AND user_id = $2
-RETURNING id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at
+WHERE id = $1 AND user_id = $2 AND ended_at IS NULL
+RETURNING id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at, is_billable
 `
 
 type StopTimeEntryParams struct {
@@ -237,6 +786,7 @@ type StopTimeEntryParams struct {
 	EndedAt pgtype.Timestamptz `json:"ended_at"`
 }
 
+// 計測中のエントリだけを止める (終わったエントリの ended_at は変えない)
 func (q *Queries) StopTimeEntry(ctx context.Context, arg StopTimeEntryParams) (TimeEntry, error) {
 	row := q.db.QueryRow(ctx, stopTimeEntry, arg.ID, arg.UserID, arg.EndedAt)
 	var i TimeEntry
@@ -251,6 +801,7 @@ func (q *Queries) StopTimeEntry(ctx context.Context, arg StopTimeEntryParams) (T
 		&i.IsAutoGenerated,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsBillable,
 	)
 	return i, err
 }
@@ -263,19 +814,21 @@ SET
     started_at = COALESCE($5, started_at),
     ended_at = COALESCE($6, ended_at),
     note = COALESCE($7, note),
+    is_billable = COALESCE($8, is_billable),
     updated_at = NOW()
 WHERE id = $1 AND user_id = $2
-RETURNING id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at
+RETURNING id, user_id, project_id, task_id, started_at, ended_at, note, is_auto_generated, created_at, updated_at, is_billable
 `
 
 type UpdateTimeEntryParams struct {
-	ID        uuid.UUID          `json:"id"`
-	UserID    uuid.UUID          `json:"user_id"`
-	ProjectID pgtype.UUID        `json:"project_id"`
-	TaskID    pgtype.UUID        `json:"task_id"`
-	StartedAt pgtype.Timestamptz `json:"started_at"`
-	EndedAt   pgtype.Timestamptz `json:"ended_at"`
-	Note      pgtype.Text        `json:"note"`
+	ID         uuid.UUID          `json:"id"`
+	UserID     uuid.UUID          `json:"user_id"`
+	ProjectID  pgtype.UUID        `json:"project_id"`
+	TaskID     pgtype.UUID        `json:"task_id"`
+	StartedAt  pgtype.Timestamptz `json:"started_at"`
+	EndedAt    pgtype.Timestamptz `json:"ended_at"`
+	Note       pgtype.Text        `json:"note"`
+	IsBillable pgtype.Bool        `json:"is_billable"`
 }
 
 func (q *Queries) UpdateTimeEntry(ctx context.Context, arg UpdateTimeEntryParams) (TimeEntry, error) {
@@ -287,6 +840,7 @@ func (q *Queries) UpdateTimeEntry(ctx context.Context, arg UpdateTimeEntryParams
 		arg.StartedAt,
 		arg.EndedAt,
 		arg.Note,
+		arg.IsBillable,
 	)
 	var i TimeEntry
 	err := row.Scan(
@@ -300,6 +854,7 @@ func (q *Queries) UpdateTimeEntry(ctx context.Context, arg UpdateTimeEntryParams
 		&i.IsAutoGenerated,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsBillable,
 	)
 	return i, err
 }
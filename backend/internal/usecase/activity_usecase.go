@@ -0,0 +1,457 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// 1回のトランザクションでロールアップするイベントの数
+	activityRollupBatchSize = 1000
+	// heartbeat でまだ延びるかもしれないので、終わってからこの時間がたったイベントをロールアップする
+	activityRollupDelay = 2 * time.Minute
+	// レポートに出す未分類のアプリ・タイトルの数
+	maxUnclassifiedActivityRows = 50
+	// 1件のイベントの長さの上限
+	maxActivityEventDuration = 24 * time.Hour
+	// 送信元の時計のずれとして許す、未来に終わるイベントの幅
+	maxActivityClockSkew = 5 * time.Minute
+)
+
+// ActivityEventInput はアプリ・ウィンドウのフォーカスのイベント
+type ActivityEventInput struct {
+	App       string
+	Title     string
+	Timestamp time.Time
+	Duration  time.Duration
+}
+
+// ActivityRuleInput は分類のルール。パターンは正規表現で、空のものは問わない
+type ActivityRuleInput struct {
+	AppPattern   string
+	TitlePattern string
+	ProjectID    *uuid.UUID
+	CategoryID   *uuid.UUID
+	Position     int32
+}
+
+// ActivityReport は期間 [from, to) のアクティビティのうち、ルールで分類できなかった時間
+type ActivityReport struct {
+	From                time.Time `json:"from"`
+	To                  time.Time `json:"to"`
+	TotalSeconds        int64     `json:"total_seconds"`
+	ClassifiedSeconds   int64     `json:"classified_seconds"`
+	UnclassifiedSeconds int64     `json:"unclassified_seconds"`
+	// まだロールアップしていないイベントの数 (分類はまだ決まっていない)
+	PendingEvents int64                                    `json:"pending_events"`
+	Unclassified  []repository.ListUnclassifiedActivityRow `json:"unclassified"`
+}
+
+type ActivityUsecase interface {
+	// IngestEvents はバケットにイベントを追加し、件数を返す
+	IngestEvents(ctx context.Context, userID uuid.UUID, bucket string, events []ActivityEventInput) (int, error)
+	// Heartbeat は直前のイベントと同じアプリ・タイトルで pulsetime 以内に続いていればそのイベントを延ばし、違えば新しいイベントにする
+	Heartbeat(ctx context.Context, userID uuid.UUID, bucket string, event ActivityEventInput, pulsetime time.Duration) (*repository.ActivityEvent, error)
+	CreateRule(ctx context.Context, userID uuid.UUID, input ActivityRuleInput) (*repository.ActivityRule, error)
+	ListRules(ctx context.Context, userID uuid.UUID) ([]repository.ActivityRule, error)
+	DeleteRule(ctx context.Context, userID, ruleID uuid.UUID) error
+	GetReport(ctx context.Context, userID uuid.UUID, from, to time.Time) (*ActivityReport, error)
+	// Rollup は終わったイベントを分類し、プロジェクトに対応づいたものを自動生成の time_entries にまとめる
+	Rollup(ctx context.Context, now time.Time) (int, error)
+	// Prune は保持期間を過ぎた生のイベントを削除し、件数を返す
+	Prune(ctx context.Context, now time.Time) (int64, error)
+}
+
+type activityUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+	retention time.Duration // 0 なら削除しない
+}
+
+func NewActivityUsecase(repo *repository.Queries, txManager db.TxManager, retentionDays int) ActivityUsecase {
+	return &activityUsecase{
+		repo:      repo,
+		txManager: txManager,
+		retention: time.Duration(max(retentionDays, 0)) * 24 * time.Hour,
+	}
+}
+
+func (u *activityUsecase) IngestEvents(ctx context.Context, userID uuid.UUID, bucket string, events []ActivityEventInput) (int, error) {
+	now := time.Now()
+	for _, ev := range events {
+		if err := checkActivityEvent(ev, now); err != nil {
+			return 0, err
+		}
+	}
+	saved := 0
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		for _, ev := range events {
+			_, err := q.UpsertActivityEvent(ctx, activityEventParams(userID, bucket, ev))
+			if err != nil {
+				// ロールアップ済みのイベントの再送
+				if errors.Is(err, pgx.ErrNoRows) {
+					continue
+				}
+				return err
+			}
+			saved++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save activity events: %w", err)
+	}
+	return saved, nil
+}
+
+func (u *activityUsecase) Heartbeat(ctx context.Context, userID uuid.UUID, bucket string, event ActivityEventInput, pulsetime time.Duration) (*repository.ActivityEvent, error) {
+	if err := checkActivityEvent(event, time.Now()); err != nil {
+		return nil, err
+	}
+	var saved repository.ActivityEvent
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		last, err := q.GetLastActivityEvent(ctx, repository.GetLastActivityEventParams{UserID: userID, Bucket: bucket})
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		end := event.Timestamp.Add(event.Duration)
+		if err == nil && !last.RolledUpAt.Valid && last.App == event.App && last.Title == event.Title &&
+			!event.Timestamp.Before(last.StartedAt.Time) && !event.Timestamp.After(last.EndedAt.Time.Add(pulsetime)) {
+			saved, err = q.ExtendActivityEvent(ctx, repository.ExtendActivityEventParams{ID: last.ID, EndedAt: toTimestamp(&end)})
+			return err
+		}
+		saved, err = q.UpsertActivityEvent(ctx, activityEventParams(userID, bucket, event))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return NewConflictError("event has already been rolled up")
+		}
+		return err
+	})
+	if err != nil {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to save activity heartbeat: %w", err)
+	}
+	return &saved, nil
+}
+
+// checkActivityEvent は終わらないまま残るイベントを作らないよう、長すぎるものと未来に終わるものを拒否する
+func checkActivityEvent(ev ActivityEventInput, now time.Time) error {
+	if ev.Duration > maxActivityEventDuration {
+		return NewBadRequestError("duration must be at most 24 hours")
+	}
+	if ev.Timestamp.Add(max(ev.Duration, 0)).After(now.Add(maxActivityClockSkew)) {
+		return NewBadRequestError("event must not end in the future")
+	}
+	return nil
+}
+
+func activityEventParams(userID uuid.UUID, bucket string, ev ActivityEventInput) repository.UpsertActivityEventParams {
+	end := ev.Timestamp.Add(max(ev.Duration, 0))
+	return repository.UpsertActivityEventParams{
+		UserID:    userID,
+		Bucket:    bucket,
+		App:       ev.App,
+		Title:     ev.Title,
+		StartedAt: toTimestamp(&ev.Timestamp),
+		EndedAt:   toTimestamp(&end),
+	}
+}
+
+func (u *activityUsecase) CreateRule(ctx context.Context, userID uuid.UUID, input ActivityRuleInput) (*repository.ActivityRule, error) {
+	if input.AppPattern == "" && input.TitlePattern == "" {
+		return nil, NewBadRequestError("rule needs app_pattern or title_pattern")
+	}
+	if input.ProjectID == nil && input.CategoryID == nil {
+		return nil, NewBadRequestError("rule needs project_id or category_id")
+	}
+	for _, p := range []string{input.AppPattern, input.TitlePattern} {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, NewBadRequestError(fmt.Sprintf("invalid pattern: %v", err))
+		}
+	}
+
+	var rule repository.ActivityRule
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		categoryID := toUUID(input.CategoryID)
+		if input.ProjectID != nil {
+			project, err := q.GetProject(ctx, repository.GetProjectParams{ID: *input.ProjectID, UserID: userID})
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return NewNotFoundError("project not found")
+				}
+				return err
+			}
+			// レポートではプロジェクトのカテゴリで数える
+			if !categoryID.Valid {
+				categoryID = pgtype.UUID{Bytes: project.CategoryID, Valid: true}
+			}
+		}
+		if input.CategoryID != nil {
+			if _, err := q.GetCategory(ctx, repository.GetCategoryParams{ID: *input.CategoryID, UserID: userID}); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return NewNotFoundError("category not found")
+				}
+				return err
+			}
+		}
+		var err error
+		rule, err = q.CreateActivityRule(ctx, repository.CreateActivityRuleParams{
+			UserID:       userID,
+			AppPattern:   toTextFromStr(input.AppPattern),
+			TitlePattern: toTextFromStr(input.TitlePattern),
+			ProjectID:    toUUID(input.ProjectID),
+			CategoryID:   categoryID,
+			Position:     input.Position,
+		})
+		return err
+	})
+	if err != nil {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create activity rule: %w", err)
+	}
+	return &rule, nil
+}
+
+func (u *activityUsecase) ListRules(ctx context.Context, userID uuid.UUID) ([]repository.ActivityRule, error) {
+	rules, err := u.repo.ListActivityRules(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (u *activityUsecase) DeleteRule(ctx context.Context, userID, ruleID uuid.UUID) error {
+	n, err := u.repo.DeleteActivityRule(ctx, repository.DeleteActivityRuleParams{ID: ruleID, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete activity rule: %w", err)
+	}
+	if n == 0 {
+		return NewNotFoundError("activity rule not found")
+	}
+	return nil
+}
+
+// GetReport の from/to は日付で、ユーザーのタイムゾーンの [from, to) とする。ゼロ値なら to は明日 (今日まで)、from は to の7日前
+func (u *activityUsecase) GetReport(ctx context.Context, userID uuid.UUID, from, to time.Time) (*ActivityReport, error) {
+	prefs, err := loadPreferences(ctx, u.repo, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity report: %w", err)
+	}
+	loc := prefs.Location()
+	if to.IsZero() {
+		to = time.Now().In(loc).AddDate(0, 0, 1)
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -7)
+	}
+	from, to = localDay(from, loc), localDay(to, loc)
+	if !to.After(from) {
+		return nil, NewBadRequestError("to must be after from")
+	}
+	totals, err := u.repo.GetActivityTotals(ctx, repository.GetActivityTotalsParams{
+		ToTime:   toTimestamp(&to),
+		FromTime: toTimestamp(&from),
+		UserID:   userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity totals: %w", err)
+	}
+	rows, err := u.repo.ListUnclassifiedActivity(ctx, repository.ListUnclassifiedActivityParams{
+		ToTime:   toTimestamp(&to),
+		FromTime: toTimestamp(&from),
+		UserID:   userID,
+		MaxRows:  maxUnclassifiedActivityRows,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unclassified activity: %w", err)
+	}
+	if rows == nil {
+		rows = []repository.ListUnclassifiedActivityRow{}
+	}
+	return &ActivityReport{
+		From:                from,
+		To:                  to,
+		TotalSeconds:        totals.TotalSeconds,
+		ClassifiedSeconds:   totals.TotalSeconds - totals.UnclassifiedSeconds,
+		UnclassifiedSeconds: totals.UnclassifiedSeconds,
+		PendingEvents:       totals.PendingEvents,
+		Unclassified:        rows,
+	}, nil
+}
+
+func (u *activityUsecase) Rollup(ctx context.Context, now time.Time) (int, error) {
+	total := 0
+	for {
+		n, err := u.rollupBatch(ctx, now)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("failed to roll up activity: %w", err)
+		}
+		if n < activityRollupBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (u *activityUsecase) rollupBatch(ctx context.Context, now time.Time) (int, error) {
+	processed := 0
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		processed = 0
+		locked, err := q.TryLockActivityRollup(ctx)
+		if err != nil || !locked {
+			return err
+		}
+		settled := now.Add(-activityRollupDelay)
+		pending, err := q.ListPendingActivityEvents(ctx, repository.ListPendingActivityEventsParams{
+			EndedAt: toTimestamp(&settled),
+			Limit:   activityRollupBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		// ユーザーごと・時刻順に並んでいる
+		var cls *activityClassifier
+		for _, ev := range pending {
+			if cls == nil || cls.userID != ev.UserID {
+				if cls, err = newActivityClassifier(ctx, q, ev.UserID); err != nil {
+					return err
+				}
+			}
+			if err := cls.add(ctx, q, ev, now); err != nil {
+				return err
+			}
+			processed++
+		}
+		return nil
+	})
+	return processed, err
+}
+
+func (u *activityUsecase) Prune(ctx context.Context, now time.Time) (int64, error) {
+	if u.retention <= 0 {
+		return 0, nil
+	}
+	before := now.Add(-u.retention)
+	n, err := u.repo.DeleteActivityEventsBefore(ctx, toTimestamp(&before))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune activity events: %w", err)
+	}
+	return n, nil
+}
+
+type compiledActivityRule struct {
+	rule  repository.ActivityRule
+	app   *regexp.Regexp
+	title *regexp.Regexp
+}
+
+// activityClassifier は1人分のイベントをルールで分類し、同じプロジェクトのイベントが idle 以内の間隔で続く間を1つのエントリにする
+type activityClassifier struct {
+	userID uuid.UUID
+	idle   time.Duration
+	rules  []compiledActivityRule
+}
+
+func newActivityClassifier(ctx context.Context, q *repository.Queries, userID uuid.UUID) (*activityClassifier, error) {
+	prefs, err := loadPreferences(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := q.ListActivityRules(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	cls := &activityClassifier{
+		userID: userID,
+		idle:   time.Duration(prefs.HeartbeatIdleMinutes) * time.Minute,
+	}
+	for _, r := range rules {
+		c := compiledActivityRule{rule: r}
+		// 作成時に検証しているので、コンパイルできないルールは使わない
+		if r.AppPattern.Valid {
+			if c.app, err = regexp.Compile(r.AppPattern.String); err != nil {
+				continue
+			}
+		}
+		if r.TitlePattern.Valid {
+			if c.title, err = regexp.Compile(r.TitlePattern.String); err != nil {
+				continue
+			}
+		}
+		cls.rules = append(cls.rules, c)
+	}
+	return cls, nil
+}
+
+func (c *activityClassifier) classify(ev repository.ActivityEvent) *repository.ActivityRule {
+	for _, r := range c.rules {
+		if r.app != nil && !r.app.MatchString(ev.App) {
+			continue
+		}
+		if r.title != nil && !r.title.MatchString(ev.Title) {
+			continue
+		}
+		return &r.rule
+	}
+	return nil
+}
+
+func (c *activityClassifier) add(ctx context.Context, q *repository.Queries, ev repository.ActivityEvent, now time.Time) error {
+	var projectID, categoryID, entryID pgtype.UUID
+	if rule := c.classify(ev); rule != nil {
+		projectID, categoryID = rule.ProjectID, rule.CategoryID
+	}
+	if projectID.Valid {
+		prev, err := q.GetPreviousActivityEvent(ctx, repository.GetPreviousActivityEventParams{UserID: c.userID, StartedAt: ev.StartedAt})
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if err == nil && prev.ProjectID == projectID && prev.TimeEntryID.Valid && ev.StartedAt.Time.Sub(prev.EndedAt.Time) <= c.idle {
+			n, err := q.ExtendAutoTimeEntry(ctx, repository.ExtendAutoTimeEntryParams{
+				EndedAt: ev.EndedAt,
+				ID:      prev.TimeEntryID.Bytes,
+				UserID:  c.userID,
+			})
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				entryID = prev.TimeEntryID
+			}
+		}
+		if !entryID.Valid && ev.EndedAt.Time.After(ev.StartedAt.Time) {
+			entry, err := q.CreateAutoTimeEntry(ctx, repository.CreateAutoTimeEntryParams{
+				UserID:    c.userID,
+				ProjectID: projectID.Bytes,
+				StartedAt: ev.StartedAt,
+				EndedAt:   ev.EndedAt,
+				Note:      toTextFromStr("Auto-generated from desktop activity"),
+			})
+			if err != nil {
+				return err
+			}
+			entryID = pgtype.UUID{Bytes: entry.ID, Valid: true}
+		}
+	}
+	return q.MarkActivityEventRolledUp(ctx, repository.MarkActivityEventRolledUpParams{
+		ID:          ev.ID,
+		RolledUpAt:  toTimestamp(&now),
+		ProjectID:   projectID,
+		CategoryID:  categoryID,
+		TimeEntryID: entryID,
+	})
+}
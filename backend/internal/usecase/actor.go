@@ -0,0 +1,33 @@
+package usecase
+
+import "context"
+
+// ActorSource は操作の経路
+type ActorSource string
+
+const (
+	ActorSourceWeb    ActorSource = "web"
+	ActorSourceToken  ActorSource = "token"
+	ActorSourceCalDAV ActorSource = "caldav"
+	ActorSourceSystem ActorSource = "system"
+)
+
+// Actor は操作した主体。Name はトークン名やCalDAVクライアント名
+type Actor struct {
+	Source ActorSource
+	Name   string
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFrom はリクエストの主体を返す。設定されていなければ system
+func ActorFrom(ctx context.Context) Actor {
+	if a, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return a
+	}
+	return Actor{Source: ActorSourceSystem}
+}
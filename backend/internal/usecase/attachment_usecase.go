@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/pkg/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AttachmentTarget は添付ファイルを付けられるエンティティの種類
+type AttachmentTarget string
+
+const (
+	AttachmentTargetTask  AttachmentTarget = "task"
+	AttachmentTargetEvent AttachmentTarget = "event"
+)
+
+// 種類の判定に読む先頭のバイト数 (mimetype の既定の上限と同じ)
+const sniffLen = 3072
+
+type AttachmentUsecase interface {
+	Upload(ctx context.Context, userID uuid.UUID, target AttachmentTarget, targetID uuid.UUID, filename string, r io.Reader, size int64) (*repository.Attachment, error)
+	List(ctx context.Context, userID uuid.UUID, target AttachmentTarget, targetID uuid.UUID) ([]repository.Attachment, error)
+	// Open は添付ファイルの情報と中身を返す。呼び出し側が中身を Close する
+	Open(ctx context.Context, userID, attachmentID uuid.UUID) (*repository.Attachment, io.ReadCloser, error)
+	Delete(ctx context.Context, userID, attachmentID uuid.UUID) error
+	MaxBytes() int64
+}
+
+type attachmentUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+	storage   storage.Storage
+	maxBytes  int64
+}
+
+func NewAttachmentUsecase(repo *repository.Queries, txManager db.TxManager, store storage.Storage, maxBytes int64) AttachmentUsecase {
+	return &attachmentUsecase{
+		repo:      repo,
+		txManager: txManager,
+		storage:   store,
+		maxBytes:  maxBytes,
+	}
+}
+
+func (u *attachmentUsecase) MaxBytes() int64 {
+	return u.maxBytes
+}
+
+// Upload は所有者を確認してから保存する。種類はクライアントの申告ではなく中身から判定する
+func (u *attachmentUsecase) Upload(ctx context.Context, userID uuid.UUID, target AttachmentTarget, targetID uuid.UUID, filename string, r io.Reader, size int64) (*repository.Attachment, error) {
+	if size <= 0 {
+		return nil, NewBadRequestError("file is empty")
+	}
+	if size > u.maxBytes {
+		return nil, NewBadRequestError(fmt.Sprintf("file is too large (max %d bytes)", u.maxBytes))
+	}
+
+	head := make([]byte, min(size, sniffLen))
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, NewBadRequestError("failed to read file")
+	}
+	contentType := mimetype.Detect(head).String()
+	body := io.MultiReader(bytes.NewReader(head), r)
+
+	id := uuid.New()
+	key := fmt.Sprintf("attachments/%s/%s", userID, id)
+	var att repository.Attachment
+	stored := false
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		var err error
+		switch target {
+		case AttachmentTargetTask:
+			att, err = q.CreateTaskAttachment(ctx, repository.CreateTaskAttachmentParams{
+				ID:          id,
+				Filename:    sanitizeFilename(filename),
+				ContentType: contentType,
+				SizeBytes:   size,
+				StorageKey:  key,
+				TaskID:      targetID,
+				UserID:      userID,
+			})
+		case AttachmentTargetEvent:
+			att, err = q.CreateEventAttachment(ctx, repository.CreateEventAttachmentParams{
+				ID:          id,
+				Filename:    sanitizeFilename(filename),
+				ContentType: contentType,
+				SizeBytes:   size,
+				StorageKey:  key,
+				EventID:     targetID,
+				UserID:      userID,
+			})
+		default:
+			return NewBadRequestError("invalid attachment target")
+		}
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return NewNotFoundError(string(target) + " not found")
+			}
+			return err
+		}
+		// 保存に失敗したら行も残さない
+		if err := u.storage.Put(ctx, key, body, size, contentType); err != nil {
+			return err
+		}
+		stored = true
+		return nil
+	})
+	if err != nil {
+		if stored {
+			_ = u.storage.Delete(ctx, key)
+		}
+		return nil, fmt.Errorf("failed to upload attachment: %w", err)
+	}
+	return &att, nil
+}
+
+func (u *attachmentUsecase) List(ctx context.Context, userID uuid.UUID, target AttachmentTarget, targetID uuid.UUID) ([]repository.Attachment, error) {
+	var (
+		items []repository.Attachment
+		err   error
+	)
+	switch target {
+	case AttachmentTargetTask:
+		items, err = u.repo.ListTaskAttachments(ctx, repository.ListTaskAttachmentsParams{TaskID: toUUID(&targetID), UserID: userID})
+	case AttachmentTargetEvent:
+		items, err = u.repo.ListEventAttachments(ctx, repository.ListEventAttachmentsParams{EventID: toUUID(&targetID), UserID: userID})
+	default:
+		return nil, NewBadRequestError("invalid attachment target")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	if items == nil {
+		items = []repository.Attachment{}
+	}
+	return items, nil
+}
+
+func (u *attachmentUsecase) Open(ctx context.Context, userID, attachmentID uuid.UUID) (*repository.Attachment, io.ReadCloser, error) {
+	att, err := u.repo.GetAttachment(ctx, repository.GetAttachmentParams{ID: attachmentID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, NewNotFoundError("attachment not found")
+		}
+		return nil, nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	rc, err := u.storage.Get(ctx, att.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, NewNotFoundError("attachment content not found")
+		}
+		return nil, nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	return &att, rc, nil
+}
+
+// Delete は行と実体を削除する。実体の削除に失敗したら行も残し、やり直せるようにする
+func (u *attachmentUsecase) Delete(ctx context.Context, userID, attachmentID uuid.UUID) error {
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		att, err := q.DeleteAttachment(ctx, repository.DeleteAttachmentParams{ID: attachmentID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		return u.storage.Delete(ctx, att.StorageKey)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return NewNotFoundError("attachment not found")
+		}
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	return nil
+}
+
+// sanitizeFilename はパスを除いたファイル名を255文字以内にする
+func sanitizeFilename(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = strings.TrimSpace(filepath.Base("/" + name))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "/" || name == "." {
+		return "attachment"
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		name = string([]rune(name)[:255])
+	}
+	return name
+}
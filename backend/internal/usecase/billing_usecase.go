@@ -0,0 +1,306 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// 請求時間の丸め方 (エントリごとに rounding_minutes の倍数にする)
+const (
+	RoundingUp      = "up"      // 切り上げ
+	RoundingDown    = "down"    // 切り捨て
+	RoundingNearest = "nearest" // 四捨五入
+)
+
+// Money は通貨の補助単位 (JPY なら円、USD ならセント) での金額。JSON では通貨の小数桁数の数値にする
+type Money struct {
+	Minor    int64
+	Currency string // ISO 4217 のコード
+}
+
+// currencyExponents は補助単位が 1/100 でない通貨の小数桁数 (ISO 4217)
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// currencyExponent は通貨の小数桁数。表にない通貨は2桁とする
+func currencyExponent(currency string) int {
+	if e, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return e
+	}
+	return 2
+}
+
+// NewMoney は通貨の単位での金額を補助単位に直す。補助単位未満は四捨五入する
+func NewMoney(amount float64, currency string) Money {
+	return Money{Minor: int64(math.Round(amount * math.Pow10(currencyExponent(currency)))), Currency: currency}
+}
+
+func (m Money) String() string {
+	minor, sign := m.Minor, ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	exp := currencyExponent(m.Currency)
+	if exp == 0 {
+		return fmt.Sprintf("%s%d", sign, minor)
+	}
+	unit := int64(math.Pow10(exp))
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/unit, exp, minor%unit)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// BillingInput はプロジェクトの請求の設定
+type BillingInput struct {
+	HourlyRate      Money  // 通貨は Currency と同じにする
+	Currency        string // ISO 4217 のコード
+	RoundingMinutes int32  // 0 なら丸めない
+	RoundingMode    string
+}
+
+// Billing はプロジェクトの請求の設定
+type Billing struct {
+	ProjectID       uuid.UUID `json:"project_id"`
+	HourlyRate      Money     `json:"hourly_rate"`
+	Currency        string    `json:"currency"`
+	RoundingMinutes int32     `json:"rounding_minutes"`
+	RoundingMode    string    `json:"rounding_mode"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// InvoiceLine は請求書の1行 (請求の対象のエントリ1件)
+type InvoiceLine struct {
+	EntryID       uuid.UUID `json:"entry_id"`
+	Date          string    `json:"date"` // タイムゾーンでの開始日 (YYYY-MM-DD)
+	StartedAt     time.Time `json:"started_at"`
+	EndedAt       time.Time `json:"ended_at"`
+	TaskTitle     string    `json:"task_title"`
+	Note          string    `json:"note"`
+	ActualSeconds int64     `json:"actual_seconds"`
+	BilledSeconds int64     `json:"billed_seconds"` // 丸めた後の時間
+	Amount        Money     `json:"amount"`
+}
+
+// Invoice は期間 [from, to) に始まったエントリの請求書。合計は各行の金額の和
+type Invoice struct {
+	ProjectID       uuid.UUID     `json:"project_id"`
+	ProjectTitle    string        `json:"project_title"`
+	From            time.Time     `json:"from"`
+	To              time.Time     `json:"to"`
+	Timezone        string        `json:"timezone"`
+	Currency        string        `json:"currency"`
+	HourlyRate      Money         `json:"hourly_rate"`
+	RoundingMinutes int32         `json:"rounding_minutes"`
+	RoundingMode    string        `json:"rounding_mode"`
+	Lines           []InvoiceLine `json:"lines"`
+	ActualSeconds   int64         `json:"actual_seconds"`
+	BilledSeconds   int64         `json:"billed_seconds"`
+	// 請求の対象外にしたエントリの時間 (金額には含めない)
+	NonBillableSeconds int64     `json:"non_billable_seconds"`
+	Total              Money     `json:"total"`
+	IssuedAt           time.Time `json:"issued_at"`
+}
+
+type BillingUsecase interface {
+	GetBilling(ctx context.Context, userID, projectID uuid.UUID) (*Billing, error)
+	SetBilling(ctx context.Context, userID, projectID uuid.UUID, input BillingInput) (*Billing, error)
+	DeleteBilling(ctx context.Context, userID, projectID uuid.UUID) error
+	// GetInvoice は期間の請求書を作る。from/to はユーザーのタイムゾーンの日付で、省略すると今月
+	GetInvoice(ctx context.Context, userID, projectID uuid.UUID, from, to time.Time) (*Invoice, error)
+}
+
+type billingUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewBillingUsecase(repo *repository.Queries, txManager db.TxManager) BillingUsecase {
+	return &billingUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+func (u *billingUsecase) GetBilling(ctx context.Context, userID, projectID uuid.UUID) (*Billing, error) {
+	b, err := u.repo.GetProjectBilling(ctx, repository.GetProjectBillingParams{ProjectID: projectID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("billing not configured")
+		}
+		return nil, fmt.Errorf("failed to get billing: %w", err)
+	}
+	return toBilling(b), nil
+}
+
+func (u *billingUsecase) SetBilling(ctx context.Context, userID, projectID uuid.UUID, input BillingInput) (*Billing, error) {
+	if input.HourlyRate.Minor < 0 {
+		return nil, NewBadRequestError("hourly_rate must not be negative")
+	}
+	if input.RoundingMode == "" {
+		input.RoundingMode = RoundingUp
+	}
+	var b repository.ProjectBilling
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		if _, err := q.GetProject(ctx, repository.GetProjectParams{ID: projectID, UserID: userID}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return NewNotFoundError("project not found")
+			}
+			return err
+		}
+		var err error
+		b, err = q.UpsertProjectBilling(ctx, repository.UpsertProjectBillingParams{
+			ProjectID:       projectID,
+			UserID:          userID,
+			HourlyRateMinor: input.HourlyRate.Minor,
+			Currency:        input.Currency,
+			RoundingMinutes: input.RoundingMinutes,
+			RoundingMode:    input.RoundingMode,
+		})
+		return err
+	})
+	if err != nil {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to set billing: %w", err)
+	}
+	return toBilling(b), nil
+}
+
+func (u *billingUsecase) DeleteBilling(ctx context.Context, userID, projectID uuid.UUID) error {
+	n, err := u.repo.DeleteProjectBilling(ctx, repository.DeleteProjectBillingParams{ProjectID: projectID, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete billing: %w", err)
+	}
+	if n == 0 {
+		return NewNotFoundError("billing not configured")
+	}
+	return nil
+}
+
+func (u *billingUsecase) GetInvoice(ctx context.Context, userID, projectID uuid.UUID, from, to time.Time) (*Invoice, error) {
+	project, err := u.repo.GetProject(ctx, repository.GetProjectParams{ID: projectID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("project not found")
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	billing, err := u.GetBilling(ctx, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := loadPreferences(ctx, u.repo, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	loc := prefs.Location()
+	now := time.Now().In(loc)
+	if to.IsZero() {
+		to = now.AddDate(0, 0, 1)
+	}
+	if from.IsZero() {
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	}
+	from, to = localDay(from, loc), localDay(to, loc)
+	if !to.After(from) {
+		return nil, NewBadRequestError("to must be after from")
+	}
+
+	entries, err := u.repo.ListInvoiceTimeEntries(ctx, repository.ListInvoiceTimeEntriesParams{
+		UserID:    userID,
+		ProjectID: projectID,
+		FromDate:  toTimestamp(&from),
+		ToDate:    toTimestamp(&to),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list time entries: %w", err)
+	}
+
+	invoice := &Invoice{
+		ProjectID:       projectID,
+		ProjectTitle:    project.Title,
+		From:            from,
+		To:              to,
+		Timezone:        prefs.Timezone,
+		Currency:        billing.Currency,
+		HourlyRate:      billing.HourlyRate,
+		RoundingMinutes: billing.RoundingMinutes,
+		RoundingMode:    billing.RoundingMode,
+		Lines:           []InvoiceLine{},
+		Total:           Money{Currency: billing.Currency},
+		IssuedAt:        now,
+	}
+	for _, e := range entries {
+		actual := int64(e.EndedAt.Time.Sub(e.StartedAt.Time).Round(time.Second) / time.Second)
+		if !e.IsBillable {
+			invoice.NonBillableSeconds += actual
+			continue
+		}
+		billed := roundBilledSeconds(actual, billing.RoundingMinutes, billing.RoundingMode)
+		line := InvoiceLine{
+			EntryID:       e.ID,
+			Date:          e.StartedAt.Time.In(loc).Format(time.DateOnly),
+			StartedAt:     e.StartedAt.Time.In(loc),
+			EndedAt:       e.EndedAt.Time.In(loc),
+			TaskTitle:     e.TaskTitle.String,
+			Note:          e.Note.String,
+			ActualSeconds: actual,
+			BilledSeconds: billed,
+			Amount:        amountFor(billed, billing.HourlyRate),
+		}
+		invoice.Lines = append(invoice.Lines, line)
+		invoice.ActualSeconds += actual
+		invoice.BilledSeconds += billed
+		invoice.Total.Minor += line.Amount.Minor
+	}
+	return invoice, nil
+}
+
+// roundBilledSeconds は秒数を minutes 分の倍数に丸める
+func roundBilledSeconds(seconds int64, minutes int32, mode string) int64 {
+	unit := int64(minutes) * 60
+	if unit <= 0 {
+		return seconds
+	}
+	switch mode {
+	case RoundingDown:
+		return seconds / unit * unit
+	case RoundingNearest:
+		return (seconds + unit/2) / unit * unit
+	default:
+		return (seconds + unit - 1) / unit * unit
+	}
+}
+
+// amountFor は時給での金額。補助単位未満は四捨五入する
+func amountFor(seconds int64, hourlyRate Money) Money {
+	return Money{Minor: (seconds*hourlyRate.Minor + 1800) / 3600, Currency: hourlyRate.Currency}
+}
+
+func toBilling(b repository.ProjectBilling) *Billing {
+	return &Billing{
+		ProjectID:       b.ProjectID,
+		HourlyRate:      Money{Minor: b.HourlyRateMinor, Currency: b.Currency},
+		Currency:        b.Currency,
+		RoundingMinutes: b.RoundingMinutes,
+		RoundingMode:    b.RoundingMode,
+		UpdatedAt:       b.UpdatedAt.Time,
+	}
+}
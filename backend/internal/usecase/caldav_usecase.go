@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,29 +35,40 @@ type CalDavUsecase interface {
 type calDavUsecase struct {
 	repo      *repository.Queries
 	txManager db.TxManager
+	publicURL string // 添付ファイルの ATTACH URL の基点
 }
 
-func NewCalDavUsecase(repo *repository.Queries, txManager db.TxManager) CalDavUsecase {
+func NewCalDavUsecase(repo *repository.Queries, txManager db.TxManager, publicURL string) CalDavUsecase {
 	return &calDavUsecase{
 		repo:      repo,
 		txManager: txManager,
+		publicURL: strings.TrimRight(publicURL, "/"),
 	}
 }
 
 func (u *calDavUsecase) DeleteResource(ctx context.Context, userID uuid.UUID, icalUID string) error {
-	// Try event first
-	err := u.repo.DeleteEventByICalUID(ctx, repository.DeleteEventByICalUIDParams{
+	// 予定を先に探し、なければタスクをゴミ箱に入れる
+	n, err := u.repo.TrashEventByICalUID(ctx, repository.TrashEventByICalUIDParams{
 		UserID:  userID,
 		IcalUid: toTextFromStr(icalUID),
 	})
-	if err == nil {
+	if err != nil {
+		return err
+	}
+	if n > 0 {
 		return nil
 	}
-	// Try task
-	return u.repo.DeleteTaskByICalUID(ctx, repository.DeleteTaskByICalUIDParams{
+	n, err = u.repo.TrashTaskByICalUID(ctx, repository.TrashTaskByICalUIDParams{
 		UserID:  userID,
 		IcalUid: toTextFromStr(icalUID),
 	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return NewNotFoundError("resource not found")
+	}
+	return nil
 }
 
 func (u *calDavUsecase) GetCalendars(ctx context.Context, userID uuid.UUID) ([]repository.Calendar, error) {
@@ -121,17 +133,45 @@ func (u *calDavUsecase) ExportCalendarToICal(ctx context.Context, userID, calend
 		return "", err
 	}
 
+	eventIDs := make([]uuid.UUID, len(events))
+	for i, e := range events {
+		eventIDs[i] = e.ID
+	}
+	eventTags, err := u.eventTagNames(ctx, eventIDs...)
+	if err != nil {
+		return "", err
+	}
+	eventAttachments, err := u.eventAttachments(ctx, eventIDs...)
+	if err != nil {
+		return "", err
+	}
+
+	taskIDs := make([]uuid.UUID, len(tasks))
+	for i, t := range tasks {
+		taskIDs[i] = t.ID
+	}
+	taskTags, err := u.taskTagNames(ctx, taskIDs...)
+	if err != nil {
+		return "", err
+	}
+	taskAttachments, err := u.taskAttachments(ctx, taskIDs...)
+	if err != nil {
+		return "", err
+	}
+
 	cal := ical.NewCalendar()
 	cal.Props.SetText(ical.PropProductID, "-//Taskalyst//EN")
 	cal.Props.SetText(ical.PropVersion, "2.0")
 
 	for _, e := range events {
-		event := eventToVEvent(&e)
+		event := eventToVEvent(&e, eventTags[e.ID])
+		u.setAttachments(event.Props, eventAttachments[e.ID])
 		cal.Children = append(cal.Children, event.Component)
 	}
 
 	for _, t := range tasks {
-		todo := taskToVTodo(&t)
+		todo := taskToVTodo(&t, taskTags[t.ID])
+		u.setAttachments(todo.Props, taskAttachments[t.ID])
 		cal.Children = append(cal.Children, todo)
 	}
 
@@ -151,12 +191,21 @@ func (u *calDavUsecase) ExportEventToICal(ctx context.Context, userID uuid.UUID,
 	if err != nil {
 		return "", err
 	}
+	tags, err := u.eventTagNames(ctx, e.ID)
+	if err != nil {
+		return "", err
+	}
+	attachments, err := u.eventAttachments(ctx, e.ID)
+	if err != nil {
+		return "", err
+	}
 
 	cal := ical.NewCalendar()
 	cal.Props.SetText(ical.PropProductID, "-//Taskalyst//EN")
 	cal.Props.SetText(ical.PropVersion, "2.0")
 
-	event := eventToVEvent(&e)
+	event := eventToVEvent(&e, tags[e.ID])
+	u.setAttachments(event.Props, attachments[e.ID])
 	cal.Children = append(cal.Children, event.Component)
 
 	var sb strings.Builder
@@ -174,12 +223,21 @@ func (u *calDavUsecase) ExportTaskToICal(ctx context.Context, userID uuid.UUID,
 	if err != nil {
 		return "", err
 	}
+	tags, err := u.taskTagNames(ctx, t.ID)
+	if err != nil {
+		return "", err
+	}
+	attachments, err := u.taskAttachments(ctx, t.ID)
+	if err != nil {
+		return "", err
+	}
 
 	cal := ical.NewCalendar()
 	cal.Props.SetText(ical.PropProductID, "-//Taskalyst//EN")
 	cal.Props.SetText(ical.PropVersion, "2.0")
 
-	todo := taskToVTodo(&t)
+	todo := taskToVTodo(&t, tags[t.ID])
+	u.setAttachments(todo.Props, attachments[t.ID])
 	cal.Children = append(cal.Children, todo)
 
 	var sb strings.Builder
@@ -232,16 +290,17 @@ func (u *calDavUsecase) ImportFromICal(ctx context.Context, userID, calendarID u
 				location, _ := event.Props.Text(ical.PropLocation)
 				start, _ := event.Props.DateTime(ical.PropDateTimeStart, time.UTC)
 				end, _ := event.Props.DateTime(ical.PropDateTimeEnd, time.UTC)
+				categories := icalCategories(event.Props)
 
 				// Check if exists
-				_, err := q.GetEventByICalUID(ctx, repository.GetEventByICalUIDParams{
+				saved, err := q.GetEventByICalUID(ctx, repository.GetEventByICalUIDParams{
 					UserID:  userID,
 					IcalUid: toTextFromStr(uid),
 				})
 
 				if err == nil {
 					// Update
-					_, err = q.UpdateEventByICalUID(ctx, repository.UpdateEventByICalUIDParams{
+					saved, err = q.UpdateEventByICalUID(ctx, repository.UpdateEventByICalUIDParams{
 						UserID:      userID,
 						IcalUid:     toTextFromStr(uid),
 						Title:       toTextFromStr(summary),
@@ -252,7 +311,7 @@ func (u *calDavUsecase) ImportFromICal(ctx context.Context, userID, calendarID u
 					})
 				} else {
 					// Create
-					_, err = q.CreateEvent(ctx, repository.CreateEventParams{
+					saved, err = q.CreateEvent(ctx, repository.CreateEventParams{
 						UserID:      userID,
 						ProjectID:   targetProjectID,
 						CalendarID:  toUUID(&calendarID),
@@ -269,6 +328,9 @@ func (u *calDavUsecase) ImportFromICal(ctx context.Context, userID, calendarID u
 				if err != nil {
 					return err
 				}
+				if err := setEventTagsByName(ctx, q, userID, saved.ID, categories); err != nil {
+					return err
+				}
 			}
 
 			for _, child := range cal.Children {
@@ -280,46 +342,148 @@ func (u *calDavUsecase) ImportFromICal(ctx context.Context, userID, calendarID u
 				description, _ := child.Props.Text(ical.PropDescription)
 				due, _ := child.Props.DateTime(ical.PropDue, time.UTC)
 				status, _ := child.Props.Text(ical.PropStatus)
+				priority := icalPriorityToTaskPriority(child.Props)
+				categories := icalCategories(child.Props)
 
-				_, err := q.GetTaskByICalUID(ctx, repository.GetTaskByICalUIDParams{
+				saved, err := q.GetTaskByICalUID(ctx, repository.GetTaskByICalUIDParams{
 					UserID:  userID,
 					IcalUid: toTextFromStr(uid),
 				})
 
+				var before *repository.Task
 				if err == nil {
 					// Update
-					_, err = q.UpdateTaskByICalUID(ctx, repository.UpdateTaskByICalUIDParams{
+					before = &saved
+					saved, err = q.UpdateTaskByICalUID(ctx, repository.UpdateTaskByICalUIDParams{
 						UserID:       userID,
 						IcalUid:      toTextFromStr(uid),
 						Title:        toTextFromStr(summary),
 						NoteMarkdown: toTextFromStr(description),
 						DueDate:      toTimestamp(&due),
+						Priority:     pgtype.Int2{Int16: priority, Valid: true},
 						Status:       repository.NullTaskStatus{TaskStatus: icalStatusToTaskStatus(status), Valid: true},
 					})
 				} else {
 					// Create
-					_, err = q.CreateTask(ctx, repository.CreateTaskParams{
+					var position string
+					position, err = nextTaskPosition(ctx, q, targetProjectID)
+					if err != nil {
+						return err
+					}
+					saved, err = q.CreateTask(ctx, repository.CreateTaskParams{
 						UserID:       userID,
 						ProjectID:    targetProjectID,
 						Title:        summary,
 						NoteMarkdown: toTextFromStr(description),
 						DueDate:      toTimestamp(&due),
-						Priority:     pgtype.Int2{Int16: 0, Valid: true},
+						Priority:     pgtype.Int2{Int16: priority, Valid: true},
 						CalendarID:   toUUID(&calendarID),
 						IcalUid:      toTextFromStr(uid),
 						Status:       icalStatusToTaskStatus(status),
+						Position:     position,
 					})
 				}
 				if err != nil {
 					return err
 				}
+				if err := recordTaskActivity(ctx, q, before, saved); err != nil {
+					return err
+				}
+				if err := setTaskTagsByName(ctx, q, userID, saved.ID, categories); err != nil {
+					return err
+				}
 			}
 		}
 		return nil
 	})
 }
 
-func eventToVEvent(e *repository.ScheduledEvent) *ical.Event {
+// taskTagNames はタスクIDごとのタグ名を返す
+func (u *calDavUsecase) taskTagNames(ctx context.Context, taskIDs ...uuid.UUID) (map[uuid.UUID][]string, error) {
+	rows, err := u.repo.ListTaskTagNames(ctx, taskIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task tags: %w", err)
+	}
+	names := make(map[uuid.UUID][]string)
+	for _, r := range rows {
+		names[r.TaskID] = append(names[r.TaskID], r.Name)
+	}
+	return names, nil
+}
+
+// eventTagNames は予定IDごとのタグ名を返す
+func (u *calDavUsecase) eventTagNames(ctx context.Context, eventIDs ...uuid.UUID) (map[uuid.UUID][]string, error) {
+	rows, err := u.repo.ListEventTagNames(ctx, eventIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list event tags: %w", err)
+	}
+	names := make(map[uuid.UUID][]string)
+	for _, r := range rows {
+		names[r.EventID] = append(names[r.EventID], r.Name)
+	}
+	return names, nil
+}
+
+// taskAttachments はタスクIDごとの添付ファイルを返す
+func (u *calDavUsecase) taskAttachments(ctx context.Context, taskIDs ...uuid.UUID) (map[uuid.UUID][]repository.Attachment, error) {
+	rows, err := u.repo.ListAttachmentsByTaskIDs(ctx, taskIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task attachments: %w", err)
+	}
+	atts := make(map[uuid.UUID][]repository.Attachment)
+	for _, a := range rows {
+		atts[a.TaskID.Bytes] = append(atts[a.TaskID.Bytes], a)
+	}
+	return atts, nil
+}
+
+// eventAttachments は予定IDごとの添付ファイルを返す
+func (u *calDavUsecase) eventAttachments(ctx context.Context, eventIDs ...uuid.UUID) (map[uuid.UUID][]repository.Attachment, error) {
+	rows, err := u.repo.ListAttachmentsByEventIDs(ctx, eventIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list event attachments: %w", err)
+	}
+	atts := make(map[uuid.UUID][]repository.Attachment)
+	for _, a := range rows {
+		atts[a.EventID.Bytes] = append(atts[a.EventID.Bytes], a)
+	}
+	return atts, nil
+}
+
+// setAttachments は添付ファイルをダウンロードURLの ATTACH として書き出す (中身は埋め込まない)
+func (u *calDavUsecase) setAttachments(props ical.Props, atts []repository.Attachment) {
+	for _, a := range atts {
+		prop := ical.NewProp(ical.PropAttach)
+		prop.SetValueType(ical.ValueURI)
+		prop.Value = u.publicURL + "/dav/attachments/" + a.ID.String()
+		prop.Params.Set(ical.ParamFormatType, a.ContentType)
+		prop.Params.Set("FILENAME", a.Filename)
+		props.Add(prop)
+	}
+}
+
+// icalCategories collects every CATEGORIES value of a component (the property may repeat)
+func icalCategories(props ical.Props) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, prop := range props.Values(ical.PropCategories) {
+		list, err := prop.TextList()
+		if err != nil {
+			continue
+		}
+		for _, name := range list {
+			name = strings.TrimSpace(name)
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func eventToVEvent(e *repository.ScheduledEvent, tags []string) *ical.Event {
 	event := ical.NewEvent()
 	event.Props.SetText(ical.PropUID, e.IcalUid.String)
 	event.Props.SetText(ical.PropSummary, e.Title)
@@ -334,10 +498,13 @@ func eventToVEvent(e *repository.ScheduledEvent) *ical.Event {
 	if e.Status.Valid {
 		event.Props.SetText(ical.PropStatus, e.Status.String)
 	}
+	if len(tags) > 0 {
+		setCategories(event.Props, tags)
+	}
 	return event
 }
 
-func taskToVTodo(t *repository.Task) *ical.Component {
+func taskToVTodo(t *repository.Task, tags []string) *ical.Component {
 	todo := ical.NewComponent(ical.CompToDo)
 	todo.Props.SetText(ical.PropUID, t.IcalUid.String)
 	todo.Props.SetText(ical.PropSummary, t.Title)
@@ -348,12 +515,27 @@ func taskToVTodo(t *repository.Task) *ical.Component {
 		todo.Props.SetDateTime(ical.PropDue, t.DueDate.Time)
 	}
 	todo.Props.SetText(ical.PropStatus, taskStatusToICalStatus(t.Status))
+	if p := taskPriorityToICalPriority(t.Priority); p > 0 {
+		prop := ical.NewProp(ical.PropPriority)
+		prop.SetValueType(ical.ValueInt)
+		prop.Value = strconv.Itoa(p)
+		todo.Props.Set(prop)
+	}
 	if t.CompletedAt.Valid {
 		todo.Props.SetDateTime(ical.PropCompleted, t.CompletedAt.Time)
 	}
+	if len(tags) > 0 {
+		setCategories(todo.Props, tags)
+	}
 	return todo
 }
 
+func setCategories(props ical.Props, tags []string) {
+	prop := ical.NewProp(ical.PropCategories)
+	prop.SetTextList(tags)
+	props.Set(prop)
+}
+
 func taskStatusToICalStatus(s repository.TaskStatus) string {
 	switch s {
 	case repository.TaskStatusDONE:
@@ -365,6 +547,39 @@ func taskStatusToICalStatus(s repository.TaskStatus) string {
 	}
 }
 
+// taskPriorityToICalPriority は RFC 5545 の PRIORITY (1 が最高、9 が最低、0 は未定義) に変換する
+func taskPriorityToICalPriority(p pgtype.Int2) int {
+	switch {
+	case !p.Valid:
+		return 0
+	case p.Int16 >= PriorityHigh:
+		return 1
+	case p.Int16 == PriorityMedium:
+		return 5
+	case p.Int16 == PriorityLow:
+		return 9
+	}
+	return 0
+}
+
+// icalPriorityToTaskPriority は PRIORITY を 1-4 を高、5 を中、6-9 を低として読み取る
+func icalPriorityToTaskPriority(props ical.Props) int16 {
+	prop := props.Get(ical.PropPriority)
+	if prop == nil {
+		return PriorityNone
+	}
+	n, err := prop.Int()
+	switch {
+	case err != nil || n <= 0 || n > 9:
+		return PriorityNone
+	case n <= 4:
+		return PriorityHigh
+	case n == 5:
+		return PriorityMedium
+	}
+	return PriorityLow
+}
+
 func icalStatusToTaskStatus(s string) repository.TaskStatus {
 	switch s {
 	case "COMPLETED":
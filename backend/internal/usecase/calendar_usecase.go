@@ -19,7 +19,8 @@ type CalendarUsecase interface {
 	DeleteCalendar(ctx context.Context, userID, calendarID uuid.UUID) error
 
 	CreateEvent(ctx context.Context, userID, projectID uuid.UUID, title, description, location string, startAt, endAt time.Time, isAllDay bool) (*repository.ScheduledEvent, error)
-	ListEvents(ctx context.Context, userID uuid.UUID, start, end time.Time) ([]repository.ListEventsByRangeRow, error)
+	ListEvents(ctx context.Context, userID uuid.UUID, start, end time.Time, tagID *uuid.UUID) ([]repository.ListEventsByRangeRow, error)
+	DeleteEvent(ctx context.Context, userID, eventID uuid.UUID) error
 
 	CreateTimetableSlot(ctx context.Context, userID, projectID uuid.UUID, dayOfWeek int32, start, end time.Time, location string) (*repository.TimetableSlot, error)
 	ListTimetable(ctx context.Context, userID uuid.UUID) ([]repository.ListTimetableSlotsRow, error)
@@ -72,11 +73,12 @@ func (u *calendarUsecase) CreateEvent(ctx context.Context, userID, projectID uui
 	return &event, nil
 }
 
-func (u *calendarUsecase) ListEvents(ctx context.Context, userID uuid.UUID, start, end time.Time) ([]repository.ListEventsByRangeRow, error) {
+func (u *calendarUsecase) ListEvents(ctx context.Context, userID uuid.UUID, start, end time.Time, tagID *uuid.UUID) ([]repository.ListEventsByRangeRow, error) {
 	events, err := u.repo.ListEventsByRange(ctx, repository.ListEventsByRangeParams{
 		UserID:    userID,
 		StartTime: toTimestamp(&start),
 		EndTime:   toTimestamp(&end),
+		TagID:     toUUID(tagID),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list events: %w", err)
@@ -84,6 +86,24 @@ func (u *calendarUsecase) ListEvents(ctx context.Context, userID uuid.UUID, star
 	return events, nil
 }
 
+// DeleteEvent は予定をゴミ箱に入れる
+func (u *calendarUsecase) DeleteEvent(ctx context.Context, userID, eventID uuid.UUID) error {
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		n, err := q.TrashEvent(ctx, repository.TrashEventParams{ID: eventID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return NewNotFoundError("event not found")
+		}
+		return journal(ctx, q, userID, OperationEventDelete, restoreStep(TrashKindEvent, eventID))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	return nil
+}
+
 func (u *calendarUsecase) CreateTimetableSlot(ctx context.Context, userID, projectID uuid.UUID, dayOfWeek int32, start, end time.Time, location string) (*repository.TimetableSlot, error) {
 
 	slot, err := u.repo.CreateTimetableSlot(ctx, repository.CreateTimetableSlotParams{
@@ -172,12 +192,21 @@ func (u *calendarUsecase) ListCalendars(ctx context.Context, userID uuid.UUID) (
 }
 
 func (u *calendarUsecase) DeleteCalendar(ctx context.Context, userID, calendarID uuid.UUID) error {
-	err := u.repo.DeleteCalendar(ctx, repository.DeleteCalendarParams{
-		ID:     calendarID,
-		UserID: userID,
+	// 中のタスク・予定と一緒にゴミ箱に入れる
+	return u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		_, err := q.TrashCalendar(ctx, repository.TrashCalendarParams{
+			ID:     calendarID,
+			UserID: userID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return NewNotFoundError("calendar not found")
+			}
+			return fmt.Errorf("failed to delete calendar: %w", err)
+		}
+		if err := q.TrashCalendarItems(ctx, calendarID); err != nil {
+			return err
+		}
+		return journal(ctx, q, userID, OperationCalendarDelete, restoreStep(TrashKindCalendar, calendarID))
 	})
-	if err != nil {
-		return fmt.Errorf("failed to delete calendar: %w", err)
-	}
-	return nil
 }
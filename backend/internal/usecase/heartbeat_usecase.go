@@ -0,0 +1,325 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// 1回のトランザクションで集計する heartbeat の数
+const heartbeatBatchSize = 1000
+
+// HeartbeatInput はエディタプラグインなどから受け取った作業の記録 (WakaTime の heartbeat)
+type HeartbeatInput struct {
+	Entity   string
+	Type     string // file / app / domain など。空なら file
+	Category string
+	Project  string
+	Branch   string
+	Language string
+	IsWrite  bool
+	Time     time.Time
+}
+
+// HeartbeatRuleInput は heartbeat をプロジェクトに対応づける条件。空の条件は問わない
+type HeartbeatRuleInput struct {
+	SourceProject string
+	EntityPrefix  string
+	Language      string
+	ProjectID     uuid.UUID
+	TaskID        *uuid.UUID
+	Position      int32
+}
+
+type HeartbeatUsecase interface {
+	// Ingest は heartbeat を保存し、保存した件数を返す (再送された重複は数えない)
+	Ingest(ctx context.Context, userID uuid.UUID, userAgent string, heartbeats []HeartbeatInput) (int, error)
+	CreateRule(ctx context.Context, userID uuid.UUID, input HeartbeatRuleInput) (*repository.HeartbeatRule, error)
+	ListRules(ctx context.Context, userID uuid.UUID) ([]repository.HeartbeatRule, error)
+	DeleteRule(ctx context.Context, userID, ruleID uuid.UUID) error
+	// Aggregate は未集計の heartbeat を自動生成の time_entries にまとめ、処理した件数を返す
+	Aggregate(ctx context.Context, now time.Time) (int, error)
+}
+
+type heartbeatUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewHeartbeatUsecase(repo *repository.Queries, txManager db.TxManager) HeartbeatUsecase {
+	return &heartbeatUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+func (u *heartbeatUsecase) Ingest(ctx context.Context, userID uuid.UUID, userAgent string, heartbeats []HeartbeatInput) (int, error) {
+	if r := []rune(userAgent); len(r) > 255 {
+		userAgent = string(r[:255])
+	}
+	created := 0
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		for _, hb := range heartbeats {
+			if hb.Type == "" {
+				hb.Type = "file"
+			}
+			n, err := q.CreateHeartbeat(ctx, repository.CreateHeartbeatParams{
+				UserID:    userID,
+				Entity:    hb.Entity,
+				Type:      hb.Type,
+				Category:  toTextFromStr(hb.Category),
+				Project:   toTextFromStr(hb.Project),
+				Branch:    toTextFromStr(hb.Branch),
+				Language:  toTextFromStr(hb.Language),
+				IsWrite:   hb.IsWrite,
+				UserAgent: toTextFromStr(userAgent),
+				Time:      toTimestamp(&hb.Time),
+			})
+			if err != nil {
+				return err
+			}
+			created += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save heartbeats: %w", err)
+	}
+	return created, nil
+}
+
+func (u *heartbeatUsecase) CreateRule(ctx context.Context, userID uuid.UUID, input HeartbeatRuleInput) (*repository.HeartbeatRule, error) {
+	input.SourceProject = strings.TrimSpace(input.SourceProject)
+	input.Language = strings.TrimSpace(input.Language)
+	if input.SourceProject == "" && input.EntityPrefix == "" && input.Language == "" {
+		return nil, NewBadRequestError("rule needs source_project, entity_prefix or language")
+	}
+
+	var rule repository.HeartbeatRule
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		if err := checkTimeEntryTarget(ctx, q, userID, input.ProjectID, input.TaskID); err != nil {
+			return err
+		}
+		var err error
+		rule, err = q.CreateHeartbeatRule(ctx, repository.CreateHeartbeatRuleParams{
+			UserID:        userID,
+			SourceProject: toTextFromStr(input.SourceProject),
+			EntityPrefix:  toTextFromStr(input.EntityPrefix),
+			Language:      toTextFromStr(input.Language),
+			ProjectID:     input.ProjectID,
+			TaskID:        toUUID(input.TaskID),
+			Position:      input.Position,
+		})
+		return err
+	})
+	if err != nil {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create heartbeat rule: %w", err)
+	}
+	return &rule, nil
+}
+
+func (u *heartbeatUsecase) ListRules(ctx context.Context, userID uuid.UUID) ([]repository.HeartbeatRule, error) {
+	rules, err := u.repo.ListHeartbeatRules(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list heartbeat rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (u *heartbeatUsecase) DeleteRule(ctx context.Context, userID, ruleID uuid.UUID) error {
+	n, err := u.repo.DeleteHeartbeatRule(ctx, repository.DeleteHeartbeatRuleParams{ID: ruleID, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete heartbeat rule: %w", err)
+	}
+	if n == 0 {
+		return NewNotFoundError("heartbeat rule not found")
+	}
+	return nil
+}
+
+func (u *heartbeatUsecase) Aggregate(ctx context.Context, now time.Time) (int, error) {
+	total := 0
+	for {
+		n, err := u.aggregateBatch(ctx, now)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("failed to aggregate heartbeats: %w", err)
+		}
+		if n < heartbeatBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (u *heartbeatUsecase) aggregateBatch(ctx context.Context, now time.Time) (int, error) {
+	processed := 0
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		processed = 0
+		locked, err := q.TryLockHeartbeatAggregation(ctx)
+		if err != nil || !locked {
+			return err
+		}
+		pending, err := q.ListPendingHeartbeats(ctx, heartbeatBatchSize)
+		if err != nil {
+			return err
+		}
+		// ユーザーごと・時刻順に並んでいる
+		var agg *heartbeatAggregator
+		for _, hb := range pending {
+			if agg == nil || agg.userID != hb.UserID {
+				if agg, err = newHeartbeatAggregator(ctx, q, hb.UserID); err != nil {
+					return err
+				}
+			}
+			if err := agg.add(ctx, q, hb, now); err != nil {
+				return err
+			}
+			processed++
+		}
+		return nil
+	})
+	return processed, err
+}
+
+// heartbeatAggregator は1人分の heartbeat を直前の heartbeat とつないでエントリにする。
+// 同じプロジェクト・タスクの heartbeat が idle 以内の間隔で続く間を1つのエントリとする
+type heartbeatAggregator struct {
+	userID  uuid.UUID
+	idle    time.Duration
+	rules   []repository.HeartbeatRule
+	byTitle map[string]pgtype.UUID
+}
+
+func newHeartbeatAggregator(ctx context.Context, q *repository.Queries, userID uuid.UUID) (*heartbeatAggregator, error) {
+	prefs, err := loadPreferences(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := q.ListHeartbeatRules(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &heartbeatAggregator{
+		userID:  userID,
+		idle:    time.Duration(prefs.HeartbeatIdleMinutes) * time.Minute,
+		rules:   rules,
+		byTitle: map[string]pgtype.UUID{},
+	}, nil
+}
+
+func (a *heartbeatAggregator) add(ctx context.Context, q *repository.Queries, hb repository.Heartbeat, now time.Time) error {
+	projectID, taskID, err := a.resolve(ctx, q, hb)
+	if err != nil {
+		return err
+	}
+	var entryID pgtype.UUID
+	if projectID.Valid {
+		// 遅れて届いた heartbeat も、その時刻の直前の heartbeat とつなぐ
+		prev, err := q.GetPreviousHeartbeat(ctx, repository.GetPreviousHeartbeatParams{UserID: a.userID, Time: hb.Time})
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if err == nil && prev.ProjectID == projectID && prev.TaskID == taskID && hb.Time.Time.Sub(prev.Time.Time) <= a.idle {
+			if entryID, err = a.extend(ctx, q, prev, hb); err != nil {
+				return err
+			}
+		}
+	}
+	return q.MarkHeartbeatAggregated(ctx, repository.MarkHeartbeatAggregatedParams{
+		ID:           hb.ID,
+		AggregatedAt: toTimestamp(&now),
+		ProjectID:    projectID,
+		TaskID:       taskID,
+		TimeEntryID:  entryID,
+	})
+}
+
+// extend は直前の heartbeat のエントリを hb まで延ばす。エントリがなければ2つの heartbeat の間で作る
+func (a *heartbeatAggregator) extend(ctx context.Context, q *repository.Queries, prev, hb repository.Heartbeat) (pgtype.UUID, error) {
+	if prev.TimeEntryID.Valid {
+		n, err := q.ExtendAutoTimeEntry(ctx, repository.ExtendAutoTimeEntryParams{
+			EndedAt: hb.Time,
+			ID:      prev.TimeEntryID.Bytes,
+			UserID:  a.userID,
+		})
+		if err != nil {
+			return pgtype.UUID{}, err
+		}
+		if n > 0 {
+			return prev.TimeEntryID, nil
+		}
+	}
+	if !hb.Time.Time.After(prev.Time.Time) {
+		return pgtype.UUID{}, nil
+	}
+	entry, err := q.CreateAutoTimeEntry(ctx, repository.CreateAutoTimeEntryParams{
+		UserID:    a.userID,
+		ProjectID: prev.ProjectID.Bytes,
+		TaskID:    prev.TaskID,
+		StartedAt: prev.Time,
+		EndedAt:   hb.Time,
+		Note:      toTextFromStr("Auto-generated from heartbeats"),
+	})
+	if err != nil {
+		return pgtype.UUID{}, err
+	}
+	entryID := pgtype.UUID{Bytes: entry.ID, Valid: true}
+	err = q.MarkHeartbeatAggregated(ctx, repository.MarkHeartbeatAggregatedParams{
+		ID:           prev.ID,
+		AggregatedAt: prev.AggregatedAt,
+		ProjectID:    prev.ProjectID,
+		TaskID:       prev.TaskID,
+		TimeEntryID:  entryID,
+	})
+	return entryID, err
+}
+
+// resolve はルールで heartbeat のプロジェクトを決める。合うルールがなければ同じ名前のプロジェクトを使う
+func (a *heartbeatAggregator) resolve(ctx context.Context, q *repository.Queries, hb repository.Heartbeat) (pgtype.UUID, pgtype.UUID, error) {
+	for _, rule := range a.rules {
+		if matchHeartbeatRule(rule, hb) {
+			return pgtype.UUID{Bytes: rule.ProjectID, Valid: true}, rule.TaskID, nil
+		}
+	}
+	if !hb.Project.Valid {
+		return pgtype.UUID{}, pgtype.UUID{}, nil
+	}
+	key := strings.ToLower(hb.Project.String)
+	if id, ok := a.byTitle[key]; ok {
+		return id, pgtype.UUID{}, nil
+	}
+	var projectID pgtype.UUID
+	id, err := q.GetProjectByTitle(ctx, repository.GetProjectByTitleParams{UserID: a.userID, Title: hb.Project.String})
+	if err == nil {
+		projectID = pgtype.UUID{Bytes: id, Valid: true}
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return pgtype.UUID{}, pgtype.UUID{}, err
+	}
+	a.byTitle[key] = projectID
+	return projectID, pgtype.UUID{}, nil
+}
+
+func matchHeartbeatRule(rule repository.HeartbeatRule, hb repository.Heartbeat) bool {
+	if rule.SourceProject.Valid && !(hb.Project.Valid && strings.EqualFold(hb.Project.String, rule.SourceProject.String)) {
+		return false
+	}
+	if rule.EntityPrefix.Valid && !strings.HasPrefix(hb.Entity, rule.EntityPrefix.String) {
+		return false
+	}
+	if rule.Language.Valid && !(hb.Language.Valid && strings.EqualFold(hb.Language.String, rule.Language.String)) {
+		return false
+	}
+	return true
+}
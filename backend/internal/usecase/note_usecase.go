@@ -0,0 +1,321 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/pkg/markdown"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// NoteLink は解決済み(ID あり)または未解決の wiki リンク
+type NoteLink struct {
+	markdown.WikiLink
+	ID *uuid.UUID `json:"id"`
+}
+
+type RenderedNote struct {
+	Markdown  string              `json:"markdown"`
+	HTML      string              `json:"html"`
+	Links     []NoteLink          `json:"links"`
+	TaskItems []markdown.TaskItem `json:"task_items"`
+}
+
+// Backlink は対象を参照しているタスクのノート(CommentID があればそのコメント)
+type Backlink struct {
+	TaskID       uuid.UUID             `json:"task_id"`
+	TaskTitle    string                `json:"task_title"`
+	TaskStatus   repository.TaskStatus `json:"task_status"`
+	ProjectID    uuid.UUID             `json:"project_id"`
+	ProjectTitle string                `json:"project_title"`
+	CommentID    *uuid.UUID            `json:"comment_id,omitempty"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+type NoteUsecase interface {
+	RenderTaskNote(ctx context.Context, userID, taskID uuid.UUID) (*RenderedNote, error)
+	Preview(ctx context.Context, userID uuid.UUID, src string) (*RenderedNote, error)
+	ListBacklinks(ctx context.Context, userID uuid.UUID, targetType string, targetID uuid.UUID) ([]Backlink, error)
+	// SyncChecklist はノートのタスクリスト(- [ ])をチェックリストに反映する
+	SyncChecklist(ctx context.Context, userID, taskID uuid.UUID) ([]repository.ChecklistItem, error)
+}
+
+type noteUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewNoteUsecase(repo *repository.Queries, txManager db.TxManager) NoteUsecase {
+	return &noteUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+func (u *noteUsecase) RenderTaskNote(ctx context.Context, userID, taskID uuid.UUID) (*RenderedNote, error) {
+	task, err := u.repo.GetTask(ctx, repository.GetTaskParams{ID: taskID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("task not found")
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	return u.render(ctx, userID, task.NoteMarkdown.String)
+}
+
+func (u *noteUsecase) Preview(ctx context.Context, userID uuid.UUID, src string) (*RenderedNote, error) {
+	return u.render(ctx, userID, src)
+}
+
+func (u *noteUsecase) render(ctx context.Context, userID uuid.UUID, src string) (*RenderedNote, error) {
+	links := markdown.WikiLinks(src)
+	ids, err := resolveWikiLinks(ctx, u.repo, userID, links)
+	if err != nil {
+		return nil, err
+	}
+
+	note := &RenderedNote{
+		Markdown:  src,
+		Links:     make([]NoteLink, 0, len(links)),
+		TaskItems: markdown.TaskItems(src),
+	}
+	if note.TaskItems == nil {
+		note.TaskItems = []markdown.TaskItem{}
+	}
+	for _, l := range links {
+		nl := NoteLink{WikiLink: l}
+		if id, ok := ids[wikiLinkKey(l)]; ok {
+			nl.ID = &id
+		}
+		note.Links = append(note.Links, nl)
+	}
+	note.HTML = markdown.Render(src, func(l markdown.WikiLink) (string, bool) {
+		id, ok := ids[wikiLinkKey(l)]
+		if !ok {
+			return "", false
+		}
+		return "/" + l.Kind + "s/" + id.String(), true
+	})
+	return note, nil
+}
+
+func (u *noteUsecase) ListBacklinks(ctx context.Context, userID uuid.UUID, targetType string, targetID uuid.UUID) ([]Backlink, error) {
+	// 未解決のリンクを照合するため対象の現在のタイトルを取る
+	var title string
+	switch targetType {
+	case markdown.KindTask:
+		task, err := u.repo.GetTask(ctx, repository.GetTaskParams{ID: targetID, UserID: userID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, NewNotFoundError("task not found")
+			}
+			return nil, fmt.Errorf("failed to get task: %w", err)
+		}
+		title = task.Title
+	case markdown.KindProject:
+		project, err := u.repo.GetProject(ctx, repository.GetProjectParams{ID: targetID, UserID: userID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, NewNotFoundError("project not found")
+			}
+			return nil, fmt.Errorf("failed to get project: %w", err)
+		}
+		title = project.Title
+	default:
+		return nil, NewBadRequestError("invalid backlink target")
+	}
+
+	rows, err := u.repo.ListBacklinks(ctx, repository.ListBacklinksParams{
+		UserID:      userID,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		TargetTitle: title,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backlinks: %w", err)
+	}
+	backlinks := make([]Backlink, 0, len(rows))
+	for _, r := range rows {
+		b := Backlink{
+			TaskID:       r.TaskID,
+			TaskTitle:    r.TaskTitle,
+			TaskStatus:   r.TaskStatus,
+			ProjectID:    r.ProjectID,
+			ProjectTitle: r.ProjectTitle,
+			UpdatedAt:    r.UpdatedAt.Time,
+		}
+		if r.CommentID.Valid {
+			b.CommentID = ptr(uuid.UUID(r.CommentID.Bytes))
+		}
+		backlinks = append(backlinks, b)
+	}
+	return backlinks, nil
+}
+
+func (u *noteUsecase) SyncChecklist(ctx context.Context, userID, taskID uuid.UUID) ([]repository.ChecklistItem, error) {
+	var items []repository.ChecklistItem
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		task, err := q.GetTask(ctx, repository.GetTaskParams{ID: taskID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		items, err = syncChecklistFromNote(ctx, q, task)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("task not found")
+		}
+		return nil, fmt.Errorf("failed to sync checklist: %w", err)
+	}
+	return items, nil
+}
+
+func wikiLinkKey(l markdown.WikiLink) string {
+	return l.Kind + ":" + strings.ToLower(l.Target)
+}
+
+// resolveWikiLinks はリンクをタイトルの一致(大文字小文字は無視)で ID に解決する
+func resolveWikiLinks(ctx context.Context, q *repository.Queries, userID uuid.UUID, links []markdown.WikiLink) (map[string]uuid.UUID, error) {
+	ids := map[string]uuid.UUID{}
+	var taskTitles, projectTitles []string
+	for _, l := range links {
+		if l.Kind == markdown.KindProject {
+			projectTitles = append(projectTitles, strings.ToLower(l.Target))
+		} else {
+			taskTitles = append(taskTitles, strings.ToLower(l.Target))
+		}
+	}
+	// 同じタイトルが複数あれば先頭(優先度の高いもの)を使う
+	if len(taskTitles) > 0 {
+		rows, err := q.ListTasksByTitles(ctx, repository.ListTasksByTitlesParams{UserID: userID, Titles: taskTitles})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve task links: %w", err)
+		}
+		for _, r := range rows {
+			key := markdown.KindTask + ":" + strings.ToLower(r.Title)
+			if _, ok := ids[key]; !ok {
+				ids[key] = r.ID
+			}
+		}
+	}
+	if len(projectTitles) > 0 {
+		rows, err := q.ListProjectsByTitles(ctx, repository.ListProjectsByTitlesParams{UserID: userID, Titles: projectTitles})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve project links: %w", err)
+		}
+		for _, r := range rows {
+			key := markdown.KindProject + ":" + strings.ToLower(r.Title)
+			if _, ok := ids[key]; !ok {
+				ids[key] = r.ID
+			}
+		}
+	}
+	return ids, nil
+}
+
+// syncNoteLinks はノートの wiki リンクを note_links に登録し直す(comment が nil ならタスク本体のノート)
+func syncNoteLinks(ctx context.Context, q *repository.Queries, task repository.Task, comment *repository.TaskComment) error {
+	src := task.NoteMarkdown.String
+	var commentID pgtype.UUID
+	if comment != nil {
+		src = comment.BodyMarkdown
+		commentID = pgtype.UUID{Bytes: comment.ID, Valid: true}
+		if err := q.DeleteCommentNoteLinks(ctx, commentID); err != nil {
+			return err
+		}
+	} else if err := q.DeleteTaskNoteLinks(ctx, task.ID); err != nil {
+		return err
+	}
+
+	links := markdown.WikiLinks(src)
+	if len(links) == 0 {
+		return nil
+	}
+	ids, err := resolveWikiLinks(ctx, q, task.UserID, links)
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		var targetID pgtype.UUID
+		if id, ok := ids[wikiLinkKey(l)]; ok {
+			targetID = pgtype.UUID{Bytes: id, Valid: true}
+		}
+		text := l.Target
+		if r := []rune(text); len(r) > 255 {
+			text = string(r[:255])
+		}
+		if err := q.CreateNoteLink(ctx, repository.CreateNoteLinkParams{
+			UserID:     task.UserID,
+			TaskID:     task.ID,
+			CommentID:  commentID,
+			TargetType: l.Kind,
+			TargetID:   targetID,
+			TargetText: text,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncChecklistFromNote はノートのタスクリストの項目を内容の一致でチェックリストに対応付け、
+// 完了状態を揃え、ないものを追加する。ノートにない既存の項目は消さない。
+func syncChecklistFromNote(ctx context.Context, q *repository.Queries, task repository.Task) ([]repository.ChecklistItem, error) {
+	existing, err := q.ListChecklistItems(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	used := make([]bool, len(existing))
+	for _, item := range markdown.TaskItems(task.NoteMarkdown.String) {
+		if item.Text == "" {
+			continue
+		}
+		matched := false
+		for i, e := range existing {
+			if used[i] || e.Content != item.Text {
+				continue
+			}
+			used[i], matched = true, true
+			if e.IsCompleted != item.Done {
+				updated, err := q.UpdateChecklistItem(ctx, repository.UpdateChecklistItemParams{
+					ID:          e.ID,
+					IsCompleted: pgtype.Bool{Bool: item.Done, Valid: true},
+				})
+				if err != nil {
+					return nil, err
+				}
+				existing[i] = updated
+			}
+			break
+		}
+		if matched {
+			continue
+		}
+		created, err := q.CreateChecklistItem(ctx, repository.CreateChecklistItemParams{
+			TaskID:  task.ID,
+			Content: item.Text,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if item.Done {
+			if created, err = q.UpdateChecklistItem(ctx, repository.UpdateChecklistItemParams{
+				ID:          created.ID,
+				IsCompleted: pgtype.Bool{Bool: true, Valid: true},
+			}); err != nil {
+				return nil, err
+			}
+		}
+		existing = append(existing, created)
+		used = append(used, true)
+	}
+	return existing, nil
+}
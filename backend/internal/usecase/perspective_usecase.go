@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/pkg/filter"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PerspectiveUsecase は名前付きで保存したタスクのフィルタ式を扱う
+type PerspectiveUsecase interface {
+	CreatePerspective(ctx context.Context, userID uuid.UUID, name, filterExpr string) (*repository.Perspective, error)
+	ListPerspectives(ctx context.Context, userID uuid.UUID) ([]repository.Perspective, error)
+	UpdatePerspective(ctx context.Context, userID, perspectiveID uuid.UUID, name, filterExpr *string) (*repository.Perspective, error)
+	DeletePerspective(ctx context.Context, userID, perspectiveID uuid.UUID) error
+}
+
+type perspectiveUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewPerspectiveUsecase(repo *repository.Queries, txManager db.TxManager) PerspectiveUsecase {
+	return &perspectiveUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+func (u *perspectiveUsecase) CreatePerspective(ctx context.Context, userID uuid.UUID, name, filterExpr string) (*repository.Perspective, error) {
+	if _, err := parseTaskFilter(filterExpr); err != nil {
+		return nil, err
+	}
+	p, err := u.repo.CreatePerspective(ctx, repository.CreatePerspectiveParams{
+		UserID: userID,
+		Name:   name,
+		Filter: strings.TrimSpace(filterExpr),
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, NewConflictError("perspective already exists")
+		}
+		return nil, fmt.Errorf("failed to create perspective: %w", err)
+	}
+	return &p, nil
+}
+
+func (u *perspectiveUsecase) ListPerspectives(ctx context.Context, userID uuid.UUID) ([]repository.Perspective, error) {
+	perspectives, err := u.repo.ListPerspectives(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list perspectives: %w", err)
+	}
+	return perspectives, nil
+}
+
+func (u *perspectiveUsecase) UpdatePerspective(ctx context.Context, userID, perspectiveID uuid.UUID, name, filterExpr *string) (*repository.Perspective, error) {
+	var argFilter pgtype.Text
+	if filterExpr != nil {
+		if _, err := parseTaskFilter(*filterExpr); err != nil {
+			return nil, err
+		}
+		argFilter = toTextFromStr(strings.TrimSpace(*filterExpr))
+	}
+	p, err := u.repo.UpdatePerspective(ctx, repository.UpdatePerspectiveParams{
+		ID:     perspectiveID,
+		UserID: userID,
+		Name:   toText(name),
+		Filter: argFilter,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("perspective not found")
+		}
+		if isUniqueViolation(err) {
+			return nil, NewConflictError("perspective already exists")
+		}
+		return nil, fmt.Errorf("failed to update perspective: %w", err)
+	}
+	return &p, nil
+}
+
+func (u *perspectiveUsecase) DeletePerspective(ctx context.Context, userID, perspectiveID uuid.UUID) error {
+	n, err := u.repo.DeletePerspective(ctx, repository.DeletePerspectiveParams{ID: perspectiveID, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete perspective: %w", err)
+	}
+	if n == 0 {
+		return NewNotFoundError("perspective not found")
+	}
+	return nil
+}
+
+// parseTaskFilter はフィルタ式を解析し、タスクの条件として使えるか検証する
+func parseTaskFilter(src string) (filter.Expr, error) {
+	expr, err := filter.Parse(src)
+	if err == nil {
+		err = repository.CompileTaskFilter(expr)
+	}
+	var fe *filter.Error
+	if errors.As(err, &fe) {
+		return nil, NewBadRequestError(fe.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
@@ -0,0 +1,364 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ポモドーロのフェーズ
+const (
+	PomodoroWork       = "work"
+	PomodoroShortBreak = "short_break"
+	PomodoroLongBreak  = "long_break"
+)
+
+// PomodoroState は端末間で同じカウントダウンを表示するための状態。
+// 残り時間は server_time 時点の値
+type PomodoroState struct {
+	ID                 uuid.UUID  `json:"id"`
+	ProjectID          uuid.UUID  `json:"project_id"`
+	TaskID             *uuid.UUID `json:"task_id,omitempty"`
+	Phase              string     `json:"phase"`
+	PhaseSeconds       int32      `json:"phase_seconds"`
+	RemainingSeconds   int64      `json:"remaining_seconds"`
+	PhaseEndsAt        *time.Time `json:"phase_ends_at,omitempty"` // 一時停止中は nil
+	Paused             bool       `json:"paused"`
+	CompletedPomodoros int32      `json:"completed_pomodoros"`
+	Interruptions      int32      `json:"interruptions"`
+	TimeEntryID        *uuid.UUID `json:"time_entry_id,omitempty"`
+	Ended              bool       `json:"ended"`
+	ServerTime         time.Time  `json:"server_time"`
+}
+
+type PomodoroUsecase interface {
+	// Start は設定の長さで新しいセッションを作業フェーズから始める
+	Start(ctx context.Context, userID, projectID uuid.UUID, taskID *uuid.UUID, note string) (*PomodoroState, error)
+	// Get は進行中のセッションの状態を返す。なければ nil
+	Get(ctx context.Context, userID uuid.UUID) (*PomodoroState, error)
+	Pause(ctx context.Context, userID uuid.UUID) (*PomodoroState, error)
+	Resume(ctx context.Context, userID uuid.UUID) (*PomodoroState, error)
+	// Skip は現在のフェーズを終えて次のフェーズに進む。途中で終えた作業は完了数に数えない
+	Skip(ctx context.Context, userID uuid.UUID) (*PomodoroState, error)
+	// Interrupt は中断の回数を数える
+	Interrupt(ctx context.Context, userID uuid.UUID) (*PomodoroState, error)
+	// Stop はセッションを終了する
+	Stop(ctx context.Context, userID uuid.UUID) (*PomodoroState, error)
+	// AdvanceDue はフェーズの終わりを過ぎたセッションを進め、進めた件数を返す (フェーズの切り替えを通知するため)
+	AdvanceDue(ctx context.Context, now time.Time) (int, error)
+}
+
+type pomodoroUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewPomodoroUsecase(repo *repository.Queries, txManager db.TxManager) PomodoroUsecase {
+	return &pomodoroUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+var errNoPomodoro = NewNotFoundError("no active pomodoro session")
+
+func (u *pomodoroUsecase) Start(ctx context.Context, userID, projectID uuid.UUID, taskID *uuid.UUID, note string) (*PomodoroState, error) {
+	now := time.Now()
+	var session repository.PomodoroSession
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		if _, err := q.GetActivePomodoroSession(ctx, userID); err == nil {
+			return NewConflictError("a pomodoro session is already running")
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if err := checkTimeEntryTarget(ctx, q, userID, projectID, taskID); err != nil {
+			return err
+		}
+		prefs, err := loadPreferences(ctx, q, userID)
+		if err != nil {
+			return err
+		}
+
+		arg := repository.CreatePomodoroSessionParams{
+			UserID:            userID,
+			ProjectID:         projectID,
+			TaskID:            toUUID(taskID),
+			Note:              toTextFromStr(note),
+			WorkSeconds:       int32(prefs.PomodoroWorkMinutes * 60),
+			ShortBreakSeconds: int32(prefs.PomodoroShortBreakMinutes * 60),
+			LongBreakSeconds:  int32(prefs.PomodoroLongBreakMinutes * 60),
+			LongBreakEvery:    int32(prefs.PomodoroLongBreakEvery),
+			Phase:             PomodoroWork,
+			PhaseStartedAt:    toTimestamp(&now),
+			PhaseSeconds:      int32(prefs.PomodoroWorkMinutes * 60),
+		}
+		if arg.TimeEntryID, err = startPomodoroWork(ctx, q, userID, arg.ProjectID, arg.TaskID, arg.Note, now); err != nil {
+			return err
+		}
+		session, err = q.CreatePomodoroSession(ctx, arg)
+		if isUniqueViolation(err) {
+			return NewConflictError("a pomodoro session is already running")
+		}
+		return err
+	})
+	if err != nil {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to start pomodoro: %w", err)
+	}
+	return pomodoroState(session, now), nil
+}
+
+func (u *pomodoroUsecase) Get(ctx context.Context, userID uuid.UUID) (*PomodoroState, error) {
+	state, err := u.update(ctx, userID, nil)
+	if errors.Is(err, errNoPomodoro) {
+		return nil, nil
+	}
+	return state, err
+}
+
+func (u *pomodoroUsecase) Pause(ctx context.Context, userID uuid.UUID) (*PomodoroState, error) {
+	return u.update(ctx, userID, func(q *repository.Queries, s *repository.PomodoroSession, now time.Time) error {
+		if s.PausedAt.Valid {
+			return NewConflictError("pomodoro is already paused")
+		}
+		s.PausedAt = toTimestamp(&now)
+		// 止めている間は作業時間に数えない
+		return finishPomodoroWork(ctx, q, userID, s, now)
+	})
+}
+
+func (u *pomodoroUsecase) Resume(ctx context.Context, userID uuid.UUID) (*PomodoroState, error) {
+	return u.update(ctx, userID, func(q *repository.Queries, s *repository.PomodoroSession, now time.Time) error {
+		if !s.PausedAt.Valid {
+			return NewConflictError("pomodoro is not paused")
+		}
+		s.PausedSeconds += int32(now.Sub(s.PausedAt.Time).Seconds())
+		s.PausedAt = pgtype.Timestamptz{}
+		if s.Phase != PomodoroWork {
+			return nil
+		}
+		var err error
+		s.TimeEntryID, err = startPomodoroWork(ctx, q, userID, s.ProjectID, s.TaskID, s.Note, now)
+		return err
+	})
+}
+
+func (u *pomodoroUsecase) Skip(ctx context.Context, userID uuid.UUID) (*PomodoroState, error) {
+	return u.update(ctx, userID, func(q *repository.Queries, s *repository.PomodoroSession, now time.Time) error {
+		if s.Phase == PomodoroWork {
+			if err := finishPomodoroWork(ctx, q, userID, s, now); err != nil {
+				return err
+			}
+			enterPomodoroPhase(s, PomodoroShortBreak, now)
+			return nil
+		}
+		enterPomodoroPhase(s, PomodoroWork, now)
+		var err error
+		s.TimeEntryID, err = startPomodoroWork(ctx, q, userID, s.ProjectID, s.TaskID, s.Note, now)
+		return err
+	})
+}
+
+func (u *pomodoroUsecase) Interrupt(ctx context.Context, userID uuid.UUID) (*PomodoroState, error) {
+	return u.update(ctx, userID, func(q *repository.Queries, s *repository.PomodoroSession, now time.Time) error {
+		s.Interruptions++
+		return nil
+	})
+}
+
+func (u *pomodoroUsecase) Stop(ctx context.Context, userID uuid.UUID) (*PomodoroState, error) {
+	return u.update(ctx, userID, func(q *repository.Queries, s *repository.PomodoroSession, now time.Time) error {
+		s.EndedAt = toTimestamp(&now)
+		return finishPomodoroWork(ctx, q, userID, s, now)
+	})
+}
+
+func (u *pomodoroUsecase) AdvanceDue(ctx context.Context, now time.Time) (int, error) {
+	userIDs, err := u.repo.ListDuePomodoroSessions(ctx, toTimestamp(&now))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due pomodoro sessions: %w", err)
+	}
+	advanced := 0
+	for _, userID := range userIDs {
+		if _, err := u.update(ctx, userID, nil); err != nil {
+			// 同時に終了されたもの
+			if errors.Is(err, errNoPomodoro) {
+				continue
+			}
+			return advanced, err
+		}
+		advanced++
+	}
+	return advanced, nil
+}
+
+// update は進行中のセッションを現在時刻まで進めてから fn を適用し、保存する
+func (u *pomodoroUsecase) update(ctx context.Context, userID uuid.UUID, fn func(q *repository.Queries, s *repository.PomodoroSession, now time.Time) error) (*PomodoroState, error) {
+	now := time.Now()
+	var session repository.PomodoroSession
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		s, err := q.GetActivePomodoroSession(ctx, userID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errNoPomodoro
+			}
+			return err
+		}
+		if err := advancePomodoro(ctx, q, userID, &s, now); err != nil {
+			return err
+		}
+		if fn != nil {
+			if err := fn(q, &s, now); err != nil {
+				return err
+			}
+		}
+		session, err = q.UpdatePomodoroSession(ctx, repository.UpdatePomodoroSessionParams{
+			ID:                 s.ID,
+			Phase:              s.Phase,
+			PhaseStartedAt:     s.PhaseStartedAt,
+			PhaseSeconds:       s.PhaseSeconds,
+			PausedAt:           s.PausedAt,
+			PausedSeconds:      s.PausedSeconds,
+			CompletedPomodoros: s.CompletedPomodoros,
+			Interruptions:      s.Interruptions,
+			TimeEntryID:        s.TimeEntryID,
+			EndedAt:            s.EndedAt,
+		})
+		return err
+	})
+	if err != nil {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update pomodoro: %w", err)
+	}
+	return pomodoroState(session, now), nil
+}
+
+// advancePomodoro は now までに終わったフェーズを順に進める。
+// 作業の終わりで休憩に入り、休憩の終わりでは次の作業を一時停止した状態で待つ
+func advancePomodoro(ctx context.Context, q *repository.Queries, userID uuid.UUID, s *repository.PomodoroSession, now time.Time) error {
+	for !s.PausedAt.Valid {
+		end := pomodoroPhaseEnd(*s)
+		if now.Before(end) {
+			return nil
+		}
+		if s.Phase != PomodoroWork {
+			enterPomodoroPhase(s, PomodoroWork, end)
+			s.PausedAt = toTimestamp(&end)
+			return nil
+		}
+		if err := finishPomodoroWork(ctx, q, userID, s, end); err != nil {
+			return err
+		}
+		s.CompletedPomodoros++
+		next := PomodoroShortBreak
+		if s.CompletedPomodoros%s.LongBreakEvery == 0 {
+			next = PomodoroLongBreak
+		}
+		enterPomodoroPhase(s, next, end)
+	}
+	return nil
+}
+
+func enterPomodoroPhase(s *repository.PomodoroSession, phase string, at time.Time) {
+	s.Phase = phase
+	s.PhaseStartedAt = toTimestamp(&at)
+	s.PausedAt = pgtype.Timestamptz{}
+	s.PausedSeconds = 0
+	switch phase {
+	case PomodoroWork:
+		s.PhaseSeconds = s.WorkSeconds
+	case PomodoroShortBreak:
+		s.PhaseSeconds = s.ShortBreakSeconds
+	case PomodoroLongBreak:
+		s.PhaseSeconds = s.LongBreakSeconds
+	}
+}
+
+func pomodoroPhaseEnd(s repository.PomodoroSession) time.Time {
+	return s.PhaseStartedAt.Time.Add(time.Duration(s.PhaseSeconds+s.PausedSeconds) * time.Second)
+}
+
+// startPomodoroWork は作業フェーズのエントリを開始する。計測中のタイマーは設定に従って拒否するか止める
+func startPomodoroWork(ctx context.Context, q *repository.Queries, userID, projectID uuid.UUID, taskID pgtype.UUID, note pgtype.Text, at time.Time) (pgtype.UUID, error) {
+	if err := checkTimerConflict(ctx, q, userID); err != nil {
+		return pgtype.UUID{}, err
+	}
+	if _, err := q.StopRunningTimeEntry(ctx, repository.StopRunningTimeEntryParams{UserID: userID, EndedAt: toTimestamp(&at)}); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return pgtype.UUID{}, err
+	}
+	entry, err := q.CreateTimeEntry(ctx, repository.CreateTimeEntryParams{
+		UserID:    userID,
+		ProjectID: projectID,
+		TaskID:    taskID,
+		StartedAt: toTimestamp(&at),
+		Note:      note,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return pgtype.UUID{}, NewConflictError("another timer is running")
+		}
+		return pgtype.UUID{}, err
+	}
+	return toUUID(&entry.ID), nil
+}
+
+// finishPomodoroWork は作業フェーズのエントリを at で止める
+func finishPomodoroWork(ctx context.Context, q *repository.Queries, userID uuid.UUID, s *repository.PomodoroSession, at time.Time) error {
+	if !s.TimeEntryID.Valid {
+		return nil
+	}
+	if _, err := q.FinishTimeEntry(ctx, repository.FinishTimeEntryParams{
+		ID:      s.TimeEntryID.Bytes,
+		UserID:  userID,
+		EndedAt: toTimestamp(&at),
+	}); err != nil {
+		return err
+	}
+	s.TimeEntryID = pgtype.UUID{}
+	return nil
+}
+
+func pomodoroState(s repository.PomodoroSession, now time.Time) *PomodoroState {
+	state := &PomodoroState{
+		ID:                 s.ID,
+		ProjectID:          s.ProjectID,
+		Phase:              s.Phase,
+		PhaseSeconds:       s.PhaseSeconds,
+		Paused:             s.PausedAt.Valid,
+		CompletedPomodoros: s.CompletedPomodoros,
+		Interruptions:      s.Interruptions,
+		Ended:              s.EndedAt.Valid,
+		ServerTime:         now,
+	}
+	if s.TaskID.Valid {
+		state.TaskID = ptr(uuid.UUID(s.TaskID.Bytes))
+	}
+	if s.TimeEntryID.Valid {
+		state.TimeEntryID = ptr(uuid.UUID(s.TimeEntryID.Bytes))
+	}
+
+	end := pomodoroPhaseEnd(s)
+	if s.PausedAt.Valid {
+		// 止めた時点の残り時間のまま
+		end = end.Add(now.Sub(s.PausedAt.Time))
+	} else if !state.Ended {
+		state.PhaseEndsAt = &end
+	}
+	if !state.Ended {
+		state.RemainingSeconds = max(int64(end.Sub(now).Seconds()), 0)
+	}
+	return state
+}
@@ -2,11 +2,13 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
 	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -15,6 +17,13 @@ type ProjectUsecase interface {
 	ListCategories(ctx context.Context, userID uuid.UUID) ([]repository.Category, error)
 	CreateProject(ctx context.Context, userID, categoryID uuid.UUID, title, description, color string) (*repository.Project, error)
 	ListProjects(ctx context.Context, userID uuid.UUID, isArchived *bool) ([]repository.ListProjectsRow, error)
+	UpdateProject(ctx context.Context, userID, projectID uuid.UUID, title, description, color *string, isArchived *bool, wipLimit *int32) (*repository.Project, error)
+	DeleteProject(ctx context.Context, userID, projectID uuid.UUID) error
+
+	CreateProjectStatus(ctx context.Context, userID, projectID uuid.UUID, name string, category repository.TaskStatus, color string) (*repository.ProjectStatus, error)
+	ListProjectStatuses(ctx context.Context, userID, projectID uuid.UUID) ([]repository.ProjectStatus, error)
+	UpdateProjectStatus(ctx context.Context, userID, statusID uuid.UUID, name, color *string, position *int32) (*repository.ProjectStatus, error)
+	DeleteProjectStatus(ctx context.Context, userID, statusID uuid.UUID) error
 }
 
 type projectUsecase struct {
@@ -94,3 +103,118 @@ func (u *projectUsecase) ListProjects(ctx context.Context, userID uuid.UUID, isA
 	}
 	return projects, nil
 }
+
+// UpdateProject は指定された項目だけを更新する(wipLimit に 0 を渡すとWIP制限を解除)
+func (u *projectUsecase) UpdateProject(ctx context.Context, userID, projectID uuid.UUID, title, description, color *string, isArchived *bool, wipLimit *int32) (*repository.Project, error) {
+	arg := repository.UpdateProjectParams{
+		ID:          projectID,
+		UserID:      userID,
+		Title:       toText(title),
+		Description: toText(description),
+		Color:       toText(color),
+	}
+	if isArchived != nil {
+		arg.IsArchived = pgtype.Bool{Bool: *isArchived, Valid: true}
+	}
+	if wipLimit != nil {
+		arg.WipLimit = pgtype.Int4{Int32: *wipLimit, Valid: true}
+	}
+
+	project, err := u.repo.UpdateProject(ctx, arg)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("project not found")
+		}
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+	return &project, nil
+}
+
+// DeleteProject は中のタスク・予定・実績と一緒にプロジェクトをゴミ箱に入れる
+func (u *projectUsecase) DeleteProject(ctx context.Context, userID, projectID uuid.UUID) error {
+	return u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		_, err := q.TrashProject(ctx, repository.TrashProjectParams{ID: projectID, UserID: userID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return NewNotFoundError("project not found")
+			}
+			return fmt.Errorf("failed to delete project: %w", err)
+		}
+		if err := q.TrashProjectItems(ctx, projectID); err != nil {
+			return err
+		}
+		return journal(ctx, q, userID, OperationProjectDelete, restoreStep(TrashKindProject, projectID))
+	})
+}
+
+// --- Project Status (ワークフロー) ---
+
+func (u *projectUsecase) CreateProjectStatus(ctx context.Context, userID, projectID uuid.UUID, name string, category repository.TaskStatus, color string) (*repository.ProjectStatus, error) {
+	status, err := u.repo.CreateProjectStatus(ctx, repository.CreateProjectStatusParams{
+		Name:      name,
+		Category:  category,
+		Color:     toTextFromStr(color),
+		ProjectID: projectID,
+		UserID:    userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("project not found")
+		}
+		if isUniqueViolation(err) {
+			return nil, NewConflictError("status already exists")
+		}
+		return nil, fmt.Errorf("failed to create project status: %w", err)
+	}
+	return &status, nil
+}
+
+func (u *projectUsecase) ListProjectStatuses(ctx context.Context, userID, projectID uuid.UUID) ([]repository.ProjectStatus, error) {
+	statuses, err := u.repo.ListProjectStatuses(ctx, repository.ListProjectStatusesParams{
+		ProjectID: projectID,
+		UserID:    userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project statuses: %w", err)
+	}
+	return statuses, nil
+}
+
+// UpdateProjectStatus は名前・色・並び順のみ変更できる(基本カテゴリは作成後に変更しない)
+func (u *projectUsecase) UpdateProjectStatus(ctx context.Context, userID, statusID uuid.UUID, name, color *string, position *int32) (*repository.ProjectStatus, error) {
+	arg := repository.UpdateProjectStatusParams{
+		ID:     statusID,
+		UserID: userID,
+		Name:   toText(name),
+		Color:  toText(color),
+	}
+	if position != nil {
+		arg.Position = pgtype.Int4{Int32: *position, Valid: true}
+	}
+
+	status, err := u.repo.UpdateProjectStatus(ctx, arg)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("status not found")
+		}
+		if isUniqueViolation(err) {
+			return nil, NewConflictError("status already exists")
+		}
+		return nil, fmt.Errorf("failed to update project status: %w", err)
+	}
+	return &status, nil
+}
+
+func (u *projectUsecase) DeleteProjectStatus(ctx context.Context, userID, statusID uuid.UUID) error {
+	n, err := u.repo.DeleteProjectStatus(ctx, repository.DeleteProjectStatusParams{
+		ID:     statusID,
+		UserID: userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete project status: %w", err)
+	}
+	if n == 0 {
+		return NewNotFoundError("status not found")
+	}
+	return nil
+}
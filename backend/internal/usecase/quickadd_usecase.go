@@ -0,0 +1,309 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/pkg/quickadd"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// 時刻の範囲がない予定の長さ
+const quickAddEventDuration = time.Hour
+
+type QuickAddOptions struct {
+	Kind     *quickadd.Kind // 指定がなければ解析結果に従う
+	Location *time.Location // 指定がなければユーザー設定のタイムゾーン
+	Preview  bool           // true の場合は解釈結果だけを返し、作成しない
+}
+
+type QuickAddTag struct {
+	Name string     `json:"name"`
+	ID   *uuid.UUID `json:"id,omitempty"`
+	New  bool       `json:"new"` // 作成時に新しく作られるタグ
+}
+
+// QuickAddResult は入力の解釈結果。プレビューでなければ作成したタスク・予定を含む
+type QuickAddResult struct {
+	Kind         quickadd.Kind `json:"kind"`
+	Title        string        `json:"title"`
+	ProjectID    *uuid.UUID    `json:"project_id"`
+	ProjectTitle string        `json:"project_title,omitempty"`
+	DueDate      *time.Time    `json:"due_date,omitempty"`
+	StartAt      *time.Time    `json:"start_at,omitempty"`
+	EndAt        *time.Time    `json:"end_at,omitempty"`
+	IsAllDay     bool          `json:"is_all_day,omitempty"`
+	Priority     int16         `json:"priority"`
+	Tags         []QuickAddTag `json:"tags"`
+	Warnings     []string      `json:"warnings"`
+	Preview      bool          `json:"preview"`
+
+	Task  *repository.Task           `json:"task,omitempty"`
+	Event *repository.ScheduledEvent `json:"event,omitempty"`
+}
+
+type QuickAddUsecase interface {
+	QuickAdd(ctx context.Context, userID uuid.UUID, text string, opts QuickAddOptions) (*QuickAddResult, error)
+}
+
+type quickAddUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewQuickAddUsecase(repo *repository.Queries, txManager db.TxManager) QuickAddUsecase {
+	return &quickAddUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+func (u *quickAddUsecase) QuickAdd(ctx context.Context, userID uuid.UUID, text string, opts QuickAddOptions) (*QuickAddResult, error) {
+	loc := opts.Location
+	if loc == nil {
+		prefs, err := loadPreferences(ctx, u.repo, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load preferences: %w", err)
+		}
+		loc = prefs.Location()
+	}
+	parsed := quickadd.Parse(text, time.Now().In(loc))
+
+	res := &QuickAddResult{
+		Kind:     parsed.Kind,
+		Title:    parsed.Title,
+		Tags:     []QuickAddTag{},
+		Warnings: []string{},
+		Preview:  opts.Preview,
+	}
+	if opts.Kind != nil {
+		res.Kind = *opts.Kind
+	}
+	if parsed.Priority != nil {
+		res.Priority = *parsed.Priority
+	}
+	u.applySchedule(res, parsed)
+
+	if err := u.resolveProject(ctx, userID, parsed.Project, res); err != nil {
+		return nil, err
+	}
+	if err := u.resolveTags(ctx, userID, parsed.Tags, res); err != nil {
+		return nil, err
+	}
+
+	if res.Title == "" {
+		res.Warnings = append(res.Warnings, "title is empty")
+	}
+	if opts.Preview {
+		return res, nil
+	}
+
+	if res.Title == "" {
+		return nil, NewBadRequestError("title is required")
+	}
+	if res.ProjectID == nil {
+		return nil, NewBadRequestError("project could not be resolved")
+	}
+	if res.Kind == quickadd.KindEvent && res.StartAt == nil {
+		return nil, NewBadRequestError("event requires a date or time")
+	}
+	for _, t := range res.Tags {
+		if utf8.RuneCountInString(t.Name) > 50 {
+			return nil, NewBadRequestError("tag name is too long: " + t.Name)
+		}
+	}
+
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		if res.Kind == quickadd.KindEvent {
+			return u.createEvent(ctx, q, userID, res)
+		}
+		return u.createTask(ctx, q, userID, res)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to quick-add: %w", err)
+	}
+	return res, nil
+}
+
+// applySchedule は解析した日時を種別に応じた期限・開始終了に振り分ける
+func (u *quickAddUsecase) applySchedule(res *QuickAddResult, parsed quickadd.Result) {
+	if parsed.Start == nil {
+		return
+	}
+	start := *parsed.Start
+	if res.Kind == quickadd.KindTask {
+		// 日付のみの期限はその日の終わり
+		if !parsed.HasTime {
+			start = start.Add(24*time.Hour - time.Minute)
+		}
+		res.DueDate = &start
+		return
+	}
+
+	end := start.Add(quickAddEventDuration)
+	switch {
+	case parsed.End != nil:
+		end = *parsed.End
+	case !parsed.HasTime:
+		end = start.AddDate(0, 0, 1)
+		res.IsAllDay = true
+	}
+	res.StartAt = &start
+	res.EndAt = &end
+}
+
+// resolveProject は @名前 をプロジェクトのタイトルで解決する。
+// 完全一致(大文字小文字は無視)を優先し、なければ前方一致が1件だけの場合に採用する。
+// 指定がなければ既定のプロジェクト。
+func (u *quickAddUsecase) resolveProject(ctx context.Context, userID uuid.UUID, name string, res *QuickAddResult) error {
+	if name == "" {
+		p, err := u.repo.GetDefaultProject(ctx, userID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				res.Warnings = append(res.Warnings, "no project found for user")
+				return nil
+			}
+			return fmt.Errorf("failed to get default project: %w", err)
+		}
+		res.ProjectID = &p.ID
+		res.ProjectTitle = p.Title
+		return nil
+	}
+
+	projects, err := u.repo.ListProjects(ctx, repository.ListProjectsParams{
+		UserID:     userID,
+		IsArchived: pgtype.Bool{Bool: false, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list projects: %w", err)
+	}
+	var prefix []repository.ListProjectsRow
+	for _, p := range projects {
+		if strings.EqualFold(p.Title, name) {
+			res.ProjectID = &p.ID
+			res.ProjectTitle = p.Title
+			return nil
+		}
+		if strings.HasPrefix(strings.ToLower(p.Title), strings.ToLower(name)) {
+			prefix = append(prefix, p)
+		}
+	}
+	if len(prefix) == 1 {
+		res.ProjectID = &prefix[0].ID
+		res.ProjectTitle = prefix[0].Title
+		return nil
+	}
+	if len(prefix) > 1 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("project %q is ambiguous", name))
+		return nil
+	}
+	res.Warnings = append(res.Warnings, fmt.Sprintf("project %q not found", name))
+	return nil
+}
+
+func (u *quickAddUsecase) resolveTags(ctx context.Context, userID uuid.UUID, names []string, res *QuickAddResult) error {
+	if len(names) == 0 {
+		return nil
+	}
+	tags, err := u.repo.ListTags(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list tags: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+
+		t := QuickAddTag{Name: name, New: true}
+		for _, tag := range tags {
+			if strings.EqualFold(tag.Name, name) {
+				t = QuickAddTag{Name: tag.Name, ID: &tag.ID}
+				break
+			}
+		}
+		res.Tags = append(res.Tags, t)
+	}
+	return nil
+}
+
+func (u *quickAddUsecase) tagNames(res *QuickAddResult) []string {
+	names := make([]string, 0, len(res.Tags))
+	for _, t := range res.Tags {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func (u *quickAddUsecase) createTask(ctx context.Context, q *repository.Queries, userID uuid.UUID, res *QuickAddResult) error {
+	var calendarID pgtype.UUID
+	if cal, err := q.GetDefaultCalendar(ctx, userID); err == nil {
+		calendarID = pgtype.UUID{Bytes: cal.ID, Valid: true}
+	}
+	position, err := nextTaskPosition(ctx, q, *res.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	task, err := q.CreateTask(ctx, repository.CreateTaskParams{
+		UserID:     userID,
+		ProjectID:  *res.ProjectID,
+		Title:      res.Title,
+		DueDate:    toTimestamp(res.DueDate),
+		Priority:   pgtype.Int2{Int16: res.Priority, Valid: true},
+		CalendarID: calendarID,
+		IcalUid:    pgtype.Text{String: uuid.NewString(), Valid: true},
+		Status:     repository.TaskStatusTODO,
+		Position:   position,
+	})
+	if err != nil {
+		return err
+	}
+	if err := recordTaskActivity(ctx, q, nil, task); err != nil {
+		return err
+	}
+	if err := setTaskTagsByName(ctx, q, userID, task.ID, u.tagNames(res)); err != nil {
+		return err
+	}
+	res.Task = &task
+	return nil
+}
+
+func (u *quickAddUsecase) createEvent(ctx context.Context, q *repository.Queries, userID uuid.UUID, res *QuickAddResult) error {
+	cal, err := q.GetDefaultCalendar(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return NewNotFoundError("no calendar found for user")
+		}
+		return err
+	}
+
+	event, err := q.CreateEvent(ctx, repository.CreateEventParams{
+		UserID:     userID,
+		ProjectID:  *res.ProjectID,
+		CalendarID: pgtype.UUID{Bytes: cal.ID, Valid: true},
+		Title:      res.Title,
+		StartAt:    toTimestamp(res.StartAt),
+		EndAt:      toTimestamp(res.EndAt),
+		IsAllDay:   res.IsAllDay,
+		IcalUid:    pgtype.Text{String: uuid.NewString(), Valid: true},
+		Status:     toTextFromStr("CONFIRMED"),
+	})
+	if err != nil {
+		return err
+	}
+	if err := setEventTagsByName(ctx, q, userID, event.ID, u.tagNames(res)); err != nil {
+		return err
+	}
+	res.Event = &event
+	return nil
+}
@@ -13,7 +13,7 @@ import (
 
 type ResultUsecase interface {
 	CreateResult(ctx context.Context, userID, projectID uuid.UUID, taskID *uuid.UUID, resultType string, value float64, recordedAt time.Time, note string) (*repository.Result, error)
-	ListResults(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, from, to time.Time) ([]repository.ListResultsRow, error)
+	ListResults(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, from, to time.Time, tagID *uuid.UUID) ([]repository.ListResultsRow, error)
 	DeleteResult(ctx context.Context, userID, resultID uuid.UUID) error
 }
 
@@ -51,12 +51,13 @@ func (u *resultUsecase) CreateResult(ctx context.Context, userID, projectID uuid
 	return &result, nil
 }
 
-func (u *resultUsecase) ListResults(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, from, to time.Time) ([]repository.ListResultsRow, error) {
+func (u *resultUsecase) ListResults(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, from, to time.Time, tagID *uuid.UUID) ([]repository.ListResultsRow, error) {
 	arg := repository.ListResultsParams{
 		UserID:    userID,
 		ProjectID: toUUID(projectID),
 		FromDate:  toTimestamp(&from),
 		ToDate:    toTimestamp(&to),
+		TagID:     toUUID(tagID),
 	}
 
 	results, err := u.repo.ListResults(ctx, arg)
@@ -67,9 +68,18 @@ func (u *resultUsecase) ListResults(ctx context.Context, userID uuid.UUID, proje
 }
 
 func (u *resultUsecase) DeleteResult(ctx context.Context, userID, resultID uuid.UUID) error {
-	err := u.repo.DeleteResult(ctx, repository.DeleteResultParams{
-		ID:     resultID,
-		UserID: userID,
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		n, err := q.TrashResult(ctx, repository.TrashResultParams{
+			ID:     resultID,
+			UserID: userID,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return NewNotFoundError("result not found")
+		}
+		return journal(ctx, q, userID, OperationResultDelete, restoreStep(TrashKindResult, resultID))
 	})
 	if err != nil {
 		return fmt.Errorf("failed to delete result: %w", err)
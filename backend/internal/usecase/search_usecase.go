@@ -0,0 +1,235 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// スニペットとしてマッチ箇所の前後に残す文字数
+const snippetContext = 40
+
+type SearchHit struct {
+	ID         uuid.UUID `json:"id"`
+	ProjectID  uuid.UUID `json:"project_id"`
+	Title      string    `json:"title"`
+	Snippet    string    `json:"snippet"`
+	Rank       float64   `json:"rank"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Status     string    `json:"status,omitempty"`
+}
+
+type SearchGroup struct {
+	Type  string      `json:"type"`
+	Count int         `json:"count"`
+	Items []SearchHit `json:"items"`
+}
+
+type SearchResult struct {
+	Query  string        `json:"query"`
+	Total  int           `json:"total"`
+	Groups []SearchGroup `json:"groups"`
+}
+
+type SearchUsecase interface {
+	// Search は種別ごとに上位 limit 件を返す。status はタスクの状態で、指定するとタスク以外は返さない
+	Search(ctx context.Context, userID uuid.UUID, query string, projectID *uuid.UUID, status *repository.TaskStatus, from, to *time.Time, limit int) (*SearchResult, error)
+}
+
+type searchUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewSearchUsecase(repo *repository.Queries, txManager db.TxManager) SearchUsecase {
+	return &searchUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+func (u *searchUsecase) Search(ctx context.Context, userID uuid.UUID, query string, projectID *uuid.UUID, status *repository.TaskStatus, from, to *time.Time, limit int) (*SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, NewBadRequestError("query is required")
+	}
+
+	var st pgtype.Text
+	if status != nil {
+		st = pgtype.Text{String: string(*status), Valid: true}
+	}
+
+	rows, err := u.repo.SearchAll(ctx, repository.SearchAllParams{
+		Query:     query,
+		Pattern:   "%" + escapeLike(query) + "%",
+		UserID:    userID,
+		ProjectID: toUUID(projectID),
+		FromDate:  toTimestamp(from),
+		ToDate:    toTimestamp(to),
+		Status:    st,
+		PerType:   int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	terms := searchTerms(query)
+	groups := map[string]*SearchGroup{}
+	best := map[string]float64{}
+	var order []string
+	for _, r := range rows {
+		g, ok := groups[r.EntityType]
+		if !ok {
+			g = &SearchGroup{Type: r.EntityType, Items: []SearchHit{}}
+			groups[r.EntityType] = g
+			order = append(order, r.EntityType)
+		}
+		source := r.Body
+		if !containsAny(source, terms) {
+			source = r.Title
+		}
+		g.Items = append(g.Items, SearchHit{
+			ID:         r.ID,
+			ProjectID:  r.ProjectID,
+			Title:      r.Title,
+			Snippet:    highlight(source, terms),
+			Rank:       r.Rank,
+			OccurredAt: r.OccurredAt.Time,
+			Status:     r.Status,
+		})
+		g.Count++
+		if r.Rank > best[r.EntityType] {
+			best[r.EntityType] = r.Rank
+		}
+	}
+
+	// 最もスコアの高いヒットを含む種別から順に並べる
+	sort.SliceStable(order, func(i, j int) bool {
+		return best[order[i]] > best[order[j]]
+	})
+
+	res := &SearchResult{Query: query, Groups: []SearchGroup{}}
+	for _, t := range order {
+		res.Groups = append(res.Groups, *groups[t])
+		res.Total += groups[t].Count
+	}
+	return res, nil
+}
+
+// escapeLike は LIKE/ILIKE のメタ文字をエスケープする
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// searchTerms は websearch_to_tsquery 形式のクエリからハイライト対象の語を取り出す
+func searchTerms(query string) []string {
+	var terms []string
+	for _, f := range strings.Fields(query) {
+		if strings.HasPrefix(f, "-") || strings.EqualFold(f, "or") {
+			continue
+		}
+		f = strings.Trim(f, `"`)
+		if f != "" {
+			terms = append(terms, strings.ToLower(f))
+		}
+	}
+	return terms
+}
+
+func lowerRunes(s string) []rune {
+	rs := []rune(s)
+	for i, r := range rs {
+		rs[i] = unicode.ToLower(r)
+	}
+	return rs
+}
+
+func indexRunes(s, sub []rune, from int) int {
+	for i := from; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsAny(text string, terms []string) bool {
+	lower := lowerRunes(text)
+	for _, t := range terms {
+		if indexRunes(lower, []rune(t), 0) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// highlight はマッチ箇所周辺を切り出し、HTMLエスケープした上で <mark> で囲む
+func highlight(text string, terms []string) string {
+	rs := []rune(text)
+	lower := lowerRunes(text)
+
+	marked := make([]bool, len(rs))
+	first := -1
+	for _, t := range terms {
+		tr := []rune(t)
+		if len(tr) == 0 {
+			continue
+		}
+		for i := indexRunes(lower, tr, 0); i >= 0; i = indexRunes(lower, tr, i+len(tr)) {
+			if first < 0 || i < first {
+				first = i
+			}
+			for j := i; j < i+len(tr); j++ {
+				marked[j] = true
+			}
+		}
+	}
+
+	start, end := 0, len(rs)
+	if first > snippetContext {
+		start = first - snippetContext
+	}
+	if end > start+snippetContext*2 {
+		end = start + snippetContext*2
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	inMark := false
+	for i := start; i < end; i++ {
+		if marked[i] && !inMark {
+			b.WriteString("<mark>")
+			inMark = true
+		} else if !marked[i] && inMark {
+			b.WriteString("</mark>")
+			inMark = false
+		}
+		b.WriteString(html.EscapeString(string(rs[i])))
+	}
+	if inMark {
+		b.WriteString("</mark>")
+	}
+	if end < len(rs) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
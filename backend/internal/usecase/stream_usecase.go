@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/pkg/auth"
+	"github.com/google/uuid"
+)
+
+// ストリームのチケットの有効期間。取得してすぐ接続する前提
+const streamTicketTTL = 30 * time.Second
+
+type StreamUsecase interface {
+	// Subscribe はユーザーの変更通知を受け取るチャンネルと、購読をやめる関数を返す
+	Subscribe(userID uuid.UUID) (<-chan db.Notification, func())
+	// CreateTicket はストリームに1回だけ接続できるチケットを発行する
+	CreateTicket(ctx context.Context, userID uuid.UUID) (*StreamTicket, error)
+}
+
+type StreamTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type streamUsecase struct {
+	repo     *repository.Queries
+	listener *db.Listener
+}
+
+func NewStreamUsecase(repo *repository.Queries, listener *db.Listener) StreamUsecase {
+	return &streamUsecase{repo: repo, listener: listener}
+}
+
+func (u *streamUsecase) Subscribe(userID uuid.UUID) (<-chan db.Notification, func()) {
+	return u.listener.Subscribe(userID)
+}
+
+func (u *streamUsecase) CreateTicket(ctx context.Context, userID uuid.UUID) (*StreamTicket, error) {
+	ticket, hash, err := auth.GenerateStreamTicket()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate stream ticket: %w", err)
+	}
+	// 使われなかったチケットはここでまとめて消す
+	if err := u.repo.DeleteExpiredStreamTickets(ctx); err != nil {
+		return nil, fmt.Errorf("failed to delete expired stream tickets: %w", err)
+	}
+	expiresAt := time.Now().Add(streamTicketTTL)
+	if err := u.repo.CreateStreamTicket(ctx, repository.CreateStreamTicketParams{
+		TokenHash: hash,
+		UserID:    userID,
+		ExpiresAt: toTimestamp(&expiresAt),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create stream ticket: %w", err)
+	}
+	return &StreamTicket{Ticket: ticket, ExpiresAt: expiresAt}, nil
+}
@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TagTarget はタグを付与できるエンティティの種類
+type TagTarget string
+
+// タグ名の長さの上限 (tags.name は VARCHAR(50))
+const maxTagNameLen = 50
+
+const (
+	TagTargetTask      TagTarget = "task"
+	TagTargetEvent     TagTarget = "event"
+	TagTargetTimeEntry TagTarget = "time_entry"
+	TagTargetResult    TagTarget = "result"
+)
+
+type TagUsecase interface {
+	CreateTag(ctx context.Context, userID uuid.UUID, name, color string) (*repository.Tag, error)
+	ListTags(ctx context.Context, userID uuid.UUID) ([]repository.Tag, error)
+	UpdateTag(ctx context.Context, userID, tagID uuid.UUID, name, color *string) (*repository.Tag, error)
+	DeleteTag(ctx context.Context, userID, tagID uuid.UUID) error
+
+	AttachTag(ctx context.Context, userID uuid.UUID, target TagTarget, targetID, tagID uuid.UUID) error
+	DetachTag(ctx context.Context, userID uuid.UUID, target TagTarget, targetID, tagID uuid.UUID) error
+
+	GetTagStats(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]repository.GetTagStatsRow, error)
+}
+
+type tagUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewTagUsecase(repo *repository.Queries, txManager db.TxManager) TagUsecase {
+	return &tagUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+func (u *tagUsecase) CreateTag(ctx context.Context, userID uuid.UUID, name, color string) (*repository.Tag, error) {
+	tag, err := u.repo.CreateTag(ctx, repository.CreateTagParams{
+		UserID: userID,
+		Name:   name,
+		Color:  toTextFromStr(color),
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, NewConflictError("tag already exists")
+		}
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+	return &tag, nil
+}
+
+func (u *tagUsecase) ListTags(ctx context.Context, userID uuid.UUID) ([]repository.Tag, error) {
+	tags, err := u.repo.ListTags(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (u *tagUsecase) UpdateTag(ctx context.Context, userID, tagID uuid.UUID, name, color *string) (*repository.Tag, error) {
+	tag, err := u.repo.UpdateTag(ctx, repository.UpdateTagParams{
+		ID:     tagID,
+		UserID: userID,
+		Name:   toText(name),
+		Color:  toText(color),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("tag not found")
+		}
+		if isUniqueViolation(err) {
+			return nil, NewConflictError("tag already exists")
+		}
+		return nil, fmt.Errorf("failed to update tag: %w", err)
+	}
+	return &tag, nil
+}
+
+func (u *tagUsecase) DeleteTag(ctx context.Context, userID, tagID uuid.UUID) error {
+	n, err := u.repo.DeleteTag(ctx, repository.DeleteTagParams{
+		ID:     tagID,
+		UserID: userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if n == 0 {
+		return NewNotFoundError("tag not found")
+	}
+	return nil
+}
+
+func (u *tagUsecase) AttachTag(ctx context.Context, userID uuid.UUID, target TagTarget, targetID, tagID uuid.UUID) error {
+	var n int64
+	var err error
+	switch target {
+	case TagTargetTask:
+		n, err = u.repo.AddTaskTag(ctx, repository.AddTaskTagParams{TaskID: targetID, TagID: tagID, UserID: userID})
+	case TagTargetEvent:
+		n, err = u.repo.AddEventTag(ctx, repository.AddEventTagParams{EventID: targetID, TagID: tagID, UserID: userID})
+	case TagTargetTimeEntry:
+		n, err = u.repo.AddTimeEntryTag(ctx, repository.AddTimeEntryTagParams{TimeEntryID: targetID, TagID: tagID, UserID: userID})
+	case TagTargetResult:
+		n, err = u.repo.AddResultTag(ctx, repository.AddResultTagParams{ResultID: targetID, TagID: tagID, UserID: userID})
+	default:
+		return NewBadRequestError("unsupported tag target")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to attach tag: %w", err)
+	}
+	// 対象またはタグが存在しない(他ユーザーのものを含む)
+	if n == 0 {
+		return NewNotFoundError(fmt.Sprintf("%s or tag not found", target))
+	}
+	return nil
+}
+
+func (u *tagUsecase) DetachTag(ctx context.Context, userID uuid.UUID, target TagTarget, targetID, tagID uuid.UUID) error {
+	var n int64
+	var err error
+	switch target {
+	case TagTargetTask:
+		n, err = u.repo.RemoveTaskTag(ctx, repository.RemoveTaskTagParams{TaskID: targetID, TagID: tagID, UserID: userID})
+	case TagTargetEvent:
+		n, err = u.repo.RemoveEventTag(ctx, repository.RemoveEventTagParams{EventID: targetID, TagID: tagID, UserID: userID})
+	case TagTargetTimeEntry:
+		n, err = u.repo.RemoveTimeEntryTag(ctx, repository.RemoveTimeEntryTagParams{TimeEntryID: targetID, TagID: tagID, UserID: userID})
+	case TagTargetResult:
+		n, err = u.repo.RemoveResultTag(ctx, repository.RemoveResultTagParams{ResultID: targetID, TagID: tagID, UserID: userID})
+	default:
+		return NewBadRequestError("unsupported tag target")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to detach tag: %w", err)
+	}
+	if n == 0 {
+		return NewNotFoundError("tag is not attached")
+	}
+	return nil
+}
+
+func (u *tagUsecase) GetTagStats(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]repository.GetTagStatsRow, error) {
+	stats, err := u.repo.GetTagStats(ctx, repository.GetTagStatsParams{
+		UserID:   userID,
+		FromDate: toTimestamp(&from),
+		ToDate:   toTimestamp(&to),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag stats: %w", err)
+	}
+	return stats, nil
+}
+
+// truncateTagName は CalDAV の CATEGORIES など外から来た長い名前を上限で切り詰める。
+// 1つの長い名前のためにタスクや予定の保存全体を失敗させない
+func truncateTagName(name string) string {
+	if r := []rune(name); len(r) > maxTagNameLen {
+		return strings.TrimSpace(string(r[:maxTagNameLen]))
+	}
+	return name
+}
+
+// setTaskTagsByName はタスクのタグを名前の一覧で置き換える(存在しないタグは作成)
+func setTaskTagsByName(ctx context.Context, q *repository.Queries, userID, taskID uuid.UUID, names []string) error {
+	if err := q.ClearTaskTags(ctx, taskID); err != nil {
+		return err
+	}
+	for _, name := range names {
+		tag, err := q.UpsertTagByName(ctx, repository.UpsertTagByNameParams{UserID: userID, Name: truncateTagName(name)})
+		if err != nil {
+			return err
+		}
+		if _, err := q.AddTaskTag(ctx, repository.AddTaskTagParams{TaskID: taskID, TagID: tag.ID, UserID: userID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setEventTagsByName は予定のタグを名前の一覧で置き換える(存在しないタグは作成)
+func setEventTagsByName(ctx context.Context, q *repository.Queries, userID, eventID uuid.UUID, names []string) error {
+	if err := q.ClearEventTags(ctx, eventID); err != nil {
+		return err
+	}
+	for _, name := range names {
+		tag, err := q.UpsertTagByName(ctx, repository.UpsertTagByNameParams{UserID: userID, Name: truncateTagName(name)})
+		if err != nil {
+			return err
+		}
+		if _, err := q.AddEventTag(ctx, repository.AddEventTagParams{EventID: eventID, TagID: tag.ID, UserID: userID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	TaskActionCreated = "CREATED"
+	TaskActionUpdated = "UPDATED"
+)
+
+// FieldChange は1項目の変更前後の値
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// TimelineEntry は履歴とコメントを時系列に並べた1件
+type TimelineEntry struct {
+	Type      string                 `json:"type"` // activity / comment
+	ID        uuid.UUID              `json:"id"`
+	CreatedAt time.Time              `json:"created_at"`
+	Action    string                 `json:"action,omitempty"`
+	Source    string                 `json:"source,omitempty"`
+	Actor     string                 `json:"actor,omitempty"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	Body      string                 `json:"body,omitempty"`
+	UpdatedAt *time.Time             `json:"updated_at,omitempty"`
+}
+
+type CycleTimeStat struct {
+	ProjectID        uuid.UUID `json:"project_id"`
+	ProjectTitle     string    `json:"project_title"`
+	CompletedCount   int64     `json:"completed_count"`
+	AvgCycleHours    float64   `json:"avg_cycle_hours"`
+	MedianCycleHours float64   `json:"median_cycle_hours"`
+	AvgLeadHours     float64   `json:"avg_lead_hours"`
+}
+
+type TaskActivityUsecase interface {
+	GetTimeline(ctx context.Context, userID, taskID uuid.UUID) ([]TimelineEntry, error)
+	AddComment(ctx context.Context, userID, taskID uuid.UUID, body string) (*repository.TaskComment, error)
+	UpdateComment(ctx context.Context, userID, commentID uuid.UUID, body string) (*repository.TaskComment, error)
+	DeleteComment(ctx context.Context, userID, commentID uuid.UUID) error
+	GetCycleTime(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]CycleTimeStat, error)
+}
+
+type taskActivityUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewTaskActivityUsecase(repo *repository.Queries, txManager db.TxManager) TaskActivityUsecase {
+	return &taskActivityUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+func (u *taskActivityUsecase) GetTimeline(ctx context.Context, userID, taskID uuid.UUID) ([]TimelineEntry, error) {
+	if _, err := u.repo.GetTask(ctx, repository.GetTaskParams{ID: taskID, UserID: userID}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("task not found")
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	activities, err := u.repo.ListTaskActivities(ctx, repository.ListTaskActivitiesParams{TaskID: taskID, UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task activities: %w", err)
+	}
+	comments, err := u.repo.ListTaskComments(ctx, repository.ListTaskCommentsParams{TaskID: taskID, UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task comments: %w", err)
+	}
+
+	entries := make([]TimelineEntry, 0, len(activities)+len(comments))
+	for _, a := range activities {
+		var changes map[string]FieldChange
+		if err := json.Unmarshal(a.Changes, &changes); err != nil {
+			return nil, fmt.Errorf("failed to decode task activity: %w", err)
+		}
+		entries = append(entries, TimelineEntry{
+			Type:      "activity",
+			ID:        a.ID,
+			CreatedAt: a.CreatedAt.Time,
+			Action:    a.Action,
+			Source:    a.Source,
+			Actor:     a.Actor.String,
+			Changes:   changes,
+		})
+	}
+	for _, c := range comments {
+		e := TimelineEntry{
+			Type:      "comment",
+			ID:        c.ID,
+			CreatedAt: c.CreatedAt.Time,
+			Body:      c.BodyMarkdown,
+		}
+		if c.UpdatedAt.Time.After(c.CreatedAt.Time) {
+			e.UpdatedAt = &c.UpdatedAt.Time
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+func (u *taskActivityUsecase) AddComment(ctx context.Context, userID, taskID uuid.UUID, body string) (*repository.TaskComment, error) {
+	var comment repository.TaskComment
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		var err error
+		comment, err = q.CreateTaskComment(ctx, repository.CreateTaskCommentParams{
+			BodyMarkdown: body,
+			TaskID:       taskID,
+			UserID:       userID,
+		})
+		if err != nil {
+			return err
+		}
+		return u.syncCommentLinks(ctx, q, comment)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("task not found")
+		}
+		return nil, fmt.Errorf("failed to add comment: %w", err)
+	}
+	return &comment, nil
+}
+
+func (u *taskActivityUsecase) UpdateComment(ctx context.Context, userID, commentID uuid.UUID, body string) (*repository.TaskComment, error) {
+	var comment repository.TaskComment
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		var err error
+		comment, err = q.UpdateTaskComment(ctx, repository.UpdateTaskCommentParams{
+			ID:           commentID,
+			UserID:       userID,
+			BodyMarkdown: body,
+		})
+		if err != nil {
+			return err
+		}
+		return u.syncCommentLinks(ctx, q, comment)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("comment not found")
+		}
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+	return &comment, nil
+}
+
+// syncCommentLinks はコメント内の wiki リンクの索引を更新する
+func (u *taskActivityUsecase) syncCommentLinks(ctx context.Context, q *repository.Queries, comment repository.TaskComment) error {
+	task, err := q.GetTask(ctx, repository.GetTaskParams{ID: comment.TaskID, UserID: comment.UserID})
+	if err != nil {
+		return err
+	}
+	return syncNoteLinks(ctx, q, task, &comment)
+}
+
+func (u *taskActivityUsecase) DeleteComment(ctx context.Context, userID, commentID uuid.UUID) error {
+	n, err := u.repo.DeleteTaskComment(ctx, repository.DeleteTaskCommentParams{ID: commentID, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	if n == 0 {
+		return NewNotFoundError("comment not found")
+	}
+	return nil
+}
+
+func (u *taskActivityUsecase) GetCycleTime(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]CycleTimeStat, error) {
+	rows, err := u.repo.GetCycleTimeStats(ctx, repository.GetCycleTimeStatsParams{
+		UserID:   userID,
+		FromDate: toTimestamp(&from),
+		ToDate:   toTimestamp(&to),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cycle time: %w", err)
+	}
+	stats := make([]CycleTimeStat, 0, len(rows))
+	for _, r := range rows {
+		stats = append(stats, CycleTimeStat{
+			ProjectID:        r.ProjectID,
+			ProjectTitle:     r.ProjectTitle,
+			CompletedCount:   r.CompletedCount,
+			AvgCycleHours:    r.AvgCycleSeconds / 3600,
+			MedianCycleHours: r.MedianCycleSeconds / 3600,
+			AvgLeadHours:     r.AvgLeadSeconds / 3600,
+		})
+	}
+	return stats, nil
+}
+
+// recordTaskActivity はタスクの作成(before が nil)または変更を履歴に追記する。
+// 主体はコンテキストの Actor から取る。変更がなければ何も記録しない。
+// ノートが変わった場合は wiki リンクの索引も更新する。
+func recordTaskActivity(ctx context.Context, q *repository.Queries, before *repository.Task, after repository.Task) error {
+	if before == nil || before.NoteMarkdown != after.NoteMarkdown {
+		if err := syncNoteLinks(ctx, q, after, nil); err != nil {
+			return err
+		}
+	}
+
+	action := TaskActionCreated
+	changes := map[string]FieldChange{}
+	if before != nil {
+		action = TaskActionUpdated
+		changes = diffTask(*before, after)
+		if len(changes) == 0 {
+			return nil
+		}
+	}
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	actor := ActorFrom(ctx)
+	return q.CreateTaskActivity(ctx, repository.CreateTaskActivityParams{
+		TaskID:  after.ID,
+		UserID:  after.UserID,
+		Action:  action,
+		Source:  string(actor.Source),
+		Actor:   toTextFromStr(actor.Name),
+		Changes: b,
+	})
+}
+
+// diffTask は利用者が意味を持つ項目の差分を返す(順位キーやetagなどは除く)
+func diffTask(before, after repository.Task) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	add := func(field string, from, to any) {
+		if from != to {
+			changes[field] = FieldChange{From: from, To: to}
+		}
+	}
+	add("title", before.Title, after.Title)
+	add("note_markdown", textValue(before.NoteMarkdown), textValue(after.NoteMarkdown))
+	add("status", string(before.Status), string(after.Status))
+	add("custom_status_id", uuidValue(before.CustomStatusID), uuidValue(after.CustomStatusID))
+	add("due_date", timeValue(before.DueDate), timeValue(after.DueDate))
+	add("priority", int2Value(before.Priority), int2Value(after.Priority))
+	add("project_id", before.ProjectID.String(), after.ProjectID.String())
+	add("estimated_minutes", int4Value(before.EstimatedMinutes), int4Value(after.EstimatedMinutes))
+	return changes
+}
+
+func textValue(t pgtype.Text) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.String
+}
+
+func uuidValue(u pgtype.UUID) any {
+	if !u.Valid {
+		return nil
+	}
+	return uuid.UUID(u.Bytes).String()
+}
+
+func timeValue(t pgtype.Timestamptz) any {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.UTC().Format(time.RFC3339)
+}
+
+func int2Value(i pgtype.Int2) any {
+	if !i.Valid {
+		return nil
+	}
+	return i.Int16
+}
+
+func int4Value(i pgtype.Int4) any {
+	if !i.Valid {
+		return nil
+	}
+	return i.Int32
+}
@@ -0,0 +1,355 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/pkg/filter"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// BulkOperation は一括操作の種類
+type BulkOperation string
+
+const (
+	BulkSetStatus   BulkOperation = "set_status"
+	BulkMoveProject BulkOperation = "move_project"
+	BulkShiftDue    BulkOperation = "shift_due"
+	BulkAddTag      BulkOperation = "add_tag"
+	BulkRemoveTag   BulkOperation = "remove_tag"
+	BulkDelete      BulkOperation = "delete"
+)
+
+// 1回の一括操作で扱えるタスクの上限
+const maxBulkTasks = 500
+
+// 各タスクの処理結果
+const (
+	BulkResultOK         = "ok"
+	BulkResultSkipped    = "skipped" // 変更の必要がなかった
+	BulkResultFailed     = "failed"
+	BulkResultRolledBack = "rolled_back" // 成功したが、他のタスクの失敗で取り消された
+)
+
+// TaskBulkInput は対象(TaskIDs または Filter のどちらか)と操作の内容
+type TaskBulkInput struct {
+	TaskIDs   []uuid.UUID
+	Filter    string
+	Operation BulkOperation
+	Status    repository.TaskStatus // set_status
+	StatusID  *uuid.UUID            // set_status (プロジェクト独自のステータス)
+	ProjectID *uuid.UUID            // move_project
+	Days      int                   // shift_due
+	TagID     *uuid.UUID            // add_tag / remove_tag
+	// Atomic が true なら1件でも失敗したとき全体を取り消す
+	Atomic bool
+}
+
+type BulkItemResult struct {
+	TaskID uuid.UUID `json:"task_id"`
+	Result string    `json:"result"`
+	Error  string    `json:"error,omitempty"`
+}
+
+type BulkResult struct {
+	Operation BulkOperation    `json:"operation"`
+	Atomic    bool             `json:"atomic"`
+	Applied   bool             `json:"applied"` // false なら何も変更されていない
+	Succeeded int              `json:"succeeded"`
+	Skipped   int              `json:"skipped"`
+	Failed    int              `json:"failed"`
+	Items     []BulkItemResult `json:"items"`
+}
+
+type TaskBulkUsecase interface {
+	Bulk(ctx context.Context, userID uuid.UUID, input TaskBulkInput) (*BulkResult, error)
+}
+
+type taskBulkUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewTaskBulkUsecase(repo *repository.Queries, txManager db.TxManager) TaskBulkUsecase {
+	return &taskBulkUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+// atomic 指定で失敗があったときにトランザクションを取り消すための目印
+var errBulkRollback = errors.New("bulk operation rolled back")
+
+// Bulk は対象のタスクそれぞれにセーブポイントを置いて操作を適用する。
+// 失敗したタスクだけを取り消して残りを確定し、Atomic なら全体を取り消す
+func (u *taskBulkUsecase) Bulk(ctx context.Context, userID uuid.UUID, input TaskBulkInput) (*BulkResult, error) {
+	if err := u.validate(ctx, userID, input); err != nil {
+		return nil, err
+	}
+	var expr filter.Expr
+	if strings.TrimSpace(input.Filter) != "" {
+		var err error
+		if expr, err = parseTaskFilter(input.Filter); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *BulkResult
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		var steps []undoStep
+		ids, err := u.targets(ctx, q, userID, input.TaskIDs, expr)
+		if err != nil {
+			return err
+		}
+		result = &BulkResult{
+			Operation: input.Operation,
+			Atomic:    input.Atomic,
+			Items:     make([]BulkItemResult, 0, len(ids)),
+		}
+		for _, id := range ids {
+			item := BulkItemResult{TaskID: id, Result: BulkResultOK}
+			var step *undoStep
+			err := q.Savepoint(ctx, func(q *repository.Queries) error {
+				task, err := q.GetTask(ctx, repository.GetTaskParams{ID: id, UserID: userID})
+				if err != nil {
+					return err
+				}
+				step, err = u.apply(ctx, q, userID, task, input)
+				return err
+			})
+			switch {
+			case err != nil:
+				msg, ok := bulkItemError(err)
+				if !ok {
+					return err
+				}
+				item.Result, item.Error = BulkResultFailed, msg
+			case step == nil:
+				item.Result = BulkResultSkipped
+			default:
+				steps = append(steps, *step)
+			}
+			result.Items = append(result.Items, item)
+		}
+
+		for _, item := range result.Items {
+			switch item.Result {
+			case BulkResultOK:
+				result.Succeeded++
+			case BulkResultSkipped:
+				result.Skipped++
+			case BulkResultFailed:
+				result.Failed++
+			}
+		}
+		if input.Atomic && result.Failed > 0 {
+			return errBulkRollback
+		}
+		return journal(ctx, q, userID, OperationTaskBulk, steps...)
+	})
+	if err != nil && !errors.Is(err, errBulkRollback) {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to run bulk operation: %w", err)
+	}
+
+	if errors.Is(err, errBulkRollback) {
+		for i := range result.Items {
+			if result.Items[i].Result == BulkResultOK {
+				result.Items[i].Result = BulkResultRolledBack
+			}
+		}
+		result.Succeeded = 0
+		return result, nil
+	}
+	result.Applied = true
+	return result, nil
+}
+
+// validate は操作に必要な値がそろっているか、参照先が存在するかを確認する
+func (u *taskBulkUsecase) validate(ctx context.Context, userID uuid.UUID, input TaskBulkInput) error {
+	hasFilter := strings.TrimSpace(input.Filter) != ""
+	switch {
+	case len(input.TaskIDs) == 0 && !hasFilter:
+		return NewBadRequestError("task_ids or filter is required")
+	case len(input.TaskIDs) > 0 && hasFilter:
+		return NewBadRequestError("specify either task_ids or filter, not both")
+	case len(input.TaskIDs) > maxBulkTasks:
+		return NewBadRequestError(fmt.Sprintf("too many tasks (max %d)", maxBulkTasks))
+	}
+
+	switch input.Operation {
+	case BulkSetStatus:
+		if input.Status == "" && input.StatusID == nil {
+			return NewBadRequestError("status or status_id is required")
+		}
+	case BulkMoveProject:
+		if input.ProjectID == nil {
+			return NewBadRequestError("project_id is required")
+		}
+		project, err := u.repo.GetProject(ctx, repository.GetProjectParams{ID: *input.ProjectID, UserID: userID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return NewNotFoundError("project not found")
+			}
+			return fmt.Errorf("failed to get project: %w", err)
+		}
+		if project.IsArchived {
+			return NewBadRequestError("project is archived")
+		}
+	case BulkShiftDue:
+		if input.Days == 0 {
+			return NewBadRequestError("days must not be zero")
+		}
+	case BulkAddTag, BulkRemoveTag:
+		if input.TagID == nil {
+			return NewBadRequestError("tag_id is required")
+		}
+		if _, err := u.repo.GetTag(ctx, repository.GetTagParams{ID: *input.TagID, UserID: userID}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return NewNotFoundError("tag not found")
+			}
+			return fmt.Errorf("failed to get tag: %w", err)
+		}
+	case BulkDelete:
+	default:
+		return NewBadRequestError("unsupported operation")
+	}
+	return nil
+}
+
+// targets は対象のタスク ID を重複なく返す。フィルタ指定なら一致したタスクすべて
+func (u *taskBulkUsecase) targets(ctx context.Context, q *repository.Queries, userID uuid.UUID, taskIDs []uuid.UUID, expr filter.Expr) ([]uuid.UUID, error) {
+	if expr == nil {
+		seen := map[uuid.UUID]bool{}
+		ids := make([]uuid.UUID, 0, len(taskIDs))
+		for _, id := range taskIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	}
+
+	prefs, err := loadPreferences(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListTasksWithFilter(ctx, repository.ListTasksWithStatsParams{UserID: userID}, expr, time.Now().In(prefs.Location()))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > maxBulkTasks {
+		return nil, NewBadRequestError(fmt.Sprintf("filter matches %d tasks (max %d)", len(rows), maxBulkTasks))
+	}
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.ID)
+	}
+	return ids, nil
+}
+
+// apply は1件のタスクに操作を適用し、取り消し用の逆操作を返す。変更がなければ nil を返す
+func (u *taskBulkUsecase) apply(ctx context.Context, q *repository.Queries, userID uuid.UUID, task repository.Task, input TaskBulkInput) (*undoStep, error) {
+	switch input.Operation {
+	case BulkSetStatus:
+		if input.StatusID != nil {
+			if task.CustomStatusID.Valid && uuid.UUID(task.CustomStatusID.Bytes) == *input.StatusID {
+				return nil, nil
+			}
+		} else if task.Status == input.Status {
+			return nil, nil
+		}
+		updated, err := applyTaskStatus(ctx, q, userID, task, input.Status, input.StatusID)
+		if err != nil {
+			return nil, err
+		}
+		return ptr(revertTaskStep(task, updated)), nil
+
+	case BulkMoveProject:
+		if task.ProjectID == *input.ProjectID {
+			return nil, nil
+		}
+		position, err := nextTaskPosition(ctx, q, *input.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		moved, err := q.SetTaskProject(ctx, repository.SetTaskProjectParams{
+			ProjectID: *input.ProjectID,
+			Position:  position,
+			ID:        task.ID,
+			UserID:    userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := recordTaskActivity(ctx, q, &task, moved); err != nil {
+			return nil, err
+		}
+		return ptr(revertTaskStep(task, moved)), nil
+
+	case BulkShiftDue:
+		if !task.DueDate.Valid {
+			return nil, nil
+		}
+		updated, err := q.UpdateTask(ctx, repository.UpdateTaskParams{
+			ID:      task.ID,
+			UserID:  userID,
+			DueDate: toTimestamp(ptr(task.DueDate.Time.AddDate(0, 0, input.Days))),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := recordTaskActivity(ctx, q, &task, updated); err != nil {
+			return nil, err
+		}
+		return ptr(revertTaskStep(task, updated)), nil
+
+	case BulkAddTag:
+		has, err := q.TaskHasTag(ctx, repository.TaskHasTagParams{TaskID: task.ID, TagID: *input.TagID})
+		if err != nil || has {
+			return nil, err
+		}
+		if _, err := q.AddTaskTag(ctx, repository.AddTaskTagParams{TaskID: task.ID, TagID: *input.TagID, UserID: userID}); err != nil {
+			return nil, err
+		}
+		return &undoStep{Op: undoRemoveTaskTag, ID: task.ID, TagID: input.TagID}, nil
+
+	case BulkRemoveTag:
+		n, err := q.RemoveTaskTag(ctx, repository.RemoveTaskTagParams{TaskID: task.ID, TagID: *input.TagID, UserID: userID})
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		return &undoStep{Op: undoAddTaskTag, ID: task.ID, TagID: input.TagID}, nil
+
+	case BulkDelete:
+		// ゴミ箱に入れる。完全に消すのはゴミ箱から
+		n, err := q.TrashTask(ctx, repository.TrashTaskParams{ID: task.ID, UserID: userID})
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		return ptr(restoreStep(TrashKindTask, task.ID)), nil
+	}
+	return nil, NewBadRequestError("unsupported operation")
+}
+
+// bulkItemError はタスク単位の失敗として扱えるエラーならそのメッセージを返す
+func bulkItemError(err error) (string, bool) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "task not found", true
+	}
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return domainErr.Message, true
+	}
+	return "", false
+}
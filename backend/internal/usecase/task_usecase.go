@@ -4,22 +4,100 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
 	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/pkg/filter"
+	"github.com/gigaonion/taskalyst/backend/pkg/rank"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type TaskUsecase interface {
-	CreateTask(ctx context.Context, userID, projectID uuid.UUID, title, note string, dueDate *time.Time) (*repository.Task, error)
-	ListTasks(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, status *repository.TaskStatus, from, to *time.Time) ([]repository.ListTasksWithStatsRow, error)
-	UpdateTaskStatus(ctx context.Context, userID, taskID uuid.UUID, status repository.TaskStatus) (*repository.Task, error)
+	CreateTask(ctx context.Context, userID, projectID uuid.UUID, title, note string, dueDate *time.Time, priority int16) (*repository.Task, error)
+	ListTasks(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, status *repository.TaskStatus, from, to *time.Time, tagID *uuid.UUID, filterExpr string, perspectiveID *uuid.UUID) ([]repository.ListTasksWithStatsRow, error)
+	UpdateTask(ctx context.Context, userID, taskID uuid.UUID, input UpdateTaskInput) (*repository.Task, error)
+	UpdateTaskStatus(ctx context.Context, userID, taskID uuid.UUID, status repository.TaskStatus, statusID *uuid.UUID) (*repository.Task, error)
+	DeleteTask(ctx context.Context, userID, taskID uuid.UUID) error
 
-	AddChecklistItem(ctx context.Context, taskID uuid.UUID, content string) (*repository.ChecklistItem, error)
-	ToggleChecklistItem(ctx context.Context, itemID uuid.UUID, isCompleted bool) (*repository.ChecklistItem, error)
+	GetBoard(ctx context.Context, userID, projectID uuid.UUID) (*Board, error)
+	GetMatrix(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, urgentWithin time.Duration, now time.Time) (*Matrix, error)
+	MoveTask(ctx context.Context, userID, taskID uuid.UUID, status repository.TaskStatus, statusID, prevID, nextID *uuid.UUID) (*repository.Task, error)
+
+	AddChecklistItem(ctx context.Context, taskID uuid.UUID, content string, estimatedMinutes *int32) (*repository.ChecklistItem, error)
+	UpdateChecklistItem(ctx context.Context, itemID uuid.UUID, isCompleted *bool, estimatedMinutes *int32) (*repository.ChecklistItem, error)
+
+	GetEstimateReport(ctx context.Context, userID uuid.UUID, from, to time.Time, interval string, groupBy string) (*EstimateReport, error)
+}
+
+// タスクの優先度 (tasks.priority)。PriorityMedium 以上を重要として扱う
+const (
+	PriorityNone   int16 = 0
+	PriorityLow    int16 = 1
+	PriorityMedium int16 = 2
+	PriorityHigh   int16 = 3
+)
+
+func validPriority(priority int16) bool {
+	return priority >= PriorityNone && priority <= PriorityHigh
+}
+
+func isImportant(priority pgtype.Int2) bool {
+	return priority.Valid && priority.Int16 >= PriorityMedium
+}
+
+// UpdateTaskInput は部分更新する項目。nil の項目は変更しない
+// EstimatedMinutes に負の値を指定すると見積もりを解除する
+type UpdateTaskInput struct {
+	Title            *string
+	Note             *string
+	DueDate          *time.Time
+	Priority         *int16
+	EstimatedMinutes *int32
+	// SyncChecklist が true ならノートのタスクリストをチェックリストに反映する
+	SyncChecklist bool
+}
+
+// BoardLane はカンバンの1列(ステータス)
+// StatusID はプロジェクト独自のステータスの場合のみ設定され、Status はその基本カテゴリ
+type BoardLane struct {
+	Status   repository.TaskStatus          `json:"status"`
+	StatusID *uuid.UUID                     `json:"status_id,omitempty"`
+	Name     string                         `json:"name"`
+	Color    string                         `json:"color,omitempty"`
+	WipLimit *int32                         `json:"wip_limit,omitempty"` // 進行中カテゴリ全体での上限
+	Count    int                            `json:"count"`
+	Tasks    []repository.ListBoardTasksRow `json:"tasks"`
+}
+
+type Board struct {
+	ProjectID uuid.UUID   `json:"project_id"`
+	Lanes     []BoardLane `json:"lanes"`
+}
+
+// MatrixQuadrant は優先度マトリクスの1象限。Key は do / schedule / delegate / eliminate
+type MatrixQuadrant struct {
+	Key       string                          `json:"key"`
+	Urgent    bool                            `json:"urgent"`
+	Important bool                            `json:"important"`
+	Count     int                             `json:"count"`
+	Tasks     []repository.ListMatrixTasksRow `json:"tasks"`
+}
+
+// Matrix は未完了タスクを緊急度(期限)と重要度(優先度)で分けたもの
+type Matrix struct {
+	// これより前に期限があるタスク(期限切れを含む)を緊急とする
+	UrgentBefore time.Time        `json:"urgent_before"`
+	Quadrants    []MatrixQuadrant `json:"quadrants"`
+}
+
+var boardStatuses = []repository.TaskStatus{
+	repository.TaskStatusTODO,
+	repository.TaskStatusDOING,
+	repository.TaskStatusDONE,
 }
 
 type taskUsecase struct {
@@ -34,7 +112,7 @@ func NewTaskUsecase(repo *repository.Queries, txManager db.TxManager) TaskUsecas
 	}
 }
 
-func (u *taskUsecase) CreateTask(ctx context.Context, userID, projectID uuid.UUID, title, note string, dueDate *time.Time) (*repository.Task, error) {
+func (u *taskUsecase) CreateTask(ctx context.Context, userID, projectID uuid.UUID, title, note string, dueDate *time.Time, priority int16) (*repository.Task, error) {
 	// Find default calendar for user
 	var calendarID pgtype.UUID
 	defaultCal, err := u.repo.GetDefaultCalendar(ctx, userID)
@@ -42,26 +120,39 @@ func (u *taskUsecase) CreateTask(ctx context.Context, userID, projectID uuid.UUI
 		calendarID = pgtype.UUID{Bytes: defaultCal.ID, Valid: true}
 	}
 
+	position, err := nextTaskPosition(ctx, u.repo, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+
 	arg := repository.CreateTaskParams{
 		UserID:       userID,
 		ProjectID:    projectID,
 		Title:        title,
 		NoteMarkdown: toTextFromStr(note),
 		DueDate:      toTimestamp(dueDate),
-		Priority:     pgtype.Int2{Int16: 0, Valid: true}, // Default: None
+		Priority:     pgtype.Int2{Int16: priority, Valid: true},
 		Status:       repository.TaskStatusTODO,
 		IcalUid:      pgtype.Text{String: uuid.NewString(), Valid: true},
 		CalendarID:   calendarID,
+		Position:     position,
 	}
 
-	task, err := u.repo.CreateTask(ctx, arg)
+	var task repository.Task
+	err = u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		task, err = q.CreateTask(ctx, arg)
+		if err != nil {
+			return err
+		}
+		return recordTaskActivity(ctx, q, nil, task)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
 	return &task, nil
 }
 
-func (u *taskUsecase) ListTasks(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, status *repository.TaskStatus, from, to *time.Time) ([]repository.ListTasksWithStatsRow, error) {
+func (u *taskUsecase) ListTasks(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, status *repository.TaskStatus, from, to *time.Time, tagID *uuid.UUID, filterExpr string, perspectiveID *uuid.UUID) ([]repository.ListTasksWithStatsRow, error) {
 	var argStatus repository.NullTaskStatus
 	if status != nil {
 		argStatus = repository.NullTaskStatus{TaskStatus: *status, Valid: true}
@@ -73,43 +164,446 @@ func (u *taskUsecase) ListTasks(ctx context.Context, userID uuid.UUID, projectID
 		Status:    argStatus,
 		FromDate:  toTimestamp(from),
 		ToDate:    toTimestamp(to),
+		TagID:     toUUID(tagID),
 	}
 
-	tasks, err := u.repo.ListTasksWithStats(ctx, arg)
+	expr, err := u.taskFilter(ctx, userID, filterExpr, perspectiveID)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []repository.ListTasksWithStatsRow
+	if expr != nil {
+		// today や due<7d はユーザーのタイムゾーンの日付で解釈する
+		var prefs Preferences
+		if prefs, err = loadPreferences(ctx, u.repo, userID); err == nil {
+			tasks, err = u.repo.ListTasksWithFilter(ctx, arg, expr, time.Now().In(prefs.Location()))
+		}
+	} else {
+		tasks, err = u.repo.ListTasksWithStats(ctx, arg)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tasks: %w", err)
 	}
 	return tasks, nil
 }
 
-func (u *taskUsecase) UpdateTaskStatus(ctx context.Context, userID, taskID uuid.UUID, status repository.TaskStatus) (*repository.Task, error) {
+// taskFilter は指定されたフィルタ式と保存済みパースペクティブの式を AND で結合する
+func (u *taskUsecase) taskFilter(ctx context.Context, userID uuid.UUID, filterExpr string, perspectiveID *uuid.UUID) (filter.Expr, error) {
+	var expr filter.Expr
+	if perspectiveID != nil {
+		p, err := u.repo.GetPerspective(ctx, repository.GetPerspectiveParams{ID: *perspectiveID, UserID: userID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, NewNotFoundError("perspective not found")
+			}
+			return nil, fmt.Errorf("failed to get perspective: %w", err)
+		}
+		if expr, err = parseTaskFilter(p.Filter); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(filterExpr) != "" {
+		e, err := parseTaskFilter(filterExpr)
+		if err != nil {
+			return nil, err
+		}
+		if expr == nil {
+			expr = e
+		} else {
+			expr = filter.And{Left: expr, Right: e}
+		}
+	}
+	return expr, nil
+}
+
+func (u *taskUsecase) UpdateTask(ctx context.Context, userID, taskID uuid.UUID, input UpdateTaskInput) (*repository.Task, error) {
+	var task repository.Task
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		current, err := q.GetTask(ctx, repository.GetTaskParams{ID: taskID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		task, err = q.UpdateTask(ctx, repository.UpdateTaskParams{
+			ID:               taskID,
+			UserID:           userID,
+			Title:            toText(input.Title),
+			NoteMarkdown:     toText(input.Note),
+			DueDate:          toTimestamp(input.DueDate),
+			Priority:         toInt2(input.Priority),
+			EstimatedMinutes: toInt4(input.EstimatedMinutes),
+		})
+		if err != nil {
+			return err
+		}
+		if input.SyncChecklist {
+			if _, err := syncChecklistFromNote(ctx, q, task); err != nil {
+				return err
+			}
+		}
+		if err := recordTaskActivity(ctx, q, &current, task); err != nil {
+			return err
+		}
+		return journal(ctx, q, userID, OperationTaskUpdate, revertTaskStep(current, task))
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("task not found")
+		}
+		return nil, fmt.Errorf("failed to update task: %w", err)
+	}
+	return &task, nil
+}
+
+// UpdateTaskStatus は基本ステータスまたはプロジェクト独自のステータス(statusID)へ変更する
+func (u *taskUsecase) UpdateTaskStatus(ctx context.Context, userID, taskID uuid.UUID, status repository.TaskStatus, statusID *uuid.UUID) (*repository.Task, error) {
+	var task repository.Task
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		current, err := q.GetTask(ctx, repository.GetTaskParams{ID: taskID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if task, err = applyTaskStatus(ctx, q, userID, current, status, statusID); err != nil {
+			return err
+		}
+		return journal(ctx, q, userID, OperationTaskStatus, revertTaskStep(current, task))
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("task not found")
+		}
+		return nil, fmt.Errorf("failed to update task: %w", err)
+	}
+	return &task, nil
+}
+
+// DeleteTask はタスクをゴミ箱に入れる
+func (u *taskUsecase) DeleteTask(ctx context.Context, userID, taskID uuid.UUID) error {
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		n, err := q.TrashTask(ctx, repository.TrashTaskParams{ID: taskID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return NewNotFoundError("task not found")
+		}
+		return journal(ctx, q, userID, OperationTaskDelete, restoreStep(TrashKindTask, taskID))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
+	}
+	return nil
+}
+
+func (u *taskUsecase) GetBoard(ctx context.Context, userID, projectID uuid.UUID) (*Board, error) {
+	project, err := u.repo.GetProject(ctx, repository.GetProjectParams{ID: projectID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("project not found")
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	statuses, err := u.repo.ListProjectStatuses(ctx, repository.ListProjectStatusesParams{ProjectID: projectID, UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project statuses: %w", err)
+	}
+
+	tasks, err := u.repo.ListBoardTasks(ctx, repository.ListBoardTasksParams{ProjectID: projectID, UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list board tasks: %w", err)
+	}
+
+	// 独自ステータスの列を先に並べ、対応する独自ステータスがない基本カテゴリの列を補う
+	board := &Board{ProjectID: projectID}
+	byID := make(map[uuid.UUID]int, len(statuses))
+	byCategory := make(map[repository.TaskStatus]int, len(boardStatuses))
+	for _, ps := range statuses {
+		byID[ps.ID] = len(board.Lanes)
+		if _, ok := byCategory[ps.Category]; !ok {
+			byCategory[ps.Category] = len(board.Lanes)
+		}
+		board.Lanes = append(board.Lanes, BoardLane{
+			Status:   ps.Category,
+			StatusID: &ps.ID,
+			Name:     ps.Name,
+			Color:    ps.Color.String,
+		})
+	}
+	for _, st := range boardStatuses {
+		if _, ok := byCategory[st]; ok {
+			continue
+		}
+		byCategory[st] = len(board.Lanes)
+		board.Lanes = append(board.Lanes, BoardLane{Status: st, Name: string(st)})
+	}
+	for i := range board.Lanes {
+		board.Lanes[i].Tasks = []repository.ListBoardTasksRow{}
+		if board.Lanes[i].Status == repository.TaskStatusDOING && project.WipLimit.Valid {
+			board.Lanes[i].WipLimit = &project.WipLimit.Int32
+		}
+	}
+
+	for _, t := range tasks {
+		idx, ok := byCategory[t.Status]
+		if t.CustomStatusID.Valid {
+			if i, found := byID[t.CustomStatusID.Bytes]; found {
+				idx, ok = i, true
+			}
+		}
+		if !ok {
+			continue
+		}
+		board.Lanes[idx].Tasks = append(board.Lanes[idx].Tasks, t)
+		board.Lanes[idx].Count++
+	}
+	return board, nil
+}
+
+// MoveTask はタスクを prevID と nextID の間へ移動し、ステータスも同時に変更する。
+// 両方 nil の場合はレーンの末尾に置く。
+func (u *taskUsecase) MoveTask(ctx context.Context, userID, taskID uuid.UUID, status repository.TaskStatus, statusID, prevID, nextID *uuid.UUID) (*repository.Task, error) {
+	var task repository.Task
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		current, err := q.GetTask(ctx, repository.GetTaskParams{ID: taskID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		status, customStatusID, err := resolveTaskStatus(ctx, q, userID, current, status, statusID)
+		if err != nil {
+			return err
+		}
+		// プロジェクトをロックし、同じボードへの並行した移動を直列化する
+		if err := checkWipLimit(ctx, q, userID, current, status); err != nil {
+			return err
+		}
+
+		position, err := boardPosition(ctx, q, userID, current, prevID, nextID)
+		if err != nil {
+			return err
+		}
+
+		task, err = q.MoveTask(ctx, repository.MoveTaskParams{
+			ID:             taskID,
+			UserID:         userID,
+			Status:         status,
+			CustomStatusID: customStatusID,
+			Position:       position,
+		})
+		if err != nil {
+			return err
+		}
+		if err := recordTaskActivity(ctx, q, &current, task); err != nil {
+			return err
+		}
+		return journal(ctx, q, userID, OperationTaskMove, revertTaskStep(current, task))
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("task not found")
+		}
+		return nil, fmt.Errorf("failed to move task: %w", err)
+	}
+	return &task, nil
+}
+
+// applyTaskStatus は取得済みのタスクのステータスを変更し、履歴を記録する
+func applyTaskStatus(ctx context.Context, q *repository.Queries, userID uuid.UUID, current repository.Task, status repository.TaskStatus, statusID *uuid.UUID) (repository.Task, error) {
+	status, customStatusID, err := resolveTaskStatus(ctx, q, userID, current, status, statusID)
+	if err != nil {
+		return repository.Task{}, err
+	}
+	if err := checkWipLimit(ctx, q, userID, current, status); err != nil {
+		return repository.Task{}, err
+	}
+
 	var completedAt pgtype.Timestamptz
 	if status == repository.TaskStatusDONE {
 		completedAt = toTimestamp(ptr(time.Now()))
 	} else {
 		completedAt = pgtype.Timestamptz{Valid: false}
 	}
-	arg := repository.UpdateTaskParams{
-		ID:          taskID,
-		UserID:      userID,
-		Status:      repository.NullTaskStatus{TaskStatus: status, Valid: true},
-		CompletedAt: completedAt,
+	task, err := q.UpdateTask(ctx, repository.UpdateTaskParams{
+		ID:             current.ID,
+		UserID:         userID,
+		Status:         repository.NullTaskStatus{TaskStatus: status, Valid: true},
+		CompletedAt:    completedAt,
+		CustomStatusID: customStatusID,
+	})
+	if err != nil {
+		return repository.Task{}, err
 	}
+	return task, recordTaskActivity(ctx, q, &current, task)
+}
 
-	task, err := u.repo.UpdateTask(ctx, arg)
+// GetMatrix は期限が urgentWithin 以内のタスクを緊急、優先度が中以上のタスクを重要として4象限に分ける
+func (u *taskUsecase) GetMatrix(ctx context.Context, userID uuid.UUID, projectID *uuid.UUID, urgentWithin time.Duration, now time.Time) (*Matrix, error) {
+	tasks, err := u.repo.ListMatrixTasks(ctx, repository.ListMatrixTasksParams{
+		UserID:    userID,
+		ProjectID: toUUID(projectID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	matrix := &Matrix{
+		UrgentBefore: now.Add(urgentWithin),
+		Quadrants: []MatrixQuadrant{
+			{Key: "do", Urgent: true, Important: true},
+			{Key: "schedule", Urgent: false, Important: true},
+			{Key: "delegate", Urgent: true, Important: false},
+			{Key: "eliminate", Urgent: false, Important: false},
+		},
+	}
+	for i := range matrix.Quadrants {
+		matrix.Quadrants[i].Tasks = []repository.ListMatrixTasksRow{}
+	}
+	for _, t := range tasks {
+		urgent := t.DueDate.Valid && t.DueDate.Time.Before(matrix.UrgentBefore)
+		for i := range matrix.Quadrants {
+			q := &matrix.Quadrants[i]
+			if q.Urgent == urgent && q.Important == isImportant(t.Priority) {
+				q.Tasks = append(q.Tasks, t)
+				q.Count++
+				break
+			}
+		}
+	}
+	return matrix, nil
+}
+
+// resolveTaskStatus は statusID が指定されていればその基本カテゴリを返す
+func resolveTaskStatus(ctx context.Context, q *repository.Queries, userID uuid.UUID, task repository.Task, status repository.TaskStatus, statusID *uuid.UUID) (repository.TaskStatus, pgtype.UUID, error) {
+	if statusID == nil {
+		return status, pgtype.UUID{}, nil
+	}
+	ps, err := q.GetProjectStatus(ctx, repository.GetProjectStatusParams{ID: *statusID, UserID: userID})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, NewNotFoundError("task not found")
+			return "", pgtype.UUID{}, NewBadRequestError("status not found")
 		}
-		return nil, fmt.Errorf("failed to update task: %w", err)
+		return "", pgtype.UUID{}, err
 	}
-	return &task, nil
+	if ps.ProjectID != task.ProjectID {
+		return "", pgtype.UUID{}, NewBadRequestError("status belongs to another project")
+	}
+	return ps.Category, toUUID(&ps.ID), nil
+}
+
+// checkWipLimit はプロジェクトを行ロックした上で、DOINGへの変更がWIP制限を超えないか確認する
+func checkWipLimit(ctx context.Context, q *repository.Queries, userID uuid.UUID, task repository.Task, status repository.TaskStatus) error {
+	project, err := q.GetProjectForUpdate(ctx, repository.GetProjectForUpdateParams{ID: task.ProjectID, UserID: userID})
+	if err != nil {
+		return err
+	}
+	if status != repository.TaskStatusDOING || task.Status == repository.TaskStatusDOING || !project.WipLimit.Valid {
+		return nil
+	}
+
+	count, err := q.CountTasksInLane(ctx, repository.CountTasksInLaneParams{
+		ProjectID: task.ProjectID,
+		Status:    repository.TaskStatusDOING,
+		ID:        task.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if count >= int64(project.WipLimit.Int32) {
+		return NewConflictError(fmt.Sprintf("WIP limit reached (%d tasks in DOING)", project.WipLimit.Int32))
+	}
+	return nil
 }
 
-func (u *taskUsecase) AddChecklistItem(ctx context.Context, taskID uuid.UUID, content string) (*repository.ChecklistItem, error) {
+// boardPosition は前後のタスクから移動先の順位キーを求める。
+// キーが詰まって間に入れられない場合や、順位キーを持たない (空の) 前後のタスクがある場合は
+// プロジェクト内の順位を振り直す。空のキーは Between では上下限なしと同じ意味になってしまうため。
+func boardPosition(ctx context.Context, q *repository.Queries, userID uuid.UUID, task repository.Task, prevID, nextID *uuid.UUID) (string, error) {
+	if prevID == nil && nextID == nil {
+		return nextTaskPosition(ctx, q, task.ProjectID)
+	}
+
+	neighbor := func(id *uuid.UUID) (string, error) {
+		if id == nil {
+			return "", nil
+		}
+		if *id == task.ID {
+			return "", NewBadRequestError("task cannot be its own neighbor")
+		}
+		t, err := q.GetTask(ctx, repository.GetTaskParams{ID: *id, UserID: userID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return "", NewBadRequestError("neighbor task not found")
+			}
+			return "", err
+		}
+		if t.ProjectID != task.ProjectID {
+			return "", NewBadRequestError("neighbor task belongs to another project")
+		}
+		return t.Position, nil
+	}
+
+	prev, err := neighbor(prevID)
+	if err != nil {
+		return "", err
+	}
+	next, err := neighbor(nextID)
+	if err != nil {
+		return "", err
+	}
+	legacy := (prevID != nil && prev == "") || (nextID != nil && next == "")
+	if !legacy {
+		if pos, err := rank.Between(prev, next); err == nil {
+			return pos, nil
+		}
+	}
+
+	positions, err := rebalanceTaskPositions(ctx, q, task.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	if prevID != nil {
+		prev = positions[*prevID]
+	}
+	if nextID != nil {
+		next = positions[*nextID]
+	}
+	pos, err := rank.Between(prev, next)
+	if err != nil {
+		return "", NewBadRequestError("prev task must be placed before next task")
+	}
+	return pos, nil
+}
+
+// rebalanceTaskPositions はプロジェクト内の順位キーを現在の並び順のまま等間隔に振り直す
+func rebalanceTaskPositions(ctx context.Context, q *repository.Queries, projectID uuid.UUID) (map[uuid.UUID]string, error) {
+	rows, err := q.ListTaskPositions(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	keys := rank.Sequence(len(rows))
+	positions := make(map[uuid.UUID]string, len(rows))
+	for i, r := range rows {
+		if err := q.SetTaskPosition(ctx, repository.SetTaskPositionParams{ID: r.ID, Position: keys[i]}); err != nil {
+			return nil, err
+		}
+		positions[r.ID] = keys[i]
+	}
+	return positions, nil
+}
+
+// nextTaskPosition はプロジェクトの末尾に置くための順位キーを返す
+func nextTaskPosition(ctx context.Context, q *repository.Queries, projectID uuid.UUID) (string, error) {
+	last, err := q.GetLastTaskPosition(ctx, projectID)
+	if err != nil {
+		return "", err
+	}
+	return rank.After(last)
+}
+
+func (u *taskUsecase) AddChecklistItem(ctx context.Context, taskID uuid.UUID, content string, estimatedMinutes *int32) (*repository.ChecklistItem, error) {
 	item, err := u.repo.CreateChecklistItem(ctx, repository.CreateChecklistItemParams{
-		TaskID:  taskID,
-		Content: content,
+		TaskID:           taskID,
+		Content:          content,
+		EstimatedMinutes: toInt4(estimatedMinutes),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to add item: %w", err)
@@ -117,16 +611,134 @@ func (u *taskUsecase) AddChecklistItem(ctx context.Context, taskID uuid.UUID, co
 	return &item, nil
 }
 
-func (u *taskUsecase) ToggleChecklistItem(ctx context.Context, itemID uuid.UUID, isCompleted bool) (*repository.ChecklistItem, error) {
+// UpdateChecklistItem は完了状態と見積もりを更新する(負の見積もりは解除)
+func (u *taskUsecase) UpdateChecklistItem(ctx context.Context, itemID uuid.UUID, isCompleted *bool, estimatedMinutes *int32) (*repository.ChecklistItem, error) {
+	var done pgtype.Bool
+	if isCompleted != nil {
+		done = pgtype.Bool{Bool: *isCompleted, Valid: true}
+	}
 	item, err := u.repo.UpdateChecklistItem(ctx, repository.UpdateChecklistItemParams{
-		ID:          itemID,
-		IsCompleted: pgtype.Bool{Bool: isCompleted, Valid: true},
+		ID:               itemID,
+		IsCompleted:      done,
+		EstimatedMinutes: toInt4(estimatedMinutes),
 	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, NewNotFoundError("checklist item not found")
 		}
-		return nil, fmt.Errorf("failed to toggle item: %w", err)
+		return nil, fmt.Errorf("failed to update item: %w", err)
 	}
 	return &item, nil
 }
+
+// EstimateGroup は期間内の1グループ(プロジェクトまたはカテゴリ)の見積もり精度
+// AccuracyRatio は見積もりのあるタスクの 実績/見積もり(1 を超えると過小見積もり)
+type EstimateGroup struct {
+	ID                  uuid.UUID `json:"id"`
+	Name                string    `json:"name"`
+	TaskCount           int64     `json:"task_count"`
+	EstimatedCount      int64     `json:"estimated_count"`
+	UnderestimatedCount int64     `json:"underestimated_count"`
+	EstimatedMinutes    int64     `json:"estimated_minutes"`
+	ActualMinutes       float64   `json:"actual_minutes"`
+	AccuracyRatio       *float64  `json:"accuracy_ratio"`
+
+	estimatedActual float64
+}
+
+type EstimatePeriod struct {
+	Period time.Time       `json:"period"`
+	Groups []EstimateGroup `json:"groups"`
+}
+
+type EstimateReport struct {
+	Interval string           `json:"interval"`
+	GroupBy  string           `json:"group_by"`
+	Periods  []EstimatePeriod `json:"periods"`
+	Total    EstimateGroup    `json:"total"`
+}
+
+func (g *EstimateGroup) add(r repository.GetEstimateReportRow) {
+	g.TaskCount += r.TaskCount
+	g.EstimatedCount += r.EstimatedCount
+	g.UnderestimatedCount += r.UnderestimatedCount
+	g.EstimatedMinutes += r.EstimatedMinutes
+	g.ActualMinutes += r.ActualMinutes
+	g.estimatedActual += r.EstimatedActualMinutes
+}
+
+func (g *EstimateGroup) finish() {
+	if g.EstimatedMinutes > 0 {
+		g.AccuracyRatio = ptr(g.estimatedActual / float64(g.EstimatedMinutes))
+	}
+}
+
+// GetEstimateReport は期間内に完了したタスクの見積もりと実績を interval(day/week/month)ごとに集計する。
+// from/to は日付で、ユーザーのタイムゾーンの [from, to) とする。ゼロ値なら to は明日 (今日まで)、from は to の3か月前
+func (u *taskUsecase) GetEstimateReport(ctx context.Context, userID uuid.UUID, from, to time.Time, interval string, groupBy string) (*EstimateReport, error) {
+	switch interval {
+	case "day", "week", "month":
+	default:
+		return nil, NewBadRequestError("interval must be day, week or month")
+	}
+	if groupBy != "project" && groupBy != "category" {
+		return nil, NewBadRequestError("group_by must be project or category")
+	}
+
+	prefs, err := loadPreferences(ctx, u.repo, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get estimate report: %w", err)
+	}
+	loc := prefs.Location()
+	if to.IsZero() {
+		to = time.Now().In(loc).AddDate(0, 0, 1)
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, -3, 0)
+	}
+	from, to = localDay(from, loc), localDay(to, loc)
+
+	rows, err := u.repo.GetEstimateReport(ctx, repository.GetEstimateReportParams{
+		UserID:   userID,
+		FromDate: toTimestamp(&from),
+		ToDate:   toTimestamp(&to),
+		Interval: interval,
+		Timezone: prefs.Timezone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get estimate report: %w", err)
+	}
+
+	report := &EstimateReport{Interval: interval, GroupBy: groupBy, Periods: []EstimatePeriod{}}
+	// 行は期間順に並んでいる
+	var index map[uuid.UUID]int
+	for _, r := range rows {
+		n := len(report.Periods)
+		if n == 0 || !report.Periods[n-1].Period.Equal(r.Period.Time) {
+			report.Periods = append(report.Periods, EstimatePeriod{Period: r.Period.Time, Groups: []EstimateGroup{}})
+			index = map[uuid.UUID]int{}
+			n++
+		}
+		p := &report.Periods[n-1]
+
+		id, name := r.ProjectID, r.ProjectTitle
+		if groupBy == "category" {
+			id, name = r.CategoryID, r.CategoryName
+		}
+		i, ok := index[id]
+		if !ok {
+			p.Groups = append(p.Groups, EstimateGroup{ID: id, Name: name})
+			i = len(p.Groups) - 1
+			index[id] = i
+		}
+		p.Groups[i].add(r)
+		report.Total.add(r)
+	}
+	for pi := range report.Periods {
+		for gi := range report.Periods[pi].Groups {
+			report.Periods[pi].Groups[gi].finish()
+		}
+	}
+	report.Total.finish()
+	return report, nil
+}
@@ -0,0 +1,611 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TaskTemplate は TASK テンプレートの本体。文字列には {{name}} 形式のプレースホルダを含められる。
+// 基準日の {{date}} と、その ISO 週番号の {{week}} は指定しなくても使える
+type TaskTemplate struct {
+	Title        string   `json:"title"`
+	NoteMarkdown string   `json:"note_markdown,omitempty"`
+	Priority     int16    `json:"priority,omitempty"`
+	Checklist    []string `json:"checklist,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	// 基準日の0時(UTC)からの相対的な期限(分)
+	DueOffsetMinutes *int64 `json:"due_offset_minutes,omitempty"`
+	// プロジェクトテンプレート内の statuses の名前
+	CustomStatus string `json:"custom_status,omitempty"`
+}
+
+type StatusTemplate struct {
+	Name     string                `json:"name"`
+	Category repository.TaskStatus `json:"category"`
+	Color    string                `json:"color,omitempty"`
+}
+
+type CalendarTemplate struct {
+	Name        string `json:"name"`
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type TimetableSlotTemplate struct {
+	DayOfWeek int16  `json:"day_of_week"`
+	StartTime string `json:"start_time"` // HH:MM
+	EndTime   string `json:"end_time"`   // HH:MM
+	Location  string `json:"location,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// ProjectTemplate は PROJECT テンプレートの本体
+type ProjectTemplate struct {
+	Title          string                  `json:"title"`
+	Description    string                  `json:"description,omitempty"`
+	Color          string                  `json:"color,omitempty"`
+	CategoryID     *uuid.UUID              `json:"category_id,omitempty"`
+	WipLimit       *int32                  `json:"wip_limit,omitempty"`
+	Statuses       []StatusTemplate        `json:"statuses,omitempty"`
+	Calendars      []CalendarTemplate      `json:"calendars,omitempty"`
+	TimetableSlots []TimetableSlotTemplate `json:"timetable_slots,omitempty"`
+	Tasks          []TaskTemplate          `json:"tasks,omitempty"`
+}
+
+type InstantiateOptions struct {
+	// TASK テンプレートの作成先(必須)
+	ProjectID *uuid.UUID
+	// PROJECT テンプレートのカテゴリ(テンプレート側の指定を上書き)
+	CategoryID *uuid.UUID
+	BaseDate   time.Time
+	Variables  map[string]string
+}
+
+type InstantiateResult struct {
+	Project        *repository.Project        `json:"project,omitempty"`
+	Tasks          []repository.Task          `json:"tasks"`
+	Calendars      []repository.Calendar      `json:"calendars,omitempty"`
+	TimetableSlots []repository.TimetableSlot `json:"timetable_slots,omitempty"`
+}
+
+type TemplateUsecase interface {
+	CreateTemplate(ctx context.Context, userID uuid.UUID, kind repository.TemplateKind, name string, body json.RawMessage) (*repository.Template, error)
+	CaptureTask(ctx context.Context, userID, taskID uuid.UUID, name string) (*repository.Template, error)
+	CaptureProject(ctx context.Context, userID, projectID uuid.UUID, name string) (*repository.Template, error)
+	ListTemplates(ctx context.Context, userID uuid.UUID, kind *repository.TemplateKind) ([]repository.Template, error)
+	GetTemplate(ctx context.Context, userID, templateID uuid.UUID) (*repository.Template, error)
+	DeleteTemplate(ctx context.Context, userID, templateID uuid.UUID) error
+
+	Instantiate(ctx context.Context, userID, templateID uuid.UUID, opts InstantiateOptions) (*InstantiateResult, error)
+}
+
+type templateUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+}
+
+func NewTemplateUsecase(repo *repository.Queries, txManager db.TxManager) TemplateUsecase {
+	return &templateUsecase{
+		repo:      repo,
+		txManager: txManager,
+	}
+}
+
+func (u *templateUsecase) CreateTemplate(ctx context.Context, userID uuid.UUID, kind repository.TemplateKind, name string, body json.RawMessage) (*repository.Template, error) {
+	var tmpl any
+	switch kind {
+	case repository.TemplateKindTASK:
+		var t TaskTemplate
+		if err := json.Unmarshal(body, &t); err != nil || t.Title == "" {
+			return nil, NewBadRequestError("invalid task template")
+		}
+		if !validPriority(t.Priority) {
+			return nil, NewBadRequestError("priority must be between 0 and 3")
+		}
+		tmpl = t
+	case repository.TemplateKindPROJECT:
+		var p ProjectTemplate
+		if err := json.Unmarshal(body, &p); err != nil || p.Title == "" {
+			return nil, NewBadRequestError("invalid project template")
+		}
+		for _, t := range p.Tasks {
+			if !validPriority(t.Priority) {
+				return nil, NewBadRequestError("priority must be between 0 and 3")
+			}
+		}
+		if err := checkStatusTemplates(p.Statuses); err != nil {
+			return nil, err
+		}
+		tmpl = p
+	default:
+		return nil, NewBadRequestError("unsupported template kind")
+	}
+	return u.saveTemplate(ctx, userID, kind, name, tmpl)
+}
+
+// checkStatusTemplates はステータスの名前と、基本カテゴリが TODO / DOING / DONE のいずれかであることを確認する
+func checkStatusTemplates(statuses []StatusTemplate) error {
+	for _, s := range statuses {
+		if s.Name == "" {
+			return NewBadRequestError("status name is required")
+		}
+		switch s.Category {
+		case repository.TaskStatusTODO, repository.TaskStatusDOING, repository.TaskStatusDONE:
+		default:
+			return NewBadRequestError(fmt.Sprintf("invalid status category %q", s.Category))
+		}
+	}
+	return nil
+}
+
+func (u *templateUsecase) CaptureTask(ctx context.Context, userID, taskID uuid.UUID, name string) (*repository.Template, error) {
+	task, err := u.repo.GetTask(ctx, repository.GetTaskParams{ID: taskID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("task not found")
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	captured, err := u.captureTasks(ctx, []repository.Task{task}, truncateDay(task.CreatedAt.Time), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture task: %w", err)
+	}
+	return u.saveTemplate(ctx, userID, repository.TemplateKindTASK, name, captured[0])
+}
+
+func (u *templateUsecase) CaptureProject(ctx context.Context, userID, projectID uuid.UUID, name string) (*repository.Template, error) {
+	project, err := u.repo.GetProject(ctx, repository.GetProjectParams{ID: projectID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("project not found")
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	tmpl := ProjectTemplate{
+		Title:       project.Title,
+		Description: project.Description.String,
+		Color:       project.Color.String,
+		CategoryID:  &project.CategoryID,
+	}
+	if project.WipLimit.Valid {
+		tmpl.WipLimit = &project.WipLimit.Int32
+	}
+
+	statuses, err := u.repo.ListProjectStatuses(ctx, repository.ListProjectStatusesParams{ProjectID: projectID, UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project statuses: %w", err)
+	}
+	statusNames := make(map[uuid.UUID]string, len(statuses))
+	for _, s := range statuses {
+		tmpl.Statuses = append(tmpl.Statuses, StatusTemplate{Name: s.Name, Category: s.Category, Color: s.Color.String})
+		statusNames[s.ID] = s.Name
+	}
+
+	calendars, err := u.repo.ListCalendarsByProject(ctx, repository.ListCalendarsByProjectParams{UserID: userID, ProjectID: toUUID(&projectID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendars: %w", err)
+	}
+	for _, c := range calendars {
+		tmpl.Calendars = append(tmpl.Calendars, CalendarTemplate{Name: c.Name, Color: c.Color.String, Description: c.Description.String})
+	}
+
+	slots, err := u.repo.ListTimetableSlotsByProject(ctx, repository.ListTimetableSlotsByProjectParams{UserID: userID, ProjectID: projectID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list timetable slots: %w", err)
+	}
+	for _, s := range slots {
+		tmpl.TimetableSlots = append(tmpl.TimetableSlots, TimetableSlotTemplate{
+			DayOfWeek: s.DayOfWeek,
+			StartTime: formatPgTime(s.StartTime),
+			EndTime:   formatPgTime(s.EndTime),
+			Location:  s.Location.String,
+			Note:      s.Note.String,
+		})
+	}
+
+	tasks, err := u.repo.ListTasksByProject(ctx, repository.ListTasksByProjectParams{ProjectID: projectID, UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	tmpl.Tasks, err = u.captureTasks(ctx, tasks, truncateDay(project.CreatedAt.Time), statusNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture tasks: %w", err)
+	}
+
+	return u.saveTemplate(ctx, userID, repository.TemplateKindPROJECT, name, tmpl)
+}
+
+func (u *templateUsecase) ListTemplates(ctx context.Context, userID uuid.UUID, kind *repository.TemplateKind) ([]repository.Template, error) {
+	var argKind repository.NullTemplateKind
+	if kind != nil {
+		argKind = repository.NullTemplateKind{TemplateKind: *kind, Valid: true}
+	}
+	templates, err := u.repo.ListTemplates(ctx, repository.ListTemplatesParams{UserID: userID, Kind: argKind})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	return templates, nil
+}
+
+func (u *templateUsecase) GetTemplate(ctx context.Context, userID, templateID uuid.UUID) (*repository.Template, error) {
+	tmpl, err := u.repo.GetTemplate(ctx, repository.GetTemplateParams{ID: templateID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("template not found")
+		}
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return &tmpl, nil
+}
+
+func (u *templateUsecase) DeleteTemplate(ctx context.Context, userID, templateID uuid.UUID) error {
+	n, err := u.repo.DeleteTemplate(ctx, repository.DeleteTemplateParams{ID: templateID, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	if n == 0 {
+		return NewNotFoundError("template not found")
+	}
+	return nil
+}
+
+// Instantiate はテンプレートから1トランザクションでタスク・プロジェクト一式を作成する
+func (u *templateUsecase) Instantiate(ctx context.Context, userID, templateID uuid.UUID, opts InstantiateOptions) (*InstantiateResult, error) {
+	tmpl, err := u.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	base := truncateDay(opts.BaseDate)
+	_, week := base.ISOWeek()
+	vars := map[string]string{
+		"date": base.Format("2006-01-02"),
+		"week": strconv.Itoa(week),
+	}
+	for k, v := range opts.Variables {
+		vars[k] = v
+	}
+	ex := &expander{vars: vars}
+
+	res := &InstantiateResult{Tasks: []repository.Task{}}
+	switch tmpl.Kind {
+	case repository.TemplateKindTASK:
+		var t TaskTemplate
+		if err := json.Unmarshal(tmpl.Body, &t); err != nil {
+			return nil, fmt.Errorf("failed to decode template: %w", err)
+		}
+		if opts.ProjectID == nil {
+			return nil, NewBadRequestError("project_id is required")
+		}
+		err = u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+			if _, err := q.GetProject(ctx, repository.GetProjectParams{ID: *opts.ProjectID, UserID: userID}); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return NewNotFoundError("project not found")
+				}
+				return err
+			}
+			calendarID, err := defaultCalendarID(ctx, q, userID)
+			if err != nil {
+				return err
+			}
+			task, err := createTaskFromTemplate(ctx, q, userID, *opts.ProjectID, calendarID, t, base, ex, nil)
+			if err != nil {
+				return err
+			}
+			res.Tasks = append(res.Tasks, *task)
+			// 未定義の変数があればロールバックする
+			return ex.err
+		})
+
+	case repository.TemplateKindPROJECT:
+		var p ProjectTemplate
+		if err := json.Unmarshal(tmpl.Body, &p); err != nil {
+			return nil, fmt.Errorf("failed to decode template: %w", err)
+		}
+		categoryID := p.CategoryID
+		if opts.CategoryID != nil {
+			categoryID = opts.CategoryID
+		}
+		if categoryID == nil {
+			return nil, NewBadRequestError("category_id is required")
+		}
+		if err := checkStatusTemplates(p.Statuses); err != nil {
+			return nil, err
+		}
+		err = u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+			if err := instantiateProject(ctx, q, userID, *categoryID, p, base, ex, res); err != nil {
+				return err
+			}
+			return ex.err
+		})
+
+	default:
+		return nil, NewBadRequestError("unsupported template kind")
+	}
+
+	if err != nil {
+		if isExclusionViolation(err) {
+			return nil, NewConflictError("timetable slot overlaps an existing slot")
+		}
+		if isUniqueViolation(err) {
+			return nil, NewConflictError("duplicate entry in template")
+		}
+		return nil, fmt.Errorf("failed to instantiate template: %w", err)
+	}
+	return res, nil
+}
+
+func instantiateProject(ctx context.Context, q *repository.Queries, userID, categoryID uuid.UUID, p ProjectTemplate, base time.Time, ex *expander, res *InstantiateResult) error {
+	project, err := q.CreateProject(ctx, repository.CreateProjectParams{
+		UserID:      userID,
+		CategoryID:  categoryID,
+		Title:       ex.expand(p.Title),
+		Description: toTextFromStr(ex.expand(p.Description)),
+		Color:       toTextFromStr(p.Color),
+		IsArchived:  false,
+	})
+	if err != nil {
+		return err
+	}
+	if p.WipLimit != nil {
+		project, err = q.UpdateProject(ctx, repository.UpdateProjectParams{
+			ID:       project.ID,
+			UserID:   userID,
+			WipLimit: pgtype.Int4{Int32: *p.WipLimit, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	res.Project = &project
+
+	statuses := make(map[string]repository.ProjectStatus, len(p.Statuses))
+	for _, s := range p.Statuses {
+		status, err := q.CreateProjectStatus(ctx, repository.CreateProjectStatusParams{
+			Name:      s.Name,
+			Category:  s.Category,
+			Color:     toTextFromStr(s.Color),
+			ProjectID: project.ID,
+			UserID:    userID,
+		})
+		if err != nil {
+			return err
+		}
+		statuses[s.Name] = status
+	}
+
+	for _, c := range p.Calendars {
+		cal, err := q.CreateCalendar(ctx, repository.CreateCalendarParams{
+			UserID:      userID,
+			Name:        ex.expand(c.Name),
+			Color:       toTextFromStr(c.Color),
+			Description: toTextFromStr(ex.expand(c.Description)),
+			ProjectID:   toUUID(&project.ID),
+		})
+		if err != nil {
+			return err
+		}
+		res.Calendars = append(res.Calendars, cal)
+	}
+
+	for _, s := range p.TimetableSlots {
+		start, err := time.Parse("15:04", s.StartTime)
+		if err != nil {
+			return NewBadRequestError("invalid timetable start_time: " + s.StartTime)
+		}
+		end, err := time.Parse("15:04", s.EndTime)
+		if err != nil {
+			return NewBadRequestError("invalid timetable end_time: " + s.EndTime)
+		}
+		slot, err := q.CreateTimetableSlot(ctx, repository.CreateTimetableSlotParams{
+			UserID:    userID,
+			ProjectID: project.ID,
+			DayOfWeek: s.DayOfWeek,
+			StartTime: toPgTime(start),
+			EndTime:   toPgTime(end),
+			Location:  toTextFromStr(ex.expand(s.Location)),
+			Note:      toTextFromStr(ex.expand(s.Note)),
+		})
+		if err != nil {
+			return err
+		}
+		res.TimetableSlots = append(res.TimetableSlots, slot)
+	}
+
+	// タスクはテンプレートで作ったカレンダーがあればそこへ、なければ既定のカレンダーへ
+	var calendarID pgtype.UUID
+	if len(res.Calendars) > 0 {
+		calendarID = toUUID(&res.Calendars[0].ID)
+	} else if calendarID, err = defaultCalendarID(ctx, q, userID); err != nil {
+		return err
+	}
+	for _, t := range p.Tasks {
+		task, err := createTaskFromTemplate(ctx, q, userID, project.ID, calendarID, t, base, ex, statuses)
+		if err != nil {
+			return err
+		}
+		res.Tasks = append(res.Tasks, *task)
+	}
+	return nil
+}
+
+func createTaskFromTemplate(ctx context.Context, q *repository.Queries, userID, projectID uuid.UUID, calendarID pgtype.UUID, t TaskTemplate, base time.Time, ex *expander, statuses map[string]repository.ProjectStatus) (*repository.Task, error) {
+	position, err := nextTaskPosition(ctx, q, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var due *time.Time
+	if t.DueOffsetMinutes != nil {
+		due = ptr(base.Add(time.Duration(*t.DueOffsetMinutes) * time.Minute))
+	}
+
+	status := repository.TaskStatusTODO
+	var customStatusID pgtype.UUID
+	if ps, ok := statuses[t.CustomStatus]; ok && t.CustomStatus != "" {
+		status = ps.Category
+		customStatusID = toUUID(&ps.ID)
+	}
+
+	task, err := q.CreateTask(ctx, repository.CreateTaskParams{
+		UserID:       userID,
+		ProjectID:    projectID,
+		Title:        ex.expand(t.Title),
+		NoteMarkdown: toTextFromStr(ex.expand(t.NoteMarkdown)),
+		DueDate:      toTimestamp(due),
+		Priority:     pgtype.Int2{Int16: t.Priority, Valid: true},
+		CalendarID:   calendarID,
+		IcalUid:      pgtype.Text{String: uuid.NewString(), Valid: true},
+		Status:       status,
+		Position:     position,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if customStatusID.Valid {
+		task, err = q.UpdateTask(ctx, repository.UpdateTaskParams{
+			ID:             task.ID,
+			UserID:         userID,
+			CustomStatusID: customStatusID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := recordTaskActivity(ctx, q, nil, task); err != nil {
+		return nil, err
+	}
+
+	for _, item := range t.Checklist {
+		if _, err := q.CreateChecklistItem(ctx, repository.CreateChecklistItemParams{
+			TaskID:  task.ID,
+			Content: ex.expand(item),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if len(t.Tags) > 0 {
+		if err := setTaskTagsByName(ctx, q, userID, task.ID, t.Tags); err != nil {
+			return nil, err
+		}
+	}
+	return &task, nil
+}
+
+// captureTasks はタスクをテンプレート形式に変換する。期限は base からの相対値で保存する
+func (u *templateUsecase) captureTasks(ctx context.Context, tasks []repository.Task, base time.Time, statusNames map[uuid.UUID]string) ([]TaskTemplate, error) {
+	ids := make([]uuid.UUID, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	tagRows, err := u.repo.ListTaskTagNames(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[uuid.UUID][]string)
+	for _, r := range tagRows {
+		tags[r.TaskID] = append(tags[r.TaskID], r.Name)
+	}
+
+	out := make([]TaskTemplate, 0, len(tasks))
+	for _, t := range tasks {
+		tt := TaskTemplate{
+			Title:        t.Title,
+			NoteMarkdown: t.NoteMarkdown.String,
+			Priority:     t.Priority.Int16,
+			Tags:         tags[t.ID],
+		}
+		if t.DueDate.Valid {
+			tt.DueOffsetMinutes = ptr(int64(t.DueDate.Time.Sub(base) / time.Minute))
+		}
+		if t.CustomStatusID.Valid {
+			tt.CustomStatus = statusNames[t.CustomStatusID.Bytes]
+		}
+
+		items, err := u.repo.ListChecklistItems(ctx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			tt.Checklist = append(tt.Checklist, item.Content)
+		}
+		out = append(out, tt)
+	}
+	return out, nil
+}
+
+func (u *templateUsecase) saveTemplate(ctx context.Context, userID uuid.UUID, kind repository.TemplateKind, name string, body any) (*repository.Template, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode template: %w", err)
+	}
+	tmpl, err := u.repo.CreateTemplate(ctx, repository.CreateTemplateParams{
+		UserID: userID,
+		Kind:   kind,
+		Name:   name,
+		Body:   b,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, NewConflictError("template already exists")
+		}
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+	return &tmpl, nil
+}
+
+func defaultCalendarID(ctx context.Context, q *repository.Queries, userID uuid.UUID) (pgtype.UUID, error) {
+	cal, err := q.GetDefaultCalendar(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgtype.UUID{}, nil
+		}
+		return pgtype.UUID{}, err
+	}
+	return toUUID(&cal.ID), nil
+}
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// expander は {{name}} を変数で置き換える。未定義の変数があれば最初のものを err に記録する
+type expander struct {
+	vars map[string]string
+	err  error
+}
+
+func (e *expander) expand(s string) string {
+	return placeholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholderPattern.FindStringSubmatch(m)[1]
+		v, ok := e.vars[name]
+		if !ok {
+			if e.err == nil {
+				e.err = NewBadRequestError("missing template variable: " + name)
+			}
+			return m
+		}
+		return v
+	})
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func formatPgTime(t pgtype.Time) string {
+	d := time.Duration(t.Microseconds) * time.Microsecond
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
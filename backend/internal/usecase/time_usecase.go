@@ -2,21 +2,130 @@ package usecase
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
 	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type TimeUsecase interface {
-	StartTimeEntry(ctx context.Context, userID, projectID uuid.UUID, taskID *uuid.UUID, note string) (*repository.TimeEntry, error)
+	// StartTimeEntry は計測中のタイマーがあれば設定 (timer_conflict) に従って拒否するか止める
+	StartTimeEntry(ctx context.Context, userID, projectID uuid.UUID, taskID *uuid.UUID, note string, billable *bool) (*repository.TimeEntry, error)
+	// SwitchTimeEntry は計測中のタイマーを止めて新しく開始する
+	SwitchTimeEntry(ctx context.Context, userID, projectID uuid.UUID, taskID *uuid.UUID, note string, billable *bool) (*TimerSwitch, error)
 	StopTimeEntry(ctx context.Context, userID, entryID uuid.UUID) (*repository.TimeEntry, error)
-	ListTimeEntries(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]repository.ListTimeEntriesRow, error)
+	// CreateTimeEntry は範囲を指定してエントリを手動で追加する
+	CreateTimeEntry(ctx context.Context, userID uuid.UUID, input ManualTimeEntryInput) (*TimeEntryChange, error)
+	UpdateTimeEntry(ctx context.Context, userID, entryID uuid.UUID, input UpdateTimeEntryInput) (*TimeEntryChange, error)
+	DeleteTimeEntry(ctx context.Context, userID, entryID uuid.UUID) error
+	// SplitTimeEntry は at の時刻で2つのエントリに分ける
+	SplitTimeEntry(ctx context.Context, userID, entryID uuid.UUID, at time.Time) ([]repository.TimeEntry, error)
+	// MergeTimeEntries は同じプロジェクト・タスクの連続または重なったエントリを最初のものにまとめる
+	MergeTimeEntries(ctx context.Context, userID uuid.UUID, entryIDs []uuid.UUID, overlap OverlapMode) (*TimeEntryChange, error)
+	// GetCurrentTimeEntry は計測中のエントリを返す。なければ nil
+	GetCurrentTimeEntry(ctx context.Context, userID uuid.UUID) (*repository.GetRunningTimeEntryRow, error)
+	// ListTimeEntries は started_at の新しい順に1ページ分を返す
+	ListTimeEntries(ctx context.Context, userID uuid.UUID, filter TimeEntryFilter) (*TimeEntryPage, error)
 	GetContributionStats(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]repository.GetGrowthStatsRow, error)
+	// AutoStopTimers は設定の自動停止の条件を満たした計測中のタイマーを止め、止めた件数を返す。
+	// 失敗したタイマーがあっても残りは処理し、エラーはまとめて返す
+	AutoStopTimers(ctx context.Context, now time.Time) (int, error)
+	// ListAutoStops は自動で止めた計測を新しい順に返す
+	ListAutoStops(ctx context.Context, userID uuid.UUID) ([]repository.ListTimerAutoStopsRow, error)
+}
+
+// タイマーを自動で止めた理由
+const (
+	AutoStopMaxDuration = "max_duration"
+	AutoStopClock       = "clock"
+	AutoStopIdle        = "idle"
+)
+
+// 自動停止の一覧の件数
+const maxTimerAutoStops = 100
+
+// 一覧の1ページの件数
+const (
+	DefaultTimeEntryPageSize = 50
+	MaxTimeEntryPageSize     = 500
+)
+
+// TimeEntryFilter は一覧の条件。nil の項目では絞り込まない
+type TimeEntryFilter struct {
+	From          time.Time
+	To            time.Time // 含まない
+	ProjectID     *uuid.UUID
+	CategoryID    *uuid.UUID
+	RootType      *repository.RootCategoryType
+	TaskID        *uuid.UUID
+	TagID         *uuid.UUID
+	AutoGenerated *bool
+	Cursor        string // 前のページの next_cursor
+	Limit         int
+}
+
+// TimeEntryPage は一覧の1ページとそのページの合計
+type TimeEntryPage struct {
+	Entries      []repository.ListTimeEntriesRow `json:"entries"`
+	Count        int                             `json:"count"`
+	TotalSeconds int64                           `json:"total_seconds"`
+	NextCursor   string                          `json:"next_cursor,omitempty"` // 続きがなければ空
+}
+
+// TimerSwitch は切り替えで止めたエントリと開始したエントリ
+type TimerSwitch struct {
+	Stopped *repository.TimeEntry `json:"stopped"`
+	Started repository.TimeEntry  `json:"started"`
+}
+
+// OverlapMode は他のエントリと時間が重なったときの扱い
+type OverlapMode string
+
+const (
+	OverlapReject OverlapMode = "reject" // 409 を返す
+	OverlapTrim   OverlapMode = "trim"   // 重なった側を縮める・分ける・消す
+	OverlapAllow  OverlapMode = "allow"  // そのまま保存し、重なったエントリを返す
+)
+
+// 一度に結合できるエントリの上限
+const maxMergeEntries = 100
+
+type ManualTimeEntryInput struct {
+	ProjectID uuid.UUID
+	TaskID    *uuid.UUID
+	StartedAt time.Time
+	EndedAt   time.Time
+	Note      string
+	Billable  *bool // nil なら請求の対象にする
+	Overlap   OverlapMode
+}
+
+// UpdateTimeEntryInput は部分更新する項目。nil の項目は変更しない
+type UpdateTimeEntryInput struct {
+	ProjectID *uuid.UUID
+	TaskID    *uuid.UUID
+	StartedAt *time.Time
+	EndedAt   *time.Time
+	Note      *string
+	Billable  *bool
+	Overlap   OverlapMode
+}
+
+// TimeEntryChange は保存したエントリと、重なりの扱いの結果
+type TimeEntryChange struct {
+	Entry    repository.TimeEntry   `json:"entry"`
+	Overlaps []repository.TimeEntry `json:"overlaps,omitempty"` // allow: 重なっているエントリ (警告)
+	Adjusted []repository.TimeEntry `json:"adjusted,omitempty"` // trim: 縮めた・分けたエントリ
+	Removed  []uuid.UUID            `json:"removed,omitempty"`  // trim: 全体が重なったため消したエントリ
 }
 
 type timeUsecase struct {
@@ -31,59 +140,741 @@ func NewTimeUsecase(repo *repository.Queries, txManager db.TxManager) TimeUsecas
 	}
 }
 
-func (u *timeUsecase) StartTimeEntry(ctx context.Context, userID, projectID uuid.UUID, taskID *uuid.UUID, note string) (*repository.TimeEntry, error) {
-	arg := repository.CreateTimeEntryParams{
-		UserID:    userID,
-		ProjectID: projectID,
-		TaskID:    toUUID(taskID),
-		StartedAt: toTimestamp(ptr(time.Now())),
-		Note:      toTextFromStr(note),
-	}
-
-	entry, err := u.repo.CreateTimeEntry(ctx, arg)
+func (u *timeUsecase) StartTimeEntry(ctx context.Context, userID, projectID uuid.UUID, taskID *uuid.UUID, note string, billable *bool) (*repository.TimeEntry, error) {
+	var entry repository.TimeEntry
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		if err := checkTimerConflict(ctx, q, userID); err != nil {
+			return err
+		}
+		sw, err := switchTimer(ctx, q, userID, projectID, taskID, note, billable, OperationTimerStart)
+		if err != nil {
+			return err
+		}
+		entry = sw.Started
+		return nil
+	})
 	if err != nil {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to start timer: %w", err)
 	}
 	return &entry, nil
 }
 
+func (u *timeUsecase) SwitchTimeEntry(ctx context.Context, userID, projectID uuid.UUID, taskID *uuid.UUID, note string, billable *bool) (*TimerSwitch, error) {
+	var sw *TimerSwitch
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		var err error
+		sw, err = switchTimer(ctx, q, userID, projectID, taskID, note, billable, OperationTimerSwitch)
+		return err
+	})
+	if err != nil {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to switch timer: %w", err)
+	}
+	return sw, nil
+}
+
+// checkTimerConflict は設定が reject で計測中のタイマーがあれば Conflict を返す
+func checkTimerConflict(ctx context.Context, q *repository.Queries, userID uuid.UUID) error {
+	prefs, err := loadPreferences(ctx, q, userID)
+	if err != nil {
+		return err
+	}
+	if prefs.TimerConflict != TimerConflictReject {
+		return nil
+	}
+	if _, err := q.GetRunningTimeEntry(ctx, userID); err == nil {
+		return NewConflictError("another timer is running")
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// switchTimer は計測中のエントリがあれば止めてから新しいエントリを開始し、両方を1つの操作として記録する
+func switchTimer(ctx context.Context, q *repository.Queries, userID, projectID uuid.UUID, taskID *uuid.UUID, note string, billable *bool, kind string) (*TimerSwitch, error) {
+	now := time.Now()
+	var (
+		sw    TimerSwitch
+		steps []undoStep
+	)
+	stopped, err := q.StopRunningTimeEntry(ctx, repository.StopRunningTimeEntryParams{
+		UserID:  userID,
+		EndedAt: toTimestamp(&now),
+	})
+	switch {
+	case err == nil:
+		sw.Stopped = &stopped
+		steps = append(steps, undoStep{Op: undoReopenTimeEntry, ID: stopped.ID, UpdatedAt: ptr(stopped.UpdatedAt.Time)})
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, err
+	}
+
+	sw.Started, err = q.CreateTimeEntry(ctx, repository.CreateTimeEntryParams{
+		UserID:     userID,
+		ProjectID:  projectID,
+		TaskID:     toUUID(taskID),
+		StartedAt:  toTimestamp(&now),
+		Note:       toTextFromStr(note),
+		IsBillable: toBool(billable),
+	})
+	if err != nil {
+		// 同時に開始されたタイマーと競合した
+		if isUniqueViolation(err) {
+			return nil, NewConflictError("another timer is running")
+		}
+		return nil, err
+	}
+	steps = append(steps, undoStep{Op: undoDeleteTimeEntry, ID: sw.Started.ID, UpdatedAt: ptr(sw.Started.UpdatedAt.Time)})
+	if err := journal(ctx, q, userID, kind, steps...); err != nil {
+		return nil, err
+	}
+	return &sw, nil
+}
+
 func (u *timeUsecase) StopTimeEntry(ctx context.Context, userID, entryID uuid.UUID) (*repository.TimeEntry, error) {
 	now := toTimestamp(ptr(time.Now()))
-	entry, err := u.repo.StopTimeEntry(ctx, repository.StopTimeEntryParams{
-		ID:      entryID,
-		UserID:  userID,
-		EndedAt: now,
+	var entry repository.TimeEntry
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		var err error
+		entry, err = q.StopTimeEntry(ctx, repository.StopTimeEntryParams{
+			ID:      entryID,
+			UserID:  userID,
+			EndedAt: now,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// 終わったエントリを止め直すと ended_at が上書きされ、取り消しで計測中に戻ってしまう
+			if _, err := q.GetTimeEntry(ctx, repository.GetTimeEntryParams{ID: entryID, UserID: userID}); err != nil {
+				return err
+			}
+			return NewConflictError("time entry is not running")
+		}
+		if err != nil {
+			return err
+		}
+		return journal(ctx, q, userID, OperationTimerStop, undoStep{Op: undoReopenTimeEntry, ID: entry.ID, UpdatedAt: ptr(entry.UpdatedAt.Time)})
 	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, NewNotFoundError("time entry not found")
 		}
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to stop timer: %w", err)
 	}
 	return &entry, nil
 }
 
-func (u *timeUsecase) ListTimeEntries(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]repository.ListTimeEntriesRow, error) {
+func (u *timeUsecase) CreateTimeEntry(ctx context.Context, userID uuid.UUID, input ManualTimeEntryInput) (*TimeEntryChange, error) {
+	if !input.EndedAt.After(input.StartedAt) {
+		return nil, NewBadRequestError("ended_at must be after started_at")
+	}
+	var change *TimeEntryChange
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		if err := checkTimeEntryTarget(ctx, q, userID, input.ProjectID, input.TaskID); err != nil {
+			return err
+		}
+		var (
+			steps []undoStep
+			err   error
+		)
+		change, steps, err = resolveOverlaps(ctx, q, userID, nil, input.StartedAt, &input.EndedAt, input.Overlap)
+		if err != nil {
+			return err
+		}
+		change.Entry, err = q.CreateTimeEntry(ctx, repository.CreateTimeEntryParams{
+			UserID:     userID,
+			ProjectID:  input.ProjectID,
+			TaskID:     toUUID(input.TaskID),
+			StartedAt:  toTimestamp(&input.StartedAt),
+			EndedAt:    toTimestamp(&input.EndedAt),
+			Note:       toTextFromStr(input.Note),
+			IsBillable: toBool(input.Billable),
+		})
+		if err != nil {
+			return err
+		}
+		steps = append(steps, deleteTimeEntryStep(change.Entry))
+		return journal(ctx, q, userID, OperationTimeEntryCreate, steps...)
+	})
+	if err != nil {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create time entry: %w", err)
+	}
+	return change, nil
+}
+
+func (u *timeUsecase) UpdateTimeEntry(ctx context.Context, userID, entryID uuid.UUID, input UpdateTimeEntryInput) (*TimeEntryChange, error) {
+	var change *TimeEntryChange
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		entry, err := q.GetTimeEntry(ctx, repository.GetTimeEntryParams{ID: entryID, UserID: userID})
+		if err != nil {
+			return err
+		}
+
+		if input.ProjectID != nil || input.TaskID != nil {
+			projectID := entry.ProjectID
+			if input.ProjectID != nil {
+				projectID = *input.ProjectID
+			}
+			taskID := input.TaskID
+			if taskID == nil && entry.TaskID.Valid {
+				taskID = ptr(uuid.UUID(entry.TaskID.Bytes))
+			}
+			if err := checkTimeEntryTarget(ctx, q, userID, projectID, taskID); err != nil {
+				return err
+			}
+		}
+
+		start := entry.StartedAt.Time
+		if input.StartedAt != nil {
+			start = *input.StartedAt
+		}
+		var end *time.Time
+		if input.EndedAt != nil {
+			end = input.EndedAt
+		} else if entry.EndedAt.Valid {
+			end = &entry.EndedAt.Time
+		}
+		if end != nil && !end.After(start) {
+			return NewBadRequestError("ended_at must be after started_at")
+		}
+
+		var steps []undoStep
+		if input.StartedAt != nil || input.EndedAt != nil {
+			change, steps, err = resolveOverlaps(ctx, q, userID, []uuid.UUID{entryID}, start, end, input.Overlap)
+			if err != nil {
+				return err
+			}
+		} else {
+			change = &TimeEntryChange{}
+		}
+
+		arg := repository.UpdateTimeEntryParams{
+			ID:         entryID,
+			UserID:     userID,
+			ProjectID:  toUUID(input.ProjectID),
+			TaskID:     toUUID(input.TaskID),
+			StartedAt:  toTimestamp(input.StartedAt),
+			EndedAt:    toTimestamp(input.EndedAt),
+			IsBillable: toBool(input.Billable),
+		}
+		if input.Note != nil {
+			arg.Note = toTextFromStr(*input.Note)
+		}
+		change.Entry, err = q.UpdateTimeEntry(ctx, arg)
+		if err != nil {
+			return err
+		}
+		steps = append(steps, revertTimeEntryStep(entry, change.Entry))
+		return journal(ctx, q, userID, OperationTimeEntryUpdate, steps...)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("time entry not found")
+		}
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update time entry: %w", err)
+	}
+	return change, nil
+}
+
+func (u *timeUsecase) DeleteTimeEntry(ctx context.Context, userID, entryID uuid.UUID) error {
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		entry, err := q.GetTimeEntry(ctx, repository.GetTimeEntryParams{ID: entryID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		step, err := restoreTimeEntryStep(ctx, q, entry)
+		if err != nil {
+			return err
+		}
+		if _, err := q.DeleteTimeEntry(ctx, repository.DeleteTimeEntryParams{ID: entryID, UserID: userID}); err != nil {
+			return err
+		}
+		return journal(ctx, q, userID, OperationTimeEntryDelete, step)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return NewNotFoundError("time entry not found")
+		}
+		return fmt.Errorf("failed to delete time entry: %w", err)
+	}
+	return nil
+}
+
+func (u *timeUsecase) SplitTimeEntry(ctx context.Context, userID, entryID uuid.UUID, at time.Time) ([]repository.TimeEntry, error) {
+	var entries []repository.TimeEntry
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		entry, err := q.GetTimeEntry(ctx, repository.GetTimeEntryParams{ID: entryID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		end := time.Now()
+		if entry.EndedAt.Valid {
+			end = entry.EndedAt.Time
+		}
+		if !at.After(entry.StartedAt.Time) || !at.Before(end) {
+			return NewBadRequestError("split time must be within the entry")
+		}
+		first, second, err := splitTimeEntry(ctx, q, userID, entry, at, at)
+		if err != nil {
+			return err
+		}
+		entries = []repository.TimeEntry{first, *second}
+		return journal(ctx, q, userID, OperationTimeEntrySplit, revertTimeEntryStep(entry, first), deleteTimeEntryStep(*second))
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("time entry not found")
+		}
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to split time entry: %w", err)
+	}
+	return entries, nil
+}
+
+func (u *timeUsecase) MergeTimeEntries(ctx context.Context, userID uuid.UUID, entryIDs []uuid.UUID, overlap OverlapMode) (*TimeEntryChange, error) {
+	ids := make([]uuid.UUID, 0, len(entryIDs))
+	seen := map[uuid.UUID]bool{}
+	for _, id := range entryIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	switch {
+	case len(ids) < 2:
+		return nil, NewBadRequestError("at least two entries are required")
+	case len(ids) > maxMergeEntries:
+		return nil, NewBadRequestError(fmt.Sprintf("too many entries (max %d)", maxMergeEntries))
+	}
+
+	var change *TimeEntryChange
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		entries := make([]repository.TimeEntry, 0, len(ids))
+		for _, id := range ids {
+			entry, err := q.GetTimeEntry(ctx, repository.GetTimeEntryParams{ID: id, UserID: userID})
+			if err != nil {
+				return err
+			}
+			if !entry.EndedAt.Valid {
+				return NewBadRequestError("stop the running timer before merging")
+			}
+			entries = append(entries, entry)
+		}
+		slices.SortFunc(entries, func(a, b repository.TimeEntry) int {
+			return a.StartedAt.Time.Compare(b.StartedAt.Time)
+		})
+
+		target := entries[0]
+		start, end := target.StartedAt.Time, target.EndedAt.Time
+		var notes []string
+		for _, e := range entries {
+			if e.ProjectID != target.ProjectID || e.TaskID != target.TaskID {
+				return NewBadRequestError("entries must belong to the same project and task")
+			}
+			// 間が空いていると、その時間まで計測したことになる
+			if e.StartedAt.Time.After(end) {
+				return NewBadRequestError("entries must be contiguous or overlapping")
+			}
+			if e.EndedAt.Time.After(end) {
+				end = e.EndedAt.Time
+			}
+			if e.Note.Valid && e.Note.String != "" && !slices.Contains(notes, e.Note.String) {
+				notes = append(notes, e.Note.String)
+			}
+		}
+
+		var (
+			steps []undoStep
+			err   error
+		)
+		change, steps, err = resolveOverlaps(ctx, q, userID, ids, start, &end, overlap)
+		if err != nil {
+			return err
+		}
+		targetTags, err := q.ListTimeEntryTagIDs(ctx, target.ID)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries[1:] {
+			step, err := restoreTimeEntryStep(ctx, q, e)
+			if err != nil {
+				return err
+			}
+			// 引き継いだタグは取り消しで外す
+			for _, tagID := range step.TagIDs {
+				if !slices.Contains(targetTags, tagID) {
+					targetTags = append(targetTags, tagID)
+					steps = append(steps, undoStep{Op: undoRemoveTimeEntryTag, ID: target.ID, TagID: ptr(tagID)})
+				}
+			}
+			steps = append(steps, step)
+			if err := q.CopyTimeEntryTags(ctx, repository.CopyTimeEntryTagsParams{DstID: target.ID, SrcID: e.ID}); err != nil {
+				return err
+			}
+			if _, err := q.DeleteTimeEntry(ctx, repository.DeleteTimeEntryParams{ID: e.ID, UserID: userID}); err != nil {
+				return err
+			}
+		}
+		change.Entry, err = q.UpdateTimeEntry(ctx, repository.UpdateTimeEntryParams{
+			ID:      target.ID,
+			UserID:  userID,
+			EndedAt: toTimestamp(&end),
+			Note:    toTextFromStr(strings.Join(notes, "\n")),
+		})
+		if err != nil {
+			return err
+		}
+		steps = append(steps, revertTimeEntryStep(target, change.Entry))
+		return journal(ctx, q, userID, OperationTimeEntryMerge, steps...)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("time entry not found")
+		}
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to merge time entries: %w", err)
+	}
+	return change, nil
+}
+
+// checkTimeEntryTarget はプロジェクトが有効で、タスクがそのプロジェクトのものか確認する
+func checkTimeEntryTarget(ctx context.Context, q *repository.Queries, userID, projectID uuid.UUID, taskID *uuid.UUID) error {
+	if _, err := q.GetProject(ctx, repository.GetProjectParams{ID: projectID, UserID: userID}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return NewNotFoundError("project not found")
+		}
+		return err
+	}
+	if taskID != nil {
+		task, err := q.GetTask(ctx, repository.GetTaskParams{ID: *taskID, UserID: userID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return NewNotFoundError("task not found")
+			}
+			return err
+		}
+		if task.ProjectID != projectID {
+			return NewBadRequestError("task does not belong to the project")
+		}
+	}
+	return nil
+}
+
+// resolveOverlaps は [start, end) と重なる他のエントリを mode に従って処理し、変更を戻す手順を返す。end が nil なら終わりなし
+func resolveOverlaps(ctx context.Context, q *repository.Queries, userID uuid.UUID, exclude []uuid.UUID, start time.Time, end *time.Time, mode OverlapMode) (*TimeEntryChange, []undoStep, error) {
+	if exclude == nil {
+		exclude = []uuid.UUID{}
+	}
+	overlaps, err := q.ListOverlappingTimeEntries(ctx, repository.ListOverlappingTimeEntriesParams{
+		UserID:     userID,
+		ExcludeIds: exclude,
+		StartedAt:  toTimestamp(&start),
+		EndedAt:    toTimestamp(end),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	change := &TimeEntryChange{}
+	var steps []undoStep
+	if len(overlaps) == 0 {
+		return change, nil, nil
+	}
+
+	switch mode {
+	case OverlapAllow:
+		change.Overlaps = overlaps
+	case OverlapTrim:
+		for _, o := range overlaps {
+			startsBefore := o.StartedAt.Time.Before(start)
+			endsAfter := end != nil && (!o.EndedAt.Valid || o.EndedAt.Time.After(*end))
+			switch {
+			case startsBefore && endsAfter:
+				// 範囲を挟む形で2つに分ける
+				first, second, err := splitTimeEntry(ctx, q, userID, o, start, *end)
+				if err != nil {
+					return nil, nil, err
+				}
+				change.Adjusted = append(change.Adjusted, first, *second)
+				steps = append(steps, revertTimeEntryStep(o, first), deleteTimeEntryStep(*second))
+			case startsBefore:
+				trimmed, err := q.UpdateTimeEntry(ctx, repository.UpdateTimeEntryParams{ID: o.ID, UserID: userID, EndedAt: toTimestamp(&start)})
+				if err != nil {
+					return nil, nil, err
+				}
+				change.Adjusted = append(change.Adjusted, trimmed)
+				steps = append(steps, revertTimeEntryStep(o, trimmed))
+			case endsAfter:
+				trimmed, err := q.UpdateTimeEntry(ctx, repository.UpdateTimeEntryParams{ID: o.ID, UserID: userID, StartedAt: toTimestamp(end)})
+				if err != nil {
+					return nil, nil, err
+				}
+				change.Adjusted = append(change.Adjusted, trimmed)
+				steps = append(steps, revertTimeEntryStep(o, trimmed))
+			default:
+				step, err := restoreTimeEntryStep(ctx, q, o)
+				if err != nil {
+					return nil, nil, err
+				}
+				if _, err := q.DeleteTimeEntry(ctx, repository.DeleteTimeEntryParams{ID: o.ID, UserID: userID}); err != nil {
+					return nil, nil, err
+				}
+				change.Removed = append(change.Removed, o.ID)
+				steps = append(steps, step)
+			}
+		}
+	default:
+		return nil, nil, NewConflictError(fmt.Sprintf("time entry overlaps %d other entries", len(overlaps)))
+	}
+	return change, steps, nil
+}
+
+// splitTimeEntry は entry を cut で終わらせ、resume から元の終わりまでの続きを作る。
+// 計測中のエントリなら続きが計測中になる
+func splitTimeEntry(ctx context.Context, q *repository.Queries, userID uuid.UUID, entry repository.TimeEntry, cut, resume time.Time) (repository.TimeEntry, *repository.TimeEntry, error) {
+	// 計測中は1件までなので、続きを作る前に止める
+	first, err := q.UpdateTimeEntry(ctx, repository.UpdateTimeEntryParams{ID: entry.ID, UserID: userID, EndedAt: toTimestamp(&cut)})
+	if err != nil {
+		return repository.TimeEntry{}, nil, err
+	}
+	second, err := q.CreateTimeEntry(ctx, repository.CreateTimeEntryParams{
+		UserID:     userID,
+		ProjectID:  entry.ProjectID,
+		TaskID:     entry.TaskID,
+		StartedAt:  toTimestamp(&resume),
+		EndedAt:    entry.EndedAt,
+		Note:       entry.Note,
+		IsBillable: toBool(&entry.IsBillable),
+	})
+	if err != nil {
+		return repository.TimeEntry{}, nil, err
+	}
+	if err := q.CopyTimeEntryTags(ctx, repository.CopyTimeEntryTagsParams{DstID: second.ID, SrcID: entry.ID}); err != nil {
+		return repository.TimeEntry{}, nil, err
+	}
+	return first, &second, nil
+}
+
+func (u *timeUsecase) GetCurrentTimeEntry(ctx context.Context, userID uuid.UUID) (*repository.GetRunningTimeEntryRow, error) {
+	entry, err := u.repo.GetRunningTimeEntry(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get running timer: %w", err)
+	}
+	return &entry, nil
+}
+
+func (u *timeUsecase) ListTimeEntries(ctx context.Context, userID uuid.UUID, filter TimeEntryFilter) (*TimeEntryPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultTimeEntryPageSize
+	}
+	limit = min(limit, MaxTimeEntryPageSize)
+
 	arg := repository.ListTimeEntriesParams{
-		UserID:   userID,
-		FromDate: toTimestamp(&from),
-		ToDate:   toTimestamp(&to),
+		UserID:     userID,
+		FromDate:   toTimestamp(&filter.From),
+		ToDate:     toTimestamp(&filter.To),
+		ProjectID:  toUUID(filter.ProjectID),
+		CategoryID: toUUID(filter.CategoryID),
+		TaskID:     toUUID(filter.TaskID),
+		TagID:      toUUID(filter.TagID),
+		// 続きがあるか知るために1件多く取る
+		PageSize: int32(limit + 1),
+	}
+	if filter.RootType != nil {
+		arg.RootType = repository.NullRootCategoryType{RootCategoryType: *filter.RootType, Valid: true}
+	}
+	if filter.AutoGenerated != nil {
+		arg.IsAutoGenerated = pgtype.Bool{Bool: *filter.AutoGenerated, Valid: true}
 	}
+	if filter.Cursor != "" {
+		startedAt, id, err := decodeTimeEntryCursor(filter.Cursor)
+		if err != nil {
+			return nil, NewBadRequestError("invalid cursor")
+		}
+		arg.CursorStartedAt = toTimestamp(&startedAt)
+		arg.CursorID = toUUID(&id)
+	}
+
 	entries, err := u.repo.ListTimeEntries(ctx, arg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list entries: %w", err)
 	}
-	return entries, nil
+
+	page := &TimeEntryPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		last := page.Entries[limit-1]
+		page.NextCursor = encodeTimeEntryCursor(last.StartedAt.Time, last.ID)
+	}
+	if page.Entries == nil {
+		page.Entries = []repository.ListTimeEntriesRow{}
+	}
+	page.Count = len(page.Entries)
+	for _, e := range page.Entries {
+		page.TotalSeconds += e.DurationSeconds
+	}
+	return page, nil
+}
+
+// カーソルは最後のエントリの started_at と id を base64 にしたもの
+func encodeTimeEntryCursor(startedAt time.Time, id uuid.UUID) string {
+	raw := strconv.FormatInt(startedAt.UnixMicro(), 10) + ":" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
 }
 
+func decodeTimeEntryCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	ts, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return time.Time{}, uuid.Nil, errors.New("malformed cursor")
+	}
+	micros, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	return time.UnixMicro(micros), id, nil
+}
+
+// GetContributionStats は from〜to の日ごとの GROWTH の実績を返す。日付はユーザーのタイムゾーンで数え、
+// ゼロ値なら to は今日、from は to の1年前とする
 func (u *timeUsecase) GetContributionStats(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]repository.GetGrowthStatsRow, error) {
+	prefs, err := loadPreferences(ctx, u.repo, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	if to.IsZero() {
+		to = time.Now().In(prefs.Location())
+	}
+	if from.IsZero() {
+		from = to.AddDate(-1, 0, 0)
+	}
 	stats, err := u.repo.GetGrowthStats(ctx, repository.GetGrowthStatsParams{
 		UserID:   userID,
-		FromDate: toTimestamp(&from),
-		ToDate:   toTimestamp(&to),
+		ToDay:    toDate(to),
+		Timezone: prefs.Timezone,
+		FromDay:  toDate(from),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
 	}
 	return stats, nil
 }
+
+func (u *timeUsecase) AutoStopTimers(ctx context.Context, now time.Time) (int, error) {
+	running, err := u.repo.ListRunningTimeEntriesWithPreferences(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list running timers: %w", err)
+	}
+	stopped := 0
+	var errs []error
+	for _, r := range running {
+		prefs := parsePreferences(r.Preferences)
+		end, reason, err := u.autoStopAt(ctx, r.UserID, r.StartedAt.Time, prefs, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to check timer policy for time entry %s: %w", r.ID, err))
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+		finished := false
+		err = u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+			n, err := q.FinishTimeEntry(ctx, repository.FinishTimeEntryParams{ID: r.ID, UserID: r.UserID, EndedAt: toTimestamp(&end)})
+			// 同時に止められたもの
+			if err != nil || n == 0 {
+				return err
+			}
+			finished = true
+			return q.CreateTimerAutoStop(ctx, repository.CreateTimerAutoStopParams{
+				TimeEntryID: r.ID,
+				UserID:      r.UserID,
+				Reason:      reason,
+				EndedAt:     toTimestamp(&end),
+			})
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop time entry %s: %w", r.ID, err))
+			continue
+		}
+		if finished {
+			stopped++
+		}
+	}
+	return stopped, errors.Join(errs...)
+}
+
+// autoStopAt は条件を満たしたもののうち最も早い終わりとその理由を返す。どれも満たさなければ理由は空
+func (u *timeUsecase) autoStopAt(ctx context.Context, userID uuid.UUID, startedAt time.Time, prefs Preferences, now time.Time) (time.Time, string, error) {
+	var end time.Time
+	var reason string
+	candidate := func(t time.Time, r string) {
+		if t.After(startedAt) && !t.After(now) && (reason == "" || t.Before(end)) {
+			end, reason = t, r
+		}
+	}
+	if prefs.TimerMaxMinutes > 0 {
+		candidate(startedAt.Add(time.Duration(prefs.TimerMaxMinutes)*time.Minute), AutoStopMaxDuration)
+	}
+	if clock, err := time.Parse(clockLayout, prefs.TimerAutoStopAt); err == nil {
+		// 開始後に最初に来るその時刻
+		loc := prefs.Location()
+		local := startedAt.In(loc)
+		t := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+		if !t.After(startedAt) {
+			t = t.AddDate(0, 0, 1)
+		}
+		candidate(t, AutoStopClock)
+	}
+	if prefs.TimerDiscardIdle {
+		// 開始後にアクティビティを送ってきた端末がしばらく何も送っていなければ、最後のアクティビティまでとする
+		last, err := u.repo.GetLastActivityAt(ctx, repository.GetLastActivityAtParams{UserID: userID, Since: toTimestamp(&startedAt)})
+		if err != nil {
+			return time.Time{}, "", err
+		}
+		if last.Valid && now.Sub(last.Time) >= time.Duration(prefs.HeartbeatIdleMinutes)*time.Minute {
+			candidate(last.Time, AutoStopIdle)
+		}
+	}
+	return end, reason, nil
+}
+
+func (u *timeUsecase) ListAutoStops(ctx context.Context, userID uuid.UUID) ([]repository.ListTimerAutoStopsRow, error) {
+	stops, err := u.repo.ListTimerAutoStops(ctx, repository.ListTimerAutoStopsParams{UserID: userID, Limit: maxTimerAutoStops})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auto stops: %w", err)
+	}
+	return stops, nil
+}
@@ -0,0 +1,331 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/gigaonion/taskalyst/backend/pkg/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TrashKind はゴミ箱に入るエンティティの種類
+type TrashKind string
+
+const (
+	TrashKindProject  TrashKind = "project"
+	TrashKindCalendar TrashKind = "calendar"
+	TrashKindTask     TrashKind = "task"
+	TrashKindEvent    TrashKind = "event"
+	TrashKindResult   TrashKind = "result"
+)
+
+// 自動削除で1回に取り出す件数
+const trashPurgeBatch = 100
+
+// TrashItem はゴミ箱の1件。プロジェクト・カレンダーと一緒に入ったものは含まない
+type TrashItem struct {
+	Kind      TrashKind  `json:"kind"`
+	ID        uuid.UUID  `json:"id"`
+	Title     string     `json:"title"`
+	ProjectID *uuid.UUID `json:"project_id,omitempty"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 自動削除しない設定なら nil
+}
+
+type TrashUsecase interface {
+	List(ctx context.Context, userID uuid.UUID, kind *TrashKind) ([]TrashItem, error)
+	// Restore は元に戻す。プロジェクト・カレンダーは一緒に入ったものも戻す
+	Restore(ctx context.Context, userID uuid.UUID, kind TrashKind, id uuid.UUID) error
+	// Purge は完全に削除する。添付ファイルの実体も消す
+	Purge(ctx context.Context, userID uuid.UUID, kind TrashKind, id uuid.UUID) error
+	// Empty はゴミ箱を空にし、削除した件数を返す
+	Empty(ctx context.Context, userID uuid.UUID) (int, error)
+	// PurgeExpired は保持期間を過ぎたものを全ユーザー分削除し、件数を返す
+	PurgeExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+type trashUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+	storage   storage.Storage
+	retention time.Duration // 0 なら自動削除しない
+}
+
+func NewTrashUsecase(repo *repository.Queries, txManager db.TxManager, store storage.Storage, retentionDays int) TrashUsecase {
+	return &trashUsecase{
+		repo:      repo,
+		txManager: txManager,
+		storage:   store,
+		retention: time.Duration(max(retentionDays, 0)) * 24 * time.Hour,
+	}
+}
+
+func (u *trashUsecase) List(ctx context.Context, userID uuid.UUID, kind *TrashKind) ([]TrashItem, error) {
+	var kindArg pgtype.Text
+	if kind != nil {
+		kindArg = toTextFromStr(string(*kind))
+	}
+	rows, err := u.repo.ListTrash(ctx, repository.ListTrashParams{UserID: userID, Kind: kindArg})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	items := make([]TrashItem, 0, len(rows))
+	for _, r := range rows {
+		item := TrashItem{
+			Kind:      TrashKind(r.Kind),
+			ID:        r.ID,
+			Title:     r.Title,
+			DeletedAt: r.DeletedAt.Time,
+		}
+		if r.ProjectID.Valid {
+			item.ProjectID = ptr(uuid.UUID(r.ProjectID.Bytes))
+		}
+		if u.retention > 0 {
+			item.ExpiresAt = ptr(r.DeletedAt.Time.Add(u.retention))
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (u *trashUsecase) Restore(ctx context.Context, userID uuid.UUID, kind TrashKind, id uuid.UUID) error {
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		return restoreTrashItem(ctx, q, userID, kind, id)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return NewNotFoundError(string(kind) + " not found in trash")
+		}
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return err
+		}
+		return fmt.Errorf("failed to restore %s: %w", kind, err)
+	}
+	return nil
+}
+
+// restoreTrashItem はゴミ箱の1件を戻し、親や UID が有効なものと衝突しないか確認する
+func restoreTrashItem(ctx context.Context, q *repository.Queries, userID uuid.UUID, kind TrashKind, id uuid.UUID) error {
+	switch kind {
+	case TrashKindTask:
+		task, err := q.RestoreTask(ctx, repository.RestoreTaskParams{ID: id, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if err := checkTrashParents(ctx, q, userID, task.ProjectID, task.CalendarID); err != nil {
+			return err
+		}
+		if task.IcalUid.Valid {
+			n, err := q.CountTasksByICalUID(ctx, repository.CountTasksByICalUIDParams{UserID: userID, IcalUid: task.IcalUid})
+			if err != nil {
+				return err
+			}
+			if n > 1 {
+				return NewConflictError("a task with the same uid already exists")
+			}
+		}
+		return nil
+
+	case TrashKindEvent:
+		event, err := q.RestoreEvent(ctx, repository.RestoreEventParams{ID: id, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if err := checkTrashParents(ctx, q, userID, event.ProjectID, event.CalendarID); err != nil {
+			return err
+		}
+		if event.IcalUid.Valid {
+			n, err := q.CountEventsByICalUID(ctx, repository.CountEventsByICalUIDParams{UserID: userID, IcalUid: event.IcalUid})
+			if err != nil {
+				return err
+			}
+			if n > 1 {
+				return NewConflictError("an event with the same uid already exists")
+			}
+		}
+		return nil
+
+	case TrashKindResult:
+		result, err := q.RestoreResult(ctx, repository.RestoreResultParams{ID: id, UserID: userID})
+		if err != nil {
+			return err
+		}
+		return checkTrashParents(ctx, q, userID, result.ProjectID, pgtype.UUID{})
+
+	case TrashKindProject:
+		// 親の deleted_at と比べるので、親より先に戻す
+		if err := q.RestoreProjectItems(ctx, repository.RestoreProjectItemsParams{ProjectID: id, UserID: userID}); err != nil {
+			return err
+		}
+		_, err := q.RestoreProject(ctx, repository.RestoreProjectParams{ID: id, UserID: userID})
+		return err
+
+	case TrashKindCalendar:
+		if err := q.RestoreCalendarItems(ctx, repository.RestoreCalendarItemsParams{CalendarID: id, UserID: userID}); err != nil {
+			return err
+		}
+		cal, err := q.RestoreCalendar(ctx, repository.RestoreCalendarParams{ID: id, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if cal.ProjectID.Valid {
+			return checkTrashParents(ctx, q, userID, cal.ProjectID.Bytes, pgtype.UUID{})
+		}
+		return nil
+	}
+	return NewBadRequestError("unknown trash kind")
+}
+
+// checkTrashParents は戻したものの親(プロジェクト・カレンダー)がゴミ箱に入っていないか確認する
+func checkTrashParents(ctx context.Context, q *repository.Queries, userID, projectID uuid.UUID, calendarID pgtype.UUID) error {
+	if _, err := q.GetProject(ctx, repository.GetProjectParams{ID: projectID, UserID: userID}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return NewConflictError("project is in the trash, restore it first")
+		}
+		return err
+	}
+	if calendarID.Valid {
+		if _, err := q.GetCalendar(ctx, repository.GetCalendarParams{ID: calendarID.Bytes, UserID: userID}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return NewConflictError("calendar is in the trash, restore it first")
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *trashUsecase) Purge(ctx context.Context, userID uuid.UUID, kind TrashKind, id uuid.UUID) error {
+	var keys []string
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		var err error
+		keys, err = purgeTrashItem(ctx, q, userID, kind, id)
+		return err
+	})
+	if err != nil {
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return err
+		}
+		return fmt.Errorf("failed to purge %s: %w", kind, err)
+	}
+	u.deleteObjects(ctx, keys)
+	return nil
+}
+
+// purgeTrashItem はゴミ箱の1件を削除し、消した添付ファイルの保存先を返す
+func purgeTrashItem(ctx context.Context, q *repository.Queries, userID uuid.UUID, kind TrashKind, id uuid.UUID) ([]string, error) {
+	target := repository.DeletePurgedAttachmentsParams{UserID: userID}
+	switch kind {
+	case TrashKindTask:
+		target.TaskID = toUUID(&id)
+	case TrashKindEvent:
+		target.EventID = toUUID(&id)
+	case TrashKindProject:
+		target.ProjectID = toUUID(&id)
+	case TrashKindCalendar:
+		target.CalendarID = toUUID(&id)
+	case TrashKindResult:
+		// 実績に添付ファイルはない
+	default:
+		return nil, NewBadRequestError("unknown trash kind")
+	}
+
+	var keys []string
+	if kind != TrashKindResult {
+		var err error
+		if keys, err = q.DeletePurgedAttachments(ctx, target); err != nil {
+			return nil, err
+		}
+	}
+
+	// ゴミ箱に入っていなければ 0 件になり、添付ファイルの削除ごと取り消される
+	var (
+		n   int64
+		err error
+	)
+	switch kind {
+	case TrashKindTask:
+		n, err = q.PurgeTask(ctx, repository.PurgeTaskParams{ID: id, UserID: userID})
+	case TrashKindEvent:
+		n, err = q.PurgeEvent(ctx, repository.PurgeEventParams{ID: id, UserID: userID})
+	case TrashKindProject:
+		n, err = q.PurgeProject(ctx, repository.PurgeProjectParams{ID: id, UserID: userID})
+	case TrashKindCalendar:
+		n, err = q.PurgeCalendar(ctx, repository.PurgeCalendarParams{ID: id, UserID: userID})
+	case TrashKindResult:
+		n, err = q.PurgeResult(ctx, repository.PurgeResultParams{ID: id, UserID: userID})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, NewNotFoundError(string(kind) + " not found in trash")
+	}
+	return keys, nil
+}
+
+func (u *trashUsecase) Empty(ctx context.Context, userID uuid.UUID) (int, error) {
+	items, err := u.repo.ListTrash(ctx, repository.ListTrashParams{UserID: userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list trash: %w", err)
+	}
+	purged := 0
+	for _, item := range items {
+		if err := u.Purge(ctx, userID, TrashKind(item.Kind), item.ID); err != nil {
+			// 先に消したプロジェクト・カレンダーと一緒に消えたもの
+			var domainErr *DomainError
+			if errors.As(err, &domainErr) {
+				continue
+			}
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (u *trashUsecase) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	if u.retention <= 0 {
+		return 0, nil
+	}
+	before := now.Add(-u.retention)
+	purged := 0
+	for {
+		items, err := u.repo.ListExpiredTrash(ctx, repository.ListExpiredTrashParams{
+			Before:   toTimestamp(&before),
+			MaxItems: trashPurgeBatch,
+		})
+		if err != nil {
+			return purged, fmt.Errorf("failed to list expired trash: %w", err)
+		}
+		for _, item := range items {
+			if err := u.Purge(ctx, item.UserID, TrashKind(item.Kind), item.ID); err != nil {
+				var domainErr *DomainError
+				if errors.As(err, &domainErr) {
+					continue
+				}
+				return purged, err
+			}
+			purged++
+		}
+		if len(items) < trashPurgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+// deleteObjects は確定後に添付ファイルの実体を消す。行はもう無いので失敗しても無視する
+func (u *trashUsecase) deleteObjects(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		_ = u.storage.Delete(ctx, key)
+	}
+}
@@ -0,0 +1,334 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
+	"github.com/gigaonion/taskalyst/backend/internal/infra/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// 取り消せる操作の種類 (operations.kind)
+const (
+	OperationTaskUpdate      = "task.update"
+	OperationTaskStatus      = "task.status"
+	OperationTaskMove        = "task.move"
+	OperationTaskDelete      = "task.delete"
+	OperationTaskBulk        = "task.bulk"
+	OperationProjectDelete   = "project.delete"
+	OperationCalendarDelete  = "calendar.delete"
+	OperationEventDelete     = "event.delete"
+	OperationResultDelete    = "result.delete"
+	OperationTimerStart      = "timer.start"
+	OperationTimerStop       = "timer.stop"
+	OperationTimerSwitch     = "timer.switch"
+	OperationTimeEntryCreate = "time_entry.create"
+	OperationTimeEntryUpdate = "time_entry.update"
+	OperationTimeEntryDelete = "time_entry.delete"
+	OperationTimeEntrySplit  = "time_entry.split"
+	OperationTimeEntryMerge  = "time_entry.merge"
+)
+
+// undoOp は逆操作の種類
+type undoOp string
+
+const (
+	undoRevertTask      undoOp = "revert_task"       // Task の内容に戻す
+	undoRestore         undoOp = "restore"           // ゴミ箱から戻す
+	undoAddTaskTag      undoOp = "add_task_tag"      // 外したタグを付け直す
+	undoRemoveTaskTag   undoOp = "remove_task_tag"   // 付けたタグを外す
+	undoReopenTimeEntry undoOp = "reopen_time_entry" // 停止した計測を再開する
+	undoDeleteTimeEntry undoOp = "delete_time_entry" // 開始した計測を消す
+
+	undoRevertTimeEntry    undoOp = "revert_time_entry"     // TimeEntry の内容に戻す
+	undoRestoreTimeEntry   undoOp = "restore_time_entry"    // 削除したエントリを TimeEntry と TagIDs で作り直す
+	undoRemoveTimeEntryTag undoOp = "remove_time_entry_tag" // 結合で引き継いだタグを外す
+)
+
+// undoStep は逆操作の1手順。operations.inverse に JSON の配列で保存する
+type undoStep struct {
+	Op    undoOp           `json:"op"`
+	ID    uuid.UUID        `json:"id"`
+	Kind  TrashKind        `json:"kind,omitempty"`
+	TagID *uuid.UUID       `json:"tag_id,omitempty"`
+	Task  *repository.Task `json:"task,omitempty"`
+
+	TimeEntry *repository.TimeEntry `json:"time_entry,omitempty"`
+	TagIDs    []uuid.UUID           `json:"tag_ids,omitempty"`
+	// 操作直後の updated_at。その後に変更されていれば取り消さない
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+func revertTaskStep(before, after repository.Task) undoStep {
+	return undoStep{Op: undoRevertTask, ID: before.ID, Task: &before, UpdatedAt: ptr(after.UpdatedAt.Time)}
+}
+
+func restoreStep(kind TrashKind, id uuid.UUID) undoStep {
+	return undoStep{Op: undoRestore, ID: id, Kind: kind}
+}
+
+func revertTimeEntryStep(before, after repository.TimeEntry) undoStep {
+	return undoStep{Op: undoRevertTimeEntry, ID: before.ID, TimeEntry: &before, UpdatedAt: ptr(after.UpdatedAt.Time)}
+}
+
+func deleteTimeEntryStep(created repository.TimeEntry) undoStep {
+	return undoStep{Op: undoDeleteTimeEntry, ID: created.ID, UpdatedAt: ptr(created.UpdatedAt.Time)}
+}
+
+// restoreTimeEntryStep は削除する前のエントリとそのタグを記録する
+func restoreTimeEntryStep(ctx context.Context, q *repository.Queries, entry repository.TimeEntry) (undoStep, error) {
+	tagIDs, err := q.ListTimeEntryTagIDs(ctx, entry.ID)
+	if err != nil {
+		return undoStep{}, err
+	}
+	return undoStep{Op: undoRestoreTimeEntry, ID: entry.ID, TimeEntry: &entry, TagIDs: tagIDs}, nil
+}
+
+// operationSlot は記録した操作のIDをハンドラに返すための入れ物
+type operationSlot struct {
+	id *uuid.UUID
+}
+
+type operationKey struct{}
+
+// WithOperationSlot は記録した操作のIDを受け取れる ctx を返す
+func WithOperationSlot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, operationKey{}, &operationSlot{})
+}
+
+// OperationIDFrom はリクエスト中に記録された操作のIDを返す
+func OperationIDFrom(ctx context.Context) (uuid.UUID, bool) {
+	if slot, ok := ctx.Value(operationKey{}).(*operationSlot); ok && slot.id != nil {
+		return *slot.id, true
+	}
+	return uuid.Nil, false
+}
+
+// journal は逆操作を変更と同じトランザクションで記録する。steps が空なら何もしない
+func journal(ctx context.Context, q *repository.Queries, userID uuid.UUID, kind string, steps ...undoStep) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	inverse, err := json.Marshal(steps)
+	if err != nil {
+		return err
+	}
+	op, err := q.CreateOperation(ctx, repository.CreateOperationParams{
+		UserID:  userID,
+		Kind:    kind,
+		Inverse: inverse,
+	})
+	if err != nil {
+		return err
+	}
+	if slot, ok := ctx.Value(operationKey{}).(*operationSlot); ok {
+		slot.id = &op.ID
+	}
+	return nil
+}
+
+type UndoResult struct {
+	OperationID uuid.UUID `json:"operation_id"`
+	Kind        string    `json:"kind"`
+	Steps       int       `json:"steps"`
+}
+
+type UndoUsecase interface {
+	Undo(ctx context.Context, userID, operationID uuid.UUID) (*UndoResult, error)
+	// Prune は取り消し期間を過ぎた記録を削除する
+	Prune(ctx context.Context, now time.Time) (int64, error)
+}
+
+type undoUsecase struct {
+	repo      *repository.Queries
+	txManager db.TxManager
+	window    time.Duration
+}
+
+func NewUndoUsecase(repo *repository.Queries, txManager db.TxManager, window time.Duration) UndoUsecase {
+	return &undoUsecase{
+		repo:      repo,
+		txManager: txManager,
+		window:    window,
+	}
+}
+
+// Undo は記録した逆操作を新しいものから順に適用する。
+// 操作の後に対象が変更・削除されていれば何も戻さずに Conflict を返す
+func (u *undoUsecase) Undo(ctx context.Context, userID, operationID uuid.UUID) (*UndoResult, error) {
+	var result *UndoResult
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		op, err := q.GetOperationForUpdate(ctx, repository.GetOperationForUpdateParams{ID: operationID, UserID: userID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return NewNotFoundError("operation not found")
+			}
+			return err
+		}
+		if op.UndoneAt.Valid {
+			return NewConflictError("operation has already been undone")
+		}
+		if time.Since(op.CreatedAt.Time) > u.window {
+			return NewConflictError("undo window has expired")
+		}
+
+		var steps []undoStep
+		if err := json.Unmarshal(op.Inverse, &steps); err != nil {
+			return err
+		}
+		for i := len(steps) - 1; i >= 0; i-- {
+			if err := applyUndoStep(ctx, q, userID, steps[i]); err != nil {
+				return err
+			}
+		}
+		result = &UndoResult{OperationID: op.ID, Kind: op.Kind, Steps: len(steps)}
+		return q.MarkOperationUndone(ctx, op.ID)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewConflictError("the changed item no longer exists")
+		}
+		var domainErr *DomainError
+		if errors.As(err, &domainErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to undo operation: %w", err)
+	}
+	return result, nil
+}
+
+func applyUndoStep(ctx context.Context, q *repository.Queries, userID uuid.UUID, step undoStep) error {
+	switch step.Op {
+	case undoRevertTask:
+		current, err := q.GetTask(ctx, repository.GetTaskParams{ID: step.ID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if step.UpdatedAt != nil && !current.UpdatedAt.Time.Equal(*step.UpdatedAt) {
+			return NewConflictError("task has been changed since the operation")
+		}
+		prev := step.Task
+		if prev.ProjectID != current.ProjectID {
+			if err := checkTrashParents(ctx, q, userID, prev.ProjectID, prev.CalendarID); err != nil {
+				return err
+			}
+		}
+		reverted, err := q.RevertTask(ctx, repository.RevertTaskParams{
+			ProjectID:        prev.ProjectID,
+			Title:            prev.Title,
+			NoteMarkdown:     prev.NoteMarkdown,
+			Status:           prev.Status,
+			CustomStatusID:   prev.CustomStatusID,
+			CompletedAt:      prev.CompletedAt,
+			DueDate:          prev.DueDate,
+			Priority:         prev.Priority,
+			Position:         prev.Position,
+			EstimatedMinutes: prev.EstimatedMinutes,
+			ID:               step.ID,
+			UserID:           userID,
+		})
+		if err != nil {
+			return err
+		}
+		return recordTaskActivity(ctx, q, &current, reverted)
+
+	case undoRestore:
+		return restoreTrashItem(ctx, q, userID, step.Kind, step.ID)
+
+	case undoAddTaskTag:
+		_, err := q.AddTaskTag(ctx, repository.AddTaskTagParams{TaskID: step.ID, TagID: *step.TagID, UserID: userID})
+		return err
+
+	case undoRemoveTaskTag:
+		_, err := q.RemoveTaskTag(ctx, repository.RemoveTaskTagParams{TaskID: step.ID, TagID: *step.TagID, UserID: userID})
+		return err
+
+	case undoReopenTimeEntry, undoDeleteTimeEntry:
+		entry, err := q.GetTimeEntry(ctx, repository.GetTimeEntryParams{ID: step.ID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if step.UpdatedAt != nil && !entry.UpdatedAt.Time.Equal(*step.UpdatedAt) {
+			return NewConflictError("time entry has been changed since the operation")
+		}
+		if step.Op == undoReopenTimeEntry {
+			_, err = q.ReopenTimeEntry(ctx, repository.ReopenTimeEntryParams{ID: step.ID, UserID: userID})
+			if isUniqueViolation(err) {
+				return NewConflictError("another timer is running")
+			}
+			return err
+		}
+		_, err = q.DeleteTimeEntry(ctx, repository.DeleteTimeEntryParams{ID: step.ID, UserID: userID})
+		return err
+
+	case undoRevertTimeEntry:
+		current, err := q.GetTimeEntry(ctx, repository.GetTimeEntryParams{ID: step.ID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if step.UpdatedAt != nil && !current.UpdatedAt.Time.Equal(*step.UpdatedAt) {
+			return NewConflictError("time entry has been changed since the operation")
+		}
+		prev := step.TimeEntry
+		_, err = q.RevertTimeEntry(ctx, repository.RevertTimeEntryParams{
+			ProjectID:  prev.ProjectID,
+			TaskID:     prev.TaskID,
+			StartedAt:  prev.StartedAt,
+			EndedAt:    prev.EndedAt,
+			Note:       prev.Note,
+			IsBillable: prev.IsBillable,
+			ID:         step.ID,
+			UserID:     userID,
+		})
+		if isUniqueViolation(err) {
+			return NewConflictError("another timer is running")
+		}
+		return err
+
+	case undoRestoreTimeEntry:
+		prev := step.TimeEntry
+		_, err := q.RestoreTimeEntry(ctx, repository.RestoreTimeEntryParams{
+			ID:              prev.ID,
+			UserID:          userID,
+			ProjectID:       prev.ProjectID,
+			TaskID:          prev.TaskID,
+			StartedAt:       prev.StartedAt,
+			EndedAt:         prev.EndedAt,
+			Note:            prev.Note,
+			IsAutoGenerated: prev.IsAutoGenerated,
+			IsBillable:      prev.IsBillable,
+			CreatedAt:       prev.CreatedAt,
+		})
+		if isUniqueViolation(err) {
+			return NewConflictError("time entry cannot be restored")
+		}
+		if err != nil {
+			return err
+		}
+		// 削除されたタグは付け直さない
+		for _, tagID := range step.TagIDs {
+			if _, err := q.AddTimeEntryTag(ctx, repository.AddTimeEntryTagParams{TimeEntryID: prev.ID, TagID: tagID, UserID: userID}); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case undoRemoveTimeEntryTag:
+		_, err := q.RemoveTimeEntryTag(ctx, repository.RemoveTimeEntryTagParams{TimeEntryID: step.ID, TagID: *step.TagID, UserID: userID})
+		return err
+	}
+	return fmt.Errorf("unknown undo step %q", step.Op)
+}
+
+func (u *undoUsecase) Prune(ctx context.Context, now time.Time) (int64, error) {
+	n, err := u.repo.DeleteOperationsBefore(ctx, toTimestamp(ptr(now.Add(-u.window))))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune operations: %w", err)
+	}
+	return n, nil
+}
@@ -2,8 +2,10 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gigaonion/taskalyst/backend/internal/config"
 	"github.com/gigaonion/taskalyst/backend/internal/infra/db"
@@ -15,11 +17,54 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// 計測中のタイマーがあるときに別のタイマーを開始した場合の動作
+const (
+	TimerConflictReject = "reject" // 409 を返す
+	TimerConflictStop   = "stop"   // 計測中のものを止めてから開始する
+)
+
+// 時刻の設定の書式
+const clockLayout = "15:04"
+
+// Preferences は users.preferences のうちサーバー側で使う設定
+type Preferences struct {
+	TimerConflict string `json:"timer_conflict"`
+	// ポモドーロの各フェーズの長さ(分)と、長い休憩を入れる間隔(作業の回数)
+	PomodoroWorkMinutes       int `json:"pomodoro_work_minutes"`
+	PomodoroShortBreakMinutes int `json:"pomodoro_short_break_minutes"`
+	PomodoroLongBreakMinutes  int `json:"pomodoro_long_break_minutes"`
+	PomodoroLongBreakEvery    int `json:"pomodoro_long_break_every"`
+	// heartbeat の間隔がこれ(分)を超えたら作業が途切れたとみなす
+	HeartbeatIdleMinutes int `json:"heartbeat_idle_minutes"`
+	// IANA のタイムゾーン名 (例: Asia/Tokyo)。時刻の設定や日ごとの集計に使う
+	Timezone string `json:"timezone"`
+	// 計測中のタイマーの自動停止。timer_max_minutes は 0、timer_auto_stop_at は空なら使わない
+	TimerMaxMinutes  int    `json:"timer_max_minutes"`
+	TimerAutoStopAt  string `json:"timer_auto_stop_at"` // timezone での時刻 (HH:MM)
+	TimerDiscardIdle bool   `json:"timer_discard_idle"` // heartbeat_idle_minutes 以上アクティビティがなければ最後のアクティビティで止める
+}
+
+// UpdatePreferencesInput は変更する設定。nil の項目は変更しない
+type UpdatePreferencesInput struct {
+	TimerConflict             *string
+	PomodoroWorkMinutes       *int
+	PomodoroShortBreakMinutes *int
+	PomodoroLongBreakMinutes  *int
+	PomodoroLongBreakEvery    *int
+	HeartbeatIdleMinutes      *int
+	Timezone                  *string
+	TimerMaxMinutes           *int
+	TimerAutoStopAt           *string
+	TimerDiscardIdle          *bool
+}
+
 type UserUsecase interface {
 	SignUp(ctx context.Context, email, plainPassword, name string) (*repository.User, error)
 	Login(ctx context.Context, email, plainPassword string) (*auth.TokenPair, error)
 	RefreshToken(ctx context.Context, refreshToken string) (*auth.TokenPair, error)
 	GetProfile(ctx context.Context, id uuid.UUID) (*repository.User, error)
+	GetPreferences(ctx context.Context, id uuid.UUID) (*Preferences, error)
+	UpdatePreferences(ctx context.Context, id uuid.UUID, input UpdatePreferencesInput) (*Preferences, error)
 }
 
 type userUsecase struct {
@@ -138,3 +183,152 @@ func (u *userUsecase) GetProfile(ctx context.Context, id uuid.UUID) (*repository
 	}
 	return &user, nil
 }
+
+func (u *userUsecase) GetPreferences(ctx context.Context, id uuid.UUID) (*Preferences, error) {
+	prefs, err := loadPreferences(ctx, u.repo, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("user not found")
+		}
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	return &prefs, nil
+}
+
+// UpdatePreferences は指定した項目だけを書き換える。クライアントが保存した他のキーはそのまま残す
+func (u *userUsecase) UpdatePreferences(ctx context.Context, id uuid.UUID, input UpdatePreferencesInput) (*Preferences, error) {
+	if input.Timezone != nil {
+		if !validTimezone(*input.Timezone) {
+			return nil, NewBadRequestError("invalid timezone")
+		}
+	}
+	if input.TimerAutoStopAt != nil && *input.TimerAutoStopAt != "" {
+		if _, err := time.Parse(clockLayout, *input.TimerAutoStopAt); err != nil {
+			return nil, NewBadRequestError("invalid timer_auto_stop_at")
+		}
+	}
+	var prefs Preferences
+	err := u.txManager.ReadCommitted(ctx, func(q *repository.Queries) error {
+		user, err := q.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		raw := map[string]json.RawMessage{}
+		if len(user.Preferences) > 0 {
+			if err := json.Unmarshal(user.Preferences, &raw); err != nil {
+				return err
+			}
+		}
+		set := func(key string, v any) error {
+			var err error
+			raw[key], err = json.Marshal(v)
+			return err
+		}
+		for key, v := range map[string]*string{
+			"timer_conflict":     input.TimerConflict,
+			"timezone":           input.Timezone,
+			"timer_auto_stop_at": input.TimerAutoStopAt,
+		} {
+			if v == nil {
+				continue
+			}
+			if err := set(key, *v); err != nil {
+				return err
+			}
+		}
+		if input.TimerDiscardIdle != nil {
+			if err := set("timer_discard_idle", *input.TimerDiscardIdle); err != nil {
+				return err
+			}
+		}
+		for key, v := range map[string]*int{
+			"pomodoro_work_minutes":        input.PomodoroWorkMinutes,
+			"pomodoro_short_break_minutes": input.PomodoroShortBreakMinutes,
+			"pomodoro_long_break_minutes":  input.PomodoroLongBreakMinutes,
+			"pomodoro_long_break_every":    input.PomodoroLongBreakEvery,
+			"heartbeat_idle_minutes":       input.HeartbeatIdleMinutes,
+			"timer_max_minutes":            input.TimerMaxMinutes,
+		} {
+			if v == nil {
+				continue
+			}
+			if err := set(key, *v); err != nil {
+				return err
+			}
+		}
+		body, err := json.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		updated, err := q.UpdateUserPreferences(ctx, repository.UpdateUserPreferencesParams{ID: id, Preferences: body})
+		if err != nil {
+			return err
+		}
+		prefs = parsePreferences(updated.Preferences)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewNotFoundError("user not found")
+		}
+		return nil, fmt.Errorf("failed to update preferences: %w", err)
+	}
+	return &prefs, nil
+}
+
+func loadPreferences(ctx context.Context, q *repository.Queries, userID uuid.UUID) (Preferences, error) {
+	user, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		return Preferences{}, err
+	}
+	return parsePreferences(user.Preferences), nil
+}
+
+// parsePreferences は未設定や不正な値を既定値で埋める
+func parsePreferences(raw []byte) Preferences {
+	var prefs Preferences
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &prefs)
+	}
+	if prefs.TimerConflict != TimerConflictStop {
+		prefs.TimerConflict = TimerConflictReject
+	}
+	defaultInt(&prefs.PomodoroWorkMinutes, 25)
+	defaultInt(&prefs.PomodoroShortBreakMinutes, 5)
+	defaultInt(&prefs.PomodoroLongBreakMinutes, 15)
+	defaultInt(&prefs.PomodoroLongBreakEvery, 4)
+	defaultInt(&prefs.HeartbeatIdleMinutes, 15)
+	if !validTimezone(prefs.Timezone) {
+		prefs.Timezone = "UTC"
+	}
+	prefs.TimerMaxMinutes = max(prefs.TimerMaxMinutes, 0)
+	if _, err := time.Parse(clockLayout, prefs.TimerAutoStopAt); err != nil {
+		prefs.TimerAutoStopAt = ""
+	}
+	return prefs
+}
+
+// validTimezone は IANA のタイムゾーン名かどうか。
+// "Local" はサーバーの設定に依存し、PostgreSQL の AT TIME ZONE でも使えないので受け付けない
+func validTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// Location は設定のタイムゾーン
+func (p Preferences) Location() *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func defaultInt(v *int, def int) {
+	if *v <= 0 {
+		*v = def
+	}
+}
@@ -1,9 +1,11 @@
 package usecase
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -39,7 +41,53 @@ func toUUID(u *uuid.UUID) pgtype.UUID {
 	return pgtype.UUID{Bytes: *u, Valid: true}
 }
 
+// toInt4 converts *int32 to pgtype.Int4
+func toInt4(v *int32) pgtype.Int4 {
+	if v == nil {
+		return pgtype.Int4{Valid: false}
+	}
+	return pgtype.Int4{Int32: *v, Valid: true}
+}
+
+// toInt2 converts *int16 to pgtype.Int2
+func toInt2(v *int16) pgtype.Int2 {
+	if v == nil {
+		return pgtype.Int2{Valid: false}
+	}
+	return pgtype.Int2{Int16: *v, Valid: true}
+}
+
+// toBool converts *bool to pgtype.Bool
+func toBool(v *bool) pgtype.Bool {
+	if v == nil {
+		return pgtype.Bool{Valid: false}
+	}
+	return pgtype.Bool{Bool: *v, Valid: true}
+}
+
+// localDay returns midnight in loc of the calendar date of t
+func localDay(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// toDate converts the calendar date of t to pgtype.Date
+func toDate(t time.Time) pgtype.Date {
+	return pgtype.Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+}
+
 // ptr returns a pointer to the given value
 func ptr[T any](v T) *T {
 	return &v
 }
+
+// isUniqueViolation reports whether err is a PostgreSQL unique_violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isExclusionViolation reports whether err is a PostgreSQL exclusion_violation
+func isExclusionViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23P01"
+}
@@ -25,3 +25,13 @@ func HashPAT(rawToken string) string {
 	hashBytes := sha256.Sum256([]byte(rawToken))
 	return hex.EncodeToString(hashBytes[:])
 }
+
+// GenerateStreamTicket はストリームへの接続に使う使い捨てのチケットとそのハッシュを返す
+func GenerateStreamTicket() (ticket string, hash string, err error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	ticket = "st_" + hex.EncodeToString(bytes)
+	return ticket, HashPAT(ticket), nil
+}
@@ -0,0 +1,253 @@
+// Package filter はタスクの絞り込みに使う小さな式言語を構文解析する。
+//
+//	priority>=2 AND due<7d AND tag:exam AND NOT done
+//	(status:REVIEW OR status:WAITING) project:"卒業研究"
+//
+// 項を並べただけの場合は AND として扱う。SQLへの変換は利用側で行う。
+// 使える項目とキーワードは変換する側で決まる (タスクは repository.ListTasksWithFilter を参照)。
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Expr はフィルタ式の構文木
+type Expr interface {
+	expr()
+}
+
+type And struct{ Left, Right Expr }
+type Or struct{ Left, Right Expr }
+type Not struct{ X Expr }
+
+// Cond は比較 (field op value) または値を持たないキーワード (Op が空)
+type Cond struct {
+	Field string
+	Op    string
+	Value string
+	Pos   int
+}
+
+func (And) expr()  {}
+func (Or) expr()   {}
+func (Not) expr()  {}
+func (Cond) expr() {}
+
+// Error は式の位置付きのエラー
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("filter: %s (at %d)", e.Msg, e.Pos)
+}
+
+func Errorf(pos int, format string, args ...any) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// 式の長さの上限(巨大な式による負荷を避ける)
+const maxLength = 1000
+
+// Parse はフィルタ式を構文木に変換する
+func Parse(src string) (Expr, error) {
+	if len(src) > maxLength {
+		return nil, Errorf(0, "expression too long")
+	}
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return nil, Errorf(0, "empty expression")
+	}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, Errorf(t.pos, "unexpected %q", t.text)
+	}
+	return e, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.+", r)
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case r == '"':
+			start := i
+			i++
+			var b strings.Builder
+			for ; i < len(rs) && rs[i] != '"'; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					i++
+				}
+				b.WriteRune(rs[i])
+			}
+			if i >= len(rs) {
+				return nil, Errorf(start, "unterminated string")
+			}
+			i++
+			toks = append(toks, token{tokString, b.String(), start})
+		case strings.ContainsRune("<>=!:~", r):
+			start := i
+			op := string(r)
+			if i+1 < len(rs) && rs[i+1] == '=' && strings.ContainsRune("<>!", r) {
+				op += "="
+			}
+			if op == "!" {
+				return nil, Errorf(start, "unexpected %q", op)
+			}
+			i += len([]rune(op))
+			toks = append(toks, token{tokOp, op, start})
+		case isWordRune(r):
+			start := i
+			for i < len(rs) && isWordRune(rs[i]) {
+				i++
+			}
+			toks = append(toks, token{tokWord, string(rs[start:i]), start})
+		default:
+			return nil, Errorf(i, "unexpected %q", string(r))
+		}
+	}
+	return append(toks, token{tokEOF, "", len(rs)}), nil
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func isKeyword(t token, kw string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for isKeyword(p.peek(), "OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if isKeyword(t, "AND") {
+			p.next()
+		} else if t.kind == tokEOF || t.kind == tokRParen || isKeyword(t, "OR") {
+			return left, nil
+		}
+		// AND の省略(項の並び)
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if isKeyword(p.peek(), "NOT") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, Errorf(r.pos, "expected ')'")
+		}
+		return e, nil
+	case tokString:
+		// 引用符だけの項はタイトル・本文の部分一致
+		return Cond{Field: "text", Op: ":", Value: t.text, Pos: t.pos}, nil
+	case tokWord:
+		if isKeyword(t, "AND") || isKeyword(t, "OR") {
+			return nil, Errorf(t.pos, "unexpected %q", t.text)
+		}
+		if p.peek().kind != tokOp {
+			return Cond{Field: strings.ToLower(t.text), Pos: t.pos}, nil
+		}
+		op := p.next()
+		v := p.next()
+		if v.kind != tokWord && v.kind != tokString {
+			return nil, Errorf(v.pos, "expected value after %q", op.text)
+		}
+		return Cond{Field: strings.ToLower(t.text), Op: op.text, Value: v.text, Pos: t.pos}, nil
+	case tokEOF:
+		return nil, Errorf(t.pos, "unexpected end of expression")
+	default:
+		return nil, Errorf(t.pos, "unexpected %q", t.text)
+	}
+}
@@ -0,0 +1,50 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResolveTime は日時の値を now 基準で解釈する。
+//
+//	today / tomorrow / yesterday / now
+//	7d, -3d, 2w, 12h (now からの相対)
+//	2025-01-31 (その日の 0 時)
+func ResolveTime(v string, now time.Time) (time.Time, error) {
+	day := DayOf(now)
+	switch strings.ToLower(v) {
+	case "now":
+		return now, nil
+	case "today":
+		return day, nil
+	case "tomorrow":
+		return day.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return day.AddDate(0, 0, -1), nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", v, now.Location()); err == nil {
+		return t, nil
+	}
+
+	if len(v) >= 2 {
+		n, err := strconv.Atoi(v[:len(v)-1])
+		if err == nil {
+			switch v[len(v)-1] {
+			case 'h':
+				return now.Add(time.Duration(n) * time.Hour), nil
+			case 'd':
+				return now.AddDate(0, 0, n), nil
+			case 'w':
+				return now.AddDate(0, 0, 7*n), nil
+			}
+		}
+	}
+	return time.Time{}, &Error{Msg: "invalid time value " + strconv.Quote(v)}
+}
+
+// DayOf は t を含む日の 0 時を返す
+func DayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
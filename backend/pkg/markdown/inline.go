@@ -0,0 +1,254 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+// バックスラッシュでエスケープできる記号
+const escapable = "\\`*_{}[]()#+-.!~|>"
+
+// リンク ([[...]] と [text](url)) の閉じを探す範囲。
+// "[" ごとに行末まで探すと長い行で O(n^2) になるため、これより長いリンクは記法として扱わない
+const maxLinkLen = 2048
+
+// inline は行内の記法を変換する。記法に当たらない文字はすべてエスケープする
+func (r *renderer) inline(s string) string {
+	var b strings.Builder
+	plain := 0 // まだ書き出していない素の文字列の開始位置
+	var unclosed map[string]bool
+	flush := func(end int) {
+		b.WriteString(html.EscapeString(s[plain:end]))
+	}
+	emit := func(start, next int, out string) int {
+		flush(start)
+		b.WriteString(out)
+		plain = next
+		return next
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(escapable, s[i+1]) >= 0:
+			i = emit(i, i+2, html.EscapeString(s[i+1:i+2]))
+			continue
+
+		case c == '`':
+			n := 1
+			for i+n < len(s) && s[i+n] == '`' {
+				n++
+			}
+			fence := s[i : i+n]
+			if end := strings.Index(s[i+n:], fence); end >= 0 {
+				code := strings.TrimSpace(s[i+n : i+n+end])
+				i = emit(i, i+n+end+n, "<code>"+html.EscapeString(code)+"</code>")
+				continue
+			}
+			i += n
+			continue
+
+		case strings.HasPrefix(s[i:], "[["):
+			if end := strings.Index(lookahead(s[i+2:]), "]]"); end > 0 {
+				if link, ok := ParseWikiLink(s[i+2 : i+2+end]); ok {
+					i = emit(i, i+2+end+2, r.wikiLink(link))
+					continue
+				}
+			}
+
+		case c == '[':
+			if text, href, n, ok := parseLink(lookahead(s[i:])); ok {
+				out := r.inline(text)
+				if u, ok := safeURL(href); ok {
+					out = `<a href="` + html.EscapeString(u) + `"` + externalAttrs(u) + `>` + out + `</a>`
+				}
+				i = emit(i, i+n, out)
+				continue
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			if unclosed == nil {
+				unclosed = map[string]bool{}
+			}
+			if out, n, ok := r.emphasis(s, i, unclosed); ok {
+				i = emit(i, i+n, out)
+				continue
+			}
+
+		case c == 'h' && (strings.HasPrefix(s[i:], "http://") || strings.HasPrefix(s[i:], "https://")):
+			if i == 0 || s[i-1] >= 0x80 || !isWordByte(s[i-1]) {
+				u := autolinkEnd(s[i:])
+				i = emit(i, i+len(u), `<a href="`+html.EscapeString(u)+`"`+externalAttrs(u)+`>`+html.EscapeString(u)+`</a>`)
+				continue
+			}
+		}
+		i++
+	}
+	flush(len(s))
+	return b.String()
+}
+
+func (r *renderer) wikiLink(link WikiLink) string {
+	r.links = append(r.links, link)
+	label := html.EscapeString(link.Label)
+	if r.resolve != nil {
+		if href, ok := r.resolve(link); ok {
+			return `<a class="wikilink" data-kind="` + link.Kind + `" href="` + html.EscapeString(href) + `">` + label + `</a>`
+		}
+	}
+	return `<span class="wikilink wikilink-missing" data-kind="` + link.Kind + `">` + label + `</span>`
+}
+
+var emphasisTags = []struct {
+	delim string
+	tag   string
+}{
+	{"**", "strong"},
+	{"__", "strong"},
+	{"~~", "del"},
+	{"*", "em"},
+	{"_", "em"},
+}
+
+// emphasis は s[i:] が強調で始まっていれば変換結果と消費した長さを返す。
+// "_" は snake_case を誤って強調しないよう単語の境界でだけ扱う。
+// 閉じになれるかは位置だけで決まるので、閉じが見つからなかった記号はそれより後ろでも見つからない。
+// unclosed にそれを覚えて探し直さないことで、閉じのない記号が続く長い行でも O(n) で済ませる
+func (r *renderer) emphasis(s string, i int, unclosed map[string]bool) (string, int, bool) {
+	for _, e := range emphasisTags {
+		d := e.delim
+		if !strings.HasPrefix(s[i:], d) || unclosed[d] {
+			continue
+		}
+		underscore := d[0] == '_'
+		if underscore && i > 0 && isWordByte(s[i-1]) {
+			return "", 0, false
+		}
+		body := s[i+len(d):]
+		if body == "" || body[0] == ' ' {
+			continue
+		}
+		for from := 0; ; {
+			end := strings.Index(body[from:], d)
+			if end < 0 {
+				break
+			}
+			end += from
+			after := i + len(d) + end + len(d)
+			switch {
+			case len(d) == 1 && end+1 < len(body) && body[end+1] == d[0]:
+				// "*" の閉じとして "**" の片方を使わない
+				from = end + 2
+				continue
+			case end == 0 || body[end-1] == ' ':
+			case underscore && after < len(s) && isWordByte(s[after]):
+			default:
+				return "<" + e.tag + ">" + r.inline(body[:end]) + "</" + e.tag + ">", len(d) + end + len(d), true
+			}
+			from = end + 1
+		}
+		unclosed[d] = true
+	}
+	return "", 0, false
+}
+
+// lookahead はリンクの閉じを探す範囲に s を切り詰める
+func lookahead(s string) string {
+	if len(s) > maxLinkLen {
+		return s[:maxLinkLen]
+	}
+	return s
+}
+
+// parseLink は [text](url) を解釈する
+func parseLink(s string) (text, href string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				if i+1 >= len(s) || s[i+1] != '(' {
+					return "", "", 0, false
+				}
+				end := closingParen(s[i+2:])
+				if end < 0 {
+					return "", "", 0, false
+				}
+				href = strings.TrimSpace(s[i+2 : i+2+end])
+				// "タイトル" の指定は無視する
+				if sp := strings.IndexByte(href, ' '); sp >= 0 {
+					href = href[:sp]
+				}
+				return s[1:i], strings.Trim(href, "<>"), i + 2 + end + 1, true
+			}
+		}
+	}
+	return "", "", 0, false
+}
+
+// closingParen は対応する ")" の位置を返す(URL 内の括弧の組は読み飛ばす)
+func closingParen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+// safeURL は許可したスキームと相対パスだけを通す
+func safeURL(u string) (string, bool) {
+	lower := strings.ToLower(u)
+	for _, p := range []string{"http://", "https://", "mailto:"} {
+		if strings.HasPrefix(lower, p) {
+			return u, true
+		}
+	}
+	if (strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//")) || strings.HasPrefix(u, "#") {
+		return u, true
+	}
+	return "", false
+}
+
+func externalAttrs(u string) string {
+	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return ` rel="noopener noreferrer nofollow" target="_blank"`
+	}
+	return ""
+}
+
+// autolinkEnd は URL として扱う範囲を返す(末尾の句読点や閉じ括弧、ASCII 以外の文字は含めない)
+func autolinkEnd(s string) string {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return r >= 0x80 || strings.ContainsRune(" <>\"'`", r)
+	})
+	if end < 0 {
+		end = len(s)
+	}
+	u := s[:end]
+	for len(u) > 0 && strings.ContainsRune(".,;:!?)]", rune(u[len(u)-1])) {
+		if u[len(u)-1] == ')' && strings.Count(u, "(") >= strings.Count(u, ")") {
+			break
+		}
+		u = u[:len(u)-1]
+	}
+	return u
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c >= 0x80
+}
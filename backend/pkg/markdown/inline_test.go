@@ -0,0 +1,48 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderEmphasis(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"**bold** and *em*", "<p><strong>bold</strong> and <em>em</em></p>\n"},
+		{"~~gone~~", "<p><del>gone</del></p>\n"},
+		{"_em_ but snake_case_name", "<p><em>em</em> but snake_case_name</p>\n"},
+		{"a * not em *", "<p>a * not em *</p>\n"},
+		{"*" + strings.Repeat("a", 10000) + "*", "<p><em>" + strings.Repeat("a", 10000) + "</em></p>\n"},
+		{"*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>\n"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.src, nil); got != tt.want {
+			t.Errorf("Render(%.40q) = %.80q, want %.80q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// 閉じのない記法が続く長い行でも、入力の上限の大きさを短い時間で変換できる
+func TestRenderPathologicalInput(t *testing.T) {
+	inputs := map[string]string{
+		"star":       strings.Repeat("*a ", MaxLen/3),
+		"underscore": strings.Repeat("_a ", MaxLen/3),
+		"mixed":      strings.Repeat("*a _b ~~c ", MaxLen/10),
+		"nested":     strings.Repeat("**a *b ", MaxLen/7),
+		"link":       strings.Repeat("[a ", MaxLen/3),
+		"wikilink":   strings.Repeat("[[a ", MaxLen/4),
+	}
+	for name, src := range inputs {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			Render(src, nil)
+			WikiLinks(src)
+			if d := time.Since(start); d > 2*time.Second {
+				t.Fatalf("rendering %d bytes took %v", len(src), d)
+			}
+		})
+	}
+}
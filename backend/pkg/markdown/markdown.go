@@ -0,0 +1,325 @@
+// Package markdown はノート用の Markdown のサブセットを HTML に変換する。
+//
+// 生の HTML は一切通さず、すべての文字列をエスケープして出力する。リンク先は
+// http / https / mailto と相対パスだけを許す。対応する記法は見出し、段落、強調、
+// 打ち消し線、インラインコード、コードブロック、引用、区切り線、リスト、
+// タスクリスト(- [ ] / - [x])、リンク、URL の自動リンク、wiki リンク([[...]])。
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 入力の上限。超えた分は切り捨てる
+const MaxLen = 200_000
+
+// WikiLink は [[Target]] / [[project:Target|Label]] 形式の参照
+type WikiLink struct {
+	Kind   string `json:"kind"` // task / project
+	Target string `json:"target"`
+	Label  string `json:"label"`
+}
+
+const (
+	KindTask    = "task"
+	KindProject = "project"
+)
+
+// TaskItem はタスクリストの1項目
+type TaskItem struct {
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// Resolver は wiki リンクのリンク先を返す。解決できなければ ok=false
+type Resolver func(link WikiLink) (href string, ok bool)
+
+// Render は src を HTML に変換する。resolve が nil なら wiki リンクはすべて未解決として出力する
+func Render(src string, resolve Resolver) string {
+	r := &renderer{resolve: resolve}
+	r.blocks(splitLines(src))
+	return r.out.String()
+}
+
+// WikiLinks は src に含まれる wiki リンクを出現順に重複なく返す(コード中のものは除く)
+func WikiLinks(src string) []WikiLink {
+	r := &renderer{}
+	r.blocks(splitLines(src))
+	seen := map[string]bool{}
+	var links []WikiLink
+	for _, l := range r.links {
+		key := l.Kind + ":" + strings.ToLower(l.Target)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		links = append(links, l)
+	}
+	return links
+}
+
+// TaskItems は src のタスクリストの項目を出現順に返す
+func TaskItems(src string) []TaskItem {
+	r := &renderer{}
+	r.blocks(splitLines(src))
+	return r.items
+}
+
+// ParseWikiLink は [[ ]] の内側を解釈する
+func ParseWikiLink(inner string) (WikiLink, bool) {
+	target, label, _ := strings.Cut(inner, "|")
+	link := WikiLink{Kind: KindTask}
+	if kind, rest, ok := strings.Cut(target, ":"); ok {
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case KindProject:
+			link.Kind, target = KindProject, rest
+		case KindTask:
+			target = rest
+		}
+	}
+	link.Target = strings.TrimSpace(target)
+	link.Label = strings.TrimSpace(label)
+	if link.Label == "" {
+		link.Label = link.Target
+	}
+	return link, link.Target != ""
+}
+
+func splitLines(src string) []string {
+	if len(src) > MaxLen {
+		src = src[:MaxLen]
+	}
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	return strings.Split(src, "\n")
+}
+
+type renderer struct {
+	resolve Resolver
+	out     strings.Builder
+	links   []WikiLink
+	items   []TaskItem
+}
+
+var (
+	headingRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*?))?[ #]*$`)
+	hrRe      = regexp.MustCompile(`^ {0,3}(?:(?:- *){3,}|(?:\* *){3,}|(?:_ *){3,})$`)
+	fenceRe   = regexp.MustCompile("^ {0,3}(```+|~~~+)[ ]*([^`\\s]*)")
+	listRe    = regexp.MustCompile(`^( {0,3})([-*+]|(\d{1,9})[.)])( +|$)`)
+)
+
+// listMarker はリスト項目の行なら種類と本文の開始位置を返す
+func listMarker(line string) (ordered bool, start int, offset int, ok bool) {
+	m := listRe.FindStringSubmatchIndex(line)
+	if m == nil || hrRe.MatchString(line) {
+		return false, 0, 0, false
+	}
+	if m[6] >= 0 {
+		ordered = true
+		start, _ = strconv.Atoi(line[m[6]:m[7]])
+	}
+	return ordered, start, m[1], true
+}
+
+// startsBlock は段落を打ち切る行か
+func startsBlock(line string) bool {
+	if headingRe.MatchString(line) || hrRe.MatchString(line) || fenceRe.MatchString(line) {
+		return true
+	}
+	if strings.HasPrefix(strings.TrimLeft(line, " "), ">") {
+		return true
+	}
+	_, _, _, ok := listMarker(line)
+	return ok
+}
+
+func (r *renderer) blocks(lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			i++
+
+		case fenceRe.MatchString(line):
+			m := fenceRe.FindStringSubmatch(line)
+			fence := m[1]
+			j := i + 1
+			for j < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[j]), fence) {
+				j++
+			}
+			if m[2] != "" {
+				r.out.WriteString(`<pre><code class="language-` + html.EscapeString(m[2]) + `">`)
+			} else {
+				r.out.WriteString("<pre><code>")
+			}
+			if j > i+1 {
+				r.out.WriteString(html.EscapeString(strings.Join(lines[i+1:j], "\n")) + "\n")
+			}
+			r.out.WriteString("</code></pre>\n")
+			i = j + 1
+
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			n := strconv.Itoa(len(m[1]))
+			r.out.WriteString("<h" + n + ">" + r.inline(m[2]) + "</h" + n + ">\n")
+			i++
+
+		case hrRe.MatchString(line):
+			r.out.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(strings.TrimLeft(line, " "), ">"):
+			var inner []string
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+				l := strings.TrimLeft(lines[i], " ")
+				if !strings.HasPrefix(l, ">") && startsBlock(lines[i]) {
+					break
+				}
+				l = strings.TrimPrefix(l, ">")
+				inner = append(inner, strings.TrimPrefix(l, " "))
+				i++
+			}
+			r.out.WriteString("<blockquote>\n")
+			r.blocks(inner)
+			r.out.WriteString("</blockquote>\n")
+
+		default:
+			if _, _, _, ok := listMarker(line); ok {
+				i = r.list(lines, i)
+				continue
+			}
+			j := i + 1
+			for j < len(lines) && strings.TrimSpace(lines[j]) != "" && !startsBlock(lines[j]) {
+				j++
+			}
+			r.out.WriteString("<p>" + r.paragraph(lines[i:j]) + "</p>\n")
+			i = j
+		}
+	}
+}
+
+// paragraph は行末の2つ以上の空白を改行として扱う
+func (r *renderer) paragraph(lines []string) string {
+	var b strings.Builder
+	for k, l := range lines {
+		b.WriteString(r.inline(strings.TrimSpace(l)))
+		if k < len(lines)-1 {
+			if strings.HasSuffix(l, "  ") {
+				b.WriteString("<br>")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// list は i 行目から始まるリストを出力し、次の行の位置を返す
+func (r *renderer) list(lines []string, i int) int {
+	ordered, start, _, _ := listMarker(lines[i])
+	var items [][]string
+	for i < len(lines) {
+		o, _, offset, ok := listMarker(lines[i])
+		if !ok || o != ordered {
+			break
+		}
+		item := []string{lines[i][offset:]}
+		i++
+		for i < len(lines) {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" {
+				// 空行の後も字下げが続けば同じ項目
+				if i+1 < len(lines) && indent(lines[i+1]) >= offset {
+					item = append(item, "")
+					i++
+					continue
+				}
+				break
+			}
+			if indent(l) >= offset {
+				item = append(item, l[offset:])
+			} else if !startsBlock(l) && strings.TrimSpace(item[len(item)-1]) != "" {
+				item = append(item, strings.TrimSpace(l))
+			} else {
+				break
+			}
+			i++
+		}
+		items = append(items, item)
+		// 項目の間の空行
+		if i+1 < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			if o, _, _, ok := listMarker(lines[i+1]); ok && o == ordered {
+				i++
+			}
+		}
+	}
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	if ordered && start != 1 {
+		r.out.WriteString(`<ol start="` + strconv.Itoa(start) + `">` + "\n")
+	} else {
+		r.out.WriteString("<" + tag + ">\n")
+	}
+	for _, item := range items {
+		r.listItem(item)
+	}
+	r.out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func (r *renderer) listItem(lines []string) {
+	checkbox := ""
+	if text, done, ok := taskItem(lines[0]); ok {
+		checkbox = `<input type="checkbox" disabled> `
+		if done {
+			checkbox = `<input type="checkbox" disabled checked> `
+		}
+		lines[0] = text
+		r.items = append(r.items, TaskItem{Text: text, Done: done})
+	}
+	if checkbox != "" {
+		r.out.WriteString(`<li class="task-list-item">` + checkbox)
+	} else {
+		r.out.WriteString("<li>")
+	}
+
+	// 最初の段落は <p> で囲まず、続くブロック(入れ子のリストなど)はそのまま出力する
+	j := 1
+	for j < len(lines) && strings.TrimSpace(lines[j]) != "" && !startsBlock(lines[j]) {
+		j++
+	}
+	if startsBlock(lines[0]) && checkbox == "" {
+		j = 0
+	}
+	r.out.WriteString(r.paragraph(lines[:j]))
+	if j < len(lines) {
+		r.out.WriteString("\n")
+		r.blocks(lines[j:])
+	}
+	r.out.WriteString("</li>\n")
+}
+
+// taskItem は "[ ] 本文" / "[x] 本文" を解釈する
+func taskItem(line string) (text string, done bool, ok bool) {
+	if len(line) < 3 || line[0] != '[' || line[2] != ']' || (len(line) > 3 && line[3] != ' ') {
+		return "", false, false
+	}
+	switch line[1] {
+	case ' ':
+	case 'x', 'X':
+		done = true
+	default:
+		return "", false, false
+	}
+	return strings.TrimSpace(line[3:]), done, true
+}
+
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
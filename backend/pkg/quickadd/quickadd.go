@@ -0,0 +1,310 @@
+// Package quickadd は1行の自然文からタスク・予定の内容を読み取る。
+//
+//	Report for Algorithms tomorrow 17:00 !2 #exam @Lab
+//	来週月曜 10時から12時 ゼミ @研究室
+//
+// @プロジェクト、#タグ、!優先度、日付・時刻の表現を取り除いた残りをタイトルとする。
+// 時刻の範囲が指定された場合は予定、それ以外はタスクとして扱う。
+package quickadd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindTask  Kind = "task"
+	KindEvent Kind = "event"
+)
+
+type Result struct {
+	Kind     Kind
+	Title    string
+	Project  string
+	Tags     []string
+	Priority *int16
+
+	// Start はタスクの期限または予定の開始。HasTime が false の場合は日付のみ(0時)
+	Start   *time.Time
+	End     *time.Time
+	HasTime bool
+}
+
+// 各パターンの最初のグループは境界で、取り除く範囲に含めない
+var (
+	projectRe  = regexp.MustCompile(`(^|\s)[@＠](?:"([^"]+)"|(\S+))`)
+	tagRe      = regexp.MustCompile(`(^|\s)[#＃]([^\s#＃]+)`)
+	priorityRe = regexp.MustCompile(`(^|\s)[!！]([0-3])\b`)
+
+	isoDateRe    = regexp.MustCompile(`(^|[^\d])(\d{4})[-/](\d{1,2})[-/](\d{1,2})(?:\s*(?:までに|まで|に))?`)
+	jaDateRe     = regexp.MustCompile(`(^|[^\d])(?:(\d{4})年)?(\d{1,2})月(\d{1,2})日(?:\s*(?:までに|まで|に))?`)
+	slashDateRe  = regexp.MustCompile(`(?i)(^|[^\d:/])(?:(?:by|on|due)\s+)?(\d{1,2})/(\d{1,2})\b(?:\s*(?:までに|まで|に))?`)
+	jaWeekdayRe  = regexp.MustCompile(`()(今週|来週|再来週)?の?([月火水木金土日])曜日?(?:\s*(?:までに|まで|に))?`)
+	jaWeekRe     = regexp.MustCompile(`()(再来週|来週)(?:\s*(?:までに|まで|に))?`)
+	jaRelativeRe = regexp.MustCompile(`()(明後日|あさって|明日|あした|今日|本日|今夜|今晩)(?:\s*(?:までに|まで|に))?`)
+	jaAfterRe    = regexp.MustCompile(`(^|[^\d])(\d+)(日|週間)後(?:\s*(?:までに|まで|に))?`)
+	enRelativeRe = regexp.MustCompile(`(?i)(^|\s)(?:(?:by|on|due)\s+)?(day after tomorrow|tomorrow|tmr|today|tonight)\b`)
+	enWeekdayRe  = regexp.MustCompile(`(?i)(^|\s)(?:(?:by|on|due)\s+)?(?:(next|this)\s+)?(monday|mon|tuesday|tues|tue|wednesday|wed|thursday|thurs|thu|friday|fri|saturday|sat|sunday|sun)\b`)
+	enInRe       = regexp.MustCompile(`(?i)(^|\s)in\s+(\d+)\s*(days?|weeks?|d|w)\b`)
+	enWeekRe     = regexp.MustCompile(`(?i)(^|\s)(?:by\s+)?next\s+week\b`)
+
+	// 17:00 / 5pm / 17時30分 / 午後5時半
+	clock        = `(午前|午後)?\s*(\d{1,2})(?::(\d{2})|(時)(?:(\d{1,2})分|(半))?)?(?:\s*([aApP][mM])\b)?`
+	timeRangeRe  = regexp.MustCompile(`(?i)(^|[^\d:])(?:(?:at|from)\s+)?` + clock + `\s*(?:-|~|〜|～|から|to)\s*` + clock + `(?:\s*まで)?`)
+	timeSingleRe = regexp.MustCompile(`(?i)(^|[^\d:])(?:(?:at|by|due)\s+)?` + clock + `(?:\s*(?:までに|まで|に))?`)
+)
+
+var jaWeekdays = map[string]time.Weekday{
+	"日": time.Sunday, "月": time.Monday, "火": time.Tuesday, "水": time.Wednesday,
+	"木": time.Thursday, "金": time.Friday, "土": time.Saturday,
+}
+
+var enWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Parse は text を now (利用者のタイムゾーン) 基準で解釈する
+func Parse(text string, now time.Time) Result {
+	p := &parser{s: text, today: startOfDay(now)}
+	var res Result
+
+	if m := p.take(projectRe, nil); m != nil {
+		res.Project = m[2] + m[3]
+	}
+	for m := p.take(tagRe, nil); m != nil; m = p.take(tagRe, nil) {
+		res.Tags = append(res.Tags, m[2])
+	}
+	if m := p.take(priorityRe, nil); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		v := int16(n)
+		res.Priority = &v
+	}
+
+	date, hasDate := p.takeDate()
+
+	var start, end *clockTime
+	if m := p.take(timeRangeRe, func(m []string) bool {
+		return parseClock(m[2:9]) != nil && parseClock(m[9:16]) != nil
+	}); m != nil {
+		start, end = parseClock(m[2:9]), parseClock(m[9:16])
+		// 「午後1時から3時」のように後ろの時刻が午前/午後を省略した場合は前に合わせる。
+		// 24時間表記 (23:00-01:00) では足さず、下で翌日として扱う
+		startPM := m[2] == "午後" || strings.EqualFold(m[8], "pm")
+		if startPM && end.hour < start.hour && end.hour < 12 && m[9] == "" && m[15] == "" {
+			end.hour += 12
+		}
+	} else if m := p.take(timeSingleRe, func(m []string) bool {
+		return parseClock(m[2:9]) != nil
+	}); m != nil {
+		start = parseClock(m[2:9])
+	}
+
+	if start != nil {
+		if !hasDate {
+			date = p.today
+		}
+		s := date.Add(time.Duration(start.hour)*time.Hour + time.Duration(start.minute)*time.Minute)
+		res.Start = &s
+		res.HasTime = true
+		if end != nil {
+			e := date.Add(time.Duration(end.hour)*time.Hour + time.Duration(end.minute)*time.Minute)
+			if !e.After(s) {
+				e = e.AddDate(0, 0, 1)
+			}
+			res.End = &e
+		}
+	} else if hasDate {
+		res.Start = &date
+	}
+
+	res.Kind = KindTask
+	if res.End != nil {
+		res.Kind = KindEvent
+	}
+	res.Title = strings.Join(strings.Fields(p.s), " ")
+	return res
+}
+
+type parser struct {
+	s     string
+	today time.Time
+}
+
+// take は re に一致し accept を満たす最初の箇所を取り除き、サブマッチを返す
+func (p *parser) take(re *regexp.Regexp, accept func(m []string) bool) []string {
+	for _, idx := range re.FindAllStringSubmatchIndex(p.s, -1) {
+		m := make([]string, len(idx)/2)
+		for i := range m {
+			if idx[2*i] >= 0 {
+				m[i] = p.s[idx[2*i]:idx[2*i+1]]
+			}
+		}
+		if accept != nil && !accept(m) {
+			continue
+		}
+		p.s = p.s[:idx[3]] + " " + p.s[idx[1]:]
+		return m
+	}
+	return nil
+}
+
+// takeDate は最初に見つかった日付表現を1つだけ取り除く
+func (p *parser) takeDate() (time.Time, bool) {
+	today := p.today
+	if m := p.take(isoDateRe, func(m []string) bool { return validDate(m[2], m[3], m[4]) }); m != nil {
+		return makeDate(today, m[2], m[3], m[4]), true
+	}
+	if m := p.take(jaDateRe, func(m []string) bool { return validDate(m[2], m[3], m[4]) }); m != nil {
+		return upcomingDate(today, m[2], m[3], m[4]), true
+	}
+	if m := p.take(slashDateRe, func(m []string) bool { return validDate("", m[2], m[3]) }); m != nil {
+		return upcomingDate(today, "", m[2], m[3]), true
+	}
+	if m := p.take(jaWeekdayRe, nil); m != nil {
+		wd := jaWeekdays[m[3]]
+		switch m[2] {
+		case "今週":
+			return weekOf(today, 0, wd), true
+		case "来週":
+			return weekOf(today, 1, wd), true
+		case "再来週":
+			return weekOf(today, 2, wd), true
+		}
+		return nextWeekday(today, wd), true
+	}
+	if m := p.take(jaWeekRe, nil); m != nil {
+		if m[2] == "再来週" {
+			return weekOf(today, 2, time.Monday), true
+		}
+		return weekOf(today, 1, time.Monday), true
+	}
+	if m := p.take(jaRelativeRe, nil); m != nil {
+		switch m[2] {
+		case "明日", "あした":
+			return today.AddDate(0, 0, 1), true
+		case "明後日", "あさって":
+			return today.AddDate(0, 0, 2), true
+		}
+		return today, true
+	}
+	if m := p.take(jaAfterRe, nil); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		if m[3] == "週間" {
+			n *= 7
+		}
+		return today.AddDate(0, 0, n), true
+	}
+	if m := p.take(enRelativeRe, nil); m != nil {
+		switch strings.ToLower(m[2]) {
+		case "tomorrow", "tmr":
+			return today.AddDate(0, 0, 1), true
+		case "day after tomorrow":
+			return today.AddDate(0, 0, 2), true
+		}
+		return today, true
+	}
+	if m := p.take(enWeekdayRe, nil); m != nil {
+		wd := enWeekdays[strings.ToLower(m[3])[:3]]
+		switch strings.ToLower(m[2]) {
+		case "next":
+			return weekOf(today, 1, wd), true
+		case "this":
+			return weekOf(today, 0, wd), true
+		}
+		return nextWeekday(today, wd), true
+	}
+	if m := p.take(enInRe, nil); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		if strings.HasPrefix(strings.ToLower(m[3]), "w") {
+			n *= 7
+		}
+		return today.AddDate(0, 0, n), true
+	}
+	if m := p.take(enWeekRe, nil); m != nil {
+		return weekOf(today, 1, time.Monday), true
+	}
+	return time.Time{}, false
+}
+
+type clockTime struct {
+	hour, minute int
+}
+
+// parseClock は clock のサブマッチ (午前午後, 時, :分, 時, 分, 半, am/pm) を解釈する
+// 区切り(: や 時)も am/pm もない単なる数字は時刻とみなさない
+func parseClock(m []string) *clockTime {
+	jaAmPm, hour, colonMin, ji, jiMin, half, amPm := m[0], m[1], m[2], m[3], m[4], m[5], strings.ToLower(m[6])
+	if colonMin == "" && ji == "" && amPm == "" {
+		return nil
+	}
+	h, _ := strconv.Atoi(hour)
+	min := 0
+	switch {
+	case colonMin != "":
+		min, _ = strconv.Atoi(colonMin)
+	case jiMin != "":
+		min, _ = strconv.Atoi(jiMin)
+	case half != "":
+		min = 30
+	}
+	if jaAmPm != "" || amPm != "" {
+		if h < 1 || h > 12 {
+			return nil
+		}
+		if h == 12 {
+			h = 0
+		}
+		if jaAmPm == "午後" || amPm == "pm" {
+			h += 12
+		}
+	}
+	if h > 24 || min > 59 || (h == 24 && min > 0) {
+		return nil
+	}
+	return &clockTime{hour: h, minute: min}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func validDate(y, m, d string) bool {
+	month, _ := strconv.Atoi(m)
+	day, _ := strconv.Atoi(d)
+	return month >= 1 && month <= 12 && day >= 1 && day <= 31
+}
+
+func makeDate(today time.Time, y, m, d string) time.Time {
+	year := today.Year()
+	if y != "" {
+		year, _ = strconv.Atoi(y)
+	}
+	month, _ := strconv.Atoi(m)
+	day, _ := strconv.Atoi(d)
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, today.Location())
+}
+
+// upcomingDate は年の省略された日付を今日以降で最も近い日とする
+func upcomingDate(today time.Time, y, m, d string) time.Time {
+	t := makeDate(today, y, m, d)
+	if y == "" && t.Before(today) {
+		t = t.AddDate(1, 0, 0)
+	}
+	return t
+}
+
+// nextWeekday は今日以降で最初の wd の日
+func nextWeekday(today time.Time, wd time.Weekday) time.Time {
+	return today.AddDate(0, 0, (int(wd)-int(today.Weekday())+7)%7)
+}
+
+// weekOf は今週(月曜始まり)から weeks 週後の wd の日
+func weekOf(today time.Time, weeks int, wd time.Weekday) time.Time {
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, 7*weeks+(int(wd)+6)%7)
+}
@@ -0,0 +1,112 @@
+// Package rank はドラッグ&ドロップの並び替えに使う辞書順の順位キーを生成する。
+// キーは digits の文字だけで構成され、末尾に最小の文字を持たない。
+// 任意の2つのキーの間に常に新しいキーを作れるため、移動は1行の更新で済む。
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+var ErrInvalidRange = errors.New("rank: invalid range")
+
+// Between は prev と next の間に並ぶキーを返す。
+// 空文字はそれぞれ先頭・末尾(上下限なし)を意味する。
+func Between(prev, next string) (string, error) {
+	if next != "" && prev >= next {
+		return "", ErrInvalidRange
+	}
+	if !valid(prev) || !valid(next) {
+		return "", ErrInvalidRange
+	}
+	return midpoint(prev, next), nil
+}
+
+// After は prev の後ろに並ぶキーを返す
+func After(prev string) (string, error) {
+	return Between(prev, "")
+}
+
+// Sequence は n 個の等間隔なキーを昇順で返す(並び順の振り直し用)
+func Sequence(n int) []string {
+	keys := make([]string, 0, n)
+	width, span := 1, len(digits)
+	for span < (n+1)*2 {
+		width++
+		span *= len(digits)
+	}
+	step := span / (n + 1)
+	for i := 1; i <= n; i++ {
+		keys = append(keys, encode(i*step, width))
+	}
+	return keys
+}
+
+func valid(key string) bool {
+	if key == "" {
+		return true
+	}
+	for _, r := range key {
+		if !strings.ContainsRune(digits, r) {
+			return false
+		}
+	}
+	return key[len(key)-1] != digits[0]
+}
+
+// midpoint は a < b (b が空なら上限なし) を満たす2つのキーの中間を求める
+func midpoint(a, b string) string {
+	if b != "" {
+		// 共通の接頭辞はそのまま残す
+		n := 0
+		for n < len(b) && charAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	da := 0
+	if a != "" {
+		da = strings.IndexByte(digits, a[0])
+	}
+	db := len(digits)
+	if b != "" {
+		db = strings.IndexByte(digits, b[0])
+	}
+	if db-da > 1 {
+		return string(digits[(da+db+1)/2])
+	}
+	// 先頭の桁が隣り合っている場合は桁を増やす
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[da]) + midpoint(rest, "")
+}
+
+func charAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return digits[0]
+}
+
+func encode(v, width int) string {
+	buf := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		buf[i] = digits[v%len(digits)]
+		v /= len(digits)
+	}
+	return strings.TrimRight(string(buf), digits[:1])
+}
@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "ap-northeast-1"
+	testBucket    = "attachments"
+)
+
+// fakeS3 は S3 互換 API の最小限の代役。署名を検証し、オブジェクトをメモリに置く
+type fakeS3 struct {
+	secretKey string
+	pathStyle bool
+
+	mu       sync.Mutex
+	objects  map[string]fakeObject
+	requests int
+}
+
+type fakeObject struct {
+	body        []byte
+	contentType string
+}
+
+func newFakeS3(pathStyle bool) *fakeS3 {
+	return &fakeS3{secretKey: testSecretKey, pathStyle: pathStyle, objects: map[string]fakeObject{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if msg := f.verify(r); msg != "" {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+msg+"</Message></Error>", http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if f.pathStyle {
+		bucket, rest, _ := strings.Cut(key, "/")
+		if bucket != testBucket {
+			http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+			return
+		}
+		key = rest
+	} else if host, _, _ := strings.Cut(r.Host, ":"); !strings.HasPrefix(host, testBucket+".") {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) != r.ContentLength {
+			http.Error(w, "<Error><Code>IncompleteBody</Code></Error>", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = fakeObject{body: body, contentType: r.Header.Get("Content-Type")}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		obj, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		_, _ = w.Write(obj.body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify はサーバー側で受け取ったリクエストから Signature Version 4 の署名を計算し直して比べる
+func (f *fakeS3) verify(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	const prefix = "AWS4-HMAC-SHA256 "
+	if !strings.HasPrefix(auth, prefix) {
+		return "missing authorization"
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(auth, prefix), ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		fields[k] = v
+	}
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 5 || credential[0] != testAccessKey || credential[2] != testRegion ||
+		credential[3] != "s3" || credential[4] != "aws4_request" {
+		return "bad credential " + fields["Credential"]
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, credential[1]) {
+		return "credential date does not match x-amz-date"
+	}
+
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signed) {
+		return "signed headers are not sorted"
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range signed {
+		v := r.Header.Get(name)
+		if name == "host" {
+			v = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(v) + "\n")
+	}
+	for _, required := range []string{"host", "x-amz-date", "x-amz-content-sha256"} {
+		if !strings.Contains(";"+fields["SignedHeaders"]+";", ";"+required+";") {
+			return required + " is not signed"
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	scope := strings.Join(credential[1:], "/")
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+f.secretKey), credential[1])
+	key = hmacSHA256(key, credential[2])
+	key = hmacSHA256(key, credential[3])
+	key = hmacSHA256(key, credential[4])
+	if want := hex.EncodeToString(hmacSHA256(key, stringToSign)); fields["Signature"] != want {
+		return "signature mismatch"
+	}
+	return ""
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// newTestS3 は fakeS3 に向けた S3 を返す。仮想ホスト形式でも接続先はテスト用のサーバーにする
+func newTestS3(t *testing.T, f *fakeS3, secretKey string) *S3 {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	endpoint := srv.URL
+	if !f.pathStyle {
+		endpoint = strings.Replace(srv.URL, "127.0.0.1", "s3.test", 1)
+	}
+	s, err := NewS3(S3Config{
+		Endpoint:  endpoint,
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secretKey,
+		PathStyle: f.pathStyle,
+	})
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	addr := srv.Listener.Addr().String()
+	s.client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	s.now = func() time.Time { return time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func TestS3PutGetDelete(t *testing.T) {
+	for _, pathStyle := range []bool{true, false} {
+		name := "virtual-hosted"
+		if pathStyle {
+			name = "path-style"
+		}
+		t.Run(name, func(t *testing.T) {
+			f := newFakeS3(pathStyle)
+			s := newTestS3(t, f, testSecretKey)
+			ctx := context.Background()
+			// 署名の正規化でエンコードが必要な文字を含むキー
+			key := "users/42/添付 ファイル+(1).txt"
+			body := []byte("hello, storage")
+
+			if err := s.Put(ctx, key, bytes.NewReader(body), int64(len(body)), "text/plain"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if got := f.objects[key]; !bytes.Equal(got.body, body) || got.contentType != "text/plain" {
+				t.Fatalf("stored object = %q (%s), want %q (text/plain)", got.body, got.contentType, body)
+			}
+
+			rc, err := s.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			got, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil || !bytes.Equal(got, body) {
+				t.Fatalf("Get = %q, %v; want %q", got, err, body)
+			}
+
+			if err := s.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get after Delete = %v, want ErrNotFound", err)
+			}
+			// 存在しないキーの削除はエラーにしない
+			if err := s.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete missing: %v", err)
+			}
+		})
+	}
+}
+
+func TestS3RejectsWrongSignature(t *testing.T) {
+	f := newFakeS3(true)
+	s := newTestS3(t, f, "wrong-secret")
+	ctx := context.Background()
+
+	err := s.Put(ctx, "a.txt", strings.NewReader("x"), 1, "")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Put with wrong secret = %v, want 403 error", err)
+	}
+	if _, err := s.Get(ctx, "a.txt"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get with wrong secret = %v, want non-not-found error", err)
+	}
+}
+
+func TestS3InvalidKey(t *testing.T) {
+	f := newFakeS3(true)
+	s := newTestS3(t, f, testSecretKey)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/abs", "a/../b", "a//b", `a\b`, "."} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put(%q) succeeded, want error", key)
+		}
+		if _, err := s.Get(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) = %v, want invalid key error", key, err)
+		}
+		if err := s.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded, want error", key)
+		}
+	}
+	if f.requests != 0 {
+		t.Fatalf("sent %d requests for invalid keys, want 0", f.requests)
+	}
+}
+
+func TestNewS3Config(t *testing.T) {
+	if _, err := NewS3(S3Config{Endpoint: "localhost:9000", Bucket: "b"}); err == nil {
+		t.Error("endpoint without scheme accepted")
+	}
+	if _, err := NewS3(S3Config{Endpoint: "http://localhost:9000"}); err == nil {
+		t.Error("empty bucket accepted")
+	}
+	s, err := NewS3(S3Config{Endpoint: "http://localhost:9000/", Bucket: "b"})
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	if s.cfg.Region != "us-east-1" {
+		t.Errorf("default region = %q, want us-east-1", s.cfg.Region)
+	}
+}
@@ -0,0 +1,35 @@
+// Package storage は添付ファイルなどのバイナリを保存する先を抽象化する
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound はキーに対応するオブジェクトが存在しない
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage はキーでオブジェクトを読み書きする保存先
+type Storage interface {
+	// Put は r から size バイトを読み取り key に保存する
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get は key のオブジェクトを開く。呼び出し側が Close する
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete は key のオブジェクトを削除する。存在しなくてもエラーにしない
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey は "/" 区切りの相対パスとして安全なキーかを確かめる
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}